@@ -84,7 +84,9 @@ env GOOS=$GOOS GOARCH=$GOARCH go build -ldflags \
     "-X github.com/openebs/maya/pkg/version.GitCommit=${GIT_COMMIT} \
     -X main.CtlName='${CTLNAME}' \
     -X github.com/openebs/maya/pkg/version.Version=${VERSION} \
-    -X github.com/openebs/maya/pkg/version.VersionMeta=${VERSION_META}"\
+    -X github.com/openebs/maya/pkg/version.VersionMeta=${VERSION_META} \
+    -X github.com/openebs/maya/cmd/maya-exporter/app/collector.GitCommit=${GIT_COMMIT} \
+    -X github.com/openebs/maya/cmd/maya-exporter/app/collector.Version=${VERSION}"\
     -o $output_name\
     ./cmd/${CTLNAME}
 if [ "${PNAME}" = "cstor-volume-mgmt" ]; then
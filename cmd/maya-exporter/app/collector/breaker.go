@@ -0,0 +1,72 @@
+package collector
+
+import (
+	"sync"
+	"time"
+)
+
+// circuitBreaker skips the collection of the stats of a volume once its
+// collections have failed threshold times in a row, so that a volume
+// controller which is down doesn't slow down every scrape. The breaker is
+// open until cooldown has passed since the last failure, then a single
+// collection probes the volume: the breaker is closed if it succeeds and
+// stays open for another cooldown if it fails. It is safe for concurrent
+// use.
+type circuitBreaker struct {
+	mu sync.Mutex
+	// failures is the no of failed collections in a row, failedAt is
+	// the time of the last failure.
+	failures int
+	failedAt time.Time
+	// probing is true while the collection which probes the volume of
+	// an open breaker is in progress.
+	probing bool
+}
+
+// allow returns true if the stats of the volume are to be collected at
+// now, false if the breaker is open. The breaker is disabled if the
+// threshold is not positive.
+func (b *circuitBreaker) allow(now time.Time, threshold int, cooldown time.Duration) bool {
+	if threshold <= 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < threshold {
+		return true
+	}
+	if b.probing || now.Sub(b.failedAt) < cooldown {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// failed records a failed collection at now and returns true if it has
+// opened the breaker.
+func (b *circuitBreaker) failed(now time.Time, threshold int) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.failedAt = now
+	b.probing = false
+	return threshold > 0 && b.failures == threshold
+}
+
+// succeeded records a successful collection and returns true if it has
+// closed the breaker.
+func (b *circuitBreaker) succeeded(threshold int) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	wasOpen := threshold > 0 && b.failures >= threshold
+	b.failures = 0
+	b.probing = false
+	return wasOpen
+}
+
+// open returns true if the breaker is open.
+func (b *circuitBreaker) open(threshold int) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return threshold > 0 && b.failures >= threshold
+}
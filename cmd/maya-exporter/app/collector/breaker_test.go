@@ -0,0 +1,159 @@
+package collector
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	start := time.Now()
+	b := &circuitBreaker{}
+	// cases are run in order since each one depends on the collections
+	// recorded by the previous ones.
+	cases := []struct {
+		name    string
+		after   time.Duration
+		allowed bool
+		// failed or succeeded is the result of the collection if it
+		// is allowed, changed is the expected return of it.
+		failed  bool
+		changed bool
+		open    bool
+	}{
+		{name: "first failure", allowed: true, failed: true},
+		{name: "second failure", after: time.Second, allowed: true, failed: true},
+		{name: "third failure opens the breaker", after: 2 * time.Second, allowed: true, failed: true, changed: true, open: true},
+		{name: "collection within the cooldown is skipped", after: 30 * time.Second, open: true},
+		{name: "probe after the cooldown fails", after: 62 * time.Second, allowed: true, failed: true, open: true},
+		{name: "collection within the cooldown of the probe is skipped", after: 90 * time.Second, open: true},
+		{name: "probe after the cooldown succeeds and closes the breaker", after: 123 * time.Second, allowed: true, changed: true},
+		{name: "failure after the recovery doesn't open the breaker", after: 124 * time.Second, allowed: true, failed: true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			now := start.Add(tt.after)
+			if got := b.allow(now, 3, time.Minute); got != tt.allowed {
+				t.Fatalf("allow() => %v, want %v", got, tt.allowed)
+			}
+			if tt.allowed {
+				var changed bool
+				if tt.failed {
+					changed = b.failed(now, 3)
+				} else {
+					changed = b.succeeded(3)
+				}
+				if changed != tt.changed {
+					t.Fatalf("got changed %v, want %v", changed, tt.changed)
+				}
+			}
+			if got := b.open(3); got != tt.open {
+				t.Fatalf("open() => %v, want %v", got, tt.open)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerSingleProbe(t *testing.T) {
+	start := time.Now()
+	b := &circuitBreaker{}
+	b.failed(start, 1)
+	if !b.allow(start.Add(time.Minute), 1, time.Minute) {
+		t.Fatal("allow() => false after the cooldown, want a probe")
+	}
+	if b.allow(start.Add(time.Minute), 1, time.Minute) {
+		t.Fatal("allow() => true while the volume is probed")
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	b := &circuitBreaker{}
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		if b.failed(now, 0) {
+			t.Fatal("failed() => opened a disabled breaker")
+		}
+		if !b.allow(now, 0, time.Minute) {
+			t.Fatal("allow() => false for a disabled breaker")
+		}
+	}
+	if b.open(0) {
+		t.Fatal("open() => true for a disabled breaker")
+	}
+}
+
+func TestJivaCollectorCircuitBreaker(t *testing.T) {
+	var requests, down int32 = 0, 1
+	controller := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == replicasAPI {
+			fmt.Fprint(w, `{"data":[]}`)
+			return
+		}
+		atomic.AddInt32(&requests, 1)
+		if atomic.LoadInt32(&down) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, validControllerResp)
+	}))
+	defer controller.Close()
+	col := NewJivaStatsExporter(mustParseURL(t, controller.URL), "jiva")
+	col.Retries = 0
+	col.BreakerThreshold = 2
+	col.BreakerCooldown = 50 * time.Millisecond
+
+	// cases are run in order since each one depends on the failures
+	// recorded by the previous ones.
+	cases := []struct {
+		name     string
+		down     bool
+		wait     time.Duration
+		requests int32
+		match    []*regexp.Regexp
+	}{
+		{
+			name: "First failure", down: true, requests: 1,
+			match: []*regexp.Regexp{regexp.MustCompile(`openebs_volume_up 0`), regexp.MustCompile(`openebs_circuit_breaker_open 0`)},
+		},
+		{
+			name: "Second failure opens the breaker", down: true, requests: 1,
+			match: []*regexp.Regexp{regexp.MustCompile(`openebs_volume_up 0`), regexp.MustCompile(`openebs_circuit_breaker_open 1`)},
+		},
+		{
+			name: "Controller is not called while the breaker is open",
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_volume_up 0`),
+				regexp.MustCompile(`openebs_circuit_breaker_open 1`),
+				regexp.MustCompile(`openebs_scrapes_total 2`),
+			},
+		},
+		{
+			name: "Controller is probed after the cooldown", wait: 60 * time.Millisecond, requests: 1,
+			match: []*regexp.Regexp{regexp.MustCompile(`openebs_volume_up 1`), regexp.MustCompile(`openebs_circuit_breaker_open 0`)},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			time.Sleep(tt.wait)
+			if tt.down {
+				atomic.StoreInt32(&down, 1)
+			} else {
+				atomic.StoreInt32(&down, 0)
+			}
+			before := atomic.LoadInt32(&requests)
+			buf := scrape(t, col)
+			if got := atomic.LoadInt32(&requests) - before; got != tt.requests {
+				t.Fatalf("got %d requests to the controller, want %d", got, tt.requests)
+			}
+			for _, re := range tt.match {
+				if !re.Match(buf) {
+					t.Errorf("failed matching: %q", re)
+				}
+			}
+		})
+	}
+}
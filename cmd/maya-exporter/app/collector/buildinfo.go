@@ -0,0 +1,37 @@
+package collector
+
+import (
+	"runtime"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Version and GitCommit are the version and the git commit of the
+// exporter, these are set using ldflags while building the binary.
+var (
+	Version   = "unknown"
+	GitCommit = "unknown"
+)
+
+// NewBuildInfoCollector returns a gauge which is always 1 and has the
+// version, commit and go version of the exporter as labels. The name of
+// the metric is prefixed with the given namespace.
+func NewBuildInfoCollector(namespace string) prometheus.Collector {
+	namespace = strings.TrimSuffix(namespace, "_")
+	if len(namespace) == 0 {
+		namespace = DefaultNamespace
+	}
+	buildInfo := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "exporter_build_info",
+		Help:      "A metric with a constant '1' value labeled by version, commit and goversion from which maya-exporter was built",
+		ConstLabels: prometheus.Labels{
+			"version":   Version,
+			"commit":    GitCommit,
+			"goversion": runtime.Version(),
+		},
+	})
+	buildInfo.Set(1)
+	return buildInfo
+}
@@ -0,0 +1,18 @@
+package collector
+
+import (
+	"fmt"
+	"regexp"
+	"runtime"
+	"testing"
+)
+
+func TestBuildInfoCollector(t *testing.T) {
+	buf := scrape(t, NewBuildInfoCollector(DefaultNamespace))
+	re := regexp.MustCompile(fmt.Sprintf(
+		`openebs_exporter_build_info{commit="unknown",goversion="%s",version="unknown"} 1`,
+		regexp.QuoteMeta(runtime.Version())))
+	if !re.Match(buf) {
+		t.Fatalf("failed matching: %q in %s", re, buf)
+	}
+}
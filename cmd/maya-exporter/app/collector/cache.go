@@ -0,0 +1,41 @@
+package collector
+
+import (
+	"sync"
+	"time"
+)
+
+// statsCache keeps the last stats fetched from the controller of a
+// volume, it is safe for concurrent use.
+type statsCache struct {
+	mu sync.Mutex
+	// res and fetchedAt are the result of the last successful fetch and
+	// the time of the fetch, fetchedAt is zero if there is none.
+	res       fetchResult
+	fetchedAt time.Time
+}
+
+// get returns the cached stats if they are younger than ttl, otherwise
+// it fetches the stats and caches them if the fetch is successful. The
+// lock is held during the fetch so that the concurrent scrapes of the
+// volume make a single request to the controller. It also returns the
+// age of the returned stats. The retries, parse errors and trace of a
+// cached result are counted only by the scrape which fetched it, so these
+// are not returned again.
+func (c *statsCache) get(ttl time.Duration, fetch func() (fetchResult, error)) (fetchResult, time.Duration, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.fetchedAt.IsZero() {
+		if age := time.Since(c.fetchedAt); age < ttl {
+			res := c.res
+			res.retries, res.parseErrors, res.trace = 0, 0, nil
+			return res, age, nil
+		}
+	}
+	res, err := fetch()
+	if err != nil {
+		return res, 0, err
+	}
+	c.res, c.fetchedAt = res, time.Now()
+	return res, 0, nil
+}
@@ -0,0 +1,272 @@
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/openebs/maya/types/v1"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestStatsCacheGet(t *testing.T) {
+	var fetches int
+	fetch := func() (fetchResult, error) {
+		fetches++
+		if fetches == 2 {
+			return fetchResult{retries: 1}, errors.New("connection refused")
+		}
+		return fetchResult{stats: v1.VolumeStats{Name: fmt.Sprintf("fetch%d", fetches)}, retries: 1}, nil
+	}
+	cache := &statsCache{}
+	// cases are run in order since each of them depends on the stats
+	// cached by the previous ones.
+	cases := []struct {
+		name    string
+		ttl     time.Duration
+		wait    time.Duration
+		want    string
+		retries int
+		fetches int
+		isErr   bool
+	}{
+		{name: "cache is empty", ttl: time.Hour, want: "fetch1", retries: 1, fetches: 1},
+		{name: "cached stats are younger than the ttl", ttl: time.Hour, want: "fetch1", fetches: 1},
+		{name: "fetch fails after the ttl", ttl: time.Millisecond, wait: 2 * time.Millisecond, retries: 1, fetches: 2, isErr: true},
+		{name: "failed fetch is not cached", ttl: time.Millisecond, want: "fetch3", retries: 1, fetches: 3},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			time.Sleep(tt.wait)
+			res, _, err := cache.get(tt.ttl, fetch)
+			if (err != nil) != tt.isErr {
+				t.Fatalf("get() => err %v, want err %v", err, tt.isErr)
+			}
+			if res.stats.Name != tt.want {
+				t.Fatalf("get() => stats %q, want %q", res.stats.Name, tt.want)
+			}
+			if res.retries != tt.retries {
+				t.Fatalf("get() => %d retries, want %d", res.retries, tt.retries)
+			}
+			if fetches != tt.fetches {
+				t.Fatalf("get() => %d fetches, want %d", fetches, tt.fetches)
+			}
+		})
+	}
+}
+
+func TestStatsCacheConcurrentGet(t *testing.T) {
+	var fetches int32
+	fetch := func() (fetchResult, error) {
+		atomic.AddInt32(&fetches, 1)
+		time.Sleep(10 * time.Millisecond)
+		return fetchResult{stats: v1.VolumeStats{Name: "vol1"}}, nil
+	}
+	cache := &statsCache{}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cache.get(time.Hour, fetch)
+		}()
+	}
+	wg.Wait()
+	if got := atomic.LoadInt32(&fetches); got != 1 {
+		t.Fatalf("concurrent get() => %d fetches, want 1", got)
+	}
+}
+
+// countingJivaController returns a fake jiva controller which counts the
+// requests made for the stats.
+func countingJivaController(requests *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == replicasAPI {
+			fmt.Fprint(w, `{"data":[]}`)
+			return
+		}
+		atomic.AddInt32(requests, 1)
+		fmt.Fprintln(w, validControllerResp)
+	}))
+}
+
+func TestJivaCollectorCache(t *testing.T) {
+	cases := map[string]struct {
+		ttl      time.Duration
+		requests int32
+		match    []*regexp.Regexp
+	}{
+		"Cache is disabled": {
+			ttl:      0,
+			requests: 2,
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_stats_cache_age_seconds 0`),
+			},
+		},
+		"Cache is enabled": {
+			ttl:      time.Hour,
+			requests: 1,
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_reads 5`),
+				regexp.MustCompile(`openebs_stats_cache_age_seconds [0-9.e-]+`),
+				regexp.MustCompile(`openebs_scrape_retries_total 0`),
+			},
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			var requests int32
+			controller := countingJivaController(&requests)
+			defer controller.Close()
+			controllerURL, _ := url.Parse(controller.URL)
+			col := NewJivaStatsExporter(controllerURL, "jiva")
+			col.CacheTTL = tt.ttl
+			scrape(t, col)
+			buf := scrape(t, col)
+			if got := atomic.LoadInt32(&requests); got != tt.requests {
+				t.Fatalf("scrapes with cache ttl %v => %d requests, want %d", tt.ttl, got, tt.requests)
+			}
+			for _, re := range tt.match {
+				if !re.Match(buf) {
+					t.Errorf("failed matching: %q", re)
+				}
+			}
+		})
+	}
+}
+
+func TestJivaCollectorCacheConcurrentRetries(t *testing.T) {
+	var requests int32
+	controller := fakeJivaController(validControllerResp)
+	defer controller.Close()
+	handler := controller.Config.Handler
+	// the first request for the stats fails so that the fetch is retried
+	// while the other scrapes wait for the cache.
+	controller.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/stats" && atomic.AddInt32(&requests, 1) == 1 {
+			closeConnection(t, w)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+	controllerURL, _ := url.Parse(controller.URL)
+	col := NewJivaStatsExporter(controllerURL, "jiva")
+	col.CacheTTL = time.Hour
+	col.Retries = 1
+	col.RetryBackoff = 10 * time.Millisecond
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ch := make(chan prometheus.Metric)
+			go func() {
+				col.Collect(ch)
+				close(ch)
+			}()
+			for range ch {
+			}
+		}()
+	}
+	wg.Wait()
+	// the retry of the fetch is counted once, the scrapes served from the
+	// cache neither count it again nor drop it.
+	buf := scrape(t, col)
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Fatalf("concurrent scrapes => %d requests, want 2", got)
+	}
+	if re := regexp.MustCompile(`openebs_scrape_retries_total 1\n`); !re.Match(buf) {
+		t.Errorf("failed matching: %q", re)
+	}
+}
+
+func TestJivaVolumesCollectorCache(t *testing.T) {
+	var requests1, requests2 int32
+	vol1 := countingJivaController(&requests1)
+	defer vol1.Close()
+	vol2 := countingJivaController(&requests2)
+	defer vol2.Close()
+	exporter, err := NewJivaVolumesStatsExporter([]VolumeTarget{
+		{Name: "vol1", URL: vol1.URL},
+		{Name: "vol2", URL: vol2.URL},
+	}, "jiva")
+	if err != nil {
+		t.Fatalf("NewJivaVolumesStatsExporter() : unexpected error %v", err)
+	}
+	exporter.CacheTTL = time.Hour
+	scrape(t, exporter)
+	buf := scrape(t, exporter)
+	// each volume has its own cache.
+	if requests1 != 1 || requests2 != 1 {
+		t.Fatalf("scrapes => %d and %d requests, want 1 for each volume", requests1, requests2)
+	}
+	for _, re := range []*regexp.Regexp{
+		regexp.MustCompile(`openebs_reads{volume="vol1"} 5`),
+		regexp.MustCompile(`openebs_reads{volume="vol2"} 5`),
+	} {
+		if !re.Match(buf) {
+			t.Errorf("failed matching: %q", re)
+		}
+	}
+}
+
+func TestJivaCollectorCacheTimestamp(t *testing.T) {
+	cases := map[string]struct {
+		ttl     time.Duration
+		stamped bool
+	}{
+		"Cache is disabled": {ttl: 0},
+		"Cache is enabled":  {ttl: time.Hour, stamped: true},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			col := NewJivaStatsFetcherExporter(newFakeStatsFetcher(t, validControllerResp))
+			col.CacheTTL = tt.ttl
+			col.LegacyMetricNames = true
+			registry := prometheus.NewRegistry()
+			if err := registry.Register(col); err != nil {
+				t.Fatal(err)
+			}
+			before := time.Now().UnixNano() / int64(time.Millisecond)
+			if _, err := registry.Gather(); err != nil {
+				t.Fatal(err)
+			}
+			after := time.Now().UnixNano() / int64(time.Millisecond)
+			// the second scrape is served from the cache if it is
+			// enabled, and carries the time of the first one.
+			time.Sleep(5 * time.Millisecond)
+			families, err := registry.Gather()
+			if err != nil {
+				t.Fatal(err)
+			}
+			timestamps := make(map[string]*int64)
+			for _, family := range families {
+				timestamps[family.GetName()] = family.GetMetric()[0].TimestampMs
+			}
+			for _, name := range []string{"openebs_reads", "openebs_size_of_volume", "reads"} {
+				ts := timestamps[name]
+				if !tt.stamped {
+					if ts != nil {
+						t.Errorf("%s => got timestamp %d, want none", name, *ts)
+					}
+					continue
+				}
+				if ts == nil || *ts < before || *ts > after {
+					t.Errorf("%s => got timestamp %v, want the time of the first scrape in [%d, %d]", name, ts, before, after)
+				}
+			}
+			for _, name := range []string{"openebs_volume_up", "openebs_stats_cache_age_seconds", "volume_up"} {
+				if ts := timestamps[name]; ts != nil {
+					t.Errorf("%s => got timestamp %d, want none", name, *ts)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package collector
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/openebs/maya/cmd/maya-exporter/app/logger"
+	apis "github.com/openebs/maya/pkg/apis/openebs.io/v1alpha1"
+	clientset "github.com/openebs/maya/pkg/client/generated/clientset/internalclientset"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// The states of the volumes counted by the ClusterCollector.
+const (
+	volumeStateHealthy  = "healthy"
+	volumeStateDegraded = "degraded"
+	volumeStateOffline  = "offline"
+)
+
+// provisionedByAnnotation is the annotation of the persistent volumes with
+// the name of their provisioner, the provisioners of OpenEBS are named
+// openebs.io/<name>.
+const provisionedByAnnotation = "pv.kubernetes.io/provisioned-by"
+
+// ClusterVolumeLister lists the persistent volumes and the cstor volumes
+// of the cluster. It is implemented by KubeVolumeLister, tests can
+// implement it to inject fake volumes.
+type ClusterVolumeLister interface {
+	ListPersistentVolumes() ([]corev1.PersistentVolume, error)
+	ListCStorVolumes() ([]apis.CStorVolume, error)
+}
+
+// KubeVolumeLister lists the volumes using the kubernetes api, the
+// clients can be the fake clientsets in tests.
+type KubeVolumeLister struct {
+	KubeClient    kubernetes.Interface
+	OpenebsClient clientset.Interface
+}
+
+// ListPersistentVolumes lists the persistent volumes of the cluster.
+func (k KubeVolumeLister) ListPersistentVolumes() ([]corev1.PersistentVolume, error) {
+	pvs, err := k.KubeClient.CoreV1().PersistentVolumes().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("could not list the persistent volumes: %v", err)
+	}
+	return pvs.Items, nil
+}
+
+// ListCStorVolumes lists the cstor volumes of all the namespaces. There
+// are none if the CStorVolume resource is not found, e.g. in the clusters
+// with only jiva volumes, so that these are still counted.
+func (k KubeVolumeLister) ListCStorVolumes() ([]apis.CStorVolume, error) {
+	cvs, err := k.OpenebsClient.OpenebsV1alpha1().CStorVolumes(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if k8serrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not list the cstor volumes: %v", err)
+	}
+	return cvs.Items, nil
+}
+
+// ClusterCollector collects the no of the volumes of the cluster by their
+// state, using the Lister once per scrape rather than the controllers of
+// the volumes. It implements the prometheus.Collector interface.
+type ClusterCollector struct {
+	Lister            ClusterVolumeLister
+	volumes           *prometheus.GaugeVec
+	volumeListErrors  prometheus.Counter
+	volumeListSuccess prometheus.Gauge
+}
+
+// NewClusterCollector returns the ClusterCollector which counts the
+// volumes listed by the given lister, the names of its metrics are
+// prefixed with the given namespace.
+func NewClusterCollector(namespace string, lister ClusterVolumeLister) *ClusterCollector {
+	namespace = strings.TrimSuffix(namespace, "_")
+	if len(namespace) == 0 {
+		namespace = DefaultNamespace
+	}
+	return &ClusterCollector{
+		Lister: lister,
+		volumes: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "volumes_total",
+				Help:      "No of the volumes of the cluster by their state, healthy, degraded or offline",
+			},
+			[]string{"state"},
+		),
+		volumeListErrors: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "cluster_volume_list_errors_total",
+				Help:      "Total no of failures in listing the volumes of the cluster",
+			}),
+		volumeListSuccess: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "cluster_volume_list_success",
+				Help:      "Whether the volumes of the cluster were listed successfully in the last scrape",
+			}),
+	}
+}
+
+// collectors returns all the metrics of the ClusterCollector.
+func (c *ClusterCollector) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		c.volumes,
+		c.volumeListErrors,
+		c.volumeListSuccess,
+	}
+}
+
+// Describe is implementation of Describe method of prometheus.Collector
+// interface.
+func (c *ClusterCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, col := range c.collectors() {
+		col.Describe(ch)
+	}
+}
+
+// Collect is implementation of prometheus's prometheus.Collector
+// interface. The counts are not emitted if the volumes can't be listed,
+// as partial counts would be misleading.
+func (c *ClusterCollector) Collect(ch chan<- prometheus.Metric) {
+	c.volumes.Reset()
+	counts, err := c.countVolumes()
+	if err != nil {
+		logger.WithError(err).Error("could not list the volumes of the cluster")
+		c.volumeListErrors.Inc()
+		c.volumeListSuccess.Set(0)
+	} else {
+		c.volumeListSuccess.Set(1)
+		for _, state := range []string{volumeStateHealthy, volumeStateDegraded, volumeStateOffline} {
+			c.volumes.WithLabelValues(state).Set(float64(counts[state]))
+		}
+	}
+	for _, col := range c.collectors() {
+		col.Collect(ch)
+	}
+}
+
+// countVolumes returns the no of volumes by their state. The state of a
+// cstor volume is the phase of its CStorVolume, whereas the state of the
+// other OpenEBS persistent volumes, e.g. of jiva, is derived from the
+// phase of the persistent volume. The persistent volumes of a cstor
+// volume are counted once, by their CStorVolume which has the same name.
+func (c *ClusterCollector) countVolumes() (map[string]int, error) {
+	pvs, err := c.Lister.ListPersistentVolumes()
+	if err != nil {
+		return nil, err
+	}
+	cvs, err := c.Lister.ListCStorVolumes()
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]int{}
+	cstorVolumes := map[string]bool{}
+	for _, cv := range cvs {
+		cstorVolumes[cv.Name] = true
+		counts[cstorVolumeState(cv.Status.Phase)]++
+	}
+	for _, pv := range pvs {
+		if cstorVolumes[pv.Name] || !isOpenEBSVolume(pv) {
+			continue
+		}
+		counts[persistentVolumeState(pv.Status.Phase)]++
+	}
+	return counts, nil
+}
+
+// isOpenEBSVolume returns true if the persistent volume is provisioned by
+// OpenEBS, i.e. it has the cas type label or an OpenEBS provisioner.
+func isOpenEBSVolume(pv corev1.PersistentVolume) bool {
+	if _, ok := pv.Labels[string(apis.CASTypeKey)]; ok {
+		return true
+	}
+	return strings.HasPrefix(pv.Annotations[provisionedByAnnotation], "openebs.io/")
+}
+
+// cstorVolumeState returns the state of a cstor volume in the given
+// phase, the volumes which are initializing or failed are offline.
+func cstorVolumeState(phase apis.CStorVolumePhase) string {
+	switch strings.ToLower(string(phase)) {
+	case "healthy", "online":
+		return volumeStateHealthy
+	case "degraded":
+		return volumeStateDegraded
+	default:
+		return volumeStateOffline
+	}
+}
+
+// persistentVolumeState returns the state of a persistent volume in the
+// given phase. A bound volume is healthy, a failed one is offline and the
+// others, e.g. released, are degraded as these can't be used as is.
+func persistentVolumeState(phase corev1.PersistentVolumePhase) string {
+	switch phase {
+	case corev1.VolumeBound:
+		return volumeStateHealthy
+	case corev1.VolumeFailed:
+		return volumeStateOffline
+	default:
+		return volumeStateDegraded
+	}
+}
@@ -0,0 +1,148 @@
+package collector
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	apis "github.com/openebs/maya/pkg/apis/openebs.io/v1alpha1"
+	openebsfake "github.com/openebs/maya/pkg/client/generated/clientset/internalclientset/fake"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// fakePV returns a persistent volume in the given phase provisioned by
+// the given provisioner.
+func fakePV(name, provisioner string, phase corev1.PersistentVolumePhase) *corev1.PersistentVolume {
+	return &corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Annotations: map[string]string{provisionedByAnnotation: provisioner},
+		},
+		Status: corev1.PersistentVolumeStatus{Phase: phase},
+	}
+}
+
+// fakeCV returns a cstor volume in the given phase.
+func fakeCV(name string, phase apis.CStorVolumePhase) *apis.CStorVolume {
+	return &apis.CStorVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "openebs"},
+		Status:     apis.CStorVolumeStatus{Phase: phase},
+	}
+}
+
+func TestClusterCollector(t *testing.T) {
+	cases := map[string]struct {
+		pvs            []runtime.Object
+		cvs            []runtime.Object
+		pvErr, cvErr   error
+		namespace      string
+		match, unmatch []*regexp.Regexp
+	}{
+		"[Success] volumes are counted by state": {
+			pvs: []runtime.Object{
+				fakePV("jiva-vol1", "openebs.io/provisioner-iscsi", corev1.VolumeBound),
+				fakePV("jiva-vol2", "openebs.io/provisioner-iscsi", corev1.VolumeReleased),
+				fakePV("jiva-vol3", "openebs.io/provisioner-iscsi", corev1.VolumeFailed),
+				// the pv of a cstor volume is counted by its CStorVolume.
+				fakePV("cstor-vol1", "openebs.io/provisioner-iscsi", corev1.VolumeBound),
+				fakePV("other-vol", "kubernetes.io/aws-ebs", corev1.VolumeBound),
+			},
+			cvs: []runtime.Object{
+				fakeCV("cstor-vol1", "Degraded"),
+				fakeCV("cstor-vol2", "Healthy"),
+				fakeCV("cstor-vol3", "Init"),
+			},
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_volumes_total{state="healthy"} 2`),
+				regexp.MustCompile(`openebs_volumes_total{state="degraded"} 2`),
+				regexp.MustCompile(`openebs_volumes_total{state="offline"} 2`),
+				regexp.MustCompile(`openebs_cluster_volume_list_success 1`),
+				regexp.MustCompile(`openebs_cluster_volume_list_errors_total 0`),
+			},
+		},
+		"[Success] no volumes": {
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_volumes_total{state="healthy"} 0`),
+				regexp.MustCompile(`openebs_volumes_total{state="degraded"} 0`),
+				regexp.MustCompile(`openebs_volumes_total{state="offline"} 0`),
+			},
+		},
+		"[Success] cstor volumes are not installed": {
+			pvs: []runtime.Object{
+				fakePV("jiva-vol1", "openebs.io/provisioner-iscsi", corev1.VolumeBound),
+			},
+			cvErr: k8serrors.NewNotFound(schema.GroupResource{Group: "openebs.io", Resource: "cstorvolumes"}, ""),
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_volumes_total{state="healthy"} 1`),
+				regexp.MustCompile(`openebs_cluster_volume_list_success 1`),
+			},
+		},
+		"[Success] metrics namespace is set": {
+			pvs: []runtime.Object{
+				fakePV("jiva-vol1", "openebs.io/provisioner-iscsi", corev1.VolumeBound),
+			},
+			namespace: "maya",
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`maya_volumes_total{state="healthy"} 1`),
+				regexp.MustCompile(`maya_cluster_volume_list_success 1`),
+			},
+			unmatch: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_`),
+			},
+		},
+		"[Failure] persistent volumes can't be listed": {
+			pvErr: errors.New("forbidden"),
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_cluster_volume_list_success 0`),
+				regexp.MustCompile(`openebs_cluster_volume_list_errors_total 1`),
+			},
+			unmatch: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_volumes_total{`),
+			},
+		},
+		"[Failure] cstor volumes can't be listed": {
+			cvs:   []runtime.Object{fakeCV("cstor-vol1", "Healthy")},
+			cvErr: errors.New("the server could not find the requested resource"),
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_cluster_volume_list_success 0`),
+			},
+			unmatch: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_volumes_total{`),
+			},
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			kubeClient := fake.NewSimpleClientset(tt.pvs...)
+			openebsClient := openebsfake.NewSimpleClientset(tt.cvs...)
+			if tt.pvErr != nil {
+				kubeClient.PrependReactor("list", "persistentvolumes", func(k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, tt.pvErr
+				})
+			}
+			if tt.cvErr != nil {
+				openebsClient.PrependReactor("list", "cstorvolumes", func(k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, tt.cvErr
+				})
+			}
+			lister := KubeVolumeLister{KubeClient: kubeClient, OpenebsClient: openebsClient}
+			buf := scrape(t, NewClusterCollector(tt.namespace, lister))
+			for _, re := range tt.match {
+				if !re.Match(buf) {
+					t.Errorf("failed matching: %q", re)
+				}
+			}
+			for _, re := range tt.unmatch {
+				if re.Match(buf) {
+					t.Errorf("unexpected match: %q", re)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+// Package collectortest provides the fakes of the volume controllers for
+// testing the code which uses the collector.
+package collectortest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/openebs/maya/types/v1"
+)
+
+const (
+	// StatsAPI and VolumeStatsAPI are the apis of the fake jiva
+	// controller which return the stats of the volume.
+	StatsAPI       = "/v1/stats"
+	VolumeStatsAPI = "/v1/volumes/stats"
+	// ReplicasAPI is the api of the fake jiva controller which lists the
+	// replicas of the volume.
+	ReplicasAPI = "/v1/replicas"
+)
+
+// NewFakeJivaController returns a started fake jiva controller which
+// serves the given stats and lists the given replicas in the same JSON
+// shape as the jiva controller. The caller must close the server.
+func NewFakeJivaController(stats v1.VolumeStats, replicas ...v1.Replica) *httptest.Server {
+	mux := http.NewServeMux()
+	serveStats := func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, stats)
+	}
+	mux.HandleFunc(StatsAPI, serveStats)
+	mux.HandleFunc(VolumeStatsAPI, serveStats)
+	mux.HandleFunc(ReplicasAPI, func(w http.ResponseWriter, r *http.Request) {
+		collection := v1.ReplicaCollection{Data: replicas}
+		if collection.Data == nil {
+			collection.Data = []v1.Replica{}
+		}
+		writeJSON(w, collection)
+	})
+	return httptest.NewServer(mux)
+}
+
+// writeJSON writes v as the JSON response.
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
@@ -0,0 +1,66 @@
+package collectortest
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+
+	"github.com/openebs/maya/cmd/maya-exporter/app/collector"
+	"github.com/openebs/maya/types/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+func TestNewFakeJivaController(t *testing.T) {
+	controller := NewFakeJivaController(v1.VolumeStats{
+		Name:                 "vol1",
+		Reads:                "5",
+		Writes:               "11",
+		TotalReadBlockCount:  "25",
+		TotalWriteBlockCount: "6",
+		SectorSize:           "4096",
+		Size:                 "1073741824",
+		UpTime:               158,
+		RevisionCounter:      "10",
+		ReplicaCounter:       "2",
+	}, v1.Replica{Address: "tcp://127.0.0.2:1", Mode: "ERR"})
+	defer controller.Close()
+	controllerURL, err := url.Parse(controller.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(collector.NewJivaStatsExporter(controllerURL, "jiva")); err != nil {
+		t.Fatalf("collector failed to register: %s", err)
+	}
+	server := httptest.NewServer(promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	defer server.Close()
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected failed response from prometheus: %s", err)
+	}
+	defer resp.Body.Close()
+	buf, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed reading server response: %s", err)
+	}
+	for _, re := range []*regexp.Regexp{
+		regexp.MustCompile(`openebs_volume_up 1`),
+		regexp.MustCompile(`openebs_reads 5`),
+		regexp.MustCompile(`openebs_writes 11`),
+		regexp.MustCompile(`openebs_read_bytes_total 102400`),
+		regexp.MustCompile(`openebs_write_bytes_total 24576`),
+		regexp.MustCompile(`openebs_volume_uptime_seconds 158`),
+		regexp.MustCompile(`openebs_revision_counter 10`),
+		regexp.MustCompile(`openebs_replica_count 2`),
+		regexp.MustCompile(`openebs_replica_status{mode="ERR",replica="127.0.0.2"} 0`),
+	} {
+		if !re.Match(buf) {
+			t.Errorf("failed matching: %q", re)
+		}
+	}
+}
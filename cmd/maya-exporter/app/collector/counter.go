@@ -0,0 +1,58 @@
+package collector
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// cumulativeMetric is a metric of a cumulative total of the volume, e.g.
+// the no of reads, which is set to the total reported by the controller.
+// It is a gauge by default or a monotonicCounter in the counter mode, see
+// newCumulative.
+type cumulativeMetric interface {
+	prometheus.Metric
+	prometheus.Collector
+	Set(float64)
+}
+
+// newCumulative returns the metric of a cumulative total with the given
+// options, it is a counter if counterMode is set and a gauge otherwise.
+func newCumulative(counterMode bool, opts prometheus.GaugeOpts) cumulativeMetric {
+	if counterMode {
+		return &monotonicCounter{Counter: prometheus.NewCounter(prometheus.CounterOpts(opts))}
+	}
+	return prometheus.NewGauge(opts)
+}
+
+// monotonicCounter is a counter which follows a total of the controller,
+// it is increased by the difference between the successive totals so that
+// it never goes down. If the total goes down, e.g. after a restart of the
+// controller, the counter is increased by the new total, i.e. the total
+// counted since the restart. The first total is added as is so that the
+// counter starts at the total of the controller, and so it starts over at
+// the total on a restart of the exporter, which rate() handles like any
+// other counter reset.
+type monotonicCounter struct {
+	prometheus.Counter
+	mu   sync.Mutex
+	last float64
+	seen bool
+}
+
+// Set increases the counter by the increase of the total since the last
+// call, the negative totals are ignored as a counter can't go below 0.
+func (c *monotonicCounter) Set(total float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if total < 0 {
+		return
+	}
+	delta := total
+	if c.seen && total >= c.last {
+		delta = total - c.last
+	}
+	c.last = total
+	c.seen = true
+	c.Counter.Add(delta)
+}
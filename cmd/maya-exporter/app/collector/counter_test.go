@@ -0,0 +1,103 @@
+package collector
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestMonotonicCounter(t *testing.T) {
+	counter := newCumulative(true, prometheus.GaugeOpts{Name: "reads", Help: "Reads"}).(*monotonicCounter)
+	steps := []struct {
+		name  string
+		total float64
+		want  float64
+	}{
+		{name: "first total is added as is", total: 10, want: 10},
+		{name: "increase is added", total: 15, want: 15},
+		{name: "same total", total: 15, want: 15},
+		{name: "total after a restart of the controller is added", total: 4, want: 19},
+		{name: "increase after the restart is added", total: 6, want: 21},
+		{name: "negative total is ignored", total: -1, want: 21},
+	}
+	for _, step := range steps {
+		counter.Set(step.total)
+		if got := counterValue(counter); got != step.want {
+			t.Fatalf("%s: Set(%v) => got %v, want %v", step.name, step.total, got, step.want)
+		}
+	}
+}
+
+func TestJivaCollectorCounterMode(t *testing.T) {
+	reads := "5"
+	controller := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/stats" {
+			fmt.Fprintln(w, `{"data":[]}`)
+			return
+		}
+		fmt.Fprintf(w, `{"Name":"vol1","ReadIOPS":"%s","ReplicaCounter":2,"RevisionCounter":10,"SectorSize":"4096","Size":"1073741824","TotalReadBlockCount":"25","TotalReadTime":"45","TotalWriteTime":"30","TotatWriteBlockCount":"6","UpTime":158.667823193,"UsedBlocks":"5","UsedLogicalBlocks":"23","WriteIOPS":"11"}`, reads)
+	}))
+	defer controller.Close()
+	control, _ := url.Parse(controller.URL)
+
+	t.Run("gauges by default", func(t *testing.T) {
+		buf := scrape(t, NewJivaStatsExporter(control, "jiva"))
+		for _, re := range []*regexp.Regexp{
+			regexp.MustCompile(`# TYPE openebs_reads gauge`),
+			regexp.MustCompile(`# TYPE openebs_read_bytes_total gauge`),
+		} {
+			if !re.Match(buf) {
+				t.Errorf("failed matching: %q", re)
+			}
+		}
+	})
+
+	exporter := NewJivaStatsExporter(control, "jiva")
+	exporter.SetCounterMode(true)
+	exporter.LegacyMetricNames = true
+	steps := []struct {
+		name  string
+		reads string
+		match []*regexp.Regexp
+	}{
+		{
+			name:  "counters start at the totals of the controller",
+			reads: "5",
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`# TYPE openebs_reads counter`),
+				regexp.MustCompile(`# TYPE openebs_write_time counter`),
+				regexp.MustCompile(`# TYPE openebs_read_block_count counter`),
+				regexp.MustCompile(`# TYPE openebs_read_bytes_total counter`),
+				regexp.MustCompile(`openebs_reads 5`),
+				regexp.MustCompile(`openebs_read_bytes_total 102400`),
+				// the aliases stay gauges.
+				regexp.MustCompile(`# TYPE reads gauge`),
+				regexp.MustCompile(`\nreads 5`),
+				// the other gauges are not affected.
+				regexp.MustCompile(`# TYPE openebs_actual_used gauge`),
+			},
+		},
+		{
+			name:  "counters don't go down on a restart of the controller",
+			reads: "2",
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_reads 7`),
+				regexp.MustCompile(`\nreads 7`),
+			},
+		},
+	}
+	for _, step := range steps {
+		reads = step.reads
+		buf := scrape(t, exporter)
+		for _, re := range step.match {
+			if !re.Match(buf) {
+				t.Errorf("%s: failed matching: %q", step.name, re)
+			}
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package collector
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/openebs/maya/cmd/maya-exporter/app/logger"
+)
+
+// Credentials are the credentials of the jiva controller read from a
+// credentials file, see ReadCredentialsFile.
+type Credentials struct {
+	Username string
+	Password string
+	// CAFile, CertFile and KeyFile are the paths of the CA certificate
+	// and of the client certificate and key, see NewTLSConfig.
+	CAFile   string
+	CertFile string
+	KeyFile  string
+}
+
+// credentialKeys maps the keys of a credentials file to the fields of
+// the Credentials.
+var credentialKeys = map[string]func(c *Credentials) *string{
+	"username":    func(c *Credentials) *string { return &c.Username },
+	"password":    func(c *Credentials) *string { return &c.Password },
+	"ca-file":     func(c *Credentials) *string { return &c.CAFile },
+	"client-cert": func(c *Credentials) *string { return &c.CertFile },
+	"client-key":  func(c *Credentials) *string { return &c.KeyFile },
+}
+
+// ReadCredentialsFile reads the credentials of the jiva controller from
+// the given file, e.g. a file of a mounted kubernetes secret. The file
+// has a credential in the form of key=value per line, the keys are
+// username, password, ca-file, client-cert and client-key. Empty lines
+// and lines starting with # are ignored. The errors never contain the
+// values so that these are not logged.
+func ReadCredentialsFile(path string) (Credentials, error) {
+	var c Credentials
+	file, err := os.Open(path)
+	if err != nil {
+		return c, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return Credentials{}, fmt.Errorf("invalid line %d of %s, must be in the form of key=value", n, path)
+		}
+		field, ok := credentialKeys[strings.TrimSpace(parts[0])]
+		if !ok {
+			return Credentials{}, fmt.Errorf("unknown key at line %d of %s", n, path)
+		}
+		*field(&c) = strings.TrimSpace(parts[1])
+	}
+	if err := scanner.Err(); err != nil {
+		return Credentials{}, err
+	}
+	if len(c.Password) != 0 && len(c.Username) == 0 {
+		return Credentials{}, fmt.Errorf("password without username in %s", path)
+	}
+	return c, nil
+}
+
+// credentialsMu guards the credentials and the tls configuration of the
+// jiva volumes, as these are changed by the reloads of the credentials
+// while the requests are made.
+var credentialsMu sync.Mutex
+
+// SetCredentials sets the basic auth credentials and the tls
+// configuration of the jiva controller to c. The credentials are not
+// changed if the tls configuration can't be created from the files of c.
+func (j *Jiva) SetCredentials(c Credentials) error {
+	fingerprint, err := tlsFingerprint(c)
+	if err != nil {
+		return err
+	}
+	return j.setCredentials(c, fingerprint)
+}
+
+// setCredentials sets the credentials of the jiva controller to c, the
+// tls configuration is created again if the fingerprint of the tls files
+// of c has changed.
+func (j *Jiva) setCredentials(c Credentials, fingerprint string) error {
+	credentialsMu.Lock()
+	changed := fingerprint != j.tlsFingerprint
+	credentialsMu.Unlock()
+	var config *tls.Config
+	if changed && (len(c.CAFile) != 0 || len(c.CertFile) != 0 || len(c.KeyFile) != 0) {
+		var err error
+		if config, err = NewTLSConfig(c.CAFile, c.CertFile, c.KeyFile); err != nil {
+			return err
+		}
+	}
+	credentialsMu.Lock()
+	if changed {
+		j.TLSConfig = config
+		j.tlsFingerprint = fingerprint
+	}
+	j.Username = c.Username
+	j.Password = c.Password
+	j.credentials = c
+	credentialsMu.Unlock()
+	if changed {
+		// the new tls configuration is used by the new transport.
+		j.resetClient()
+	}
+	return nil
+}
+
+// basicAuth returns the basic auth credentials of the jiva controller,
+// the username is empty if these are not set.
+func (j *Jiva) basicAuth() (username, password string) {
+	credentialsMu.Lock()
+	defer credentialsMu.Unlock()
+	return j.Username, j.Password
+}
+
+// tlsConfig returns the tls configuration of the jiva controller.
+func (j *Jiva) tlsConfig() *tls.Config {
+	credentialsMu.Lock()
+	defer credentialsMu.Unlock()
+	return j.TLSConfig
+}
+
+// fileStamp returns the path, the modification time and the size of the
+// file, which change once the file is written or replaced, e.g. when a
+// mounted secret is updated, so that the file is read only if it is
+// changed. It is empty if path is empty.
+func fileStamp(path string) (string, error) {
+	if len(path) == 0 {
+		return "", nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s\x00%d\x00%d", path, info.ModTime().UnixNano(), info.Size()), nil
+}
+
+// tlsFingerprint returns the stamps of the tls files of c, see fileStamp,
+// so that the files rotated in place are noticed without reading them. It
+// is empty if c has no tls files.
+func tlsFingerprint(c Credentials) (string, error) {
+	if len(c.CAFile) == 0 && len(c.CertFile) == 0 && len(c.KeyFile) == 0 {
+		return "", nil
+	}
+	var stamps []string
+	for _, path := range []string{c.CAFile, c.CertFile, c.KeyFile} {
+		stamp, err := fileStamp(path)
+		if err != nil {
+			return "", err
+		}
+		stamps = append(stamps, stamp)
+	}
+	return strings.Join(stamps, "\x00"), nil
+}
+
+// reloadCredentials re-reads the credentials of the jiva controller from
+// CredentialsFile, so that the rotated credentials are used once the file
+// or the tls files it refers to are changed, e.g. when the mounted secret
+// is updated. The files are read only if their stamps have changed since
+// the last reload, see fileStamp. The last known credentials are kept if
+// the files can't be read or are not valid.
+func (j *Jiva) reloadCredentials() {
+	if len(j.CredentialsFile) == 0 {
+		return
+	}
+	// the stamp is taken before the file is read, so that a change made
+	// while it is read is noticed by the next reload.
+	stamp, err := fileStamp(j.CredentialsFile)
+	if err != nil {
+		logger.Warningf("could not reload the credentials, using the last known credentials: %v", err)
+		return
+	}
+	credentialsMu.Lock()
+	last, lastStamp, lastFingerprint := j.credentials, j.credentialsStamp, j.tlsFingerprint
+	credentialsMu.Unlock()
+	if stamp == lastStamp {
+		if fingerprint, err := tlsFingerprint(last); err == nil && fingerprint == lastFingerprint {
+			return
+		}
+	}
+	c, err := ReadCredentialsFile(j.CredentialsFile)
+	var fingerprint string
+	if err == nil {
+		fingerprint, err = tlsFingerprint(c)
+	}
+	changed := err == nil && (c != last || fingerprint != lastFingerprint)
+	if changed {
+		err = j.setCredentials(c, fingerprint)
+	}
+	if err != nil {
+		logger.Warningf("could not reload the credentials, using the last known credentials: %v", err)
+		return
+	}
+	credentialsMu.Lock()
+	j.credentialsStamp = stamp
+	credentialsMu.Unlock()
+	if changed {
+		logger.Infof("credentials reloaded from %s", j.CredentialsFile)
+	}
+}
@@ -0,0 +1,234 @@
+package collector
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReadCredentialsFile(t *testing.T) {
+	cases := map[string]struct {
+		content string
+		want    Credentials
+		isErr   bool
+	}{
+		"Basic auth credentials": {
+			content: "username=admin\npassword=s3cret\n",
+			want:    Credentials{Username: "admin", Password: "s3cret"},
+		},
+		"Comments, empty lines and spaces are ignored": {
+			content: "# rotated by the operator\n\n username = admin \npassword= pa=ss \n",
+			want:    Credentials{Username: "admin", Password: "pa=ss"},
+		},
+		"Paths of the tls files": {
+			content: "ca-file=/etc/secret/ca.crt\nclient-cert=/etc/secret/tls.crt\nclient-key=/etc/secret/tls.key\n",
+			want:    Credentials{CAFile: "/etc/secret/ca.crt", CertFile: "/etc/secret/tls.crt", KeyFile: "/etc/secret/tls.key"},
+		},
+		"Empty file": {},
+		"Unknown key": {
+			content: "username=admin\ntoken=s3cret\n",
+			isErr:   true,
+		},
+		"Line is not in the form of key=value": {
+			content: "admin\n",
+			isErr:   true,
+		},
+		"Password without username": {
+			content: "password=s3cret\n",
+			isErr:   true,
+		},
+	}
+	dir, err := ioutil.TempDir("", "maya-exporter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, "credentials")
+			if err := ioutil.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := ReadCredentialsFile(path)
+			if (err != nil) != tt.isErr {
+				t.Fatalf("ReadCredentialsFile() => got error %v, want error %v", err, tt.isErr)
+			}
+			if err != nil && strings.Contains(err.Error(), "s3cret") {
+				t.Fatalf("ReadCredentialsFile() => got error %q with the password", err)
+			}
+			if got != tt.want {
+				t.Fatalf("ReadCredentialsFile() => got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("Missing file", func(t *testing.T) {
+		if _, err := ReadCredentialsFile(filepath.Join(dir, "missing")); err == nil {
+			t.Fatal("ReadCredentialsFile() => got no error, want error")
+		}
+	})
+}
+
+func TestTLSFingerprint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "maya-exporter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile, _ := writeClientCert(t, dir)
+	c := Credentials{CertFile: certFile, KeyFile: keyFile}
+	before, err := tlsFingerprint(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fingerprint, _ := tlsFingerprint(Credentials{Username: "admin"}); len(fingerprint) != 0 {
+		t.Fatalf("tlsFingerprint() => got %q without tls files, want empty", fingerprint)
+	}
+	// the certificate is rotated in place.
+	writeClientCert(t, dir)
+	after, err := tlsFingerprint(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before == after {
+		t.Fatal("tlsFingerprint() => got the same fingerprint after the rotation of the certificate")
+	}
+	var j Jiva
+	if err := j.SetCredentials(c); err != nil {
+		t.Fatal(err)
+	}
+	if j.TLSConfig == nil || len(j.TLSConfig.Certificates) != 1 {
+		t.Fatalf("SetCredentials() => got tls config %v, want the client certificate", j.TLSConfig)
+	}
+}
+
+func TestJivaReloadCredentials(t *testing.T) {
+	password := "old"
+	controller := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "admin" || pass != password {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/v1/stats" {
+			fmt.Fprintln(w, `{"data":[]}`)
+			return
+		}
+		fmt.Fprint(w, validControllerResp)
+	}))
+	defer controller.Close()
+	control, _ := url.Parse(controller.URL)
+
+	dir, err := ioutil.TempDir("", "maya-exporter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "credentials")
+	writeCredentials := func(content string) {
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeCredentials("username=admin\npassword=old\n")
+	credentials, err := ReadCredentialsFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exporter := NewJivaStatsExporter(control, "jiva")
+	if err := exporter.SetCredentials(credentials); err != nil {
+		t.Fatal(err)
+	}
+	exporter.CredentialsFile = path
+
+	steps := []struct {
+		name     string
+		password string
+		file     string
+		up       string
+	}{
+		{name: "credentials of the file are used", password: "old", up: "1"},
+		{name: "password of the controller is rotated", password: "new", up: "0"},
+		{name: "credentials file is updated", password: "new", file: "username=admin\npassword=new\n", up: "1"},
+		{name: "last known credentials are kept if the file is not valid", password: "new", file: "username=admin\ninvalid\n", up: "1"},
+	}
+	for _, step := range steps {
+		password = step.password
+		if len(step.file) != 0 {
+			writeCredentials(step.file)
+		}
+		buf := scrape(t, exporter)
+		re := regexp.MustCompile(`openebs_volume_up ` + step.up)
+		if !re.Match(buf) {
+			t.Errorf("%s: failed matching: %q", step.name, re)
+		}
+	}
+}
+
+func TestJivaReloadCredentialsUnchangedFile(t *testing.T) {
+	var password atomic.Value
+	password.Store("old")
+	controller := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "admin" || pass != password.Load().(string) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/v1/stats" {
+			fmt.Fprintln(w, `{"data":[]}`)
+			return
+		}
+		fmt.Fprint(w, validControllerResp)
+	}))
+	defer controller.Close()
+	control, _ := url.Parse(controller.URL)
+
+	dir, err := ioutil.TempDir("", "maya-exporter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "credentials")
+	if err := ioutil.WriteFile(path, []byte("username=admin\npassword=old\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	exporter := NewJivaStatsExporter(control, "jiva")
+	exporter.CredentialsFile = path
+	scrape(t, exporter)
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		name     string
+		password string
+		modTime  time.Time
+		up       string
+	}{
+		// the file has the same size and mtime, so it is not read again.
+		{name: "unchanged file is not read", password: "old", modTime: info.ModTime(), up: "1"},
+		{name: "changed file is read", password: "new", modTime: info.ModTime().Add(time.Second), up: "1"},
+	}
+	if err := ioutil.WriteFile(path, []byte("username=admin\npassword=new\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, step := range steps {
+		password.Store(step.password)
+		if err := os.Chtimes(path, step.modTime, step.modTime); err != nil {
+			t.Fatal(err)
+		}
+		buf := scrape(t, exporter)
+		re := regexp.MustCompile(`openebs_volume_up ` + step.up)
+		if !re.Match(buf) {
+			t.Errorf("%s: failed matching: %q", step.name, re)
+		}
+	}
+}
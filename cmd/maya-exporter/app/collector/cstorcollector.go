@@ -2,12 +2,11 @@ package collector
 
 import (
 	"bytes"
-	"encoding/json"
-	"errors"
 	"net"
 	"strings"
+	"time"
 
-	"github.com/golang/glog"
+	"github.com/openebs/maya/cmd/maya-exporter/app/logger"
 	"github.com/openebs/maya/types/v1"
 )
 
@@ -15,11 +14,12 @@ import (
 // for the registration collectors with prometheus.
 func NewCstorStatsExporter(conn net.Conn, casType string) *VolumeStatsExporter {
 	return &VolumeStatsExporter{
-		CASType: casType,
+		CASType:   casType,
+		Namespace: DefaultNamespace,
 		Cstor: Cstor{
 			Conn: conn,
 		},
-		Metrics: *MetricsInitializer(casType),
+		Metrics: *MetricsInitializer(casType, DefaultNamespace),
 	}
 }
 
@@ -27,14 +27,18 @@ func NewCstorStatsExporter(conn net.Conn, casType string) *VolumeStatsExporter {
 // if the connection is available else retry to initiate
 // connection again.
 func (c *Cstor) collector(m *Metrics) error {
+	if c.StatsClient != nil {
+		return c.grpcCollector(m)
+	}
 
 	if c.Conn == nil {
 		// initiate the connection again if connection with the istgt closed
 		// due to timeout or some other errors from istgt side.
 		m.connectionRetryCounter.WithLabelValues("Connection closed from cstor, retry").Inc()
 		if c.InitiateConnection(); c.Conn == nil {
-			glog.Error("Error in initiating the connection")
-			return errors.New("error in initiating connection with socket")
+			logger.Error("Error in initiating the connection")
+			m.volumeUp.Set(0)
+			return ErrSocketConnection
 		}
 	}
 	// set the values of stats from cstor if cstor is reachable, else set nil to
@@ -42,12 +46,13 @@ func (c *Cstor) collector(m *Metrics) error {
 	// after the new request from prometheus comes.
 	if err := c.set(m); err != nil {
 		m.connectionErrorCounter.WithLabelValues(err.Error()).Inc()
-		glog.Error("Error in connection, closing the connection")
+		logger.Error("Error in connection, closing the connection")
 		c.Conn.Close()
 		c.Conn = nil
+		m.volumeUp.Set(0)
 		return err
 	}
-
+	m.volumeUp.Set(1)
 	return nil
 }
 
@@ -57,7 +62,7 @@ func (c *Cstor) writer() error {
 	msg := Command + "\n"
 	_, err := c.Conn.Write([]byte(msg))
 	if err != nil {
-		glog.Error("Write error:", err)
+		logger.Error("Write error:", err)
 		return err
 	}
 	return nil
@@ -77,7 +82,7 @@ func (c *Cstor) reader() (string, error) {
 	for {
 		n, err = c.Conn.Read(buf[:])
 		if err != nil {
-			glog.Error("Error in reading response, found error : ", err)
+			logger.Error("Error in reading response, found error : ", err)
 			return "", err
 		}
 		// concatnat the chunks received and then compare if it has
@@ -108,60 +113,91 @@ func splitter(resp string) string {
 	return res
 }
 
-// newResponse unmarshal the JSON into Response instances.
+// newResponse parses the JSON of the istgt response and maps
+// it into the v1.VolumeStats shared with jiva.
 func newResponse(result string) v1.VolumeStats {
-	metrics := v1.VolumeStats{}
-	if err := json.Unmarshal([]byte(result), &metrics); err != nil {
-		glog.Error("Error in unmarshalling, found error: ", err)
+	stats, err := v1.ParseCstorVolumeStats(result)
+	if err != nil {
+		logger.Error("Error in unmarshalling, found error: ", err)
 	}
-	glog.Infof("Parsed metrics : %+v", metrics)
+	metrics := stats.VolumeStats()
+	logger.Infof("Parsed metrics : %+v", metrics)
 	return metrics
 }
 
-// set make call to reader and writer to write the
-// IOSTATS command over wire and then reads the response.
+// getVolumeStats makes call to reader and writer to write the IOSTATS
+// command over wire and then reads the response which is then
+// unmarshalled into the v1.VolumeStats structure. The stats are fetched
+// using the StatsClient instead if it is set.
+func (c *Cstor) getVolumeStats(obj *v1.VolumeStats) error {
+	if c.StatsClient != nil {
+		return c.getGRPCVolumeStats(obj)
+	}
+	if err := c.writer(); err != nil {
+		return err
+	}
+	// aggregated response from cstor stored into response
+	response, err := c.reader()
+	if err != nil {
+		return err
+	}
+	// split response (string) and remove header, footer
+	// and store only JSON data.
+	response = splitter(response)
+	if len(response) == 0 {
+		logger.Error("Got empty response from cstor")
+		return ErrEmptyResponse
+	}
+
+	// unmarshal the json response into Metrics instances.
+	*obj = newResponse(response)
+	return nil
+}
 
+// set is used to set the values gathered from cstor to
+// prometheus gauges and counters.
 func (c *Cstor) set(m *Metrics) error {
 	var (
-		// aggregated response from cstor stored into response
-		response string
-		// split response (string) and remove header, footer
-		// and store only JSON data.
+		// JSON response from cstor
 		newResp v1.VolumeStats
 		// parse JSON response (string) into appropriate type
 		// (float64, int64 etc).JSON can only handle the data
 		// up to 53 bits precision, so this needs to be converted
 		// into string.
 		volStats VolumeStats
-		err      error
 	)
-	if err := c.writer(); err != nil {
+	if err := c.getVolumeStats(&newResp); err != nil {
 		return err
 	}
-	response, err = c.reader()
-	if err != nil {
-		return err
-	}
-	response = splitter(response)
-	if len(response) == 0 {
-		glog.Error("Got empty response from cstor")
-		return errors.New("Got empty response from cstor")
-	}
 
-	// unmarshal the json response into Metrics instances.
-	newResp = newResponse(response)
+	prevResp, hasPrev := m.lastStats.load()
+	m.lastStats.store(newResp)
+	m.lastScrapeSuccess.Set(unixSeconds(time.Now()))
 	volStats = c.parser(newResp)
+	if hasPrev {
+		m.observeLatencies(c.parser(prevResp), volStats)
+	}
 	m.reads.Set(volStats.reads)
 	m.writes.Set(volStats.writes)
-	m.sectorSize.Set(volStats.sectorSize)
+	m.setSectorSize(volStats.sectorSize)
 	m.totalReadBytes.Set(volStats.totalReadBytes)
 	m.totalWriteBytes.Set(volStats.totalWriteBytes)
 	m.totalReadBlockCount.Set(volStats.totalReadBlockCount)
 	m.totalWriteBlockCount.Set(volStats.totalWriteBlockCount)
+	m.readBytes.Set(volStats.readBytes)
+	m.writeBytes.Set(volStats.writeBytes)
 	m.totalReadTime.Set(volStats.totalReadTime)
 	m.totalWriteTime.Set(volStats.totalWriteTime)
+	m.avgReadLatency.Set(volStats.avgReadLatency)
+	m.avgWriteLatency.Set(volStats.avgWriteLatency)
 	m.sizeOfVolume.Set(volStats.size)
 	m.actualUsed.Set(volStats.actualSize)
+	m.volumeUptimeSeconds.Set(volStats.uptime)
+	m.setQueueStats(newResp)
+	m.setSessionStats(newResp)
+	m.setPatternStats(newResp)
+	m.setErrorStats(newResp)
+	m.setControllerInfo(newResp.Version)
 	volName := strings.TrimPrefix(newResp.Iqn, "iqn.2017-08.OpenEBS.cstor:")
 	// currently portal address is not available
 	// from the cstor.
@@ -183,6 +219,8 @@ func (c *Cstor) parser(stats v1.VolumeStats) VolumeStats {
 	volStats.totalWriteTime, _ = stats.TotalWriteTime.Float64()
 	volStats.totalReadBlockCount, _ = stats.TotalReadBlockCount.Float64()
 	volStats.totalWriteBlockCount, _ = stats.TotalWriteBlockCount.Float64()
+	volStats.blocksToBytes()
+	volStats.averageLatencies()
 	volStats.uptime, _ = stats.CstorUptime.Float64()
 	aUsed, _ := stats.UsedLogicalBlocks.Float64()
 	aUsed = aUsed * volStats.sectorSize
@@ -199,11 +237,11 @@ func (c *Cstor) parser(stats v1.VolumeStats) VolumeStats {
 func (c *Cstor) InitiateConnection() {
 	conn, err := net.Dial("unix", SocketPath)
 	if err != nil {
-		glog.Errorln("Dial error :", err)
+		logger.Errorln("Dial error :", err)
 	}
 	if conn != nil {
 		c.Conn = conn
-		glog.Info("Connection established")
+		logger.Info("Connection established")
 		c.ReadHeader()
 	}
 	return
@@ -222,7 +260,7 @@ func (c *Cstor) ReadHeader() error {
 	for {
 		n, err = c.Conn.Read(buf[:])
 		if err != nil {
-			glog.Error("Error in reading response, found error : ", err)
+			logger.Error("Error in reading response, found error : ", err)
 			return err
 		}
 		// apend the chunks into str
@@ -232,6 +270,6 @@ func (c *Cstor) ReadHeader() error {
 			break
 		}
 	}
-	glog.Infof("Got header: %#v", str)
+	logger.Infof("Got header: %#v", str)
 	return nil
 }
@@ -130,6 +130,7 @@ func TestCstorCollector(t *testing.T) {
 				regexp.MustCompile(`openebs_write_block_count 15`),
 				regexp.MustCompile(`openebs_read_time 13`),
 				regexp.MustCompile(`openebs_write_time 132`),
+				regexp.MustCompile(`openebs_volume_up 1`),
 			},
 			// unmatch is used for negative test, but this use case is for
 			// positive test, so passing default value.
@@ -148,6 +149,7 @@ func TestCstorCollector(t *testing.T) {
 				regexp.MustCompile(`openebs_write_block_count 0`),
 				regexp.MustCompile(`openebs_read_time 0`),
 				regexp.MustCompile(`openebs_write_time 0`),
+				regexp.MustCompile(`openebs_volume_up 0`),
 			},
 			// unmatch is used for negative test, but this use case is for
 			// positive test, so passing default value.
@@ -236,7 +238,7 @@ func TestCstorStatsCollector(t *testing.T) {
 				Cstor: Cstor{
 					Conn: nil,
 				},
-				Metrics: *MetricsInitializer("cstor"),
+				Metrics: *MetricsInitializer("cstor", DefaultNamespace),
 			},
 			fakeUnixServer: true,
 			err:            nil,
@@ -247,9 +249,9 @@ func TestCstorStatsCollector(t *testing.T) {
 				Cstor: Cstor{
 					Conn: nil,
 				},
-				Metrics: *MetricsInitializer("cstor"),
+				Metrics: *MetricsInitializer("cstor", DefaultNamespace),
 			},
-			err: errors.New("error in initiating connection with socket"),
+			err: ErrSocketConnection,
 		},
 	}
 	for name, tt := range cases {
@@ -268,7 +270,7 @@ func TestCstorStatsCollector(t *testing.T) {
 				tt.exporter.Conn = conn
 			}
 			got := tt.exporter.Cstor.collector(&tt.exporter.Metrics)
-			if !reflect.DeepEqual(got, tt.err) {
+			if !errors.Is(got, tt.err) {
 				t.Fatalf("collector() : expected %v, got %v", tt.err, got)
 			}
 		})
@@ -347,3 +349,59 @@ func TestReadHeader(t *testing.T) {
 		Unlink(t)
 	}
 }
+
+func TestCstorGetVolumeStats(t *testing.T) {
+	Unlink(t)
+	cases := map[string]struct {
+		response string
+		output   v1.VolumeStats
+		err      error
+	}{
+		"[Success] istgt is reachable and giving expected stats": {
+			response: CstorResponse,
+			output: v1.VolumeStats{
+				Size:                 "10737418240",
+				Iqn:                  "iqn.2017-08.OpenEBS.cstor:vol1",
+				Writes:               "0",
+				Reads:                "0",
+				TotalReadBytes:       "0",
+				TotalWriteBytes:      "0",
+				UsedLogicalBlocks:    "19",
+				SectorSize:           "512",
+				TotalReadBlockCount:  "12",
+				TotalWriteBlockCount: "15",
+				TotalReadTime:        "13",
+				TotalWriteTime:       "132",
+				CstorUptime:          "20",
+			},
+			err: nil,
+		},
+		"[Failure] istgt is reachable and giving empty stats": {
+			response: NilCstorResponse,
+			output:   v1.VolumeStats{},
+			err:      ErrEmptyResponse,
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			var wg sync.WaitGroup
+			wg.Add(1)
+			runFakeUnixServer(t, &wg, tt.response)
+			wg.Wait()
+			conn, err := net.Dial("unix", "/tmp/go.sock")
+			if err != nil {
+				t.Fatal("err in dial :", err)
+			}
+			c := &Cstor{Conn: conn}
+			var got v1.VolumeStats
+			err = c.getVolumeStats(&got)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("getVolumeStats() : expected error %v, got %v", tt.err, err)
+			}
+			if !reflect.DeepEqual(got, tt.output) {
+				t.Fatalf("getVolumeStats() : expected %v, got %v", tt.output, got)
+			}
+		})
+		Unlink(t)
+	}
+}
@@ -0,0 +1,45 @@
+package collector
+
+import (
+	"sync"
+	"time"
+)
+
+// errorLog rate limits the logging of the failures in collecting the
+// stats of a volume, the first failure is logged and then at most one
+// per interval until the volume recovers. It is safe for concurrent use.
+type errorLog struct {
+	mu sync.Mutex
+	// failing is true if the last collection has failed, loggedAt is
+	// the time at which the last failure was logged.
+	failing  bool
+	loggedAt time.Time
+	// suppressed is the no of failures which were not logged since
+	// loggedAt.
+	suppressed int
+}
+
+// failed records a failure at now and returns true if it has to be
+// logged along with the no of failures which were not logged since the
+// last logged one.
+func (l *errorLog) failed(now time.Time, interval time.Duration) (bool, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.failing && now.Sub(l.loggedAt) < interval {
+		l.suppressed++
+		return false, 0
+	}
+	suppressed := l.suppressed
+	l.failing, l.loggedAt, l.suppressed = true, now, 0
+	return true, suppressed
+}
+
+// recovered records a successful collection and returns true if the
+// volume was failing, so that the next failure is logged immediately.
+func (l *errorLog) recovered() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	failing := l.failing
+	l.failing, l.suppressed = false, 0
+	return failing
+}
@@ -0,0 +1,41 @@
+package collector
+
+import (
+	"testing"
+	"time"
+)
+
+func TestErrorLog(t *testing.T) {
+	start := time.Now()
+	l := &errorLog{}
+	// cases are run in order since each one depends on the failures
+	// recorded by the previous ones.
+	cases := []struct {
+		name       string
+		after      time.Duration
+		recovered  bool
+		log        bool
+		suppressed int
+	}{
+		{name: "first failure is logged", log: true},
+		{name: "failure within the interval is not logged", after: 10 * time.Second},
+		{name: "another failure within the interval is not logged", after: 59 * time.Second},
+		{name: "failure after the interval is logged", after: time.Minute, log: true, suppressed: 2},
+		{name: "failure after the recovery is logged", after: time.Minute + time.Second, recovered: true, log: true},
+		{name: "failure within the interval after the recovery is not logged", after: time.Minute + 2*time.Second},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.recovered && !l.recovered() {
+				t.Fatal("recovered() => false, want true for a failing volume")
+			}
+			log, suppressed := l.failed(start.Add(tt.after), time.Minute)
+			if log != tt.log || suppressed != tt.suppressed {
+				t.Fatalf("failed() => %v, %d, want %v, %d", log, suppressed, tt.log, tt.suppressed)
+			}
+		})
+	}
+	if (&errorLog{}).recovered() {
+		t.Fatal("recovered() => true, want false for a volume which never failed")
+	}
+}
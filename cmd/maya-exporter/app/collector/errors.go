@@ -0,0 +1,39 @@
+package collector
+
+import "errors"
+
+// The errors returned by the collection of the stats, these are wrapped
+// with the details of the failure so that the callers can use errors.Is
+// to know the cause of the failure.
+var (
+	// ErrCollectMetrics is returned if the metrics of a volume could
+	// not be collected.
+	ErrCollectMetrics = errors.New("error in collecting metrics")
+	// ErrControllerUnreachable is returned if the request to the volume
+	// controller failed.
+	ErrControllerUnreachable = errors.New("volume controller is unreachable")
+	// ErrUnauthorized is returned if the credentials of the jiva
+	// controller are missing or not valid.
+	ErrUnauthorized = errors.New("Unauthorized request to the volume controller")
+	// ErrUnmarshalResponse is returned if the response of the volume
+	// controller or replica is not valid JSON.
+	ErrUnmarshalResponse = errors.New("Error in unmarshalling the json response")
+	// ErrStrictDecode is returned if the strict decoding is enabled and
+	// the response of the jiva controller has fields which are not
+	// known by the exporter.
+	ErrStrictDecode = errors.New("unexpected fields in the json response")
+	// ErrEmptyResponse is returned if cstor sends an empty response.
+	ErrEmptyResponse = errors.New("Got empty response from cstor")
+	// ErrSocketConnection is returned if the connection with the cstor
+	// socket could not be initiated.
+	ErrSocketConnection = errors.New("error in initiating connection with socket")
+	// ErrInvalidURL is returned if the url of a volume controller is not
+	// valid.
+	ErrInvalidURL = errors.New("invalid url")
+	// ErrInvalidControllerHost is returned if the host of the url of a
+	// volume controller is empty or can't be dialled, e.g. 0.0.0.0.
+	ErrInvalidControllerHost = errors.New("invalid controller host")
+	// ErrTooManyRedirects is returned if a request to the jiva controller
+	// is redirected more than the max no of redirects.
+	ErrTooManyRedirects = errors.New("too many redirects")
+)
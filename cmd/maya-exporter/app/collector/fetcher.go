@@ -0,0 +1,38 @@
+package collector
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/openebs/maya/types/v1"
+)
+
+// StatsFetcher fetches the stats of a volume, it decouples the collection
+// of the metrics from the transport used to reach the volume controller.
+type StatsFetcher interface {
+	Fetch(ctx context.Context) (v1.VolumeStats, error)
+}
+
+// JivaHTTPFetcher is the StatsFetcher which fetches the stats from the
+// stats api of the jiva controller, or reads them from the StatsFile of
+// the Jiva if it is set. The Jiva fetches its stats the same way unless
+// its Fetcher is set, see Jiva.fetch.
+type JivaHTTPFetcher struct {
+	Jiva *Jiva
+}
+
+// Fetch implements StatsFetcher.
+func (f JivaHTTPFetcher) Fetch(ctx context.Context) (v1.VolumeStats, error) {
+	res, err := f.Jiva.getVolumeStats(ctx)
+	return res.stats, err
+}
+
+// NewJivaStatsFetcherExporter returns the exporter which collects the
+// stats of the jiva volume fetched by the given fetcher. The stats of the
+// replicas are not collected as they can only be fetched from the jiva
+// controller.
+func NewJivaStatsFetcherExporter(fetcher StatsFetcher) *VolumeStatsExporter {
+	exporter := NewJivaStatsExporter(&url.URL{}, "jiva")
+	exporter.Fetcher = fetcher
+	return exporter
+}
@@ -0,0 +1,75 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/openebs/maya/types/v1"
+)
+
+// fakeStatsFetcher returns the given stats or error, stats can be changed
+// between the scrapes.
+type fakeStatsFetcher struct {
+	stats   v1.VolumeStats
+	err     error
+	fetches int
+}
+
+func (f *fakeStatsFetcher) Fetch(ctx context.Context) (v1.VolumeStats, error) {
+	f.fetches++
+	return f.stats, f.err
+}
+
+// newFakeStatsFetcher returns the fakeStatsFetcher of the stats decoded
+// from the given response of the jiva controller.
+func newFakeStatsFetcher(t *testing.T, resp string) *fakeStatsFetcher {
+	f := &fakeStatsFetcher{}
+	f.setStats(t, resp)
+	return f
+}
+
+func (f *fakeStatsFetcher) setStats(t *testing.T, resp string) {
+	f.stats = v1.VolumeStats{}
+	if err := json.Unmarshal([]byte(resp), &f.stats); err != nil {
+		t.Fatalf("invalid stats %q: %v", resp, err)
+	}
+}
+
+func TestJivaCollectorFetcher(t *testing.T) {
+	cases := map[string]struct {
+		fetcher *fakeStatsFetcher
+		match   []*regexp.Regexp
+	}{
+		"[Success] stats are fetched by the fetcher": {
+			fetcher: newFakeStatsFetcher(t, validControllerResp),
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_reads 5`),
+				regexp.MustCompile(`openebs_writes 11`),
+				regexp.MustCompile(`openebs_volume_up 1`),
+			},
+		},
+		"[Failure] fetcher returns error": {
+			fetcher: &fakeStatsFetcher{err: errors.New("connection refused")},
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_volume_up 0`),
+				regexp.MustCompile(`openebs_connection_error_total{err="connection refused"} 1`),
+			},
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			buf := scrape(t, NewJivaStatsFetcherExporter(tt.fetcher))
+			if tt.fetcher.fetches != 1 {
+				t.Errorf("fetches => got %d, want 1", tt.fetcher.fetches)
+			}
+			for _, re := range tt.match {
+				if !re.Match(buf) {
+					t.Errorf("failed matching: %q", re)
+				}
+			}
+		})
+	}
+}
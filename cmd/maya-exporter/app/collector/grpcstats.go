@@ -0,0 +1,172 @@
+package collector
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/openebs/maya/types/v1"
+	"google.golang.org/grpc"
+)
+
+// DefaultGRPCStatsAddr is the address of the gRPC stats api of the cstor
+// target used if the address is not passed.
+const DefaultGRPCStatsAddr = "localhost:7777"
+
+// DefaultGRPCStatsTimeout is the timeout of the requests to the gRPC stats
+// api used if Cstor.StatsTimeout is not set.
+const DefaultGRPCStatsTimeout = 30 * time.Second
+
+// getVolumeStatsMethod is the full name of the GetVolumeStats method of
+// the VolumeStats service of volumestats.proto.
+const getVolumeStatsMethod = "/v1alpha1.VolumeStats/GetVolumeStats"
+
+// VolumeStatsRequest and VolumeStatsResponse are the messages of the
+// VolumeStats service defined in
+// pkg/apis/openebs.io/v1alpha1/volumestats.proto. They are declared with
+// the struct tags which protoc-gen-go generates, the vendored protobuf
+// package marshals them using these tags.
+type VolumeStatsRequest struct {
+	Version int32 `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+}
+
+// Reset implements proto.Message.
+func (m *VolumeStatsRequest) Reset() { *m = VolumeStatsRequest{} }
+
+// String implements proto.Message.
+func (m *VolumeStatsRequest) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage implements proto.Message.
+func (*VolumeStatsRequest) ProtoMessage() {}
+
+// VolumeStatsResponse has the same fields as the IOSTATS response of the
+// unix socket of the cstor target, see newGRPCResponse.
+type VolumeStatsResponse struct {
+	Version              int32  `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	Iqn                  string `protobuf:"bytes,2,opt,name=iqn,proto3" json:"iqn,omitempty"`
+	Name                 string `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	ReadIOPS             uint64 `protobuf:"varint,4,opt,name=read_iops,json=readIops,proto3" json:"read_iops,omitempty"`
+	TotalReadTime        uint64 `protobuf:"varint,5,opt,name=total_read_time,json=totalReadTime,proto3" json:"total_read_time,omitempty"`
+	TotalReadBlockCount  uint64 `protobuf:"varint,6,opt,name=total_read_block_count,json=totalReadBlockCount,proto3" json:"total_read_block_count,omitempty"`
+	TotalReadBytes       uint64 `protobuf:"varint,7,opt,name=total_read_bytes,json=totalReadBytes,proto3" json:"total_read_bytes,omitempty"`
+	WriteIOPS            uint64 `protobuf:"varint,8,opt,name=write_iops,json=writeIops,proto3" json:"write_iops,omitempty"`
+	TotalWriteTime       uint64 `protobuf:"varint,9,opt,name=total_write_time,json=totalWriteTime,proto3" json:"total_write_time,omitempty"`
+	TotalWriteBlockCount uint64 `protobuf:"varint,10,opt,name=total_write_block_count,json=totalWriteBlockCount,proto3" json:"total_write_block_count,omitempty"`
+	TotalWriteBytes      uint64 `protobuf:"varint,11,opt,name=total_write_bytes,json=totalWriteBytes,proto3" json:"total_write_bytes,omitempty"`
+	UsedLogicalBlocks    uint64 `protobuf:"varint,12,opt,name=used_logical_blocks,json=usedLogicalBlocks,proto3" json:"used_logical_blocks,omitempty"`
+	UsedBlocks           uint64 `protobuf:"varint,13,opt,name=used_blocks,json=usedBlocks,proto3" json:"used_blocks,omitempty"`
+	SectorSize           uint64 `protobuf:"varint,14,opt,name=sector_size,json=sectorSize,proto3" json:"sector_size,omitempty"`
+	Size                 uint64 `protobuf:"varint,15,opt,name=size,proto3" json:"size,omitempty"`
+	Uptime               uint64 `protobuf:"varint,16,opt,name=uptime,proto3" json:"uptime,omitempty"`
+	RevisionCounter      uint64 `protobuf:"varint,17,opt,name=revision_counter,json=revisionCounter,proto3" json:"revision_counter,omitempty"`
+	ReplicaCounter       uint64 `protobuf:"varint,18,opt,name=replica_counter,json=replicaCounter,proto3" json:"replica_counter,omitempty"`
+	TargetVersion        string `protobuf:"bytes,19,opt,name=target_version,json=targetVersion,proto3" json:"target_version,omitempty"`
+}
+
+// Reset implements proto.Message.
+func (m *VolumeStatsResponse) Reset() { *m = VolumeStatsResponse{} }
+
+// String implements proto.Message.
+func (m *VolumeStatsResponse) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage implements proto.Message.
+func (*VolumeStatsResponse) ProtoMessage() {}
+
+// VolumeStatsClient is the client of the VolumeStats service of the cstor
+// targets. It is implemented by the client returned by
+// NewVolumeStatsClient, tests can implement it to inject fake stats.
+type VolumeStatsClient interface {
+	GetVolumeStats(ctx context.Context, in *VolumeStatsRequest, opts ...grpc.CallOption) (*VolumeStatsResponse, error)
+}
+
+type volumeStatsClient struct {
+	cc *grpc.ClientConn
+}
+
+// NewVolumeStatsClient returns the VolumeStatsClient which calls the
+// VolumeStats service over the given connection.
+func NewVolumeStatsClient(cc *grpc.ClientConn) VolumeStatsClient {
+	return &volumeStatsClient{cc}
+}
+
+func (c *volumeStatsClient) GetVolumeStats(ctx context.Context, in *VolumeStatsRequest, opts ...grpc.CallOption) (*VolumeStatsResponse, error) {
+	out := new(VolumeStatsResponse)
+	if err := c.cc.Invoke(ctx, getVolumeStatsMethod, in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// NewCstorGRPCStatsExporter returns the exporter of the stats of the cstor
+// volume which are fetched using the given client of the gRPC stats api
+// rather than the unix socket.
+func NewCstorGRPCStatsExporter(client VolumeStatsClient, casType string) *VolumeStatsExporter {
+	return &VolumeStatsExporter{
+		CASType:   casType,
+		Namespace: DefaultNamespace,
+		Cstor: Cstor{
+			StatsClient: client,
+		},
+		Metrics: *MetricsInitializer(casType, DefaultNamespace),
+	}
+}
+
+// grpcCollector sets the metrics from the stats fetched using the
+// StatsClient, unlike collector there is no connection to initiate again
+// as the grpc connection reconnects by itself.
+func (c *Cstor) grpcCollector(m *Metrics) error {
+	if err := c.set(m); err != nil {
+		m.connectionErrorCounter.WithLabelValues(err.Error()).Inc()
+		m.volumeUp.Set(0)
+		return err
+	}
+	m.volumeUp.Set(1)
+	return nil
+}
+
+// getGRPCVolumeStats fetches the stats of the volume using the
+// StatsClient into obj.
+func (c *Cstor) getGRPCVolumeStats(obj *v1.VolumeStats) error {
+	timeout := c.StatsTimeout
+	if timeout <= 0 {
+		timeout = DefaultGRPCStatsTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	resp, err := c.StatsClient.GetVolumeStats(ctx, &VolumeStatsRequest{Version: 1})
+	if err != nil {
+		return err
+	}
+	*obj = newGRPCResponse(resp)
+	return nil
+}
+
+// newGRPCResponse maps the response of the gRPC stats api to the stats of
+// the volume, so that these are set to the metrics like the stats read
+// from the unix socket.
+func newGRPCResponse(resp *VolumeStatsResponse) v1.VolumeStats {
+	number := func(n uint64) v1.FlexNumber {
+		return v1.FlexNumber(strconv.FormatUint(n, 10))
+	}
+	return v1.VolumeStats{
+		Iqn:                  resp.Iqn,
+		Name:                 resp.Name,
+		Reads:                number(resp.ReadIOPS),
+		TotalReadTime:        number(resp.TotalReadTime),
+		TotalReadBlockCount:  number(resp.TotalReadBlockCount),
+		TotalReadBytes:       number(resp.TotalReadBytes),
+		Writes:               number(resp.WriteIOPS),
+		TotalWriteTime:       number(resp.TotalWriteTime),
+		TotalWriteBlockCount: number(resp.TotalWriteBlockCount),
+		TotalWriteBytes:      number(resp.TotalWriteBytes),
+		UsedLogicalBlocks:    number(resp.UsedLogicalBlocks),
+		UsedBlocks:           number(resp.UsedBlocks),
+		SectorSize:           number(resp.SectorSize),
+		Size:                 number(resp.Size),
+		CstorUptime:          number(resp.Uptime),
+		RevisionCounter:      number(resp.RevisionCounter),
+		ReplicaCounter:       number(resp.ReplicaCounter),
+		Version:              resp.TargetVersion,
+	}
+}
@@ -0,0 +1,105 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"net"
+	"regexp"
+	"testing"
+
+	"google.golang.org/grpc"
+)
+
+// volumeStatsServer serves the VolumeStats service of volumestats.proto
+// with the given response.
+type volumeStatsServer struct {
+	resp *VolumeStatsResponse
+}
+
+var volumeStatsServiceDesc = grpc.ServiceDesc{
+	ServiceName: "v1alpha1.VolumeStats",
+	HandlerType: (*interface{})(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "GetVolumeStats",
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			in := new(VolumeStatsRequest)
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+			return srv.(*volumeStatsServer).resp, nil
+		},
+	}},
+	Metadata: "volumestats.proto",
+}
+
+// fakeVolumeStatsClient returns the given error.
+type fakeVolumeStatsClient struct {
+	err error
+}
+
+func (f fakeVolumeStatsClient) GetVolumeStats(ctx context.Context, in *VolumeStatsRequest, opts ...grpc.CallOption) (*VolumeStatsResponse, error) {
+	return nil, f.err
+}
+
+func TestCstorGRPCCollector(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	server.RegisterService(&volumeStatsServiceDesc, &volumeStatsServer{resp: &VolumeStatsResponse{
+		Iqn:                  "iqn.2017-08.OpenEBS.cstor:vol1",
+		ReadIOPS:             5,
+		WriteIOPS:            11,
+		TotalReadTime:        13,
+		TotalWriteTime:       132,
+		TotalReadBlockCount:  12,
+		TotalWriteBlockCount: 15,
+		SectorSize:           512,
+		Size:                 10737418240,
+		Uptime:               100,
+		TargetVersion:        "1.0.0",
+	}})
+	go server.Serve(lis)
+	defer server.Stop()
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	cases := map[string]struct {
+		client VolumeStatsClient
+		match  []*regexp.Regexp
+	}{
+		"[Success] stats are fetched using grpc": {
+			client: NewVolumeStatsClient(conn),
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_reads 5`),
+				regexp.MustCompile(`openebs_writes 11`),
+				regexp.MustCompile(`openebs_read_block_count 12`),
+				regexp.MustCompile(`openebs_write_time 132`),
+				regexp.MustCompile(`openebs_size_of_volume 10`),
+				regexp.MustCompile(`openebs_volume_uptime{castype="cstor",iqn="iqn.2017-08.OpenEBS.cstor:vol1",portal="localhost",volName="vol1"} 100`),
+				regexp.MustCompile(`openebs_volume_up 1`),
+			},
+		},
+		"[Failure] stats api is not reachable": {
+			client: fakeVolumeStatsClient{err: errors.New("connection refused")},
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_volume_up 0`),
+				regexp.MustCompile(`openebs_connection_error_total{err="connection refused"} 1`),
+			},
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			buf := scrape(t, NewCstorGRPCStatsExporter(tt.client, "cstor"))
+			for _, re := range tt.match {
+				if !re.Match(buf) {
+					t.Errorf("failed matching: %q", re)
+				}
+			}
+		})
+	}
+}
@@ -4,73 +4,588 @@
 package collector
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/openebs/maya/types/v1"
+	"golang.org/x/net/http2"
 
-	"github.com/golang/glog"
+	"github.com/openebs/maya/cmd/maya-exporter/app/logger"
+)
+
+const (
+	// statsAPI is the api of the jiva controller which returns the
+	// stats of the volume.
+	statsAPI = "/v1/stats"
+	// volumeStatsAPI is the api of the newer jiva controllers which
+	// returns the stats of the volume.
+	volumeStatsAPI = "/v1/volumes/stats"
+	// unixScheme is the scheme of the url of a jiva controller which
+	// listens on a unix socket, e.g. unix:///var/run/jiva.sock.
+	unixScheme = "unix"
 )
 
 // NewJivaStatsExporter returns Jiva volume controller URL along with Path.
 func NewJivaStatsExporter(volumeControllerURL *url.URL, casType string) *VolumeStatsExporter {
-	volumeControllerURL.Path = "v1/stats"
 	return &VolumeStatsExporter{
-		CASType: casType,
+		CASType:   casType,
+		Namespace: DefaultNamespace,
 		Jiva: Jiva{
-			VolumeControllerURL: volumeControllerURL.String(),
+			VolumeControllerURL: controllerStatsURL(volumeControllerURL),
+			Retries:             DefaultRetries,
+			RetryBackoff:        DefaultRetryBackoff,
+			cache:               &statsCache{},
 		},
-		Metrics: *MetricsInitializer(casType),
+		Metrics: *MetricsInitializer(casType, DefaultNamespace),
 	}
 }
 
+// NewJivaStatsFileExporter returns the exporter which reads the stats of
+// the volume from the given file with a captured response of the stats
+// api of the jiva controller, see Jiva.StatsFile. The stats are parsed
+// the same way as the responses of the controller.
+func NewJivaStatsFileExporter(path string) *VolumeStatsExporter {
+	exporter := NewJivaStatsExporter(&url.URL{Scheme: "file"}, "jiva")
+	exporter.VolumeControllerURL = "file://" + path
+	exporter.StatsFile = path
+	return exporter
+}
+
+// NewJivaVolumesStatsExporter returns the exporter which collects the
+// stats of all the given volumes, the metrics of each volume are labelled
+// with the name of the volume. It returns error if the URL of any
+// volume is not correct.
+func NewJivaVolumesStatsExporter(volumes []VolumeTarget, casType string) (*VolumeStatsExporter, error) {
+	for _, volume := range volumes {
+		if _, err := normalizeURL(volume.URL); err != nil {
+			return nil, fmt.Errorf("invalid URL %q of volume %q: %w", volume.URL, volume.Name, err)
+		}
+	}
+	return &VolumeStatsExporter{
+		CASType:   casType,
+		Namespace: DefaultNamespace,
+		Volumes:   volumes,
+		Jiva: Jiva{
+			Retries:      DefaultRetries,
+			RetryBackoff: DefaultRetryBackoff,
+		},
+		Metrics: *MetricsInitializer(casType, DefaultNamespace),
+	}, nil
+}
+
+// initTargets initialises the targets from the Volumes and the summary of
+// the scrape durations only once. Each target inherits the configuration
+// (timeout etc.) of the exporter's Jiva, so it must be set before the
+// exporter is registered.
+func (v *VolumeStatsExporter) initTargets() {
+	v.targetsOnce.Do(func() {
+		v.disabledMu.Lock()
+		defer v.disabledMu.Unlock()
+		v.initScrapeSummary()
+		for _, volume := range v.Volumes {
+			volumeControllerURL, err := normalizeURL(volume.URL)
+			if err != nil {
+				logger.Errorf("invalid URL %q of volume %q: %v", volume.URL, volume.Name, err)
+				continue
+			}
+			jiva := v.Jiva
+			jiva.VolumeControllerURL = controllerStatsURL(volumeControllerURL)
+			jiva.ControllerURLFile = ""
+			// the cache is not shared between the volumes.
+			jiva.cache = &statsCache{}
+			jiva.client = nil
+			jiva.replicaClient = nil
+			jiva.replicas = nil
+			metrics := newMetrics(v.CASType, v.Namespace, v.targetLabels(volume.Name), v.latencyBuckets, v.counterMode)
+			metrics.filter(v.allowedMetrics, v.disabledMetrics)
+			v.targets = append(v.targets, &target{
+				name:    volume.Name,
+				Jiva:    jiva,
+				Metrics: *metrics,
+			})
+		}
+	})
+}
+
 // collector selects the container attached storage for the collection of
 // metrics.Supported CAS are jiva and cstor.
-func (j *Jiva) collector(m *Metrics) error {
+func (j *Jiva) collector(ctx context.Context, m *Metrics) error {
+	j.reloadControllerURL()
+	j.reloadCredentials()
+	// all the requests of the scrape, i.e. the requests for the stats,
+	// their retries and the requests to the replicas, share a single
+	// deadline so that the scrape never takes longer than the timeout.
+	ctx, cancel := context.WithTimeout(ctx, j.timeout())
+	defer cancel()
 	// set the metrics from jiva controller and send it via channels
-	if err := j.set(m); err != nil {
+	err := j.set(ctx, m)
+	m.scrapeRedirectsCounter.Add(float64(atomic.SwapInt64(&j.redirects, 0)))
+	if err != nil {
+		if isTimeout(err) {
+			m.scrapeTimeoutCounter.Inc()
+		}
+		if errors.Is(err, ErrStrictDecode) {
+			m.strictDecodeErrors.Inc()
+		}
 		m.connectionErrorCounter.WithLabelValues(err.Error()).Inc()
-		return errors.New("error in collecting metrics")
+		m.volumeUp.Set(0)
+		return fmt.Errorf("%w: %w", ErrCollectMetrics, err)
 	}
+	m.volumeUp.Set(1)
 	return nil
 }
 
-// getVolumeStats is used to get the response from the Jiva controller
-// which then unmarshalled into the v1.VolumeStats structure.
-func (j *Jiva) getVolumeStats(obj *v1.VolumeStats) error {
-	httpClient := http.DefaultClient
-	httpClient.Timeout = 1 * time.Second
-	resp, err := httpClient.Get(j.VolumeControllerURL)
+// controllerStatsURL returns the url of the stats api of the jiva
+// controller. The url of a controller listening on a unix socket is
+// returned as it is, the path of the url is the path of the socket.
+func controllerStatsURL(volumeControllerURL *url.URL) string {
+	if volumeControllerURL.Scheme == unixScheme {
+		return volumeControllerURL.String()
+	}
+	volumeControllerURL.Path = "v1/stats"
+	return volumeControllerURL.String()
+}
+
+// statsPaths returns the path of the stats api of the jiva controller
+// followed by its alternate path.
+func (j *Jiva) statsPaths() []string {
+	path := j.StatsPath
+	if len(path) == 0 {
+		path = statsAPI
+	}
+	if path == statsAPI {
+		return []string{path, volumeStatsAPI}
+	}
+	return []string{path, statsAPI}
+}
 
+// getStats sends the request for the stats to the stats api of the jiva
+// controller, the alternate path is tried if the stats api returns 404.
+// The retries of the requests to both the paths are added to retries.
+func (j *Jiva) getStats(ctx context.Context, retries *int) (*http.Response, error) {
+	for _, path := range j.statsPaths() {
+		statsURL, err := j.apiURL(path)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := j.getWithRetry(ctx, statsURL, retries)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrControllerUnreachable, err)
+		}
+		if resp.StatusCode != http.StatusNotFound {
+			logger.WithVolume(j.VolumeControllerURL).Debugf("got stats from %s", path)
+			return resp, nil
+		}
+		resp.Body.Close()
+		logger.WithVolume(j.VolumeControllerURL).Debugf("stats api %s not found", path)
+	}
+	return nil, fmt.Errorf("stats api not found, tried %s", strings.Join(j.statsPaths(), ", "))
+}
+
+// socketPath returns the path of the unix socket of the jiva controller
+// and false if the controller doesn't listen on a unix socket.
+func (j *Jiva) socketPath() (string, bool) {
+	u, err := url.Parse(j.VolumeControllerURL)
+	if err != nil || u.Scheme != unixScheme {
+		return "", false
+	}
+	return u.Path, true
+}
+
+// apiURL returns the url of the given api of the jiva controller, the
+// host of the url is ignored if the controller listens on a unix socket
+// as the requests are sent over the socket. It returns error if the host
+// is not valid, see validateHost.
+func (j *Jiva) apiURL(path string) (string, error) {
+	if _, ok := j.socketPath(); ok {
+		return "http://" + unixScheme + path, nil
+	}
+	u, err := url.Parse(j.VolumeControllerURL)
 	if err != nil {
-		glog.Errorf("could not retrieve OpenEBS Volume controller metrics: %v", err)
-		return err
+		return "", err
+	}
+	if err := validateHost(u); err != nil {
+		return "", err
+	}
+	u.Path = path
+	return u.String(), nil
+}
+
+// timeout returns the timeout of the requests made to the jiva
+// controller, it falls back to DefaultTimeout if Timeout is not set.
+func (j *Jiva) timeout() time.Duration {
+	if j.Timeout <= 0 {
+		return DefaultTimeout
+	}
+	return j.Timeout
+}
+
+// clientMu guards the creation of the http clients of the jiva volumes.
+var clientMu sync.Mutex
+
+// httpClient returns the http client used for the requests made to the
+// jiva controller and replicas. It is created on the first request with
+// the configuration of the Jiva and reused by the following requests, so
+// the connections are kept alive across the scrapes unless
+// DisableKeepAlives is set.
+func (j *Jiva) httpClient() *http.Client {
+	clientMu.Lock()
+	defer clientMu.Unlock()
+	if j.client == nil {
+		j.client = j.newHTTPClient(true)
+	}
+	return j.client
+}
+
+// replicaHTTPClient returns the http client used for the requests made to
+// the replicas. It is the client of the jiva controller unless the
+// controller listens on a unix socket, as the replicas are reached over
+// tcp at the addresses listed by the controller.
+func (j *Jiva) replicaHTTPClient() *http.Client {
+	if _, isUnix := j.socketPath(); !isUnix {
+		return j.httpClient()
+	}
+	clientMu.Lock()
+	defer clientMu.Unlock()
+	if j.replicaClient == nil {
+		j.replicaClient = j.newHTTPClient(false)
+	}
+	return j.replicaClient
+}
+
+// newHTTPClient returns a new http client with the configuration of the
+// Jiva, its requests are sent over the unix socket of the jiva controller
+// if the controller listens on one and dialSocket is set.
+func (j *Jiva) newHTTPClient(dialSocket bool) *http.Client {
+	config := j.tlsConfig()
+	transport := &http.Transport{
+		Proxy:             j.proxy(),
+		TLSClientConfig:   config,
+		DisableKeepAlives: j.DisableKeepAlives,
+		MaxIdleConns:      j.MaxIdleConns,
+		IdleConnTimeout:   j.IdleConnTimeout,
+	}
+	if transport.MaxIdleConns <= 0 {
+		transport.MaxIdleConns = DefaultMaxIdleConns
+	}
+	transport.MaxIdleConnsPerHost = transport.MaxIdleConns
+	if transport.IdleConnTimeout <= 0 {
+		transport.IdleConnTimeout = DefaultIdleConnTimeout
+	}
+	switch {
+	case j.DisableHTTP2:
+		// a non nil empty map disables HTTP/2.
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	case j.ForceHTTP2:
+		// the tls config may be shared with the other volumes, so it is
+		// cloned before h2 is added to its protocols.
+		if config != nil {
+			transport.TLSClientConfig = config.Clone()
+		}
+		if err := http2.ConfigureTransport(transport); err != nil {
+			logger.Errorf("could not configure HTTP/2 for the jiva controller: %v", err)
+		}
+	}
+	if socketPath, isUnix := j.socketPath(); isUnix && dialSocket {
+		// the requests over a unix socket are never proxied.
+		transport.Proxy = nil
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", socketPath)
+		}
+	}
+	return &http.Client{Timeout: j.timeout(), Transport: transport, CheckRedirect: j.checkRedirect}
+}
+
+// checkRedirect follows the redirect of a request unless the request has
+// already been redirected maxRedirects times, the redirects followed are
+// counted in scrape_redirects_total.
+func (j *Jiva) checkRedirect(req *http.Request, via []*http.Request) error {
+	if max := j.maxRedirects(); len(via) > max {
+		return fmt.Errorf("%w: %s redirected more than %d times", ErrTooManyRedirects, via[0].URL, max)
+	}
+	atomic.AddInt64(&j.redirects, 1)
+	logger.Infof("request to %s is redirected to %s", via[len(via)-1].URL, req.URL)
+	return nil
+}
+
+// maxRedirects returns the max no of redirects followed by a request.
+func (j *Jiva) maxRedirects() int {
+	switch {
+	case j.MaxRedirects < 0:
+		return 0
+	case j.MaxRedirects == 0:
+		return DefaultMaxRedirects
+	}
+	return j.MaxRedirects
+}
+
+// proxy returns the function which selects the proxy of a request, the
+// ProxyURL, if it is set, is used for all the requests otherwise the
+// proxy is taken from the environment.
+func (j *Jiva) proxy() func(*http.Request) (*url.URL, error) {
+	if j.ProxyURL != nil {
+		return http.ProxyURL(j.ProxyURL)
+	}
+	return http.ProxyFromEnvironment
+}
+
+// getWithRetry sends a GET request to the given url and retries it with
+// exponential backoff if it fails, the retries are added to retries.
+// Retries are not made if they can't complete before the deadline of
+// ctx, which is the deadline of the scrape, or within the timeout if ctx
+// has no deadline. The request and the retries are aborted if ctx is done.
+func (j *Jiva) getWithRetry(ctx context.Context, url string, retries *int) (*http.Response, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(j.timeout())
+	}
+	backoff := j.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		// the client is copied so that the timeout of the shared
+		// client is not changed, the copy uses the same transport.
+		httpClient := *j.httpClient()
+		httpClient.Timeout = time.Until(deadline)
+		req, err := j.newRequest(ctx, url)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := httpClient.Do(req)
+		// the request redirected too many times would be redirected
+		// the same way again, so it is not retried.
+		if err == nil || ctx.Err() != nil || errors.Is(err, ErrTooManyRedirects) ||
+			attempt >= j.Retries || time.Now().Add(backoff).After(deadline) {
+			return resp, err
+		}
+		// retries are counted in scrape_retries_total, only the final
+		// failure is logged.
+		logger.Debugf("request to %s failed, retrying in %v: %v", url, backoff, err)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		*retries++
 	}
-	body, err := ioutil.ReadAll(resp.Body)
+}
+
+// newRequest returns a GET request to the given url of the jiva
+// controller, the basic auth credentials are set if Username is set.
+// The gzip encoding is requested explicitly so that it doesn't depend on
+// the transport, the response must then be decompressed with readBody.
+func (j *Jiva) newRequest(ctx context.Context, url string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		glog.Error(err.Error())
-		return err
+		return nil, err
+	}
+	req.Header.Set("Accept-Encoding", gzipEncoding)
+	for name, values := range j.Headers {
+		if http.CanonicalHeaderKey(name) == "Host" {
+			// the Host header is ignored by the http client.
+			req.Host = values[len(values)-1]
+			continue
+		}
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
 	}
-	glog.Info("Got response: ", string(body))
-	err = json.Unmarshal(body, &obj)
+	// the credentials are read once so that a reload doesn't mix the
+	// username and the password of different credentials.
+	if username, password := j.basicAuth(); len(username) != 0 {
+		req.SetBasicAuth(username, password)
+	}
+	return req, nil
+}
 
+// gzipEncoding is the content encoding of the gzip compressed responses.
+const gzipEncoding = "gzip"
+
+// readBody reads the body of the response and decompresses it if it is
+// gzip compressed. The transport decompresses the response on its own
+// only if Accept-Encoding is not set on the request, in which case the
+// Content-Encoding header is removed from the response.
+func readBody(resp *http.Response) ([]byte, error) {
+	if !strings.EqualFold(resp.Header.Get("Content-Encoding"), gzipEncoding) {
+		return ioutil.ReadAll(resp.Body)
+	}
+	reader, err := gzip.NewReader(resp.Body)
 	if err != nil {
-		glog.Errorf("could not decode OpenEBS Volume controller metrics: %#v", err)
-		return errors.New("Error in unmarshalling the json response")
+		return nil, fmt.Errorf("invalid gzip response: %w", err)
 	}
+	defer reader.Close()
+	return ioutil.ReadAll(reader)
+}
 
+// isTimeout returns true if err is caused by the timeout of a request.
+func isTimeout(err error) bool {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Timeout()
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return netErr.Timeout()
+	}
+	return false
+}
+
+// jivaStatsFields are the fields of the response of the jiva controller
+// from which the metrics are set.
+var jivaStatsFields = []string{
+	"ReadIOPS", "TotalReadTime", "TotalReadBlockCount",
+	"WriteIOPS", "TotalWriteTime", "TotatWriteBlockCount",
+	"UsedLogicalBlocks", "UsedBlocks", "SectorSize", "Size",
+	"UpTime", "RevisionCounter", "ReplicaCounter",
+}
+
+// fetchResult is the result of a fetch of the stats of a volume along
+// with the details of the requests made for them, it is kept by the
+// scrape which made the fetch and by the cache rather than by the Jiva so
+// that the concurrent scrapes don't mix them up.
+type fetchResult struct {
+	stats v1.VolumeStats
+	// retries is the no of retries of the requests and parseErrors the no
+	// of stats fields which were missing or could not be parsed.
+	retries     int
+	parseErrors int
+	// responseBytes is the size of the body of the response.
+	responseBytes int
+	// trace is the trace of the request, it is nil if the tracing is
+	// disabled.
+	trace *requestTrace
+}
+
+// getVolumeStats is used to get the response from the Jiva controller
+// which then unmarshalled into the v1.VolumeStats structure. Fields
+// which are missing or can't be unmarshalled are skipped and counted
+// in parseErrors, it returns error only if the response is not JSON or
+// if StrictDecode is set and the response has unknown fields. The
+// response is read from the StatsFile instead if it is set. The result
+// has the retries made even if it returns error.
+func (j *Jiva) getVolumeStats(ctx context.Context) (fetchResult, error) {
+	var (
+		res  fetchResult
+		body []byte
+		err  error
+	)
+	if len(j.StatsFile) != 0 {
+		body, err = ioutil.ReadFile(j.StatsFile)
+	} else {
+		body, err = j.fetchVolumeStats(ctx, &res)
+	}
+	if err != nil {
+		return res, err
+	}
+	res.responseBytes = len(body)
+	// controllers may send surrounding whitespace e.g. a trailing
+	// newline, which is trimmed so that it is never treated as a
+	// part of the JSON.
+	body = bytes.TrimSpace(body)
+	logger.Info("Got response: ", string(body))
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(body, &fields)
+	if err != nil {
+		logger.Errorf("could not decode OpenEBS Volume controller metrics: %#v", err)
+		return res, fmt.Errorf("%w: %w", ErrUnmarshalResponse, err)
+	}
+	if j.StrictDecode {
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&v1.VolumeStats{}); err != nil {
+			logger.Errorf("could not strictly decode OpenEBS Volume controller metrics: %v", err)
+			return res, fmt.Errorf("%w: %w", ErrStrictDecode, err)
+		}
+	}
+	for name, value := range fields {
+		field, _ := json.Marshal(map[string]json.RawMessage{name: value})
+		if err := json.Unmarshal(field, &res.stats); err != nil {
+			logger.Warningf("could not decode field %q of OpenEBS Volume controller metrics: %v", name, err)
+			res.parseErrors++
+		}
+	}
+	for _, name := range jivaStatsFields {
+		if _, ok := fields[name]; !ok {
+			logger.Warningf("field %q is missing in OpenEBS Volume controller metrics", name)
+			res.parseErrors++
+		}
+	}
+	return res, nil
+}
+
+// fetchVolumeStats returns the body of the response of the stats api of
+// the jiva controller, the retries and the trace of the request are set
+// in res.
+func (j *Jiva) fetchVolumeStats(ctx context.Context, res *fetchResult) ([]byte, error) {
+	if j.EnableRequestTrace {
+		res.trace = newRequestTrace()
+		ctx = res.trace.withClientTrace(ctx)
+	}
+	resp, err := j.getStats(ctx, &res.retries)
+
+	if err != nil {
+		// the failure is logged by the exporter, see logError.
+		logger.WithVolume(j.VolumeControllerURL).WithError(err).Debugf("could not retrieve OpenEBS Volume controller metrics")
+		return nil, err
+	}
 	defer resp.Body.Close()
-	return nil
+	if resp.StatusCode == http.StatusUnauthorized {
+		logger.WithVolume(j.VolumeControllerURL).WithError(ErrUnauthorized).Debugf("could not retrieve OpenEBS Volume controller metrics")
+		return nil, ErrUnauthorized
+	}
+	body, err := readBody(resp)
+	if err != nil {
+		logger.Error(err.Error())
+		return nil, err
+	}
+	if res.trace != nil {
+		res.trace.finish()
+	}
+	return body, nil
+}
+
+// fetch fetches the stats of the volume using the Fetcher if it is set,
+// the details of the requests are known only if the stats are fetched
+// from the jiva controller by the Jiva itself.
+func (j *Jiva) fetch(ctx context.Context) (fetchResult, error) {
+	if j.Fetcher != nil {
+		stats, err := j.Fetcher.Fetch(ctx)
+		return fetchResult{stats: stats}, err
+	}
+	return j.getVolumeStats(ctx)
+}
+
+// volumeStats returns the stats of the volume and their age, these are
+// served from the cache if CacheTTL is set and the cached stats are
+// younger than it.
+func (j *Jiva) volumeStats(ctx context.Context) (fetchResult, time.Duration, error) {
+	fetch := func() (fetchResult, error) {
+		return j.fetch(ctx)
+	}
+	if j.CacheTTL <= 0 || j.cache == nil {
+		res, err := fetch()
+		return res, 0, err
+	}
+	return j.cache.get(j.CacheTTL, fetch)
 }
 
 // set is used to set the values gathered from Jiva volume
 // controller to prometheus gauges and counters.
-func (j *Jiva) set(m *Metrics) error {
+func (j *Jiva) set(ctx context.Context, m *Metrics) error {
 	var (
 		// JSON response from jiva controller
 		volStatsJSON v1.VolumeStats
@@ -78,26 +593,75 @@ func (j *Jiva) set(m *Metrics) error {
 		volStats VolumeStats
 	)
 
-	err := j.getVolumeStats(&volStatsJSON)
+	res, cacheAge, err := j.volumeStats(ctx)
+	m.scrapeRetriesCounter.Add(float64(res.retries))
+	m.parseErrorsCounter.Add(float64(res.parseErrors))
 	if err != nil {
 		return err
 	}
+	volStatsJSON = res.stats
+	prevStats, hasPrev := m.lastStats.load()
+	m.lastStats.store(volStatsJSON)
+	m.statsCacheAge.Set(cacheAge.Seconds())
+	// the stats served from the cache were fetched cacheAge ago, the
+	// metrics of the cached stats carry the time of their collection so
+	// that the rates are not computed over the time of the scrapes.
+	fetchedAt := time.Now().Add(-cacheAge)
+	if j.CacheTTL > 0 && j.cache != nil {
+		m.collectedAt.store(fetchedAt)
+	} else {
+		m.collectedAt.store(time.Time{})
+	}
+	m.controllerResponseBytes.Set(float64(res.responseBytes))
+	if res.trace != nil {
+		for phase, duration := range res.trace.phases() {
+			m.controllerRequestDuration.WithLabelValues(phase).Observe(duration.Seconds())
+		}
+	}
+	m.lastScrapeSuccess.Set(unixSeconds(fetchedAt))
 	volStats = j.parser(volStatsJSON)
+	if hasPrev {
+		m.observeLatencies(j.parser(prevStats), volStats)
+	}
 
 	m.reads.Set(volStats.reads)
 	m.totalReadTime.Set(volStats.totalReadTime)
 	m.writes.Set(volStats.writes)
 	m.totalWriteTime.Set(volStats.totalWriteTime)
+	m.avgReadLatency.Set(volStats.avgReadLatency)
+	m.avgWriteLatency.Set(volStats.avgWriteLatency)
 	m.totalReadBlockCount.Set(volStats.totalReadBlockCount)
 	m.totalWriteBlockCount.Set(volStats.totalWriteBlockCount)
-	m.sectorSize.Set(volStats.sectorSize)
+	m.readBytes.Set(volStats.readBytes)
+	m.writeBytes.Set(volStats.writeBytes)
+	m.setSectorSize(volStats.sectorSize)
 	m.logicalSize.Set(volStats.logicalSize)
 	m.actualUsed.Set(volStats.actualSize)
 	m.sizeOfVolume.Set(volStats.size)
+	m.volumeUptimeSeconds.Set(volStats.uptime)
+	m.revisionCounter.Set(volStats.revisionCounter)
+	m.replicaCount.Set(volStats.replicaCounter)
+	m.volumeUsedPercent.Set(volStats.usedPercent)
+	m.setQueueStats(volStatsJSON)
+	m.setSessionStats(volStatsJSON)
+	m.setPatternStats(volStatsJSON)
+	m.setErrorStats(volStatsJSON)
+	m.setControllerInfo(volStatsJSON.Version)
+	// opcodes which are not reported anymore are not emitted.
+	m.scsiIOCount.Reset()
+	for opcode, count := range volStatsJSON.SCSIIOCount {
+		m.scsiIOCount.WithLabelValues(strconv.Itoa(opcode)).Set(float64(count))
+	}
 	url := j.VolumeControllerURL
 	url = strings.TrimSuffix(url, ":9501/v1/stats")
 	url = strings.TrimPrefix(url, "http://")
+	url = strings.TrimPrefix(url, "https://")
 	m.volumeUpTime.WithLabelValues(volStatsJSON.Name, "iqn.2016-09.com.openebs.jiva:"+volStatsJSON.Name, url, "jiva").Set(volStatsJSON.UpTime)
+	// the replicas can't be reached if the stats are read from a file
+	// or fetched by a Fetcher other than the JivaHTTPFetcher.
+	if len(j.StatsFile) == 0 && j.Fetcher == nil && !j.DisableReplicaStats {
+		j.setReplicaStats(ctx, m)
+	}
 	return nil
 }
 
@@ -111,6 +675,12 @@ func (j *Jiva) parser(stats v1.VolumeStats) VolumeStats {
 	volStats.totalWriteBlockCount, _ = stats.TotalWriteBlockCount.Float64()
 
 	volStats.sectorSize, _ = stats.SectorSize.Float64()
+	volStats.blocksToBytes()
+	volStats.averageLatencies()
+	// UpTime is 0 if it is null or missing in the response.
+	volStats.uptime = stats.UpTime
+	volStats.revisionCounter, _ = stats.RevisionCounter.Float64()
+	volStats.replicaCounter, _ = stats.ReplicaCounter.Float64()
 
 	uBlocks, _ := stats.UsedBlocks.Float64()
 	uBlocks = uBlocks * volStats.sectorSize
@@ -120,5 +690,6 @@ func (j *Jiva) parser(stats v1.VolumeStats) VolumeStats {
 	volStats.actualSize, _ = v1.DivideFloat64(aUsed, v1.BytesToGB)
 	size, _ := stats.Size.Float64()
 	volStats.size, _ = v1.DivideFloat64(size, v1.BytesToGB)
+	volStats.usedPercent = usedPercentage(aUsed, size)
 	return volStats
 }
@@ -1,20 +1,32 @@
 package collector
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"math"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
 	"reflect"
 	"regexp"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/openebs/maya/types/v1"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
 	utiltesting "k8s.io/client-go/util/testing"
 )
 
@@ -50,6 +62,15 @@ func TestJivaCollector(t *testing.T) {
 				regexp.MustCompile(`openebs_write_time 15`),
 				regexp.MustCompile(`openebs_write_block_count 10`),
 				regexp.MustCompile(`openebs_size_of_volume 1`),
+				regexp.MustCompile(`openebs_volume_up 1`),
+				regexp.MustCompile(`openebs_volume_uptime_seconds 10`),
+				regexp.MustCompile(`openebs_revision_counter 100`),
+				regexp.MustCompile(`openebs_replica_count 6`),
+				regexp.MustCompile(`openebs_read_bytes_total 40960`),
+				regexp.MustCompile(`openebs_write_bytes_total 40960`),
+				regexp.MustCompile(`openebs_avg_read_latency_seconds 1e-08`),
+				regexp.MustCompile(`openebs_avg_write_latency_seconds 1e-09`),
+				regexp.MustCompile(`openebs_collector_scrape_duration_seconds [0-9.e-]+`),
 			},
 			// unmatch is used for negative test, but this use case is for
 			// positive test, so passing default value.
@@ -71,6 +92,9 @@ func TestJivaCollector(t *testing.T) {
 				regexp.MustCompile(`openebs_write_time 0`),
 				regexp.MustCompile(`openebs_write_block_count 0`),
 				regexp.MustCompile(`openebs_size_of_volume 0`),
+				regexp.MustCompile(`openebs_volume_up 0`),
+				regexp.MustCompile(`openebs_volume_uptime_seconds 0`),
+				regexp.MustCompile(`openebs_collector_scrape_duration_seconds [0-9.e-]+`),
 			},
 			// unmatch is used for negative test, but this use case is for
 			// positive test, so passing default value.
@@ -161,7 +185,7 @@ func TestJivaStatsCollector(t *testing.T) {
 				Jiva: Jiva{
 					VolumeControllerURL: "localhost:9500",
 				},
-				Metrics: *MetricsInitializer("jiva"),
+				Metrics: *MetricsInitializer("jiva", DefaultNamespace),
 			},
 			testServer: true,
 			fakehandler: utiltesting.FakeHandler{
@@ -178,9 +202,9 @@ func TestJivaStatsCollector(t *testing.T) {
 				Jiva: Jiva{
 					VolumeControllerURL: "localhost:9500",
 				},
-				Metrics: *MetricsInitializer("jiva"),
+				Metrics: *MetricsInitializer("jiva", DefaultNamespace),
 			},
-			err: errors.New("error in collecting metrics"),
+			err: ErrCollectMetrics,
 		},
 	}
 	for name, tt := range cases {
@@ -189,8 +213,8 @@ func TestJivaStatsCollector(t *testing.T) {
 				server := httptest.NewServer(&tt.fakehandler)
 				tt.exporter.VolumeControllerURL = server.URL
 			}
-			got := tt.exporter.Jiva.collector(&tt.exporter.Metrics)
-			if !reflect.DeepEqual(got, tt.err) {
+			got := tt.exporter.Jiva.collector(context.Background(), &tt.exporter.Metrics)
+			if !errors.Is(got, tt.err) {
 				t.Fatalf("collector() : expected %v, got %v", tt.err, got)
 			}
 		})
@@ -201,9 +225,10 @@ func TestGetVolumeStats(t *testing.T) {
 
 	cases := map[string]struct {
 		jiva        Jiva
-		obj         v1.VolumeStats
 		fakeHandler utiltesting.FakeHandler
 		err         error
+		parseErrors int
+		reads       v1.FlexNumber
 	}{
 		"Valid Response from jiva controller": {
 			fakeHandler: utiltesting.FakeHandler{
@@ -211,7 +236,8 @@ func TestGetVolumeStats(t *testing.T) {
 				ResponseBody: string(validControllerResp),
 				T:            t,
 			},
-			err: nil,
+			err:   nil,
+			reads: "5",
 		},
 		"Invalid Response from jiva controller": {
 			fakeHandler: utiltesting.FakeHandler{
@@ -219,7 +245,54 @@ func TestGetVolumeStats(t *testing.T) {
 				ResponseBody: string(invalidControllerResp),
 				T:            t,
 			},
-			err: errors.New("Error in unmarshalling the json response"),
+			err: ErrUnmarshalResponse,
+		},
+		"Response with missing fields from jiva controller": {
+			fakeHandler: utiltesting.FakeHandler{
+				StatusCode:   200,
+				ResponseBody: `{"Name":"vol1","ReadIOPS":"5","ReplicaCounter":2,"RevisionCounter":10,"SectorSize":"4096","Size":"1073741824","TotalReadBlockCount":"25","TotalReadTime":"45","TotalWriteTime":"30","TotatWriteBlockCount":"6","UpTime":158.667823193}`,
+				T:            t,
+			},
+			err:         nil,
+			parseErrors: 3,
+			reads:       "5",
+		},
+		"Response with invalid fields from jiva controller": {
+			fakeHandler: utiltesting.FakeHandler{
+				StatusCode:   200,
+				ResponseBody: `{"Name":"vol1","ReadIOPS":"5","ReplicaCounter":2,"RevisionCounter":10,"SectorSize":"4096","Size":"1073741824","TotalReadBlockCount":"25","TotalReadTime":"45","TotalWriteTime":"30","TotatWriteBlockCount":"6","UpTime":"invalid","UsedBlocks":{},"UsedLogicalBlocks":"23","WriteIOPS":"11"}`,
+				T:            t,
+			},
+			err:         nil,
+			parseErrors: 2,
+			reads:       "5",
+		},
+		"Response with numeric and empty fields from jiva controller": {
+			fakeHandler: utiltesting.FakeHandler{
+				StatusCode:   200,
+				ResponseBody: `{"Name":"vol1","ReadIOPS":5,"ReplicaCounter":"2","RevisionCounter":10,"SectorSize":4096,"Size":"1073741824","TotalReadBlockCount":25,"TotalReadTime":"","TotalWriteTime":null,"TotatWriteBlockCount":"6","UpTime":158.667823193,"UsedBlocks":5,"UsedLogicalBlocks":"23","WriteIOPS":11}`,
+				T:            t,
+			},
+			err:   nil,
+			reads: "5",
+		},
+		"Response with trailing newlines from jiva controller": {
+			fakeHandler: utiltesting.FakeHandler{
+				StatusCode:   200,
+				ResponseBody: validControllerResp + "\n\n",
+				T:            t,
+			},
+			err:   nil,
+			reads: "5",
+		},
+		"Response with surrounding whitespace from jiva controller": {
+			fakeHandler: utiltesting.FakeHandler{
+				StatusCode:   200,
+				ResponseBody: "\r\n  " + validControllerResp + " \t\r\n",
+				T:            t,
+			},
+			err:   nil,
+			reads: "5",
 		},
 	}
 	for name, tt := range cases {
@@ -227,10 +300,2213 @@ func TestGetVolumeStats(t *testing.T) {
 			server := httptest.NewServer(&tt.fakeHandler)
 			defer server.Close()
 			tt.jiva.VolumeControllerURL = server.URL
-			got := tt.jiva.getVolumeStats(&tt.obj)
-			if !reflect.DeepEqual(got, tt.err) {
+			res, got := tt.jiva.getVolumeStats(context.Background())
+			if !errors.Is(got, tt.err) {
 				t.Fatalf("getVolumeStats(%v) => got %v, want %v", server.URL, got, tt.err)
 			}
+			if res.parseErrors != tt.parseErrors {
+				t.Fatalf("getVolumeStats(%v) => got %v parse errors, want %v", server.URL, res.parseErrors, tt.parseErrors)
+			}
+			if res.stats.Reads != tt.reads {
+				t.Fatalf("getVolumeStats(%v) => got reads %v, want %v", server.URL, res.stats.Reads, tt.reads)
+			}
+		})
+	}
+}
+
+func TestJivaCollectorPartialStats(t *testing.T) {
+	controller := fakeJivaController(`{"Name":"vol1","ReadIOPS":"5","SectorSize":"4096","WriteIOPS":{}}`)
+	defer controller.Close()
+	controllerURL, _ := url.Parse(controller.URL)
+	buf := scrape(t, NewJivaStatsExporter(controllerURL, "jiva"))
+	for _, re := range []*regexp.Regexp{
+		regexp.MustCompile(`openebs_reads 5`),
+		regexp.MustCompile(`openebs_sector_size 4096`),
+		regexp.MustCompile(`openebs_volume_up 1`),
+		regexp.MustCompile(`openebs_parse_errors_total 11`),
+	} {
+		if !re.Match(buf) {
+			t.Errorf("failed matching: %q", re)
+		}
+	}
+}
+
+func TestJivaCollectorTimeout(t *testing.T) {
+	cases := map[string]struct {
+		timeout, delay time.Duration
+		timedOut       bool
+	}{
+		"[Success] controller responds before timeout": {
+			timeout:  time.Second,
+			timedOut: false,
+		},
+		"[Failure] controller doesn't respond before timeout": {
+			timeout:  50 * time.Millisecond,
+			delay:    500 * time.Millisecond,
+			timedOut: true,
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			controller := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(tt.delay)
+				fmt.Fprintln(w, validControllerResp)
+			}))
+			defer controller.Close()
+			control, _ := url.Parse(controller.URL)
+			exporter := NewJivaStatsExporter(control, "jiva")
+			exporter.Timeout = tt.timeout
+
+			err := exporter.Jiva.collector(context.Background(), &exporter.Metrics)
+			if (err != nil) != tt.timedOut {
+				t.Fatalf("collector() : unexpected error %v", err)
+			}
+			buf := scrape(t, exporter)
+			re := regexp.MustCompile(`openebs_scrape_timeout_total 0`)
+			if tt.timedOut {
+				re = regexp.MustCompile(`openebs_scrape_timeout_total [1-9]`)
+			}
+			if !re.Match(buf) {
+				t.Errorf("failed matching: %q", re)
+			}
+		})
+	}
+}
+
+func TestJivaParserUpTime(t *testing.T) {
+	cases := map[string]struct {
+		response string
+		uptime   float64
+	}{
+		"UpTime is a float": {
+			response: `{"UpTime":158.667823193}`,
+			uptime:   158.667823193,
+		},
+		"UpTime is null": {
+			response: `{"UpTime":null}`,
+			uptime:   0,
+		},
+		"UpTime is missing": {
+			response: `{"Name":"vol1"}`,
+			uptime:   0,
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			var stats v1.VolumeStats
+			if err := json.Unmarshal([]byte(tt.response), &stats); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			var j Jiva
+			if got := j.parser(stats).uptime; got != tt.uptime {
+				t.Fatalf("parser(%v) => got uptime %v, want %v", tt.response, got, tt.uptime)
+			}
+		})
+	}
+}
+
+func TestJivaParserCounters(t *testing.T) {
+	cases := map[string]struct {
+		response                        string
+		revisionCounter, replicaCounter float64
+	}{
+		"Counters are encoded as numbers": {
+			response:        `{"RevisionCounter":100,"ReplicaCounter":3}`,
+			revisionCounter: 100,
+			replicaCounter:  3,
+		},
+		"Counters are encoded as strings": {
+			response:        `{"RevisionCounter":"100","ReplicaCounter":"3"}`,
+			revisionCounter: 100,
+			replicaCounter:  3,
+		},
+		"Counters are missing": {
+			response: `{"Name":"vol1"}`,
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			var stats v1.VolumeStats
+			if err := json.Unmarshal([]byte(tt.response), &stats); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			var j Jiva
+			got := j.parser(stats)
+			if got.revisionCounter != tt.revisionCounter || got.replicaCounter != tt.replicaCounter {
+				t.Fatalf("parser(%v) => got %v, %v, want %v, %v", tt.response,
+					got.revisionCounter, got.replicaCounter, tt.revisionCounter, tt.replicaCounter)
+			}
+		})
+	}
+}
+
+func TestJivaVolumesCollector(t *testing.T) {
+	vol1 := fakeJivaController(fakeResponse)
+	defer vol1.Close()
+	vol2 := fakeJivaController(validControllerResp)
+	defer vol2.Close()
+
+	exporter, err := NewJivaVolumesStatsExporter([]VolumeTarget{
+		{Name: "vol1", URL: vol1.URL},
+		{Name: "vol2", URL: vol2.URL},
+		// this volume is not reachable
+		{Name: "vol3", URL: "http://127.0.0.2:1"},
+	}, "jiva")
+	if err != nil {
+		t.Fatalf("NewJivaVolumesStatsExporter() : unexpected error %v", err)
+	}
+
+	buf := scrape(t, exporter)
+	for _, re := range []*regexp.Regexp{
+		regexp.MustCompile(`openebs_reads{volume="vol1"} 1`),
+		regexp.MustCompile(`openebs_writes{volume="vol1"} 15`),
+		regexp.MustCompile(`openebs_volume_up{volume="vol1"} 1`),
+		regexp.MustCompile(`openebs_reads{volume="vol2"} 5`),
+		regexp.MustCompile(`openebs_writes{volume="vol2"} 11`),
+		regexp.MustCompile(`openebs_volume_up{volume="vol2"} 1`),
+		regexp.MustCompile(`openebs_reads{volume="vol3"} 0`),
+		regexp.MustCompile(`openebs_volume_up{volume="vol3"} 0`),
+	} {
+		if !re.Match(buf) {
+			t.Errorf("failed matching: %q", re)
+		}
+	}
+}
+
+func TestNewJivaVolumesStatsExporter(t *testing.T) {
+	_, err := NewJivaVolumesStatsExporter([]VolumeTarget{
+		{Name: "vol1", URL: "http://:9501"},
+	}, "jiva")
+	if err == nil {
+		t.Fatalf("NewJivaVolumesStatsExporter() : expected error for invalid URL")
+	}
+}
+
+// closeConnection closes the connection without sending any response so
+// that the request fails with a connection error.
+func closeConnection(t *testing.T, w http.ResponseWriter) {
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		t.Fatalf("webserver doesn't support hijacking")
+	}
+	conn, _, err := hj.Hijack()
+	if err != nil {
+		t.Fatalf("hijack failed: %v", err)
+	}
+	conn.Close()
+}
+
+func TestJivaGetVolumeStatsRetry(t *testing.T) {
+	cases := map[string]struct {
+		failures       int
+		retries        int
+		timeout        time.Duration
+		err            bool
+		attempts       int
+		retriesCounter string
+	}{
+		"[Success] controller responds after a failure": {
+			failures:       1,
+			retries:        2,
+			timeout:        time.Second,
+			attempts:       2,
+			retriesCounter: `openebs_scrape_retries_total 1`,
+		},
+		"[Failure] controller fails more than retries": {
+			failures:       5,
+			retries:        2,
+			timeout:        time.Second,
+			err:            true,
+			attempts:       3,
+			retriesCounter: `openebs_scrape_retries_total 2`,
+		},
+		"[Failure] retries are bounded by the timeout": {
+			failures:       5,
+			retries:        5,
+			timeout:        250 * time.Millisecond,
+			err:            true,
+			attempts:       2,
+			retriesCounter: `openebs_scrape_retries_total 1`,
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			var attempts int32
+			controller := fakeJivaController(validControllerResp)
+			defer controller.Close()
+			handler := controller.Config.Handler
+			controller.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/v1/stats" {
+					if int(atomic.AddInt32(&attempts, 1)) <= tt.failures {
+						closeConnection(t, w)
+						return
+					}
+				}
+				handler.ServeHTTP(w, r)
+			})
+			control, _ := url.Parse(controller.URL)
+			exporter := NewJivaStatsExporter(control, "jiva")
+			exporter.Retries = tt.retries
+			exporter.Timeout = tt.timeout
+
+			start := time.Now()
+			err := exporter.Jiva.collector(context.Background(), &exporter.Metrics)
+			if (err != nil) != tt.err {
+				t.Fatalf("collector() : unexpected error %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > tt.timeout {
+				t.Fatalf("collector() took %v, more than timeout %v", elapsed, tt.timeout)
+			}
+			if got := int(atomic.LoadInt32(&attempts)); got != tt.attempts {
+				t.Fatalf("collector() : expected %d attempts, got %d", tt.attempts, got)
+			}
+			// register only the retries counter so that the stats are not
+			// collected again.
+			registry := prometheus.NewRegistry()
+			registry.MustRegister(exporter.scrapeRetriesCounter)
+			server := httptest.NewServer(promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+			defer server.Close()
+			resp, err := http.Get(server.URL)
+			if err != nil {
+				t.Fatalf("unexpected failed response from prometheus: %s", err)
+			}
+			defer resp.Body.Close()
+			buf, _ := ioutil.ReadAll(resp.Body)
+			if re := regexp.MustCompile(tt.retriesCounter); !re.Match(buf) {
+				t.Errorf("failed matching: %q", re)
+			}
+		})
+	}
+}
+
+func TestJivaCollectorScrapeDeadline(t *testing.T) {
+	release := make(chan struct{})
+	replica := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-time.After(5 * time.Second):
+		}
+		fmt.Fprintln(w, `{"ReadIOPS":"7","WriteIOPS":"9"}`)
+	}))
+	defer replica.Close()
+	defer close(release)
+	replicaURL, _ := url.Parse(replica.URL)
+	var replicas []string
+	for i := 0; i < 3; i++ {
+		replicas = append(replicas, fmt.Sprintf(`{"address":"tcp://%s","mode":"RW"}`, replicaURL.Host))
+	}
+	controller := fakeJivaController(validControllerResp, replicas...)
+	defer controller.Close()
+	exporter := NewJivaStatsExporter(mustParseURL(t, controller.URL), "jiva")
+	exporter.Timeout = 200 * time.Millisecond
+
+	start := time.Now()
+	if err := exporter.Jiva.collector(context.Background(), &exporter.Metrics); err != nil {
+		t.Fatalf("collector() : unexpected error %v", err)
+	}
+	// the replicas which don't respond are bounded by the deadline of
+	// the scrape rather than by a timeout each.
+	if elapsed := time.Since(start); elapsed > exporter.Timeout+100*time.Millisecond {
+		t.Fatalf("collector() took %v, more than timeout %v", elapsed, exporter.Timeout)
+	}
+}
+
+func TestJivaCollectorRetriesFallbackPath(t *testing.T) {
+	attempts := 0
+	controller := fakeJivaController(validControllerResp)
+	defer controller.Close()
+	handler := controller.Config.Handler
+	controller.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case statsAPI:
+			// the first path fails once and then isn't found.
+			attempts++
+			if attempts == 1 {
+				closeConnection(t, w)
+				return
+			}
+			http.NotFound(w, r)
+		case volumeStatsAPI:
+			fmt.Fprintln(w, validControllerResp)
+		default:
+			handler.ServeHTTP(w, r)
+		}
+	})
+	exporter := NewJivaStatsExporter(mustParseURL(t, controller.URL), "jiva")
+	exporter.Retries = 2
+	buf := scrape(t, exporter)
+	// the retries of the first path are counted even if the stats are
+	// served by the alternate path.
+	for _, re := range []*regexp.Regexp{
+		regexp.MustCompile(`openebs_reads 5`),
+		regexp.MustCompile(`openebs_scrape_retries_total 1`),
+	} {
+		if !re.Match(buf) {
+			t.Errorf("failed matching: %q", re)
+		}
+	}
+}
+
+func TestJivaParserBytes(t *testing.T) {
+	cases := map[string]struct {
+		stats                 v1.VolumeStats
+		readBytes, writeBytes float64
+	}{
+		"Sector size is valid": {
+			stats: v1.VolumeStats{
+				SectorSize:           "4096",
+				TotalReadBlockCount:  "10",
+				TotalWriteBlockCount: "2",
+			},
+			readBytes:  40960,
+			writeBytes: 8192,
+		},
+		"Sector size is 0": {
+			stats: v1.VolumeStats{
+				SectorSize:           "0",
+				TotalReadBlockCount:  "10",
+				TotalWriteBlockCount: "2",
+			},
+		},
+		"Sector size is not a number": {
+			stats: v1.VolumeStats{
+				SectorSize:           "invalid",
+				TotalReadBlockCount:  "10",
+				TotalWriteBlockCount: "2",
+			},
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			var j Jiva
+			got := j.parser(tt.stats)
+			if got.readBytes != tt.readBytes || got.writeBytes != tt.writeBytes {
+				t.Fatalf("parser(%v) => got %v, %v, want %v, %v", tt.stats,
+					got.readBytes, got.writeBytes, tt.readBytes, tt.writeBytes)
+			}
+		})
+	}
+}
+
+func TestJivaParserAverageLatency(t *testing.T) {
+	cases := map[string]struct {
+		stats                           v1.VolumeStats
+		avgReadLatency, avgWriteLatency float64
+	}{
+		"Latency of the reads and writes": {
+			stats: v1.VolumeStats{
+				Reads:          "4",
+				TotalReadTime:  "2000000000",
+				Writes:         "10",
+				TotalWriteTime: "5000000",
+			},
+			avgReadLatency:  0.5,
+			avgWriteLatency: 0.0005,
+		},
+		"No reads and writes": {
+			stats: v1.VolumeStats{
+				Reads:          "0",
+				TotalReadTime:  "2000000000",
+				Writes:         "0",
+				TotalWriteTime: "5000000",
+			},
+		},
+		"Reads and writes are missing": {
+			stats: v1.VolumeStats{
+				TotalReadTime:  "2000000000",
+				TotalWriteTime: "5000000",
+			},
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			var j Jiva
+			got := j.parser(tt.stats)
+			if got.avgReadLatency != tt.avgReadLatency || got.avgWriteLatency != tt.avgWriteLatency {
+				t.Fatalf("parser(%v) => got %v, %v, want %v, %v", tt.stats,
+					got.avgReadLatency, got.avgWriteLatency, tt.avgReadLatency, tt.avgWriteLatency)
+			}
+		})
+	}
+}
+
+func TestJivaCollectorNamespace(t *testing.T) {
+	controller := fakeJivaController(fakeResponse)
+	defer controller.Close()
+	cases := map[string]struct {
+		namespace string
+		match     []*regexp.Regexp
+		notMatch  []*regexp.Regexp
+	}{
+		"Default namespace": {
+			namespace: "",
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_reads 1`),
+			},
+		},
+		"Custom namespace": {
+			namespace: "maya_jiva",
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`maya_jiva_reads 1`),
+				regexp.MustCompile(`maya_jiva_volume_up 1`),
+				regexp.MustCompile(`maya_jiva_replica_count`),
+			},
+			notMatch: []*regexp.Regexp{
+				regexp.MustCompile(`(?m)^openebs_`),
+			},
+		},
+		"Custom namespace with trailing underscore": {
+			namespace: "maya_jiva_",
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`maya_jiva_reads 1`),
+			},
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			controllerURL, _ := url.Parse(controller.URL)
+			col := NewJivaStatsExporter(controllerURL, "jiva")
+			col.SetNamespace(tt.namespace)
+			buf := scrape(t, col)
+			for _, re := range tt.match {
+				if !re.Match(buf) {
+					t.Errorf("failed matching: %q", re)
+				}
+			}
+			for _, re := range tt.notMatch {
+				if re.Match(buf) {
+					t.Errorf("unexpected match: %q", re)
+				}
+			}
+		})
+	}
+}
+
+func TestJivaCollectorUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jiva")
+	if err != nil {
+		t.Fatalf("failed creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "jiva.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("failed listening on %s: %v", socket, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle(statsAPI, &utiltesting.FakeHandler{
+		StatusCode:   200,
+		ResponseBody: validControllerResp,
+		T:            t,
+	})
+	// the replicas listed by the controller are reached over tcp
+	// rather than over the socket of the controller.
+	replica := fakeReplica(t, "127.0.0.1", `{"ReadIOPS":"7","WriteIOPS":"9"}`)
+	defer replica.Close()
+	replicaURL, _ := url.Parse(replica.URL)
+	mux.HandleFunc(replicasAPI, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"data":[{"address":"tcp://%s","mode":"RW"}]}`, replicaURL.Host)
+	})
+	server := &httptest.Server{Listener: listener, Config: &http.Server{Handler: mux}}
+	server.Start()
+	defer server.Close()
+
+	controllerURL, err := url.ParseRequestURI("unix://" + socket)
+	if err != nil {
+		t.Fatalf("failed parsing the socket url: %v", err)
+	}
+	col := NewJivaStatsExporter(controllerURL, "jiva")
+	if col.VolumeControllerURL != "unix://"+socket {
+		t.Fatalf("got VolumeControllerURL %q, want %q", col.VolumeControllerURL, "unix://"+socket)
+	}
+	buf := scrape(t, col)
+	for _, re := range []*regexp.Regexp{
+		regexp.MustCompile(`openebs_reads 5`),
+		regexp.MustCompile(`openebs_writes 11`),
+		regexp.MustCompile(`openebs_volume_up 1`),
+		regexp.MustCompile(`openebs_replica_read_iops{replica="127.0.0.1"} 7`),
+		regexp.MustCompile(`openebs_replica_write_iops{replica="127.0.0.1"} 9`),
+	} {
+		if !re.Match(buf) {
+			t.Errorf("failed matching: %q", re)
+		}
+	}
+}
+
+func TestJivaParserUsedPercent(t *testing.T) {
+	cases := map[string]struct {
+		stats       v1.VolumeStats
+		usedPercent float64
+	}{
+		"Volume is partially used": {
+			stats: v1.VolumeStats{
+				SectorSize:        "4096",
+				UsedLogicalBlocks: "256",
+				Size:              "4194304",
+			},
+			usedPercent: 25,
+		},
+		"Size is 0": {
+			stats: v1.VolumeStats{
+				SectorSize:        "4096",
+				UsedLogicalBlocks: "256",
+				Size:              "0",
+			},
+			usedPercent: 0,
+		},
+		"Size is missing": {
+			stats: v1.VolumeStats{
+				SectorSize:        "4096",
+				UsedLogicalBlocks: "256",
+			},
+			usedPercent: 0,
+		},
+		"Used is more than size": {
+			stats: v1.VolumeStats{
+				SectorSize:        "4096",
+				UsedLogicalBlocks: "2048",
+				Size:              "4194304",
+			},
+			usedPercent: 100,
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			var j Jiva
+			if got := j.parser(tt.stats).usedPercent; got != tt.usedPercent {
+				t.Fatalf("parser(%v) => got used percent %v, want %v", tt.stats, got, tt.usedPercent)
+			}
+		})
+	}
+}
+
+func TestJivaGetVolumeStatsCancel(t *testing.T) {
+	release := make(chan struct{})
+	controller := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-time.After(5 * time.Second):
+		}
+		fmt.Fprintln(w, validControllerResp)
+	}))
+	defer controller.Close()
+	// release the handler before closing the server, Close blocks
+	// until all the requests are served.
+	defer close(release)
+
+	j := Jiva{
+		VolumeControllerURL: controller.URL,
+		Timeout:             10 * time.Second,
+		Retries:             DefaultRetries,
+		RetryBackoff:        DefaultRetryBackoff,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	res, err := j.getVolumeStats(ctx)
+	if err == nil {
+		t.Fatalf("getVolumeStats() : expected error after cancellation, got nil")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("getVolumeStats() took %v after cancellation", elapsed)
+	}
+	if res.retries != 0 {
+		t.Fatalf("getVolumeStats() : expected no retries after cancellation, got %d", res.retries)
+	}
+}
+
+// slowJivaControllers returns n fake jiva controllers which take the
+// given delay to respond.
+func slowJivaControllers(n int, delay time.Duration) ([]VolumeTarget, func()) {
+	var (
+		volumes []VolumeTarget
+		servers []*httptest.Server
+	)
+	for i := 0; i < n; i++ {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(delay)
+			if r.URL.Path == replicasAPI {
+				fmt.Fprint(w, `{"data":[]}`)
+				return
+			}
+			fmt.Fprintln(w, validControllerResp)
+		}))
+		servers = append(servers, server)
+		volumes = append(volumes, VolumeTarget{Name: fmt.Sprintf("vol%d", i), URL: server.URL})
+	}
+	return volumes, func() {
+		for _, server := range servers {
+			server.Close()
+		}
+	}
+}
+
+func TestJivaVolumesCollectorConcurrency(t *testing.T) {
+	volumes, closeAll := slowJivaControllers(10, 20*time.Millisecond)
+	defer closeAll()
+
+	cases := map[string]struct {
+		maxConcurrency int
+	}{
+		"Serial collection":     {maxConcurrency: 1},
+		"Concurrent collection": {maxConcurrency: 4},
+		"Default concurrency":   {maxConcurrency: 0},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			exporter, err := NewJivaVolumesStatsExporter(volumes, "jiva")
+			if err != nil {
+				t.Fatalf("NewJivaVolumesStatsExporter() : unexpected error %v", err)
+			}
+			exporter.MaxConcurrency = tt.maxConcurrency
+			// the metrics must be sent in the same order on every
+			// collection irrespective of the order of completion.
+			var orders [2][]string
+			for i := range orders {
+				ch := make(chan prometheus.Metric)
+				go func() {
+					exporter.Collect(ch)
+					close(ch)
+				}()
+				for metric := range ch {
+					orders[i] = append(orders[i], metric.Desc().String())
+				}
+			}
+			if !reflect.DeepEqual(orders[0], orders[1]) {
+				t.Fatalf("Collect() : metrics are not sent in the same order")
+			}
+			buf := scrape(t, exporter)
+			for _, volume := range volumes {
+				re := regexp.MustCompile(fmt.Sprintf(`openebs_volume_up{volume="%s"} 1`, volume.Name))
+				if !re.Match(buf) {
+					t.Errorf("failed matching: %q", re)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkJivaVolumesCollector(b *testing.B) {
+	volumes, closeAll := slowJivaControllers(50, time.Millisecond)
+	defer closeAll()
+
+	for name, maxConcurrency := range map[string]int{"Serial": 1, "Concurrent": DefaultMaxConcurrency} {
+		b.Run(name, func(b *testing.B) {
+			exporter, err := NewJivaVolumesStatsExporter(volumes, "jiva")
+			if err != nil {
+				b.Fatalf("NewJivaVolumesStatsExporter() : unexpected error %v", err)
+			}
+			exporter.MaxConcurrency = maxConcurrency
+			exporter.initTargets()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				exporter.collectTargets()
+			}
+		})
+	}
+}
+
+func TestJivaLastStats(t *testing.T) {
+	requests := 0
+	controller := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == statsAPI {
+			requests++
+		}
+		fmt.Fprintln(w, validControllerResp)
+	}))
+	defer controller.Close()
+
+	single := NewJivaStatsExporter(mustParseURL(t, controller.URL), "jiva")
+	multi, err := NewJivaVolumesStatsExporter([]VolumeTarget{
+		{Name: "vol1", URL: controller.URL},
+		// this volume is not reachable, so it has no stats
+		{Name: "vol2", URL: "http://127.0.0.2:1"},
+	}, "jiva")
+	if err != nil {
+		t.Fatalf("NewJivaVolumesStatsExporter() : unexpected error %v", err)
+	}
+	cases := map[string]struct {
+		exporter *VolumeStatsExporter
+		volumes  []string
+	}{
+		"Single volume":    {exporter: single, volumes: []string{"vol1"}},
+		"Multiple volumes": {exporter: multi, volumes: []string{"vol1"}},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := tt.exporter.LastStats(); len(got) != 0 {
+				t.Fatalf("LastStats() before collection => got %v, want no stats", got)
+			}
+			scrape(t, tt.exporter)
+			scraped := requests
+			got := tt.exporter.LastStats()
+			if requests != scraped {
+				t.Fatalf("LastStats() made %d requests to the controller", requests-scraped)
+			}
+			if len(got) != len(tt.volumes) {
+				t.Fatalf("LastStats() => got %d volumes, want %d", len(got), len(tt.volumes))
+			}
+			for _, volume := range tt.volumes {
+				if got[volume].Reads != "5" {
+					t.Fatalf("LastStats() => got reads %q of %s, want %q", got[volume].Reads, volume, "5")
+				}
+			}
+		})
+	}
+}
+
+// mustParseURL parses the given url and fails the test on error.
+func mustParseURL(t *testing.T, rawurl string) *url.URL {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		t.Fatalf("failed parsing %q: %v", rawurl, err)
+	}
+	return u
+}
+
+func TestJivaScrapeCounters(t *testing.T) {
+	up := true
+	controller := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up {
+			closeConnection(t, w)
+			return
+		}
+		fmt.Fprintln(w, validControllerResp)
+	}))
+	defer controller.Close()
+	col := NewJivaStatsExporter(mustParseURL(t, controller.URL), "jiva")
+	col.Retries = 0
+
+	// cases are run in order since the counters are accumulated
+	// across the scrapes.
+	cases := []struct {
+		name  string
+		up    bool
+		match []*regexp.Regexp
+	}{
+		{
+			name: "[Success] controller is reachable",
+			up:   true,
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_scrapes_total 1`),
+				regexp.MustCompile(`openebs_scrape_errors_total 0`),
+			},
+		},
+		{
+			name: "[Failure] controller is not reachable",
+			up:   false,
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_scrapes_total 2`),
+				regexp.MustCompile(`openebs_scrape_errors_total 1`),
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			up = tt.up
+			buf := scrape(t, col)
+			for _, re := range tt.match {
+				if !re.Match(buf) {
+					t.Errorf("failed matching: %q", re)
+				}
+			}
+		})
+	}
+}
+
+func TestJivaGetVolumeStatsBasicAuth(t *testing.T) {
+	controller := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok || username != "admin" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, "401 Unauthorized")
+			return
+		}
+		fmt.Fprintln(w, validControllerResp)
+	}))
+	defer controller.Close()
+
+	cases := map[string]struct {
+		username, password string
+		err                error
+	}{
+		"Valid credentials":   {username: "admin", password: "secret", err: nil},
+		"Invalid credentials": {username: "admin", password: "invalid", err: ErrUnauthorized},
+		"Missing credentials": {err: ErrUnauthorized},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			j := Jiva{
+				VolumeControllerURL: controller.URL,
+				Username:            tt.username,
+				Password:            tt.password,
+			}
+			res, err := j.getVolumeStats(context.Background())
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("getVolumeStats() => got error %v, want %v", err, tt.err)
+			}
+			if err == nil && res.stats.Name != "vol1" {
+				t.Fatalf("getVolumeStats() => got volume %q, want vol1", res.stats.Name)
+			}
+		})
+	}
+}
+
+func TestJivaGetVolumeStatsRedirect(t *testing.T) {
+	cases := map[string]struct {
+		hops         int
+		maxRedirects int
+		redirects    int64
+		err          error
+	}{
+		"No redirect":                      {hops: 0, redirects: 0},
+		"Redirects are followed":           {hops: 2, redirects: 2},
+		"Max no of redirects are followed": {hops: 3, redirects: 3},
+		"Too many redirects":               {hops: 4, redirects: 3, err: ErrTooManyRedirects},
+		"Redirect loop":                    {hops: 1000, redirects: 3, err: ErrTooManyRedirects},
+		"Max no of redirects is set":       {hops: 2, maxRedirects: 1, redirects: 1, err: ErrTooManyRedirects},
+		"Redirects are disabled":           {hops: 1, maxRedirects: -1, redirects: 0, err: ErrTooManyRedirects},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			var requests int32
+			controller := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&requests, 1)
+				hop, _ := strconv.Atoi(r.URL.Query().Get("hop"))
+				if hop < tt.hops {
+					http.Redirect(w, r, fmt.Sprintf("/v1/stats?hop=%d", hop+1), http.StatusFound)
+					return
+				}
+				fmt.Fprintln(w, validControllerResp)
+			}))
+			defer controller.Close()
+			j := Jiva{
+				VolumeControllerURL: controller.URL + "/v1/stats",
+				MaxRedirects:        tt.maxRedirects,
+				Retries:             2,
+			}
+			_, err := j.getVolumeStats(context.Background())
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("getVolumeStats() => got error %v, want %v", err, tt.err)
+			}
+			if got := atomic.LoadInt64(&j.redirects); got != tt.redirects {
+				t.Fatalf("getVolumeStats() => got %d redirects, want %d", got, tt.redirects)
+			}
+			// the requests redirected too many times are not retried.
+			if got := atomic.LoadInt32(&requests); int64(got) != tt.redirects+1 {
+				t.Fatalf("getVolumeStats() => got %d requests, want %d", got, tt.redirects+1)
+			}
+		})
+	}
+
+	t.Run("Redirects are counted", func(t *testing.T) {
+		controller := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/v1/stats" {
+				http.Redirect(w, r, "/lb/v1/stats", http.StatusFound)
+				return
+			}
+			if r.URL.Path != "/lb/v1/stats" {
+				fmt.Fprintln(w, `{"data":[]}`)
+				return
+			}
+			fmt.Fprintln(w, validControllerResp)
+		}))
+		defer controller.Close()
+		control, _ := url.Parse(controller.URL)
+		exporter := NewJivaStatsExporter(control, "jiva")
+		for _, want := range []string{"1", "2"} {
+			buf := scrape(t, exporter)
+			for _, re := range []*regexp.Regexp{
+				regexp.MustCompile(`openebs_scrape_redirects_total ` + want),
+				regexp.MustCompile(`openebs_volume_up 1`),
+			} {
+				if !re.Match(buf) {
+					t.Errorf("failed matching: %q", re)
+				}
+			}
+		}
+	})
+}
+
+func TestJivaGetVolumeStatsHeaders(t *testing.T) {
+	controller := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Request-Id") != "maya-exporter" || len(r.Header["X-Route"]) != 2 || r.Host != "vol1.openebs" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "unexpected headers %v of host %s", r.Header, r.Host)
+			return
+		}
+		fmt.Fprintln(w, validControllerResp)
+	}))
+	defer controller.Close()
+
+	j := Jiva{
+		VolumeControllerURL: controller.URL,
+		Headers: http.Header{
+			"X-Request-Id": {"maya-exporter"},
+			"X-Route":      {"a", "b"},
+			"Host":         {"vol1.openebs"},
+		},
+	}
+	if _, err := j.getVolumeStats(context.Background()); err != nil {
+		t.Fatalf("getVolumeStats() => got error %v, want nil", err)
+	}
+}
+
+func TestJivaCollectorSCSIIOCount(t *testing.T) {
+	cases := map[string]struct {
+		scsiIOCount string
+		match       []*regexp.Regexp
+		notMatch    []*regexp.Regexp
+	}{
+		"SCSIIOCount is null": {
+			scsiIOCount: `null`,
+			notMatch: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_scsi_io_count{`),
+			},
+		},
+		"SCSIIOCount is empty": {
+			scsiIOCount: `{}`,
+			notMatch: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_scsi_io_count{`),
+			},
+		},
+		"SCSIIOCount with opcodes": {
+			scsiIOCount: `{"0":3,"40":12,"42":7}`,
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_scsi_io_count{opcode="0"} 3`),
+				regexp.MustCompile(`openebs_scsi_io_count{opcode="40"} 12`),
+				regexp.MustCompile(`openebs_scsi_io_count{opcode="42"} 7`),
+			},
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			controller := fakeJivaController(`{"Name":"vol1","ReadIOPS":"5","SCSIIOCount":` + tt.scsiIOCount + `}`)
+			defer controller.Close()
+			controllerURL, _ := url.Parse(controller.URL)
+			buf := scrape(t, NewJivaStatsExporter(controllerURL, "jiva"))
+			for _, re := range tt.match {
+				if !re.Match(buf) {
+					t.Errorf("failed matching: %q", re)
+				}
+			}
+			for _, re := range tt.notMatch {
+				if re.Match(buf) {
+					t.Errorf("unexpected match: %q", re)
+				}
+			}
+		})
+	}
+}
+
+func TestJivaGetVolumeStatsFallbackPath(t *testing.T) {
+	cases := map[string]struct {
+		statsPath string
+		paths     map[string]string
+		err       bool
+		name      string
+		requested []string
+	}{
+		"[Success] both the paths are served": {
+			paths: map[string]string{
+				"/v1/stats":         `{"Name":"old"}`,
+				"/v1/volumes/stats": `{"Name":"new"}`,
+			},
+			name:      "old",
+			requested: []string{"/v1/stats"},
+		},
+		"[Success] only the new path is served": {
+			paths: map[string]string{
+				"/v1/volumes/stats": `{"Name":"new"}`,
+			},
+			name:      "new",
+			requested: []string{"/v1/stats", "/v1/volumes/stats"},
+		},
+		"[Success] configured path is not served": {
+			statsPath: "/v1/volumes/stats",
+			paths: map[string]string{
+				"/v1/stats": `{"Name":"old"}`,
+			},
+			name:      "old",
+			requested: []string{"/v1/volumes/stats", "/v1/stats"},
+		},
+		"[Failure] none of the paths are served": {
+			paths:     map[string]string{},
+			err:       true,
+			requested: []string{"/v1/stats", "/v1/volumes/stats"},
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			var requested []string
+			controller := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requested = append(requested, r.URL.Path)
+				stats, ok := tt.paths[r.URL.Path]
+				if !ok {
+					http.NotFound(w, r)
+					return
+				}
+				fmt.Fprintln(w, stats)
+			}))
+			defer controller.Close()
+			controllerURL, _ := url.Parse(controller.URL)
+			col := NewJivaStatsExporter(controllerURL, "jiva")
+			col.Jiva.StatsPath = tt.statsPath
+			res, err := col.Jiva.getVolumeStats(context.Background())
+			if tt.err != (err != nil) {
+				t.Fatalf("getVolumeStats() => err %v, want err %v", err, tt.err)
+			}
+			if res.stats.Name != tt.name {
+				t.Fatalf("getVolumeStats() => name %q, want %q", res.stats.Name, tt.name)
+			}
+			if !reflect.DeepEqual(requested, tt.requested) {
+				t.Fatalf("getVolumeStats() => requested %v, want %v", requested, tt.requested)
+			}
+		})
+	}
+}
+
+func TestJivaCollectorErrors(t *testing.T) {
+	unauthorized := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer unauthorized.Close()
+	cases := map[string]struct {
+		url  string
+		errs []error
+	}{
+		"Controller is unreachable": {
+			url:  "http://127.0.0.2:1",
+			errs: []error{ErrCollectMetrics, ErrControllerUnreachable},
+		},
+		"Controller rejects the credentials": {
+			url:  unauthorized.URL,
+			errs: []error{ErrCollectMetrics, ErrUnauthorized},
+		},
+		"Controller host is unspecified": {
+			url:  "http://0.0.0.0:9501",
+			errs: []error{ErrCollectMetrics, ErrInvalidControllerHost},
+		},
+		"Controller host is empty": {
+			url:  "http://:9501",
+			errs: []error{ErrCollectMetrics, ErrInvalidControllerHost},
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			controllerURL, _ := url.Parse(tt.url)
+			exporter := NewJivaStatsExporter(controllerURL, "jiva")
+			exporter.Retries = 0
+			err := exporter.Jiva.collector(context.Background(), &exporter.Metrics)
+			for _, want := range tt.errs {
+				if !errors.Is(err, want) {
+					t.Errorf("collector() => %v, want it to wrap %v", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestJivaCollectorInvalidHost(t *testing.T) {
+	col := NewJivaStatsExporter(mustParseURL(t, "http://0.0.0.0:9501"), "jiva")
+	buf := scrape(t, col)
+	for _, re := range []*regexp.Regexp{
+		regexp.MustCompile(`openebs_volume_up 0`),
+		regexp.MustCompile(`openebs_connection_error_total{err=".*unspecified address \\"0.0.0.0\\".*"} 1`),
+	} {
+		if !re.Match(buf) {
+			t.Errorf("failed matching: %q", re)
+		}
+	}
+}
+
+func TestJivaCollectorIPv6(t *testing.T) {
+	listener, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 is not available: %v", err)
+	}
+	controller := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, validControllerResp)
+	}))
+	controller.Listener.Close()
+	controller.Listener = listener
+	controller.Start()
+	defer controller.Close()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	for _, address := range []string{
+		"http://[::1]:" + port,
+		"[::1]:" + port,
+	} {
+		t.Run(address, func(t *testing.T) {
+			controllerURL, err := ParseControllerURL(address)
+			if err != nil {
+				t.Fatalf("ParseControllerURL(%q) : unexpected error %v", address, err)
+			}
+			buf := scrape(t, NewJivaStatsExporter(controllerURL, "jiva"))
+			for _, re := range []*regexp.Regexp{
+				regexp.MustCompile(`openebs_reads 5`),
+				regexp.MustCompile(`openebs_volume_up 1`),
+			} {
+				if !re.Match(buf) {
+					t.Errorf("failed matching: %q", re)
+				}
+			}
+		})
+	}
+}
+
+func TestJivaLastScrapeSuccess(t *testing.T) {
+	controllerUp := true
+	controller := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !controllerUp {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, "not json")
+			return
+		}
+		fmt.Fprintln(w, validControllerResp)
+	}))
+	defer controller.Close()
+	controllerURL, _ := url.Parse(controller.URL)
+	exporter := NewJivaStatsExporter(controllerURL, "jiva")
+	exporter.Retries = 0
+	timestamp := func() float64 {
+		re := regexp.MustCompile(`openebs_last_scrape_success_timestamp_seconds ([0-9.e+]+)`)
+		match := re.FindSubmatch(scrape(t, exporter))
+		if match == nil {
+			t.Fatalf("failed matching: %q", re)
+		}
+		value, _ := strconv.ParseFloat(string(match[1]), 64)
+		return value
+	}
+
+	before := unixSeconds(time.Now())
+	first := timestamp()
+	if first < before {
+		t.Fatalf("last scrape success => %v, want at least %v", first, before)
+	}
+	// the timestamp is not updated if the stats can't be fetched.
+	controllerUp = false
+	if got := timestamp(); got != first {
+		t.Fatalf("last scrape success after a failure => %v, want %v", got, first)
+	}
+	controllerUp = true
+	if got := timestamp(); got <= first {
+		t.Fatalf("last scrape success after a success => %v, want more than %v", got, first)
+	}
+}
+
+func TestJivaCollectorDisableMetrics(t *testing.T) {
+	controller := fakeJivaController(validControllerResp,
+		`{"address":"tcp://127.0.0.2:1","mode":"RW"}`,
+	)
+	defer controller.Close()
+	controllerURL, _ := url.Parse(controller.URL)
+	disabled := []string{"replica_read_iops", "openebs_reads", "unknown_metric"}
+
+	single := NewJivaStatsExporter(controllerURL, "jiva")
+	single.DisableMetrics(disabled)
+	// the metrics are disabled even if the namespace is set later.
+	multi, _ := NewJivaVolumesStatsExporter([]VolumeTarget{{Name: "vol1", URL: controller.URL}}, "jiva")
+	multi.DisableMetrics(disabled)
+	multi.SetNamespace(DefaultNamespace)
+
+	cases := map[string]struct {
+		exporter *VolumeStatsExporter
+		match    []*regexp.Regexp
+		notMatch []*regexp.Regexp
+	}{
+		"Single volume": {
+			exporter: single,
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_writes 11`),
+				regexp.MustCompile(`openebs_replica_write_iops{replica="127.0.0.2"} 0`),
+			},
+			notMatch: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_reads `),
+				regexp.MustCompile(`openebs_replica_read_iops`),
+			},
+		},
+		"Multiple volumes": {
+			exporter: multi,
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_writes{volume="vol1"} 11`),
+			},
+			notMatch: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_reads{`),
+				regexp.MustCompile(`openebs_replica_read_iops`),
+			},
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			buf := scrape(t, tt.exporter)
+			for _, re := range tt.match {
+				if !re.Match(buf) {
+					t.Errorf("failed matching: %q", re)
+				}
+			}
+			for _, re := range tt.notMatch {
+				if re.Match(buf) {
+					t.Errorf("unexpected match: %q", re)
+				}
+			}
+		})
+	}
+}
+
+func TestJivaCollectorReloadDisabledMetrics(t *testing.T) {
+	controller := fakeJivaController(validControllerResp)
+	defer controller.Close()
+	exporter, _ := NewJivaVolumesStatsExporter([]VolumeTarget{{Name: "vol1", URL: controller.URL}}, "jiva")
+	exporter.DisableMetrics([]string{"reads"})
+	// the same registry is used for all the scrapes, as the metrics are
+	// changed once the exporter is registered.
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(exporter); err != nil {
+		t.Fatalf("collector failed to register: %s", err)
+	}
+	server := httptest.NewServer(promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	defer server.Close()
+
+	// cases are run in order since the disabled metrics are changed by
+	// each one.
+	cases := []struct {
+		name     string
+		disabled []string
+		match    []*regexp.Regexp
+		notMatch []*regexp.Regexp
+	}{
+		{
+			name:     "reads are disabled at the registration",
+			disabled: []string{"reads"},
+			match:    []*regexp.Regexp{regexp.MustCompile(`openebs_writes{volume="vol1"} 11`)},
+			notMatch: []*regexp.Regexp{regexp.MustCompile(`openebs_reads{`)},
+		},
+		{
+			name:     "reads are enabled and writes are disabled",
+			disabled: []string{"writes"},
+			match:    []*regexp.Regexp{regexp.MustCompile(`openebs_reads{volume="vol1"} 5`)},
+			notMatch: []*regexp.Regexp{regexp.MustCompile(`openebs_writes{`)},
+		},
+		{
+			name: "all the metrics are enabled",
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_reads{volume="vol1"} 5`),
+				regexp.MustCompile(`openebs_writes{volume="vol1"} 11`),
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter.DisableMetrics(tt.disabled)
+			resp, err := http.Get(server.URL)
+			if err != nil {
+				t.Fatalf("unexpected failed response from prometheus: %s", err)
+			}
+			defer resp.Body.Close()
+			buf, _ := ioutil.ReadAll(resp.Body)
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("got status %d: %s", resp.StatusCode, buf)
+			}
+			for _, re := range tt.match {
+				if !re.Match(buf) {
+					t.Errorf("failed matching: %q", re)
+				}
+			}
+			for _, re := range tt.notMatch {
+				if re.Match(buf) {
+					t.Errorf("unexpected match: %q", re)
+				}
+			}
+		})
+	}
+}
+
+func TestMetricsDisable(t *testing.T) {
+	m := newMetrics("jiva", "maya", nil, nil, false)
+	unknown := m.filter(nil, []string{"reads", "maya_writes", "openebs_reads", "scsi_io_count"})
+	if !reflect.DeepEqual(unknown, []string{"openebs_reads"}) {
+		t.Fatalf("filter() => unknown %v, want [openebs_reads]", unknown)
+	}
+	for _, c := range []prometheus.Collector{m.reads, m.writes, m.scsiIOCount} {
+		if !m.disabled.has(c) {
+			t.Errorf("filter() => %s is not disabled", collectorName(c))
+		}
+	}
+	if got := len(m.collectorsList()); got != len(m.allCollectors())-3 {
+		t.Fatalf("collectorsList() => %d metrics after disabling 3 metrics", got)
+	}
+}
+
+func TestMetricsFilter(t *testing.T) {
+	m := newMetrics("jiva", "maya", nil, nil, false)
+	unknown := m.filter([]string{"reads", "maya_writes", "scsi_io_count", "unknown_metric"}, []string{"writes"})
+	if !reflect.DeepEqual(unknown, []string{"unknown_metric"}) {
+		t.Fatalf("filter() => unknown %v, want [unknown_metric]", unknown)
+	}
+	got := m.collectorsList()
+	want := []prometheus.Collector{m.reads, m.scsiIOCount}
+	if len(got) != len(want) {
+		t.Fatalf("collectorsList() => %d metrics, want %d", len(got), len(want))
+	}
+	for _, c := range want {
+		if m.disabled.has(c) {
+			t.Errorf("filter() => %s is disabled", collectorName(c))
+		}
+	}
+	// all the metrics are enabled again without the allowlist.
+	m.filter(nil, nil)
+	if got := len(m.collectorsList()); got != len(m.allCollectors()) {
+		t.Fatalf("collectorsList() => %d metrics, want %d", got, len(m.allCollectors()))
+	}
+}
+
+func TestJivaCollectorAllowMetrics(t *testing.T) {
+	controller := fakeJivaController(validControllerResp)
+	defer controller.Close()
+	exporter, _ := NewJivaVolumesStatsExporter([]VolumeTarget{{Name: "vol1", URL: controller.URL}}, "jiva")
+	exporter.AllowMetrics([]string{"reads", "openebs_writes", "volume_up"})
+	exporter.DisableMetrics([]string{"writes"})
+	buf := scrape(t, exporter)
+	for _, re := range []*regexp.Regexp{
+		regexp.MustCompile(`openebs_reads{volume="vol1"} 5`),
+		regexp.MustCompile(`openebs_volume_up{volume="vol1"} 1`),
+	} {
+		if !re.Match(buf) {
+			t.Errorf("failed matching: %q", re)
+		}
+	}
+	for _, re := range []*regexp.Regexp{
+		regexp.MustCompile(`openebs_writes{`),
+		regexp.MustCompile(`openebs_size_of_volume{`),
+	} {
+		if re.Match(buf) {
+			t.Errorf("unexpected match: %q", re)
+		}
+	}
+}
+
+// connCountingJivaController returns a fake jiva controller which counts
+// the new connections made to it.
+func connCountingJivaController(conns *int32) *httptest.Server {
+	controller := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == replicasAPI {
+			fmt.Fprint(w, `{"data":[]}`)
+			return
+		}
+		fmt.Fprintln(w, validControllerResp)
+	}))
+	controller.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(conns, 1)
+		}
+	}
+	controller.Start()
+	return controller
+}
+
+func TestJivaCollectorKeepAlive(t *testing.T) {
+	cases := map[string]struct {
+		disableKeepAlives bool
+		conns             int32
+	}{
+		// the requests for the stats and the replicas of each scrape
+		// reuse the same connection.
+		"Keep-alive is enabled":  {disableKeepAlives: false, conns: 1},
+		"Keep-alive is disabled": {disableKeepAlives: true, conns: 10},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			var conns int32
+			controller := connCountingJivaController(&conns)
+			defer controller.Close()
+			controllerURL, _ := url.Parse(controller.URL)
+			exporter := NewJivaStatsExporter(controllerURL, "jiva")
+			exporter.DisableKeepAlives = tt.disableKeepAlives
+			for i := 0; i < 5; i++ {
+				scrape(t, exporter)
+			}
+			if got := atomic.LoadInt32(&conns); got != tt.conns {
+				t.Fatalf("5 scrapes with keep-alive disabled %v => %d connections, want %d", tt.disableKeepAlives, got, tt.conns)
+			}
+		})
+	}
+}
+
+func BenchmarkJivaCollectorKeepAlive(b *testing.B) {
+	var conns int32
+	controller := connCountingJivaController(&conns)
+	defer controller.Close()
+	controllerURL, _ := url.Parse(controller.URL)
+
+	for name, disableKeepAlives := range map[string]bool{"KeepAlive": false, "NoKeepAlive": true} {
+		b.Run(name, func(b *testing.B) {
+			exporter := NewJivaStatsExporter(controllerURL, "jiva")
+			exporter.DisableKeepAlives = disableKeepAlives
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				exporter.collect(&exporter.Jiva, &exporter.Metrics)
+			}
+		})
+	}
+}
+
+func TestJivaCollectorProxy(t *testing.T) {
+	var proxied []string
+	var mu sync.Mutex
+	// the proxy serves the requests itself instead of forwarding them
+	// to the controller which is not reachable.
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		proxied = append(proxied, r.URL.Host+r.URL.Path)
+		mu.Unlock()
+		if r.URL.Path == replicasAPI {
+			fmt.Fprint(w, `{"data":[]}`)
+			return
+		}
+		fmt.Fprintln(w, validControllerResp)
+	}))
+	defer proxy.Close()
+	proxyURL, _ := url.Parse(proxy.URL)
+	controllerURL, _ := url.Parse("http://jiva-controller.invalid:9501")
+
+	exporter := NewJivaStatsExporter(controllerURL, "jiva")
+	exporter.ProxyURL = proxyURL
+	buf := scrape(t, exporter)
+	for _, re := range []*regexp.Regexp{
+		regexp.MustCompile(`openebs_volume_up 1`),
+		regexp.MustCompile(`openebs_reads 5`),
+	} {
+		if !re.Match(buf) {
+			t.Errorf("failed matching: %q", re)
+		}
+	}
+	want := []string{"jiva-controller.invalid:9501" + statsAPI, "jiva-controller.invalid:9501" + replicasAPI}
+	if !reflect.DeepEqual(proxied, want) {
+		t.Fatalf("got proxied requests %v, want %v", proxied, want)
+	}
+}
+
+func TestJivaCollectorRequestTrace(t *testing.T) {
+	// the fake controller writes a newline after the stats.
+	responseBytes := regexp.MustCompile(`openebs_controller_response_bytes ` + strconv.Itoa(len(validControllerResp)+1) + `\n`)
+	cases := map[string]struct {
+		enabled  bool
+		match    []*regexp.Regexp
+		notMatch []*regexp.Regexp
+	}{
+		"Request trace is enabled": {
+			enabled: true,
+			match: []*regexp.Regexp{
+				responseBytes,
+				regexp.MustCompile(`openebs_controller_request_duration_seconds_count{phase="connect"} 1`),
+				regexp.MustCompile(`openebs_controller_request_duration_seconds_count{phase="ttfb"} 1`),
+				regexp.MustCompile(`openebs_controller_request_duration_seconds_count{phase="total"} 1`),
+			},
+			// the controller is reached using its ip.
+			notMatch: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_controller_request_duration_seconds_count{phase="dns"}`),
+			},
+		},
+		"Request trace is disabled": {
+			match: []*regexp.Regexp{
+				responseBytes,
+			},
+			notMatch: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_controller_request_duration_seconds`),
+			},
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			var requests int32
+			controller := countingJivaController(&requests)
+			defer controller.Close()
+			controllerURL, _ := url.Parse(controller.URL)
+			exporter := NewJivaStatsExporter(controllerURL, "jiva")
+			exporter.EnableRequestTrace = tt.enabled
+			buf := scrape(t, exporter)
+			for _, re := range tt.match {
+				if !re.Match(buf) {
+					t.Errorf("failed matching: %q", re)
+				}
+			}
+			for _, re := range tt.notMatch {
+				if re.Match(buf) {
+					t.Errorf("unexpected match: %q", re)
+				}
+			}
+		})
+	}
+}
+
+func TestMetricsObserveLatencies(t *testing.T) {
+	cases := map[string]struct {
+		prev, cur           VolumeStats
+		readCount, wrtCount uint64
+		readSum, writeSum   float64
+	}{
+		"Reads and writes since the previous scrape": {
+			prev:      VolumeStats{reads: 2, totalReadTime: 1e9, writes: 1, totalWriteTime: 1e6},
+			cur:       VolumeStats{reads: 6, totalReadTime: 3e9, writes: 11, totalWriteTime: 11e6},
+			readCount: 1, wrtCount: 1,
+			readSum: 0.5, writeSum: 0.001,
+		},
+		"No ios since the previous scrape": {
+			prev: VolumeStats{reads: 2, totalReadTime: 1e9, writes: 1, totalWriteTime: 1e6},
+			cur:  VolumeStats{reads: 2, totalReadTime: 1e9, writes: 1, totalWriteTime: 1e6},
+		},
+		"Counters are reset": {
+			prev:     VolumeStats{reads: 20, totalReadTime: 1e9, writes: 1, totalWriteTime: 1e6},
+			cur:      VolumeStats{reads: 2, totalReadTime: 1e8, writes: 2, totalWriteTime: 2e6},
+			wrtCount: 1, writeSum: 0.001,
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			m := newMetrics("jiva", DefaultNamespace, nil, nil, false)
+			m.observeLatencies(tt.prev, tt.cur)
+			for _, h := range []struct {
+				histogram prometheus.Histogram
+				count     uint64
+				sum       float64
+			}{
+				{m.readLatency, tt.readCount, tt.readSum},
+				{m.writeLatency, tt.wrtCount, tt.writeSum},
+			} {
+				var metric dto.Metric
+				if err := h.histogram.Write(&metric); err != nil {
+					t.Fatal(err)
+				}
+				if got := metric.GetHistogram().GetSampleCount(); got != h.count {
+					t.Errorf("got %d observations, want %d", got, h.count)
+				}
+				if got := metric.GetHistogram().GetSampleSum(); math.Abs(got-h.sum) > 1e-12 {
+					t.Errorf("got sum %v, want %v", got, h.sum)
+				}
+			}
+		})
+	}
+}
+
+func TestJivaCollectorLatencyBuckets(t *testing.T) {
+	var scrapes int32
+	controller := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&scrapes, 1)
+		// each scrape 2 reads taking 100us and 4 writes taking 2ms are made.
+		fmt.Fprintf(w, `{"ReadIOPS":"%d","TotalReadTime":"%d","WriteIOPS":"%d","TotalWriteTime":"%d"}`,
+			2*n, 200000*n, 4*n, 8000000*n)
+	}))
+	defer controller.Close()
+
+	exporter := NewJivaStatsExporter(mustParseURL(t, controller.URL), "jiva")
+	if err := exporter.SetLatencyBuckets([]float64{0.0001, 0.001, 0.01}); err != nil {
+		t.Fatalf("SetLatencyBuckets() => %v", err)
+	}
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(exporter); err != nil {
+		t.Fatalf("collector failed to register: %s", err)
+	}
+	gather := func() []byte {
+		server := httptest.NewServer(promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+		defer server.Close()
+		resp, err := http.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		buf, _ := ioutil.ReadAll(resp.Body)
+		return buf
+	}
+	// the first scrape has no previous stats to derive the latency from.
+	if buf := gather(); !regexp.MustCompile(`openebs_read_latency_seconds_count 0`).Match(buf) {
+		t.Fatalf("latency is observed on the first scrape: %s", buf)
+	}
+	buf := gather()
+	for _, re := range []*regexp.Regexp{
+		regexp.MustCompile(`openebs_read_latency_seconds_bucket{le="0.0001"} 1`),
+		regexp.MustCompile(`openebs_read_latency_seconds_count 1`),
+		regexp.MustCompile(`openebs_write_latency_seconds_bucket{le="0.001"} 0`),
+		regexp.MustCompile(`openebs_write_latency_seconds_bucket{le="0.01"} 1`),
+		regexp.MustCompile(`openebs_write_latency_seconds_count 1`),
+	} {
+		if !re.Match(buf) {
+			t.Errorf("failed matching: %q", re)
+		}
+	}
+}
+
+func TestValidateLatencyBuckets(t *testing.T) {
+	cases := map[string]struct {
+		buckets []float64
+		isErr   bool
+	}{
+		"Default buckets":    {},
+		"Increasing buckets": {buckets: []float64{0.0001, 0.001, 1}},
+		"Zero bucket":        {buckets: []float64{0, 1}, isErr: true},
+		"Negative bucket":    {buckets: []float64{-1, 1}, isErr: true},
+		"Decreasing buckets": {buckets: []float64{1, 0.1}, isErr: true},
+		"Duplicated buckets": {buckets: []float64{0.1, 0.1}, isErr: true},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			if err := ValidateLatencyBuckets(tt.buckets); (err != nil) != tt.isErr {
+				t.Fatalf("ValidateLatencyBuckets(%v) => got error %v, want error %v", tt.buckets, err, tt.isErr)
+			}
+		})
+	}
+}
+
+func TestJivaCollectorConstLabels(t *testing.T) {
+	controller := fakeJivaController(validControllerResp)
+	defer controller.Close()
+	labels := prometheus.Labels{NamespaceLabel: "openebs", PodLabel: "vol1-ctrl-0", PVLabel: ""}
+
+	single := NewJivaStatsExporter(mustParseURL(t, controller.URL), "jiva")
+	single.SetConstLabels(labels)
+	multi, err := NewJivaVolumesStatsExporter([]VolumeTarget{{Name: "vol1", URL: controller.URL}}, "jiva")
+	if err != nil {
+		t.Fatalf("NewJivaVolumesStatsExporter() : unexpected error %v", err)
+	}
+	multi.SetConstLabels(labels)
+
+	cases := map[string]struct {
+		exporter *VolumeStatsExporter
+		match    []*regexp.Regexp
+	}{
+		"Single volume": {
+			exporter: single,
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_reads{namespace="openebs",pod="vol1-ctrl-0"} 5`),
+				regexp.MustCompile(`openebs_volume_up{namespace="openebs",pod="vol1-ctrl-0"} 1`),
+			},
+		},
+		"Multiple volumes": {
+			exporter: multi,
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_reads{namespace="openebs",pod="vol1-ctrl-0",volume="vol1"} 5`),
+				regexp.MustCompile(`openebs_volume_up{namespace="openebs",pod="vol1-ctrl-0",volume="vol1"} 1`),
+			},
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			buf := scrape(t, tt.exporter)
+			for _, re := range tt.match {
+				if !re.Match(buf) {
+					t.Errorf("failed matching: %q", re)
+				}
+			}
+			// the empty pv label is omitted.
+			if re := regexp.MustCompile(`pv=`); re.Match(buf) {
+				t.Errorf("unexpected match: %q", re)
+			}
+		})
+	}
+}
+
+func TestJivaCollectorStatsAnomaly(t *testing.T) {
+	cases := map[string]struct {
+		stats     string
+		threshold time.Duration
+		match     []*regexp.Regexp
+	}{
+		"Plausible latencies": {
+			stats: `{"ReadIOPS":"10","TotalReadTime":"10000000","WriteIOPS":"10","TotalWriteTime":"20000000"}`,
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_stats_anomaly{stat="read_latency"} 0`),
+				regexp.MustCompile(`openebs_stats_anomaly{stat="write_latency"} 0`),
+			},
+		},
+		"Write latency is above the default threshold": {
+			stats: `{"ReadIOPS":"10","TotalReadTime":"10000000","WriteIOPS":"2","TotalWriteTime":"100000000000"}`,
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_stats_anomaly{stat="read_latency"} 0`),
+				regexp.MustCompile(`openebs_stats_anomaly{stat="write_latency"} 1`),
+				// the implausible value is still emitted.
+				regexp.MustCompile(`openebs_avg_write_latency_seconds 50`),
+			},
+		},
+		"Read latency is above the threshold": {
+			stats:     `{"ReadIOPS":"10","TotalReadTime":"20000000000","WriteIOPS":"0","TotalWriteTime":"0"}`,
+			threshold: time.Second,
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_stats_anomaly{stat="read_latency"} 1`),
+				regexp.MustCompile(`openebs_stats_anomaly{stat="write_latency"} 0`),
+			},
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			controller := fakeJivaController(tt.stats)
+			defer controller.Close()
+			col := NewJivaStatsExporter(mustParseURL(t, controller.URL), "jiva")
+			col.LatencyAnomalyThreshold = tt.threshold
+			buf := scrape(t, col)
+			for _, re := range tt.match {
+				if !re.Match(buf) {
+					t.Errorf("failed matching: %q", re)
+				}
+			}
+		})
+	}
+}
+
+func TestJivaCollectorStrictDecode(t *testing.T) {
+	cases := map[string]struct {
+		stats        string
+		strictDecode bool
+		match        []*regexp.Regexp
+	}{
+		"Unknown fields are ignored by default": {
+			stats: `{"ReadIOPS":"5","NewField":"1"}`,
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_volume_up 1`),
+				regexp.MustCompile(`openebs_reads 5`),
+				regexp.MustCompile(`openebs_strict_decode_errors_total 0`),
+			},
+		},
+		"Unknown fields fail the scrape in strict mode": {
+			stats:        `{"ReadIOPS":"5","NewField":"1"}`,
+			strictDecode: true,
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_volume_up 0`),
+				regexp.MustCompile(`openebs_strict_decode_errors_total 1`),
+			},
+		},
+		"Known fields pass in strict mode": {
+			stats:        `{"ReadIOPS":"5","WriteIOPS":"11"}`,
+			strictDecode: true,
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_volume_up 1`),
+				regexp.MustCompile(`openebs_writes 11`),
+				regexp.MustCompile(`openebs_strict_decode_errors_total 0`),
+			},
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			controller := fakeJivaController(tt.stats)
+			defer controller.Close()
+			col := NewJivaStatsExporter(mustParseURL(t, controller.URL), "jiva")
+			col.StrictDecode = tt.strictDecode
+			buf := scrape(t, col)
+			for _, re := range tt.match {
+				if !re.Match(buf) {
+					t.Errorf("failed matching: %q", re)
+				}
+			}
+		})
+	}
+}
+
+// gzipHandler serves the given responses gzip compressed by the path of
+// the request, if the gzip encoding is accepted by the client.
+func gzipHandler(t *testing.T, responses map[string]string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response, ok := responses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Accept-Encoding") != "gzip" {
+			fmt.Fprint(w, response)
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		defer gz.Close()
+		if _, err := gz.Write([]byte(response)); err != nil {
+			t.Errorf("failed writing gzip response: %v", err)
+		}
+	})
+}
+
+func TestJivaCollectorGzipResponse(t *testing.T) {
+	controller := httptest.NewServer(gzipHandler(t, map[string]string{
+		"/v1/stats":    `{"ReadIOPS":"5","WriteIOPS":"11"}`,
+		"/v1/replicas": `{"data":[{"address":"tcp://127.0.0.2:1","mode":"ERR"}]}`,
+	}))
+	defer controller.Close()
+	col := NewJivaStatsExporter(mustParseURL(t, controller.URL), "jiva")
+	buf := scrape(t, col)
+	for _, re := range []*regexp.Regexp{
+		regexp.MustCompile(`openebs_volume_up 1`),
+		regexp.MustCompile(`openebs_reads 5`),
+		regexp.MustCompile(`openebs_writes 11`),
+		regexp.MustCompile(`openebs_replica_status{mode="ERR",replica="127.0.0.2"} 0`),
+	} {
+		if !re.Match(buf) {
+			t.Errorf("failed matching: %q", re)
+		}
+	}
+}
+
+func TestReadBody(t *testing.T) {
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write([]byte(`{"ReadIOPS":"5"}`))
+	gz.Close()
+	cases := map[string]struct {
+		encoding string
+		body     []byte
+		want     string
+		isErr    bool
+	}{
+		"Plain response":        {body: []byte(`{"ReadIOPS":"5"}`), want: `{"ReadIOPS":"5"}`},
+		"Gzip response":         {encoding: "gzip", body: compressed.Bytes(), want: `{"ReadIOPS":"5"}`},
+		"Invalid gzip response": {encoding: "gzip", body: []byte(`{"ReadIOPS":"5"}`), isErr: true},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			resp := &http.Response{
+				Header: http.Header{},
+				Body:   ioutil.NopCloser(bytes.NewReader(tt.body)),
+			}
+			if len(tt.encoding) != 0 {
+				resp.Header.Set("Content-Encoding", tt.encoding)
+			}
+			body, err := readBody(resp)
+			if (err != nil) != tt.isErr {
+				t.Fatalf("readBody() => got error %v, want error %v", err, tt.isErr)
+			}
+			if err == nil && string(body) != tt.want {
+				t.Fatalf("readBody() => got %q, want %q", body, tt.want)
+			}
+		})
+	}
+}
+
+func TestJivaCollectorQueueStats(t *testing.T) {
+	cases := map[string]struct {
+		stats   string
+		match   []*regexp.Regexp
+		noMatch []*regexp.Regexp
+	}{
+		"Queue stats are reported": {
+			stats: `{"ReadIOPS":"5","PendingIO":"3","QueueDepth":32}`,
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_pending_io 3`),
+				regexp.MustCompile(`openebs_queue_depth 32`),
+			},
+		},
+		"Queue stats are missing": {
+			stats: `{"ReadIOPS":"5"}`,
+			noMatch: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_pending_io`),
+				regexp.MustCompile(`openebs_queue_depth`),
+			},
+		},
+		"Only the pending ios are reported": {
+			stats: `{"ReadIOPS":"5","PendingIO":"0"}`,
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_pending_io 0`),
+			},
+			noMatch: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_queue_depth`),
+			},
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			buf := scrape(t, NewJivaStatsFetcherExporter(newFakeStatsFetcher(t, tt.stats)))
+			for _, re := range tt.match {
+				if !re.Match(buf) {
+					t.Errorf("failed matching: %q", re)
+				}
+			}
+			for _, re := range tt.noMatch {
+				if re.Match(buf) {
+					t.Errorf("unexpected match: %q", re)
+				}
+			}
+		})
+	}
+}
+
+func TestJivaCollectorSessionStats(t *testing.T) {
+	cases := map[string]struct {
+		stats string
+		match *regexp.Regexp
+	}{
+		"Active sessions are reported": {
+			stats: `{"ReadIOPS":"5","ActiveSessions":2}`,
+			match: regexp.MustCompile(`openebs_active_sessions 2`),
+		},
+		"No active sessions": {
+			stats: `{"ReadIOPS":"5","ActiveSessions":"0"}`,
+			match: regexp.MustCompile(`openebs_active_sessions 0`),
+		},
+		"Active sessions are missing": {
+			stats: `{"ReadIOPS":"5"}`,
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			buf := scrape(t, NewJivaStatsFetcherExporter(newFakeStatsFetcher(t, tt.stats)))
+			if tt.match == nil {
+				if re := regexp.MustCompile(`openebs_active_sessions`); re.Match(buf) {
+					t.Errorf("unexpected match: %q", re)
+				}
+				return
+			}
+			if !tt.match.Match(buf) {
+				t.Errorf("failed matching: %q", tt.match)
+			}
+		})
+	}
+}
+
+func TestJivaCollectorPatternStats(t *testing.T) {
+	cases := map[string]struct {
+		stats   string
+		match   []*regexp.Regexp
+		noMatch []*regexp.Regexp
+	}{
+		"Sequential and random ios are reported": {
+			stats: `{"ReadIOPS":"5","WriteIOPS":"11","SequentialReadIOPS":"3","RandomReadIOPS":"2","SequentialWriteIOPS":"10","RandomWriteIOPS":"1"}`,
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_reads 5`),
+				regexp.MustCompile(`openebs_writes 11`),
+				regexp.MustCompile(`openebs_reads_by_pattern{pattern="sequential"} 3`),
+				regexp.MustCompile(`openebs_reads_by_pattern{pattern="random"} 2`),
+				regexp.MustCompile(`openebs_writes_by_pattern{pattern="sequential"} 10`),
+				regexp.MustCompile(`openebs_writes_by_pattern{pattern="random"} 1`),
+			},
+		},
+		"Sequential and random ios are missing": {
+			stats: `{"ReadIOPS":"5","WriteIOPS":"11"}`,
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_reads 5`),
+			},
+			noMatch: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_reads_by_pattern{`),
+				regexp.MustCompile(`openebs_writes_by_pattern{`),
+			},
+		},
+		"Only the reads are split": {
+			stats: `{"ReadIOPS":"5","SequentialReadIOPS":"5","RandomReadIOPS":"0"}`,
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_reads_by_pattern{pattern="random"} 0`),
+			},
+			noMatch: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_writes_by_pattern{`),
+			},
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			buf := scrape(t, NewJivaStatsFetcherExporter(newFakeStatsFetcher(t, tt.stats)))
+			for _, re := range tt.match {
+				if !re.Match(buf) {
+					t.Errorf("failed matching: %q", re)
+				}
+			}
+			for _, re := range tt.noMatch {
+				if re.Match(buf) {
+					t.Errorf("unexpected match: %q", re)
+				}
+			}
+		})
+	}
+}
+
+func TestJivaCollectorErrorStats(t *testing.T) {
+	cases := map[string]struct {
+		stats   string
+		match   []*regexp.Regexp
+		noMatch []*regexp.Regexp
+	}{
+		"Error counts are reported": {
+			stats: `{"ReadIOPS":"5","ReadErrors":"2","WriteErrors":7}`,
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`# TYPE openebs_read_errors_total counter`),
+				regexp.MustCompile(`openebs_read_errors_total 2`),
+				regexp.MustCompile(`openebs_write_errors_total 7`),
+			},
+		},
+		"Error counts are missing": {
+			stats: `{"ReadIOPS":"5"}`,
+			noMatch: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_read_errors_total`),
+				regexp.MustCompile(`openebs_write_errors_total`),
+			},
+		},
+		"Only the read errors are reported": {
+			stats: `{"ReadIOPS":"5","ReadErrors":"0"}`,
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_read_errors_total 0`),
+			},
+			noMatch: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_write_errors_total`),
+			},
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			buf := scrape(t, NewJivaStatsFetcherExporter(newFakeStatsFetcher(t, tt.stats)))
+			for _, re := range tt.match {
+				if !re.Match(buf) {
+					t.Errorf("failed matching: %q", re)
+				}
+			}
+			for _, re := range tt.noMatch {
+				if re.Match(buf) {
+					t.Errorf("unexpected match: %q", re)
+				}
+			}
+		})
+	}
+}
+
+func TestJivaCollectorStatsFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jiva")
+	if err != nil {
+		t.Fatalf("failed creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	cases := map[string]struct {
+		stats   string
+		match   []*regexp.Regexp
+		noMatch []*regexp.Regexp
+	}{
+		"Captured stats are parsed": {
+			stats: validControllerResp + "\n",
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_volume_up 1`),
+				regexp.MustCompile(`openebs_reads 5`),
+				regexp.MustCompile(`openebs_writes 11`),
+				regexp.MustCompile(`openebs_parse_errors_total 0`),
+			},
+			noMatch: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_connected_replicas`),
+			},
+		},
+		"Missing fields are counted": {
+			stats: `{"ReadIOPS":"5"}`,
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_volume_up 1`),
+				regexp.MustCompile(`openebs_reads 5`),
+				regexp.MustCompile(`openebs_parse_errors_total [1-9]`),
+			},
+		},
+		"Captured stats are not JSON": {
+			stats: invalidControllerResp,
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_volume_up 0`),
+			},
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			file := filepath.Join(dir, "stats.json")
+			if err := ioutil.WriteFile(file, []byte(tt.stats), 0644); err != nil {
+				t.Fatalf("failed writing stats file: %v", err)
+			}
+			buf := scrape(t, NewJivaStatsFileExporter(file))
+			for _, re := range tt.match {
+				if !re.Match(buf) {
+					t.Errorf("failed matching: %q", re)
+				}
+			}
+			for _, re := range tt.noMatch {
+				if re.Match(buf) {
+					t.Errorf("unexpected match: %q", re)
+				}
+			}
+		})
+	}
+}
+
+func TestJivaCollectorSectorSizeValid(t *testing.T) {
+	cases := map[string]struct {
+		stats string
+		match []*regexp.Regexp
+	}{
+		"Sector size is reported": {
+			stats: `{"ReadIOPS":"5","SectorSize":"4096","TotalReadBlockCount":"2"}`,
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_sector_size_valid 1`),
+				regexp.MustCompile(`openebs_read_bytes_total 8192`),
+			},
+		},
+		"Sector size is missing": {
+			stats: `{"ReadIOPS":"5","TotalReadBlockCount":"2"}`,
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_sector_size_valid 0`),
+				regexp.MustCompile(`openebs_read_bytes_total 0`),
+			},
+		},
+		"Sector size is 0": {
+			stats: `{"ReadIOPS":"5","SectorSize":"0"}`,
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_sector_size_valid 0`),
+			},
+		},
+		"Sector size is not a number": {
+			stats: `{"ReadIOPS":"5","SectorSize":"4k"}`,
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_sector_size_valid 0`),
+			},
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			controller := fakeJivaController(tt.stats)
+			defer controller.Close()
+			buf := scrape(t, NewJivaStatsExporter(mustParseURL(t, controller.URL), "jiva"))
+			for _, re := range tt.match {
+				if !re.Match(buf) {
+					t.Errorf("failed matching: %q", re)
+				}
+			}
+		})
+	}
+}
+
+func TestJivaCollectorControllerInfo(t *testing.T) {
+	fetcher := &fakeStatsFetcher{}
+	col := NewJivaStatsFetcherExporter(fetcher)
+
+	// cases are run in order as the version is upgraded by each one.
+	cases := []struct {
+		name    string
+		stats   string
+		match   []*regexp.Regexp
+		noMatch []*regexp.Regexp
+	}{
+		{
+			name:  "Version is reported",
+			stats: `{"ReadIOPS":"5","Version":"0.7.0"}`,
+			match: []*regexp.Regexp{regexp.MustCompile(`openebs_controller_info{version="0.7.0"} 1`)},
+		},
+		{
+			name:    "Controller is upgraded",
+			stats:   `{"ReadIOPS":"5","Version":"0.8.0"}`,
+			match:   []*regexp.Regexp{regexp.MustCompile(`openebs_controller_info{version="0.8.0"} 1`)},
+			noMatch: []*regexp.Regexp{regexp.MustCompile(`version="0.7.0"`)},
+		},
+		{
+			name:    "Version is not reported",
+			stats:   `{"ReadIOPS":"5"}`,
+			noMatch: []*regexp.Regexp{regexp.MustCompile(`openebs_controller_info`)},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			fetcher.setStats(t, tt.stats)
+			buf := scrape(t, col)
+			for _, re := range tt.match {
+				if !re.Match(buf) {
+					t.Errorf("failed matching: %q", re)
+				}
+			}
+			for _, re := range tt.noMatch {
+				if re.Match(buf) {
+					t.Errorf("unexpected match: %q", re)
+				}
+			}
 		})
 	}
 }
@@ -0,0 +1,299 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openebs/maya/cmd/maya-exporter/app/logger"
+	"github.com/openebs/maya/types/v1"
+)
+
+const (
+	// replicasAPI is the api of jiva controller which lists the replicas
+	// connected with the controller along with their mode.
+	replicasAPI = "/v1/replicas"
+	// replicaModeRW is the mode of a healthy replica.
+	replicaModeRW = "RW"
+)
+
+// replicaStats keeps the stats of a replica which are emitted with the
+// last known value even if the replica is not reachable or rebuilding.
+type replicaStats struct {
+	readIOPS  float64
+	writeIOPS float64
+}
+
+// replicaState keeps the last known stats of the replicas of a volume
+// indexed by the replica address and the last known addresses of the
+// replicas indexed by their id, it is safe for concurrent use.
+type replicaState struct {
+	mu        sync.Mutex
+	stats     map[string]replicaStats
+	addresses map[string]string
+}
+
+// replicaStateMu guards the creation of the replica state of the jiva
+// volumes.
+var replicaStateMu sync.Mutex
+
+// lastReplicas returns the replica state of the volume, it is created on
+// the first collection of the stats of the replicas.
+func (j *Jiva) lastReplicas() *replicaState {
+	replicaStateMu.Lock()
+	defer replicaStateMu.Unlock()
+	if j.replicas == nil {
+		j.replicas = &replicaState{
+			stats:     make(map[string]replicaStats),
+			addresses: make(map[string]string),
+		}
+	}
+	return j.replicas
+}
+
+// replicasURL returns the url of the replicas api of the jiva controller
+// derived from the VolumeControllerURL.
+func (j *Jiva) replicasURL() (string, error) {
+	return j.apiURL(replicasAPI)
+}
+
+// getReplicas is used to get the list of replicas from the jiva controller.
+func (j *Jiva) getReplicas(ctx context.Context) ([]v1.Replica, error) {
+	var replicas v1.ReplicaCollection
+	replicasURL, err := j.replicasURL()
+	if err != nil {
+		return nil, err
+	}
+	req, err := j.newRequest(ctx, replicasURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := getJSON(j.httpClient(), req, &replicas); err != nil {
+		return nil, err
+	}
+	return replicas.Data, nil
+}
+
+// getReplicaStats is used to get the stats of a replica, address is
+// the address of the replica as reported by the controller, for example
+// "tcp://10.42.0.3:9502".
+func (j *Jiva) getReplicaStats(ctx context.Context, address string, obj *v1.ReplicaStats) error {
+	address = strings.TrimPrefix(address, "tcp://")
+	// the credentials of the controller are not sent to the replicas.
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.replicaScheme()+"://"+address+"/v1/stats", nil)
+	if err != nil {
+		return err
+	}
+	return getJSON(j.replicaHTTPClient(), req, obj)
+}
+
+// replicaScheme returns the scheme of the requests to the replicas, these
+// are served over https if the jiva controller is.
+func (j *Jiva) replicaScheme() string {
+	if u, err := url.Parse(j.VolumeControllerURL); err == nil && u.Scheme == "https" {
+		return "https"
+	}
+	return "http"
+}
+
+// getAllReplicaStats gets the stats of all the replicas concurrently, so
+// that the replicas which don't respond are bounded by the deadline of
+// ctx together rather than one after the other. The stats and the error
+// of a replica are at its index in replicas.
+func (j *Jiva) getAllReplicaStats(ctx context.Context, replicas []v1.Replica) ([]v1.ReplicaStats, []error) {
+	stats := make([]v1.ReplicaStats, len(replicas))
+	errs := make([]error, len(replicas))
+	var wg sync.WaitGroup
+	for i, replica := range replicas {
+		wg.Add(1)
+		go func(i int, address string) {
+			defer wg.Done()
+			errs[i] = j.getReplicaStats(ctx, address, &stats[i])
+		}(i, replica.Address)
+	}
+	wg.Wait()
+	return stats, errs
+}
+
+// getJSON sends the request and unmarshal the response into obj.
+func getJSON(httpClient *http.Client, req *http.Request, obj interface{}) error {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("got status code %d from %s", resp.StatusCode, req.URL)
+	}
+	body, err := readBody(resp)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(bytes.TrimSpace(body), obj); err != nil {
+		return fmt.Errorf("%w: %w", ErrUnmarshalResponse, err)
+	}
+	return nil
+}
+
+// replicaName returns the host of the replica which is used as the
+// value of the replica label.
+func replicaName(address string) string {
+	address = strings.TrimPrefix(address, "tcp://")
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		return host
+	}
+	return address
+}
+
+// setRebuildStats sets the rebuild gauges of the replica, these are not
+// emitted if the replica doesn't report them rather than emitting 0.
+func setRebuildStats(m *Metrics, name string, obj v1.ReplicaStats) {
+	if obj.Rebuilding != nil {
+		rebuilding := 0.0
+		if *obj.Rebuilding {
+			rebuilding = 1
+		}
+		m.replicaRebuilding.WithLabelValues(name).Set(rebuilding)
+	}
+	if progress, err := obj.RebuildProgress.Float64(); err == nil {
+		m.replicaRebuildProgress.WithLabelValues(name).Set(progress)
+	}
+	if remaining, err := obj.RebuildBytesRemaining.Float64(); err == nil {
+		m.rebuildBytesRemaining.WithLabelValues(name).Set(remaining)
+	}
+}
+
+// setRevisionStats sets the revision counters of the replicas which have
+// reported them and the difference between the max and min of these. The
+// difference is not emitted if less than two replicas have reported it.
+func setRevisionStats(m *Metrics, revisions map[string]float64) {
+	min, max := math.Inf(1), math.Inf(-1)
+	for name, revision := range revisions {
+		m.replicaRevisionCounter.WithLabelValues(name).Set(revision)
+		min = math.Min(min, revision)
+		max = math.Max(max, revision)
+	}
+	if len(revisions) < 2 {
+		m.replicaRevisionMaxDiff.Reset()
+		return
+	}
+	m.replicaRevisionMaxDiff.WithLabelValues().Set(max - min)
+}
+
+// setReconnectTime sets the time at which the replica has last connected
+// with the controller, it is not emitted if the controller doesn't report
+// it or reports an invalid time.
+func setReconnectTime(m *Metrics, name string, replica v1.Replica) {
+	if len(replica.LastConnected) == 0 {
+		return
+	}
+	t, err := time.Parse(time.RFC3339Nano, replica.LastConnected)
+	if err != nil {
+		logger.Errorf("could not parse the last connect time of replica %s: %v", name, err)
+		return
+	}
+	m.replicaLastReconnect.WithLabelValues(name).Set(float64(t.UnixNano()) / 1e9)
+}
+
+// replicaID returns the id of the replica which doesn't change when it
+// connects from a new address, i.e. the id reported by the replica itself
+// or else the id reported for it by the controller. It is empty if neither
+// of them reports one.
+func replicaID(replica v1.Replica, obj v1.ReplicaStats) string {
+	if len(obj.Id) != 0 {
+		return obj.Id
+	}
+	return replica.Id
+}
+
+// setAddressChanges counts the replicas which have connected from a new
+// address, i.e. the address of a replica differs from its last known
+// address. The replicas without an id are not counted as they can't be
+// told apart from the new replicas. The addresses are kept only in memory,
+// so these are counted from the start of the exporter. It must be called
+// with the lock of the state held.
+func (state *replicaState) setAddressChanges(m *Metrics, replicas []v1.Replica, objs []v1.ReplicaStats) {
+	for i, replica := range replicas {
+		id := replicaID(replica, objs[i])
+		if len(id) == 0 {
+			continue
+		}
+		address := strings.TrimPrefix(replica.Address, "tcp://")
+		// the counter is emitted with 0 once the replica is seen.
+		counter := m.replicaAddressChanges.WithLabelValues(id)
+		if last, ok := state.addresses[id]; ok && last != address {
+			logger.Infof("replica %s has connected from %s, it was at %s", id, address, last)
+			counter.Inc()
+		}
+		state.addresses[id] = address
+	}
+}
+
+// setReplicaStats sets the per replica gauges. Errors are only logged
+// so that an unreachable replica doesn't fail the whole scrape, in that
+// case the last known values of the replica are emitted.
+func (j *Jiva) setReplicaStats(ctx context.Context, m *Metrics) {
+	replicas, err := j.getReplicas(ctx)
+	if err != nil {
+		logger.Errorf("could not retrieve replicas from OpenEBS Volume controller: %v", err)
+		// the no of connected replicas is unknown rather than 0.
+		m.connectedReplicas.Reset()
+		m.replicaRevisionMaxDiff.Reset()
+		return
+	}
+	objs, errs := j.getAllReplicaStats(ctx, replicas)
+	state := j.lastReplicas()
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	m.replicaReadIOPS.Reset()
+	m.replicaWriteIOPS.Reset()
+	m.replicaStatus.Reset()
+	m.replicaRebuilding.Reset()
+	m.replicaRebuildProgress.Reset()
+	m.rebuildBytesRemaining.Reset()
+	m.replicaRevisionCounter.Reset()
+	m.replicaLastReconnect.Reset()
+	known := make(map[string]replicaStats)
+	revisions := make(map[string]float64)
+	connected := 0
+	for i, replica := range replicas {
+		name := replicaName(replica.Address)
+		stats := state.stats[name]
+		obj := objs[i]
+		if err := errs[i]; err != nil {
+			logger.Errorf("could not retrieve stats of replica %s: %v", name, err)
+		} else {
+			stats.readIOPS, _ = obj.ReadIOPS.Float64()
+			stats.writeIOPS, _ = obj.WriteIOPS.Float64()
+			setRebuildStats(m, name, obj)
+			if revision, err := obj.RevisionCounter.Float64(); err == nil {
+				revisions[name] = revision
+			}
+		}
+		known[name] = stats
+
+		status := 0.0
+		if replica.Mode == replicaModeRW {
+			status = 1
+			connected++
+		}
+		m.replicaStatus.WithLabelValues(name, replica.Mode).Set(status)
+		setReconnectTime(m, name, replica)
+		m.replicaReadIOPS.WithLabelValues(name).Set(stats.readIOPS)
+		m.replicaWriteIOPS.WithLabelValues(name).Set(stats.writeIOPS)
+	}
+	m.connectedReplicas.WithLabelValues().Set(float64(connected))
+	setRevisionStats(m, revisions)
+	state.setAddressChanges(m, replicas, objs)
+	// forget the replicas which are no more connected with the controller
+	state.stats = known
+}
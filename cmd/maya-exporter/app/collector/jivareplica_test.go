@@ -0,0 +1,487 @@
+package collector
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// scrape registers the collector with a new registry and returns the
+// metrics exposed by it in text format.
+func scrape(t *testing.T, col prometheus.Collector) []byte {
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(col); err != nil {
+		t.Fatalf("collector failed to register: %s", err)
+	}
+	server := httptest.NewServer(promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected failed response from prometheus: %s", err)
+	}
+	defer resp.Body.Close()
+	buf, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed reading server response: %s", err)
+	}
+	return buf
+}
+
+// fakeJivaController returns a fake jiva controller which serves the
+// given stats and lists the given replicas.
+func fakeJivaController(stats string, replicas ...string) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/stats", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, stats)
+	})
+	mux.HandleFunc("/v1/replicas", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"data":[%s]}`, strings.Join(replicas, ","))
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestJivaReplicaCollector(t *testing.T) {
+	replicaUp := true
+	replica := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !replicaUp {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, `{"ReadIOPS":"7","WriteIOPS":"9","revisioncounter":"100"}`)
+	}))
+	defer replica.Close()
+	replicaURL, _ := url.Parse(replica.URL)
+
+	controller := fakeJivaController(fakeResponse,
+		fmt.Sprintf(`{"address":"tcp://%s","mode":"RW"}`, replicaURL.Host),
+		// this replica is not reachable
+		`{"address":"tcp://127.0.0.2:1","mode":"ERR"}`,
+	)
+	defer controller.Close()
+	control, _ := url.Parse(controller.URL)
+	col := NewJivaStatsExporter(control, "jiva")
+
+	// cases are run in order since the second one depends on the
+	// values collected in the first one.
+	cases := []struct {
+		name      string
+		replicaUp bool
+		match     []*regexp.Regexp
+	}{
+		{
+			name:      "[Success] replica is reachable",
+			replicaUp: true,
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_reads 1`),
+				regexp.MustCompile(`openebs_replica_read_iops{replica="127.0.0.1"} 7`),
+				regexp.MustCompile(`openebs_replica_write_iops{replica="127.0.0.1"} 9`),
+				regexp.MustCompile(`openebs_replica_status{mode="RW",replica="127.0.0.1"} 1`),
+				regexp.MustCompile(`openebs_replica_read_iops{replica="127.0.0.2"} 0`),
+				regexp.MustCompile(`openebs_replica_status{mode="ERR",replica="127.0.0.2"} 0`),
+				regexp.MustCompile(`openebs_connected_replicas 1`),
+			},
+		},
+		{
+			name:      "[Success] replica is not reachable, last known values are emitted",
+			replicaUp: false,
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_reads 1`),
+				regexp.MustCompile(`openebs_replica_read_iops{replica="127.0.0.1"} 7`),
+				regexp.MustCompile(`openebs_replica_write_iops{replica="127.0.0.1"} 9`),
+				regexp.MustCompile(`openebs_replica_status{mode="RW",replica="127.0.0.1"} 1`),
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			replicaUp = tt.replicaUp
+			buf := scrape(t, col)
+			for _, re := range tt.match {
+				if !re.Match(buf) {
+					t.Errorf("failed matching: %q", re)
+				}
+			}
+		})
+	}
+}
+
+func TestJivaReplicaRebuildStats(t *testing.T) {
+	cases := map[string]struct {
+		stats    string
+		match    []*regexp.Regexp
+		notMatch []*regexp.Regexp
+	}{
+		"replica is rebuilding": {
+			stats: `{"ReadIOPS":"7","WriteIOPS":"9","rebuilding":true,"rebuildProgress":"42.5"}`,
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_replica_rebuilding{replica="127.0.0.1"} 1`),
+				regexp.MustCompile(`openebs_replica_rebuild_progress_percent{replica="127.0.0.1"} 42.5`),
+			},
+		},
+		"replica is not rebuilding": {
+			stats: `{"ReadIOPS":"7","WriteIOPS":"9","rebuilding":false}`,
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_replica_rebuilding{replica="127.0.0.1"} 0`),
+			},
+			notMatch: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_replica_rebuild_progress_percent{`),
+			},
+		},
+		"replica reports the bytes remaining rather than the progress": {
+			stats: `{"ReadIOPS":"7","WriteIOPS":"9","rebuilding":true,"rebuildBytesRemaining":1073741824}`,
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_replica_rebuilding{replica="127.0.0.1"} 1`),
+				regexp.MustCompile(`openebs_rebuild_bytes_remaining{replica="127.0.0.1"} 1.073741824e\+09`),
+			},
+			notMatch: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_replica_rebuild_progress_percent{`),
+			},
+		},
+		"replica doesn't report rebuild info": {
+			stats: `{"ReadIOPS":"7","WriteIOPS":"9"}`,
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_replica_read_iops{replica="127.0.0.1"} 7`),
+			},
+			notMatch: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_replica_rebuilding{`),
+				regexp.MustCompile(`openebs_replica_rebuild_progress_percent{`),
+				regexp.MustCompile(`openebs_rebuild_bytes_remaining{`),
+			},
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			replica := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintln(w, tt.stats)
+			}))
+			defer replica.Close()
+			replicaURL, _ := url.Parse(replica.URL)
+			controller := fakeJivaController(fakeResponse,
+				fmt.Sprintf(`{"address":"tcp://%s","mode":"WO"}`, replicaURL.Host),
+			)
+			defer controller.Close()
+			control, _ := url.Parse(controller.URL)
+			buf := scrape(t, NewJivaStatsExporter(control, "jiva"))
+			for _, re := range tt.match {
+				if !re.Match(buf) {
+					t.Errorf("failed matching: %q", re)
+				}
+			}
+			for _, re := range tt.notMatch {
+				if re.Match(buf) {
+					t.Errorf("unexpected match: %q", re)
+				}
+			}
+		})
+	}
+}
+
+func TestReplicaName(t *testing.T) {
+	cases := map[string]struct {
+		address, name string
+	}{
+		"address with scheme and port": {"tcp://10.42.0.3:9502", "10.42.0.3"},
+		"address without scheme":       {"10.42.0.3:9502", "10.42.0.3"},
+		"address without port":         {"10.42.0.3", "10.42.0.3"},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := replicaName(tt.address); got != tt.name {
+				t.Fatalf("replicaName(%v) => got %v, want %v", tt.address, got, tt.name)
+			}
+		})
+	}
+}
+
+func TestJivaConnectedReplicas(t *testing.T) {
+	replicasUp := true
+	controller := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/stats":
+			fmt.Fprintln(w, fakeResponse)
+		case "/v1/replicas":
+			if !replicasUp {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			fmt.Fprint(w, `{"data":[{"address":"tcp://127.0.0.2:1","mode":"RW"},{"address":"tcp://127.0.0.3:1","mode":"RW"},{"address":"tcp://127.0.0.4:1","mode":"WO"}]}`)
+		}
+	}))
+	defer controller.Close()
+	col := NewJivaStatsExporter(mustParseURL(t, controller.URL), "jiva")
+
+	// cases are run in order since the second one checks that the value
+	// collected in the first one is not emitted anymore.
+	cases := []struct {
+		name       string
+		replicasUp bool
+		match      []*regexp.Regexp
+		notMatch   []*regexp.Regexp
+	}{
+		{
+			name:       "Replicas are listed",
+			replicasUp: true,
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_replica_count 6`),
+				regexp.MustCompile(`openebs_connected_replicas 2`),
+			},
+		},
+		{
+			name:       "Replicas can't be listed",
+			replicasUp: false,
+			match:      []*regexp.Regexp{regexp.MustCompile(`openebs_volume_up 1`)},
+			notMatch:   []*regexp.Regexp{regexp.MustCompile(`openebs_connected_replicas`)},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			replicasUp = tt.replicasUp
+			buf := scrape(t, col)
+			for _, re := range tt.match {
+				if !re.Match(buf) {
+					t.Errorf("failed matching: %q", re)
+				}
+			}
+			for _, re := range tt.notMatch {
+				if re.Match(buf) {
+					t.Errorf("unexpected match: %q", re)
+				}
+			}
+		})
+	}
+}
+
+// fakeReplica returns a fake jiva replica listening on the given loopback
+// address, e.g. 127.0.0.3, which serves the given stats.
+func fakeReplica(t *testing.T, host, stats string) *httptest.Server {
+	listener, err := net.Listen("tcp", host+":0")
+	if err != nil {
+		t.Skipf("can't listen on %s: %v", host, err)
+	}
+	replica := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, stats)
+	}))
+	replica.Listener.Close()
+	replica.Listener = listener
+	replica.Start()
+	return replica
+}
+
+func TestJivaReplicaRevisionStats(t *testing.T) {
+	cases := map[string]struct {
+		stats    []string
+		match    []*regexp.Regexp
+		notMatch []*regexp.Regexp
+	}{
+		"replicas are in sync": {
+			stats: []string{`{"revisioncounter":"100"}`, `{"revisioncounter":"100"}`},
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_replica_revision_counter{replica="127.0.0.3"} 100`),
+				regexp.MustCompile(`openebs_replica_revision_counter{replica="127.0.0.4"} 100`),
+				regexp.MustCompile(`openebs_replica_revision_max_diff 0`),
+			},
+		},
+		"replicas have diverged": {
+			stats: []string{`{"revisioncounter":"100"}`, `{"revisioncounter":"93"}`, `{"revisioncounter":"98"}`},
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_replica_revision_counter{replica="127.0.0.4"} 93`),
+				regexp.MustCompile(`openebs_replica_revision_max_diff 7`),
+			},
+		},
+		"only one replica reports the revision counter": {
+			stats: []string{`{"revisioncounter":"100"}`, `{"ReadIOPS":"7"}`},
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_replica_revision_counter{replica="127.0.0.3"} 100`),
+			},
+			notMatch: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_replica_revision_counter{replica="127.0.0.4"}`),
+				regexp.MustCompile(`openebs_replica_revision_max_diff`),
+			},
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			var replicas []string
+			for i, stats := range tt.stats {
+				replica := fakeReplica(t, fmt.Sprintf("127.0.0.%d", i+3), stats)
+				defer replica.Close()
+				replicaURL, _ := url.Parse(replica.URL)
+				replicas = append(replicas, fmt.Sprintf(`{"address":"tcp://%s","mode":"RW"}`, replicaURL.Host))
+			}
+			controller := fakeJivaController(fakeResponse, replicas...)
+			defer controller.Close()
+			control, _ := url.Parse(controller.URL)
+			buf := scrape(t, NewJivaStatsExporter(control, "jiva"))
+			for _, re := range tt.match {
+				if !re.Match(buf) {
+					t.Errorf("failed matching: %q", re)
+				}
+			}
+			for _, re := range tt.notMatch {
+				if re.Match(buf) {
+					t.Errorf("unexpected match: %q", re)
+				}
+			}
+		})
+	}
+}
+
+func TestJivaReplicaLastReconnect(t *testing.T) {
+	controller := fakeJivaController(fakeResponse,
+		`{"address":"tcp://127.0.0.2:1","mode":"RW","lastconnected":"2018-10-14T10:00:00Z"}`,
+		`{"address":"tcp://127.0.0.3:1","mode":"RW"}`,
+		`{"address":"tcp://127.0.0.4:1","mode":"RW","lastconnected":"yesterday"}`,
+	)
+	defer controller.Close()
+	control, _ := url.Parse(controller.URL)
+	buf := scrape(t, NewJivaStatsExporter(control, "jiva"))
+
+	re := regexp.MustCompile(`openebs_replica_last_reconnect_timestamp_seconds{replica="127.0.0.2"} 1.5395112e\+09`)
+	if !re.Match(buf) {
+		t.Errorf("failed matching: %q", re)
+	}
+	for _, replica := range []string{"127.0.0.3", "127.0.0.4"} {
+		re := regexp.MustCompile(`openebs_replica_last_reconnect_timestamp_seconds{replica="` + replica + `"}`)
+		if re.Match(buf) {
+			t.Errorf("unexpected match: %q", re)
+		}
+	}
+}
+
+func TestJivaReplicaAddressChanges(t *testing.T) {
+	var replicas []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/stats", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, fakeResponse)
+	})
+	mux.HandleFunc("/v1/replicas", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"data":[%s]}`, strings.Join(replicas, ","))
+	})
+	controller := httptest.NewServer(mux)
+	defer controller.Close()
+	control, _ := url.Parse(controller.URL)
+	col := NewJivaStatsExporter(control, "jiva")
+
+	// cases are run in order as the changes are counted from the
+	// addresses of the previous cases. The replicas are unreachable, so
+	// these are told apart by the ids reported by the controller.
+	cases := []struct {
+		name     string
+		replicas []string
+		match    []*regexp.Regexp
+	}{
+		{
+			name:     "Replicas are seen for the first time",
+			replicas: []string{`{"id":"rep1","address":"tcp://127.0.0.2:1","mode":"RW"}`, `{"id":"rep2","address":"tcp://127.0.0.3:1","mode":"RW"}`},
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_replica_address_changes_total{replica_id="rep1"} 0`),
+				regexp.MustCompile(`openebs_replica_address_changes_total{replica_id="rep2"} 0`),
+			},
+		},
+		{
+			name:     "Replica has connected from a new address",
+			replicas: []string{`{"id":"rep1","address":"tcp://127.0.0.2:1","mode":"RW"}`, `{"id":"rep2","address":"tcp://127.0.0.4:1","mode":"RW"}`},
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_replica_address_changes_total{replica_id="rep1"} 0`),
+				regexp.MustCompile(`openebs_replica_address_changes_total{replica_id="rep2"} 1`),
+			},
+		},
+		{
+			name:     "Replicas are listed in another order",
+			replicas: []string{`{"id":"rep2","address":"tcp://127.0.0.4:1","mode":"RW"}`, `{"id":"rep1","address":"tcp://127.0.0.2:1","mode":"RW"}`},
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_replica_address_changes_total{replica_id="rep1"} 0`),
+				regexp.MustCompile(`openebs_replica_address_changes_total{replica_id="rep2"} 1`),
+			},
+		},
+		{
+			name:     "Replica is disconnected",
+			replicas: []string{`{"id":"rep1","address":"tcp://127.0.0.2:1","mode":"RW"}`},
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_replica_address_changes_total{replica_id="rep2"} 1`),
+			},
+		},
+		{
+			name:     "Replica has connected again from another address",
+			replicas: []string{`{"id":"rep1","address":"tcp://127.0.0.2:1","mode":"RW"}`, `{"id":"rep2","address":"tcp://127.0.0.5:1","mode":"RW"}`},
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_replica_address_changes_total{replica_id="rep1"} 0`),
+				regexp.MustCompile(`openebs_replica_address_changes_total{replica_id="rep2"} 2`),
+			},
+		},
+		{
+			name:     "Replica without id is not counted",
+			replicas: []string{`{"address":"tcp://127.0.0.6:1","mode":"RW"}`},
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_replica_address_changes_total{replica_id="rep2"} 2`),
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			replicas = tt.replicas
+			buf := scrape(t, col)
+			for _, re := range tt.match {
+				if !re.Match(buf) {
+					t.Errorf("failed matching: %q", re)
+				}
+			}
+		})
+	}
+}
+
+func TestJivaReplicaCollectorTLS(t *testing.T) {
+	// the test servers share the same certificate.
+	replica := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"ReadIOPS":"7","WriteIOPS":"9"}`)
+	}))
+	defer replica.Close()
+	replicaURL, _ := url.Parse(replica.URL)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/stats", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, fakeResponse)
+	})
+	mux.HandleFunc("/v1/replicas", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"data":[{"address":"tcp://%s","mode":"RW"}]}`, replicaURL.Host)
+	})
+	controller := httptest.NewTLSServer(mux)
+	defer controller.Close()
+	col := NewJivaStatsExporter(mustParseURL(t, controller.URL), "jiva")
+	col.TLSConfig = controller.Client().Transport.(*http.Transport).TLSClientConfig
+
+	buf := scrape(t, col)
+	// the replicas are served over https like the controller.
+	for _, re := range []*regexp.Regexp{
+		regexp.MustCompile(`openebs_replica_read_iops{replica="127.0.0.1"} 7`),
+		regexp.MustCompile(`openebs_replica_write_iops{replica="127.0.0.1"} 9`),
+	} {
+		if !re.Match(buf) {
+			t.Errorf("failed matching: %q", re)
+		}
+	}
+}
+
+func TestJivaReplicaAddressChangesReplicaID(t *testing.T) {
+	replica := fakeReplica(t, "127.0.0.1", `{"id":"rep1","ReadIOPS":"7","WriteIOPS":"9"}`)
+	defer replica.Close()
+	address := strings.TrimPrefix(replica.URL, "http://")
+	// the id reported by the replica is used rather than the one reported
+	// for it by the controller.
+	controller := fakeJivaController(validControllerResp, `{"id":"controller-id","address":"tcp://`+address+`","mode":"RW"}`)
+	defer controller.Close()
+	buf := scrape(t, NewJivaStatsExporter(mustParseURL(t, controller.URL), "jiva"))
+	re := regexp.MustCompile(`openebs_replica_address_changes_total{replica_id="rep1"} 0`)
+	if !re.Match(buf) {
+		t.Errorf("failed matching: %q", re)
+	}
+}
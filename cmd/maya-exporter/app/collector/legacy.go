@@ -0,0 +1,114 @@
+package collector
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// legacyMetric is a core metric of a volume which has a legacy alias, it
+// is a gauge or the counter of a cumulative total in the counter mode.
+type legacyMetric interface {
+	prometheus.Metric
+	prometheus.Collector
+}
+
+// legacyAlias is a gauge emitted again under its name without the
+// namespace, as it was named by the older exporters.
+type legacyAlias struct {
+	gauge legacyMetric
+	desc  *prometheus.Desc
+}
+
+// legacyAliases keeps the aliases of the core gauges of a Metrics, these
+// are created on the first use as the most exporters don't need them.
+type legacyAliases struct {
+	once    sync.Once
+	aliases []legacyAlias
+}
+
+// coreGauges returns the gauges of the stats of the volume which are
+// emitted under the legacy names as well if LegacyMetricNames is set.
+func (m *Metrics) coreGauges() []legacyMetric {
+	return []legacyMetric{
+		m.reads,
+		m.writes,
+		m.totalReadTime,
+		m.totalWriteTime,
+		m.totalReadBlockCount,
+		m.totalWriteBlockCount,
+		m.actualUsed,
+		m.logicalSize,
+		m.sectorSize,
+		m.sizeOfVolume,
+		m.volumeUp,
+		m.volumeUptimeSeconds,
+		m.revisionCounter,
+		m.replicaCount,
+	}
+}
+
+// legacyAliases returns the aliases of the core gauges, the alias has the
+// name of the gauge without the namespace and the same constant labels.
+func (m *Metrics) legacyAliases() []legacyAlias {
+	m.legacy.once.Do(func() {
+		for _, gauge := range m.coreGauges() {
+			name := strings.TrimPrefix(collectorName(gauge), m.namespace+"_")
+			m.legacy.aliases = append(m.legacy.aliases, legacyAlias{
+				gauge: gauge,
+				desc:  prometheus.NewDesc(name, "Legacy alias of "+m.namespace+"_"+name, nil, gaugeLabels(gauge)),
+			})
+		}
+	})
+	return m.legacy.aliases
+}
+
+// gaugeLabels returns the constant labels of the gauge.
+func gaugeLabels(gauge legacyMetric) prometheus.Labels {
+	var metric dto.Metric
+	if err := gauge.Write(&metric); err != nil {
+		return nil
+	}
+	labels := prometheus.Labels{}
+	for _, pair := range metric.GetLabel() {
+		labels[pair.GetName()] = pair.GetValue()
+	}
+	return labels
+}
+
+// describeLegacy sends the descriptors of the aliases of the core gauges.
+func (m *Metrics) describeLegacy(ch chan<- *prometheus.Desc) {
+	for _, alias := range m.legacyAliases() {
+		ch <- alias.desc
+	}
+}
+
+// collectLegacy sends the aliases of the core gauges with the current
+// values of the gauges, the aliases of the disabled gauges are skipped.
+// The aliases carry the timestamp of their gauges, see collect.
+func (m *Metrics) collectLegacy(ch chan<- prometheus.Metric) {
+	t, stamped := m.collectedAt.load()
+	stats := m.statsCollectors()
+	for _, alias := range m.legacyAliases() {
+		if m.disabled.has(alias.gauge) {
+			continue
+		}
+		var metric dto.Metric
+		if err := alias.gauge.Write(&metric); err != nil {
+			continue
+		}
+		value := metric.GetGauge().GetValue()
+		if metric.Counter != nil {
+			value = metric.GetCounter().GetValue()
+		}
+		// the aliases are gauges even in the counter mode as these
+		// were gauges in the older exporters.
+		aliasMetric := prometheus.MustNewConstMetric(alias.desc, prometheus.GaugeValue, value)
+		if stamped && stats[alias.gauge] {
+			aliasMetric = newMetricWithTimestamp(t, aliasMetric)
+		}
+		ch <- aliasMetric
+	}
+}
@@ -0,0 +1,90 @@
+package collector
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestJivaCollectorLegacyMetricNames(t *testing.T) {
+	cases := map[string]struct {
+		legacy   bool
+		disabled []string
+		match    []*regexp.Regexp
+		noMatch  []*regexp.Regexp
+	}{
+		"Legacy names are not emitted by default": {
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`(?m)^openebs_reads 5$`),
+			},
+			noMatch: []*regexp.Regexp{
+				regexp.MustCompile(`(?m)^reads 5$`),
+			},
+		},
+		"Legacy names are emitted along with the new names": {
+			legacy: true,
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`(?m)^openebs_reads 5$`),
+				regexp.MustCompile(`(?m)^reads 5$`),
+				regexp.MustCompile(`(?m)^writes 11$`),
+				regexp.MustCompile(`(?m)^volume_up 1$`),
+				regexp.MustCompile(`# HELP reads Legacy alias of openebs_reads`),
+			},
+			noMatch: []*regexp.Regexp{
+				// only the core gauges have legacy names.
+				regexp.MustCompile(`(?m)^scrapes_total`),
+			},
+		},
+		"Legacy names of the disabled metrics are not emitted": {
+			legacy:   true,
+			disabled: []string{"reads"},
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`(?m)^writes 11$`),
+			},
+			noMatch: []*regexp.Regexp{
+				regexp.MustCompile(`(?m)^reads `),
+			},
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			controller := fakeJivaController(validControllerResp)
+			defer controller.Close()
+			col := NewJivaStatsExporter(mustParseURL(t, controller.URL), "jiva")
+			col.LegacyMetricNames = tt.legacy
+			col.DisableMetrics(tt.disabled)
+			buf := scrape(t, col)
+			for _, re := range tt.match {
+				if !re.Match(buf) {
+					t.Errorf("failed matching: %q", re)
+				}
+			}
+			for _, re := range tt.noMatch {
+				if re.Match(buf) {
+					t.Errorf("unexpected match: %q", re)
+				}
+			}
+		})
+	}
+}
+
+func TestJivaVolumesCollectorLegacyMetricNames(t *testing.T) {
+	controller := fakeJivaController(validControllerResp)
+	defer controller.Close()
+	col, err := NewJivaVolumesStatsExporter([]VolumeTarget{
+		{Name: "vol1", URL: controller.URL},
+		{Name: "vol2", URL: controller.URL},
+	}, "jiva")
+	if err != nil {
+		t.Fatal(err)
+	}
+	col.LegacyMetricNames = true
+	buf := scrape(t, col)
+	for _, re := range []*regexp.Regexp{
+		regexp.MustCompile(`reads{volume="vol1"} 5`),
+		regexp.MustCompile(`reads{volume="vol2"} 5`),
+	} {
+		if !re.Match(buf) {
+			t.Errorf("failed matching: %q", re)
+		}
+	}
+}
@@ -1,9 +1,23 @@
 package collector
 
 import (
+	"context"
+	"crypto/tls"
+	"fmt"
 	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/openebs/maya/cmd/maya-exporter/app/logger"
+	"github.com/openebs/maya/types/v1"
 	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 )
 
 const (
@@ -21,6 +35,40 @@ const (
 	Command = "IOSTATS"
 	// BufSize is the size of response from cstor.
 	BufSize = 256
+	// DefaultTimeout is the default timeout of the requests made
+	// to the jiva controller.
+	DefaultTimeout = 5 * time.Second
+	// DefaultRetries is the default no of retries of a failed request
+	// to the jiva controller.
+	DefaultRetries = 2
+	// DefaultRetryBackoff is the default initial delay between the
+	// retries of a failed request to the jiva controller.
+	DefaultRetryBackoff = 100 * time.Millisecond
+	// DefaultMaxRedirects is the default max no of redirects followed by
+	// a request to the jiva controller.
+	DefaultMaxRedirects = 3
+	// DefaultNamespace is the default prefix of the names of all the
+	// metrics exposed by the exporter.
+	DefaultNamespace = "openebs"
+	// DefaultMaxConcurrency is the default no of volumes whose stats
+	// are collected concurrently.
+	DefaultMaxConcurrency = 8
+	// DefaultMaxIdleConns is the default max no of idle connections
+	// kept open with the jiva controller and replicas of a volume.
+	DefaultMaxIdleConns = 8
+	// DefaultIdleConnTimeout is the default time after which an idle
+	// connection with the jiva controller or replicas is closed.
+	DefaultIdleConnTimeout = 90 * time.Second
+	// DefaultLogErrorInterval is the default min interval between the
+	// logs of the failures in collecting the stats of a volume.
+	DefaultLogErrorInterval = time.Minute
+	// DefaultBreakerCooldown is the default time for which the collection
+	// of the stats of a volume is skipped once its circuit breaker opens.
+	DefaultBreakerCooldown = time.Minute
+	// DefaultLatencyAnomalyThreshold is the default average latency of
+	// the reads or writes of a volume above which the stats of the volume
+	// are flagged as implausible.
+	DefaultLatencyAnomalyThreshold = 10 * time.Second
 )
 
 // Exporter interface defines the interfaces that has methods to be
@@ -55,8 +103,87 @@ type Set interface {
 // these properties includes metrics of the volumes.
 type VolumeStatsExporter struct {
 	CASType string
+	// Namespace is the prefix of the names of the metrics.
+	Namespace string
+	// Volumes are the volumes whose stats are collected by the
+	// exporter, if it is empty only the stats of the volume
+	// controller of Jiva are collected.
+	Volumes []VolumeTarget
+	// MaxConcurrency is the max no of Volumes whose stats are collected
+	// concurrently, DefaultMaxConcurrency is used if it is not set.
+	MaxConcurrency int
+	// LogErrorInterval is the min interval between the logs of the
+	// failures in collecting the stats of a volume, the first failure
+	// is always logged. DefaultLogErrorInterval is used if it is not set.
+	LogErrorInterval time.Duration
+	// BreakerThreshold is the no of failed collections in a row after
+	// which the circuit breaker of a volume opens, i.e. its stats are not
+	// collected for BreakerCooldown and it is reported as down. The
+	// circuit breaker is disabled if it is not set.
+	BreakerThreshold int
+	// BreakerCooldown is the time after which a volume whose circuit
+	// breaker is open is probed again, DefaultBreakerCooldown is used if
+	// it is not set.
+	BreakerCooldown time.Duration
+	// LatencyAnomalyThreshold is the average latency of the reads or
+	// writes above which the stats of a volume are flagged as an anomaly,
+	// e.g. due to a stuck queue or a corrupt response of the controller.
+	// DefaultLatencyAnomalyThreshold is used if it is not set.
+	LatencyAnomalyThreshold time.Duration
+	// LegacyMetricNames emits the core gauges of the volumes under their
+	// names without the namespace as well, as these were named by the
+	// older exporters. It doubles the no of these metrics, so it should
+	// be set only until the dashboards are migrated to the new names.
+	LegacyMetricNames bool
 	Cstor
 	Jiva
+	Replica
+	Metrics
+	// targets keeps the collector and the metrics of the Volumes.
+	targets     []*target
+	targetsOnce sync.Once
+	// collectMu serializes the collections of the stats, e.g. by the
+	// scrapes of /metrics and the pushes to the Pushgateway, as the
+	// collectors and the metrics of the volumes are shared by them.
+	collectMu sync.Mutex
+	// ready is set to 1 after the first successful collection of
+	// the stats.
+	ready int32
+	// ctx is used to abort the in-flight requests to the volume
+	// controllers, context.Background() is used if it is not set.
+	ctx context.Context
+	// disabledMetrics are the names of the metrics which are not
+	// collected and allowedMetrics the names of the only metrics which
+	// are collected if it is not nil, disabledMu guards them once the
+	// exporter is registered.
+	disabledMetrics []string
+	allowedMetrics  []string
+	disabledMu      sync.Mutex
+	// scrapeSummary is the summary of the scrape durations of all the
+	// volumes, it is created along with the targets.
+	// scrapeSummaryDisabled is guarded by disabledMu.
+	scrapeSummary         prometheus.Summary
+	scrapeSummaryDisabled bool
+	// latencyBuckets are the buckets of the latency histograms.
+	latencyBuckets []float64
+	// counterMode exports the cumulative totals as counters, see
+	// SetCounterMode.
+	counterMode bool
+	// constLabels are added to all the metrics.
+	constLabels prometheus.Labels
+}
+
+// VolumeTarget is a volume whose stats are collected by the exporter,
+// the metrics of the volume are labelled with its name.
+type VolumeTarget struct {
+	Name string
+	URL  string
+}
+
+// target keeps the collector and the metrics of a VolumeTarget.
+type target struct {
+	name string
+	Jiva
 	Metrics
 }
 
@@ -68,12 +195,126 @@ type VolumeStatsExporter struct {
 // the metrics of a OpenEBS (cstor) volume.
 type Cstor struct {
 	Conn net.Conn
+	// StatsClient is the client of the gRPC stats api of the newer cstor
+	// targets, the stats are fetched using it rather than the unix socket
+	// if it is set. StatsTimeout is the timeout of its requests,
+	// DefaultGRPCStatsTimeout is used if it is not set.
+	StatsClient  VolumeStatsClient
+	StatsTimeout time.Duration
 }
 
 // Jiva implements the prometheus.Collector interface. It exposes
 // the metrics of a OpenEBS (Jiva) volume.
 type Jiva struct {
 	VolumeControllerURL string
+	// ControllerURLFile is the file from which the address of the jiva
+	// controller is re-read before each collection, VolumeControllerURL
+	// is changed to it once the file is changed. It is ignored for the
+	// Volumes of the exporter.
+	ControllerURLFile string
+	// StatsFile is a file with a captured response of the stats api of
+	// the jiva controller, e.g. for the offline analysis of the stats of
+	// a volume. The stats are read from it instead of the controller if
+	// it is set and the stats of the replicas are not collected.
+	StatsFile string
+	// StatsPath is the path of the stats api of the jiva controller,
+	// statsAPI is used if it is not set. The alternate path of the
+	// stats api is tried if the controller doesn't serve StatsPath.
+	StatsPath string
+	// Fetcher fetches the stats of the volume, e.g. a fake injected by
+	// the tests, the stats are fetched by the Jiva itself the way the
+	// JivaHTTPFetcher does if it is not set. It is shared by the Volumes
+	// of the exporter.
+	Fetcher StatsFetcher
+	// Timeout is the timeout of the requests made to the jiva
+	// controller, DefaultTimeout is used if it is not set.
+	Timeout time.Duration
+	// Retries is the no of times a failed request to the jiva
+	// controller is retried, RetryBackoff is the initial delay
+	// between the retries which is doubled after each retry.
+	Retries      int
+	RetryBackoff time.Duration
+	// MaxRedirects is the max no of redirects followed by a request to
+	// the jiva controller, e.g. issued by a load balancer in front of
+	// it, DefaultMaxRedirects is used if it is 0. The redirects are not
+	// followed if it is negative.
+	MaxRedirects int
+	// redirects is the no of redirects followed since the last
+	// collection, it is updated atomically.
+	redirects int64
+	// Username and Password are the basic auth credentials of the
+	// jiva controller, these are sent only if Username is set.
+	Username string
+	Password string
+	// CredentialsFile is the file from which the credentials of the jiva
+	// controller are re-read before each collection, see
+	// ReadCredentialsFile. The credentials and the tls configuration are
+	// changed once the file or the tls files it refers to are changed.
+	CredentialsFile string
+	// credentials are the credentials last set by SetCredentials,
+	// tlsFingerprint is the fingerprint of their tls files and
+	// credentialsStamp the stamp of the CredentialsFile they were last
+	// read from. These, Username, Password and TLSConfig are guarded by
+	// credentialsMu once the Jiva is collected.
+	credentials      Credentials
+	tlsFingerprint   string
+	credentialsStamp string
+	// Headers are added to the requests to the jiva controller, e.g. the
+	// routing headers of a service mesh. The Host header sets the host
+	// of the requests.
+	Headers http.Header
+	// TLSConfig is used to verify the certificate of the jiva
+	// controller if it is served over https.
+	TLSConfig *tls.Config
+	// DisableKeepAlives closes the connection with the jiva controller
+	// after each request, for controllers which don't handle keep-alive.
+	DisableKeepAlives bool
+	// ForceHTTP2 negotiates HTTP/2 with the jiva controller served over
+	// https even if TLSConfig is set, the plain http requests are not
+	// affected. DisableHTTP2 never negotiates it, for the controllers
+	// with a buggy HTTP/2 support, it takes precedence over ForceHTTP2.
+	ForceHTTP2   bool
+	DisableHTTP2 bool
+	// MaxIdleConns and IdleConnTimeout tune the idle connections kept
+	// open for the reuse across the scrapes, DefaultMaxIdleConns and
+	// DefaultIdleConnTimeout are used if these are not set.
+	MaxIdleConns    int
+	IdleConnTimeout time.Duration
+	// ProxyURL is the url of the proxy through which the requests to the
+	// jiva controller and replicas are sent. It takes precedence over
+	// the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables,
+	// which are used if it is not set.
+	ProxyURL *url.URL
+	// client is the http client used for all the requests, it is
+	// created only once so that connections are reused across scrapes.
+	// replicaClient is used for the requests to the replicas instead if
+	// the jiva controller listens on a unix socket.
+	client        *http.Client
+	replicaClient *http.Client
+	// replicas keeps the last known stats of the replicas, see
+	// lastReplicas.
+	replicas *replicaState
+	// CacheTTL is the duration for which the stats fetched from the
+	// jiva controller are served from the cache, the cache is disabled
+	// if it is 0.
+	CacheTTL time.Duration
+	// cache keeps the last stats of the volume, it is created by the
+	// constructors of the exporter for each volume.
+	cache *statsCache
+	// EnableRequestTrace records the time taken by the phases of the
+	// requests for the stats, it is disabled by default due to the
+	// overhead of httptrace.
+	EnableRequestTrace bool
+	// StrictDecode fails the collection if the response of the jiva
+	// controller has fields which are not known by the exporter, so that
+	// the changes in the api of the controller are noticed early. The
+	// unknown fields are ignored by default.
+	StrictDecode bool
+	// DisableReplicaStats skips the requests to the replicas listed by
+	// the jiva controller, e.g. for the controllers which are probed on
+	// behalf of a client, as the addresses of the replicas are then not
+	// trusted.
+	DisableReplicaStats bool
 }
 
 // A gauge is a metric that represents a single numerical value that can
@@ -104,18 +345,130 @@ type Metrics struct {
 	actualUsed             prometheus.Gauge
 	logicalSize            prometheus.Gauge
 	sectorSize             prometheus.Gauge
-	reads                  prometheus.Gauge
-	totalReadTime          prometheus.Gauge
-	totalReadBlockCount    prometheus.Gauge
-	totalReadBytes         prometheus.Gauge
-	writes                 prometheus.Gauge
-	totalWriteTime         prometheus.Gauge
-	totalWriteBlockCount   prometheus.Gauge
-	totalWriteBytes        prometheus.Gauge
+	sectorSizeValid        prometheus.Gauge
+	reads                  cumulativeMetric
+	totalReadTime          cumulativeMetric
+	avgReadLatency         prometheus.Gauge
+	totalReadBlockCount    cumulativeMetric
+	totalReadBytes         cumulativeMetric
+	writes                 cumulativeMetric
+	totalWriteTime         cumulativeMetric
+	avgWriteLatency        prometheus.Gauge
+	totalWriteBlockCount   cumulativeMetric
+	totalWriteBytes        cumulativeMetric
 	sizeOfVolume           prometheus.Gauge
+	volumeUp               prometheus.Gauge
+	volumeUptimeSeconds    prometheus.Gauge
+	revisionCounter        prometheus.Gauge
+	replicaCount           prometheus.Gauge
+	connectedReplicas      *prometheus.GaugeVec
+	pendingIO              *prometheus.GaugeVec
+	queueDepth             *prometheus.GaugeVec
+	activeSessions         *prometheus.GaugeVec
+	readsByPattern         *prometheus.GaugeVec
+	writesByPattern        *prometheus.GaugeVec
+	scrapeDuration         prometheus.Gauge
+	readBytes              cumulativeMetric
+	writeBytes             cumulativeMetric
+	volumeUsedPercent      prometheus.Gauge
+	statsCacheAge          prometheus.Gauge
+	lastScrapeSuccess      prometheus.Gauge
+	circuitBreakerOpen     prometheus.Gauge
 	volumeUpTime           *prometheus.CounterVec
 	connectionRetryCounter *prometheus.CounterVec
 	connectionErrorCounter *prometheus.CounterVec
+	scrapeTimeoutCounter   prometheus.Counter
+	scrapeRetriesCounter   prometheus.Counter
+	scrapeRedirectsCounter prometheus.Counter
+	parseErrorsCounter     prometheus.Counter
+	strictDecodeErrors     prometheus.Counter
+	scrapesCounter         prometheus.Counter
+	scrapeErrorsCounter    prometheus.Counter
+	readErrors             *prometheus.CounterVec
+	writeErrors            *prometheus.CounterVec
+	replicaReadIOPS        *prometheus.GaugeVec
+	replicaWriteIOPS       *prometheus.GaugeVec
+	replicaStatus          *prometheus.GaugeVec
+	replicaRebuilding      *prometheus.GaugeVec
+	replicaRebuildProgress *prometheus.GaugeVec
+	rebuildBytesRemaining  *prometheus.GaugeVec
+	replicaRevisionCounter *prometheus.GaugeVec
+	replicaRevisionMaxDiff *prometheus.GaugeVec
+	replicaLastReconnect   *prometheus.GaugeVec
+	replicaAddressChanges  *prometheus.CounterVec
+	controllerInfo         *prometheus.GaugeVec
+	scsiIOCount            *prometheus.GaugeVec
+	statsAnomaly           *prometheus.GaugeVec
+	// controllerResponseBytes and controllerRequestDuration are the
+	// size and the duration of the requests for the stats.
+	controllerResponseBytes   prometheus.Gauge
+	controllerRequestDuration *prometheus.HistogramVec
+	// readLatency and writeLatency are derived from the total read and
+	// write times, see observeLatencies.
+	readLatency  prometheus.Histogram
+	writeLatency prometheus.Histogram
+	// lastStats keeps the stats of the last successful collection.
+	lastStats *lastStats
+	// collectedAt keeps the time of the collection of the cached stats
+	// of the last successful collection, it is not set if the cache is
+	// disabled.
+	collectedAt *collectedAt
+	// errorLog rate limits the logs of the failures in collecting
+	// the stats.
+	errorLog *errorLog
+	// breaker skips the collection of the stats of a failing volume.
+	breaker *circuitBreaker
+	// legacy keeps the aliases of the core gauges under the legacy names.
+	legacy *legacyAliases
+	// namespace is the prefix of the names of the metrics.
+	namespace string
+	// disabled keeps the metrics which are not collected.
+	disabled *disabledSet
+}
+
+// disabledSet keeps the disabled metrics, it is safe for concurrent use
+// so that the metrics can be disabled while these are collected.
+type disabledSet struct {
+	mu      sync.RWMutex
+	metrics map[prometheus.Collector]bool
+}
+
+// has returns true if c is disabled.
+func (d *disabledSet) has(c prometheus.Collector) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.metrics[c]
+}
+
+// set replaces the disabled metrics with the given ones.
+func (d *disabledSet) set(metrics map[prometheus.Collector]bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.metrics = metrics
+}
+
+// lastStats keeps the last collected stats of a volume, it is safe for
+// concurrent use.
+type lastStats struct {
+	mu    sync.Mutex
+	stats *v1.VolumeStats
+}
+
+// store keeps the given stats.
+func (l *lastStats) store(stats v1.VolumeStats) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stats = &stats
+}
+
+// load returns the stored stats and false if no stats are stored.
+func (l *lastStats) load() (v1.VolumeStats, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.stats == nil {
+		return v1.VolumeStats{}, false
+	}
+	return *l.stats, true
 }
 
 // VolumeStats keep the values of read/write I/O's and
@@ -129,155 +482,985 @@ type VolumeStats struct {
 	totalWriteBytes      float64
 	totalReadTime        float64
 	totalWriteTime       float64
+	avgReadLatency       float64
+	avgWriteLatency      float64
 	size                 float64
 	sectorSize           float64
 	logicalSize          float64
 	actualSize           float64
 	uptime               float64
+	revisionCounter      float64
+	replicaCounter       float64
+	readBytes            float64
+	writeBytes           float64
+	usedPercent          float64
+}
+
+// blocksToBytes converts the read and write block counts into bytes using
+// the sector size, bytes are left 0 if sector size is 0.
+func (v *VolumeStats) blocksToBytes() {
+	v.readBytes = v.totalReadBlockCount * v.sectorSize
+	v.writeBytes = v.totalWriteBlockCount * v.sectorSize
+}
+
+// averageLatencies sets the average latencies of the reads and writes in
+// seconds, the total read and write times are in nanoseconds. The latency
+// is 0 if there are no reads or writes.
+func (v *VolumeStats) averageLatencies() {
+	v.avgReadLatency = averageLatency(v.totalReadTime, v.reads)
+	v.avgWriteLatency = averageLatency(v.totalWriteTime, v.writes)
+}
+
+// averageLatency returns the average time in seconds of the given no of
+// ios which took totalTime nanoseconds.
+func averageLatency(totalTime, ios float64) float64 {
+	latency, ok := v1.DivideFloat64(totalTime, ios)
+	if !ok {
+		return 0
+	}
+	return latency / float64(time.Second)
+}
+
+// setSectorSize sets the sector size of the volume and whether it is
+// valid, it is not valid if it is missing, can't be parsed or is 0 as
+// the bytes derived from the block counts are 0 then.
+func (m *Metrics) setSectorSize(size float64) {
+	m.sectorSize.Set(size)
+	valid := 0.0
+	if size > 0 {
+		valid = 1
+	}
+	m.sectorSizeValid.Set(valid)
+}
+
+// setQueueStats sets the pending ios and the queue depth of the volume
+// from the given stats, these are not emitted if these are missing or not
+// valid numbers.
+func (m *Metrics) setQueueStats(stats v1.VolumeStats) {
+	setIfPresent(m.pendingIO, stats.PendingIO)
+	setIfPresent(m.queueDepth, stats.QueueDepth)
+}
+
+// setSessionStats sets the no of active sessions of the initiators of the
+// volume from the given stats, it is not emitted if it is missing or not
+// a valid number.
+func (m *Metrics) setSessionStats(stats v1.VolumeStats) {
+	setIfPresent(m.activeSessions, stats.ActiveSessions)
+}
+
+// The values of the pattern label of the reads and writes.
+const (
+	patternSequential = "sequential"
+	patternRandom     = "random"
+)
+
+// setPatternStats sets the sequential and random reads and writes of the
+// volume from the given stats, the patterns which are missing or not
+// valid numbers are not emitted.
+func (m *Metrics) setPatternStats(stats v1.VolumeStats) {
+	m.readsByPattern.Reset()
+	m.writesByPattern.Reset()
+	for pattern, value := range map[string]v1.FlexNumber{
+		patternSequential: stats.SequentialReads,
+		patternRandom:     stats.RandomReads,
+	} {
+		if f, err := value.Float64(); err == nil {
+			m.readsByPattern.WithLabelValues(pattern).Set(f)
+		}
+	}
+	for pattern, value := range map[string]v1.FlexNumber{
+		patternSequential: stats.SequentialWrites,
+		patternRandom:     stats.RandomWrites,
+	} {
+		if f, err := value.Float64(); err == nil {
+			m.writesByPattern.WithLabelValues(pattern).Set(f)
+		}
+	}
+}
+
+// setErrorStats sets the failed reads and writes of the volume from the
+// given stats. These are the cumulative counts of the controller, so the
+// counters are set rather than incremented, and are not emitted if these
+// are missing or not valid numbers.
+func (m *Metrics) setErrorStats(stats v1.VolumeStats) {
+	for counter, value := range map[*prometheus.CounterVec]v1.FlexNumber{
+		m.readErrors:  stats.ReadErrors,
+		m.writeErrors: stats.WriteErrors,
+	} {
+		f, err := value.Float64()
+		if err != nil {
+			counter.Reset()
+			continue
+		}
+		counter.WithLabelValues().Set(f)
+	}
+}
+
+// setControllerInfo sets the info gauge of the controller with its version,
+// it is not emitted if the controller doesn't report its version.
+func (m *Metrics) setControllerInfo(version string) {
+	m.controllerInfo.Reset()
+	if version = strings.TrimSpace(version); len(version) != 0 {
+		m.controllerInfo.WithLabelValues(version).Set(1)
+	}
+}
+
+// setIfPresent sets the gauge without labels to the given value, the
+// gauge is reset so that it is not emitted if the value is not a number.
+func setIfPresent(gauge *prometheus.GaugeVec, value v1.FlexNumber) {
+	f, err := value.Float64()
+	if err != nil {
+		gauge.Reset()
+		return
+	}
+	gauge.WithLabelValues().Set(f)
+}
+
+// observeLatencies observes the average latencies of the reads and writes
+// made between the prev and cur stats in the latency histograms. Nothing is
+// observed for the reads or writes if there were none, or if the counters
+// of the volume were reset e.g. on a restart of the controller.
+func (m *Metrics) observeLatencies(prev, cur VolumeStats) {
+	if reads := cur.reads - prev.reads; reads > 0 && cur.totalReadTime >= prev.totalReadTime {
+		m.readLatency.Observe(averageLatency(cur.totalReadTime-prev.totalReadTime, reads))
+	}
+	if writes := cur.writes - prev.writes; writes > 0 && cur.totalWriteTime >= prev.totalWriteTime {
+		m.writeLatency.Observe(averageLatency(cur.totalWriteTime-prev.totalWriteTime, writes))
+	}
+}
+
+// ValidateLatencyBuckets returns an error if the buckets of the latency
+// histograms are not positive and in increasing order.
+func ValidateLatencyBuckets(buckets []float64) error {
+	for i, bucket := range buckets {
+		if bucket <= 0 {
+			return fmt.Errorf("latency bucket %v is not positive", bucket)
+		}
+		if i > 0 && bucket <= buckets[i-1] {
+			return fmt.Errorf("latency buckets %v are not in increasing order", buckets)
+		}
+	}
+	return nil
+}
+
+// usedPercentage returns the percentage of size which is used, it is 0
+// if size is 0 and clamped to 100 if used is more than size which can
+// happen due to the accounting of thin provisioned volumes.
+func usedPercentage(used, size float64) float64 {
+	percent, ok := v1.DivideFloat64(used*100, size)
+	if !ok {
+		return 0
+	}
+	if percent > 100 {
+		logger.Warningf("used capacity %v is more than the size %v of the volume", used, size)
+		return 100
+	}
+	return percent
 }
 
 // MetricsInitializer returns the Metrics instance used for registration
 // of exporter while instantiating JivaStatsExporter and
-// CstorStatsExporter.
-func MetricsInitializer(casType string) *Metrics {
+// CstorStatsExporter. The names of the metrics are prefixed with the
+// given namespace, DefaultNamespace is used if it is empty.
+func MetricsInitializer(casType, namespace string) *Metrics {
+	return newMetrics(casType, namespace, nil, nil, false)
+}
+
+// normalizeNamespace returns the namespace of the metrics without the
+// trailing "_", DefaultNamespace is returned if it is empty.
+func normalizeNamespace(namespace string) string {
+	namespace = strings.TrimSuffix(namespace, "_")
+	if len(namespace) == 0 {
+		return DefaultNamespace
+	}
+	return namespace
+}
+
+// newMetrics returns the Metrics instance whose metrics have the given
+// constant labels, these are used to distinguish the metrics of
+// different volumes collected by the same exporter. The latency
+// histograms have the given buckets, prometheus.DefBuckets are used if
+// these are nil. The cumulative totals are counters rather than gauges if
+// counterMode is set.
+func newMetrics(casType, namespace string, labels prometheus.Labels, latencyBuckets []float64, counterMode bool) *Metrics {
+	namespace = normalizeNamespace(namespace)
 	return &Metrics{
+		lastStats:   &lastStats{},
+		collectedAt: &collectedAt{},
+		errorLog:    &errorLog{},
+		breaker:     &circuitBreaker{},
+		legacy:      &legacyAliases{},
+		disabled:    &disabledSet{},
+		namespace:   namespace,
 		actualUsed: prometheus.NewGauge(
 			prometheus.GaugeOpts{
-				Namespace: "openebs",
-				Name:      "actual_used",
-				Help:      "Actual volume size used",
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "actual_used",
+				Help:        "Actual volume size used",
 			}),
 
 		logicalSize: prometheus.NewGauge(
 			prometheus.GaugeOpts{
-				Namespace: "openebs",
-				Name:      "logical_size",
-				Help:      "Logical size of volume",
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "logical_size",
+				Help:        "Logical size of volume",
 			}),
 
 		sizeOfVolume: prometheus.NewGauge(
 			prometheus.GaugeOpts{
-				Namespace: "openebs",
-				Name:      "size_of_volume",
-				Help:      "Size of the volume requested",
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "size_of_volume",
+				Help:        "Size of the volume requested",
 			}),
 
 		sectorSize: prometheus.NewGauge(
 			prometheus.GaugeOpts{
-				Namespace: "openebs",
-				Name:      "sector_size",
-				Help:      "sector size of volume",
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "sector_size",
+				Help:        "sector size of volume",
 			}),
 
-		totalReadBytes: prometheus.NewGauge(
+		sectorSizeValid: prometheus.NewGauge(
 			prometheus.GaugeOpts{
-				Namespace: "openebs",
-				Name:      "total_read_bytes",
-				Help:      "Total read bytes",
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "sector_size_valid",
+				Help:        "Whether the sector size of the volume is known, the bytes read and written are 0 otherwise (1 for yes, 0 for no)",
 			}),
 
-		reads: prometheus.NewGauge(
+		volumeUp: prometheus.NewGauge(
 			prometheus.GaugeOpts{
-				Namespace: "openebs",
-				Name:      "reads",
-				Help:      "Read Input/Outputs on Volume",
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "volume_up",
+				Help:        "Whether the stats of the volume are collected successfully (1 for yes, 0 for no)",
 			}),
 
-		totalReadTime: prometheus.NewGauge(
+		volumeUptimeSeconds: prometheus.NewGauge(
 			prometheus.GaugeOpts{
-				Namespace: "openebs",
-				Name:      "read_time",
-				Help:      "Read time on volume",
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "volume_uptime_seconds",
+				Help:        "Time in seconds since the volume controller has started",
 			}),
 
-		totalReadBlockCount: prometheus.NewGauge(
+		revisionCounter: prometheus.NewGauge(
 			prometheus.GaugeOpts{
-				Namespace: "openebs",
-				Name:      "read_block_count",
-				Help:      "Read Block count of volume",
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "revision_counter",
+				Help:        "Revision counter of the volume",
 			}),
 
-		totalWriteBytes: prometheus.NewGauge(
+		replicaCount: prometheus.NewGauge(
 			prometheus.GaugeOpts{
-				Namespace: "openebs",
-				Name:      "total_write_bytes",
-				Help:      "Total write bytes",
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "replica_count",
+				Help:        "No of replicas connected with the volume controller",
 			}),
 
-		writes: prometheus.NewGauge(
+		// connectedReplicas has no labels, it is a vector so that it
+		// is not emitted if the replicas can't be listed.
+		connectedReplicas: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
-				Namespace: "openebs",
-				Name:      "writes",
-				Help:      "Write Input/Outputs on Volume",
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "connected_replicas",
+				Help:        "No of healthy (RW) replicas connected with the volume controller, not emitted if the replicas can't be listed",
+			},
+			[]string{},
+		),
+
+		// pendingIO and queueDepth have no labels, these are vectors so
+		// that they are not emitted if the controller doesn't send them.
+		pendingIO: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "pending_io",
+				Help:        "No of ios pending in the volume controller, not emitted if the controller doesn't report it",
+			},
+			[]string{},
+		),
+
+		queueDepth: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "queue_depth",
+				Help:        "Depth of the io queue of the volume controller, not emitted if the controller doesn't report it",
+			},
+			[]string{},
+		),
+
+		// activeSessions has no labels either, a sudden change of it is a
+		// sign of the initiators reconnecting, e.g. on a restart storm of
+		// the application pods.
+		activeSessions: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "active_sessions",
+				Help:        "No of active iSCSI sessions of the initiators connected to the volume controller, not emitted if the controller doesn't report it",
+			},
+			[]string{},
+		),
+
+		// readsByPattern and writesByPattern split the reads and writes
+		// into the sequential and random ones, these can't be named
+		// reads and writes as the unlabeled aggregates are kept.
+		readsByPattern: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "reads_by_pattern",
+				Help:        "Read Input/Outputs on Volume per access pattern (sequential or random), not emitted if the controller doesn't report it",
+			},
+			[]string{"pattern"},
+		),
+
+		writesByPattern: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "writes_by_pattern",
+				Help:        "Write Input/Outputs on Volume per access pattern (sequential or random), not emitted if the controller doesn't report it",
+			},
+			[]string{"pattern"},
+		),
+
+		scrapeDuration: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "collector_scrape_duration_seconds",
+				Help:        "Time taken in seconds to collect the stats of the volume",
 			}),
 
-		totalWriteTime: prometheus.NewGauge(
+		readBytes: newCumulative(counterMode,
 			prometheus.GaugeOpts{
-				Namespace: "openebs",
-				Name:      "write_time",
-				Help:      "Write time on volume",
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "read_bytes_total",
+				Help:        "Total bytes read from the volume (read block count * sector size)",
 			}),
 
-		totalWriteBlockCount: prometheus.NewGauge(
+		writeBytes: newCumulative(counterMode,
 			prometheus.GaugeOpts{
-				Namespace: "openebs",
-				Name:      "write_block_count",
-				Help:      "Write Block count of volume",
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "write_bytes_total",
+				Help:        "Total bytes written on the volume (write block count * sector size)",
+			}),
+
+		volumeUsedPercent: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "volume_used_percent",
+				Help:        "Percentage of the capacity of the volume which is used",
+			}),
+
+		lastScrapeSuccess: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "last_scrape_success_timestamp_seconds",
+				Help:        "Unix time of the last successful fetch of the stats from the volume controller",
+			}),
+
+		circuitBreakerOpen: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "circuit_breaker_open",
+				Help:        "Whether the stats of the volume are not collected due to the failures in a row (1 for yes, 0 for no)",
+			}),
+
+		statsCacheAge: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "stats_cache_age_seconds",
+				Help:        "Age of the stats of the volume served from the cache, 0 if they are fetched from the controller",
+			}),
+
+		totalReadBytes: newCumulative(counterMode,
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "total_read_bytes",
+				Help:        "Total read bytes",
+			}),
+
+		reads: newCumulative(counterMode,
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "reads",
+				Help:        "Read Input/Outputs on Volume",
+			}),
+
+		totalReadTime: newCumulative(counterMode,
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "read_time",
+				Help:        "Read time on volume",
+			}),
+
+		avgReadLatency: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "avg_read_latency_seconds",
+				Help:        "Average latency of the reads on volume in seconds",
+			}),
+
+		avgWriteLatency: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "avg_write_latency_seconds",
+				Help:        "Average latency of the writes on volume in seconds",
+			}),
+
+		totalReadBlockCount: newCumulative(counterMode,
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "read_block_count",
+				Help:        "Read Block count of volume",
+			}),
+
+		totalWriteBytes: newCumulative(counterMode,
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "total_write_bytes",
+				Help:        "Total write bytes",
+			}),
+
+		writes: newCumulative(counterMode,
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "writes",
+				Help:        "Write Input/Outputs on Volume",
+			}),
+
+		totalWriteTime: newCumulative(counterMode,
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "write_time",
+				Help:        "Write time on volume",
+			}),
+
+		totalWriteBlockCount: newCumulative(counterMode,
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "write_block_count",
+				Help:        "Write Block count of volume",
 			}),
 
 		volumeUpTime: prometheus.NewCounterVec(
 			prometheus.CounterOpts{
-				Namespace: "openebs",
-				Name:      "volume_uptime",
-				Help:      "Time since volume has registered",
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "volume_uptime",
+				Help:        "Time since volume has registered",
 			},
 			[]string{"volName", "iqn", "portal", "castype"},
 		),
 
 		connectionRetryCounter: prometheus.NewCounterVec(
 			prometheus.CounterOpts{
-				Namespace: "openebs",
-				Name:      "connection_retry_total",
-				Help:      "Total no of connection retry requests",
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "connection_retry_total",
+				Help:        "Total no of connection retry requests",
 			},
 			[]string{"err"},
 		),
 
 		connectionErrorCounter: prometheus.NewCounterVec(
 			prometheus.CounterOpts{
-				Namespace: "openebs",
-				Name:      "connection_error_total",
-				Help:      "Total no of connection errors",
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "connection_error_total",
+				Help:        "Total no of connection errors",
 			},
 			[]string{"err"},
 		),
+
+		scrapeTimeoutCounter: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "scrape_timeout_total",
+				Help:        "Total no of timed out requests to the volume controller",
+			}),
+
+		scrapeRetriesCounter: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "scrape_retries_total",
+				Help:        "Total no of retried requests to the volume controller",
+			}),
+
+		scrapeRedirectsCounter: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "scrape_redirects_total",
+				Help:        "Total no of redirects followed by the requests to the volume controller",
+			}),
+
+		parseErrorsCounter: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "parse_errors_total",
+				Help:        "Total no of stats fields which were missing or could not be parsed",
+			}),
+
+		strictDecodeErrors: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "strict_decode_errors_total",
+				Help:        "Total no of responses of the volume controller with unexpected fields, counted only if the strict decoding is enabled",
+			}),
+
+		scrapesCounter: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "scrapes_total",
+				Help:        "Total no of scrapes of the volume stats",
+			}),
+
+		scrapeErrorsCounter: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "scrape_errors_total",
+				Help:        "Total no of scrapes of the volume stats which have failed",
+			}),
+
+		readErrors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "read_errors_total",
+				Help:        "Total no of reads of the volume which have failed",
+			},
+			[]string{},
+		),
+
+		writeErrors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "write_errors_total",
+				Help:        "Total no of writes of the volume which have failed",
+			},
+			[]string{},
+		),
+
+		replicaReadIOPS: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "replica_read_iops",
+				Help:        "Read Input/Outputs on replica",
+			},
+			[]string{"replica"},
+		),
+
+		replicaWriteIOPS: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "replica_write_iops",
+				Help:        "Write Input/Outputs on replica",
+			},
+			[]string{"replica"},
+		),
+
+		replicaStatus: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "replica_status",
+				Help:        "Status of replica (1 if mode is RW, 0 otherwise)",
+			},
+			[]string{"replica", "mode"},
+		),
+
+		replicaRebuilding: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "replica_rebuilding",
+				Help:        "Rebuild status of replica (1 if it is rebuilding, 0 otherwise)",
+			},
+			[]string{"replica"},
+		),
+
+		replicaRebuildProgress: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "replica_rebuild_progress_percent",
+				Help:        "Rebuild progress of replica in percentage",
+			},
+			[]string{"replica"},
+		),
+
+		rebuildBytesRemaining: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "rebuild_bytes_remaining",
+				Help:        "Bytes of the replica remaining to be rebuilt",
+			},
+			[]string{"replica"},
+		),
+
+		replicaRevisionCounter: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "replica_revision_counter",
+				Help:        "Revision counter of replica",
+			},
+			[]string{"replica"},
+		),
+
+		replicaRevisionMaxDiff: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "replica_revision_max_diff",
+				Help:        "Difference between the max and min revision counters of the reachable replicas, nonzero if the replicas have diverged",
+			},
+			[]string{},
+		),
+
+		replicaLastReconnect: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "replica_last_reconnect_timestamp_seconds",
+				Help:        "Unix time at which the replica has last connected with the controller",
+			},
+			[]string{"replica"},
+		),
+
+		replicaAddressChanges: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "replica_address_changes_total",
+				Help:        "Total no of times the replica has connected from a new address",
+			},
+			[]string{"replica_id"},
+		),
+
+		controllerInfo: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "controller_info",
+				Help:        "A metric with a constant '1' value labeled by the version of the volume controller",
+			},
+			[]string{"version"},
+		),
+
+		controllerResponseBytes: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "controller_response_bytes",
+				Help:        "Size of the body of the last response with the stats of the volume controller",
+			}),
+
+		controllerRequestDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "controller_request_duration_seconds",
+				Help:        "Duration of the phases of the requests for the stats of the volume controller, observed only if the request trace is enabled",
+			},
+			[]string{"phase"},
+		),
+
+		readLatency: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "read_latency_seconds",
+				Help:        "Average latency in seconds of the reads on volume since the previous scrape",
+				Buckets:     latencyBuckets,
+			}),
+
+		writeLatency: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "write_latency_seconds",
+				Help:        "Average latency in seconds of the writes on volume since the previous scrape",
+				Buckets:     latencyBuckets,
+			}),
+
+		scsiIOCount: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "scsi_io_count",
+				Help:        "Count of the SCSI commands per opcode",
+			},
+			[]string{"opcode"},
+		),
+
+		statsAnomaly: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				ConstLabels: labels,
+				Name:        "stats_anomaly",
+				Help:        "Whether the stat of the volume has an implausible value, e.g. an average write latency above the threshold (1 for yes, 0 for no)",
+			},
+			[]string{"stat"},
+		),
 	}
 }
 
 // gaugeList returns the list of the registered gauge variables
-func (v *VolumeStatsExporter) gaugesList() []prometheus.Gauge {
+func (m *Metrics) gaugesList() []prometheus.Gauge {
 	return []prometheus.Gauge{
-		v.reads,
-		v.writes,
-		v.totalReadBytes,
-		v.totalWriteBytes,
-		v.totalReadTime,
-		v.totalWriteTime,
-		v.totalReadBlockCount,
-		v.totalWriteBlockCount,
-		v.actualUsed,
-		v.logicalSize,
-		v.sectorSize,
-		v.sizeOfVolume,
+		m.avgReadLatency,
+		m.avgWriteLatency,
+		m.actualUsed,
+		m.logicalSize,
+		m.sectorSize,
+		m.sectorSizeValid,
+		m.sizeOfVolume,
+		m.volumeUp,
+		m.volumeUptimeSeconds,
+		m.revisionCounter,
+		m.replicaCount,
+		m.scrapeDuration,
+		m.volumeUsedPercent,
+		m.statsCacheAge,
+		m.lastScrapeSuccess,
+		m.circuitBreakerOpen,
+		m.controllerResponseBytes,
+	}
+}
+
+// cumulativesList returns the list of the registered cumulative totals,
+// these are gauges or counters depending on the counter mode.
+func (m *Metrics) cumulativesList() []cumulativeMetric {
+	return []cumulativeMetric{
+		m.reads,
+		m.writes,
+		m.totalReadBytes,
+		m.totalWriteBytes,
+		m.totalReadTime,
+		m.totalWriteTime,
+		m.totalReadBlockCount,
+		m.totalWriteBlockCount,
+		m.readBytes,
+		m.writeBytes,
+	}
+}
+
+// gaugeVecsList returns the list of the registered gauge vectors
+func (m *Metrics) gaugeVecsList() []*prometheus.GaugeVec {
+	return []*prometheus.GaugeVec{
+		m.replicaReadIOPS,
+		m.replicaWriteIOPS,
+		m.replicaStatus,
+		m.replicaRebuilding,
+		m.replicaRebuildProgress,
+		m.rebuildBytesRemaining,
+		m.replicaRevisionCounter,
+		m.replicaRevisionMaxDiff,
+		m.replicaLastReconnect,
+		m.controllerInfo,
+		m.scsiIOCount,
+		m.connectedReplicas,
+		m.pendingIO,
+		m.queueDepth,
+		m.activeSessions,
+		m.readsByPattern,
+		m.writesByPattern,
+		m.statsAnomaly,
+	}
+}
+
+// histogramVecsList returns the list of the registered histogram vectors
+func (m *Metrics) histogramVecsList() []*prometheus.HistogramVec {
+	return []*prometheus.HistogramVec{
+		m.controllerRequestDuration,
+	}
+}
+
+// histogramsList returns the list of the registered histograms
+func (m *Metrics) histogramsList() []prometheus.Histogram {
+	return []prometheus.Histogram{
+		m.readLatency,
+		m.writeLatency,
 	}
 }
 
 // counterList returns the list of registered counter variables
-func (v *VolumeStatsExporter) countersList() []prometheus.Collector {
+func (m *Metrics) countersList() []prometheus.Collector {
 	return []prometheus.Collector{
-		v.volumeUpTime,
-		v.connectionErrorCounter,
-		v.connectionRetryCounter,
+		m.volumeUpTime,
+		m.connectionErrorCounter,
+		m.connectionRetryCounter,
+		m.scrapeTimeoutCounter,
+		m.scrapeRetriesCounter,
+		m.scrapeRedirectsCounter,
+		m.parseErrorsCounter,
+		m.strictDecodeErrors,
+		m.scrapesCounter,
+		m.scrapeErrorsCounter,
+		m.readErrors,
+		m.writeErrors,
+		m.replicaAddressChanges,
+	}
+}
+
+// allCollectors returns the list of all the metrics including the
+// disabled ones.
+func (m *Metrics) allCollectors() []prometheus.Collector {
+	var collectors []prometheus.Collector
+	for _, cumulative := range m.cumulativesList() {
+		collectors = append(collectors, cumulative)
+	}
+	for _, gauge := range m.gaugesList() {
+		collectors = append(collectors, gauge)
+	}
+	for _, gaugeVec := range m.gaugeVecsList() {
+		collectors = append(collectors, gaugeVec)
+	}
+	for _, histogramVec := range m.histogramVecsList() {
+		collectors = append(collectors, histogramVec)
+	}
+	for _, histogram := range m.histogramsList() {
+		collectors = append(collectors, histogram)
+	}
+	return append(collectors, m.countersList()...)
+}
+
+// collectorsList returns the list of all the metrics which are not
+// disabled.
+func (m *Metrics) collectorsList() []prometheus.Collector {
+	var enabled []prometheus.Collector
+	for _, c := range m.allCollectors() {
+		if !m.disabled.has(c) {
+			enabled = append(enabled, c)
+		}
+	}
+	return enabled
+}
+
+// filter disables the metrics with the names of denied and, if allowed
+// is not nil, all the metrics which are not in allowed. A metric in both
+// is disabled. A name is either the full name of the metric or the name
+// without the namespace, e.g. openebs_replica_read_iops or
+// replica_read_iops. It returns the names of allowed and denied which
+// don't match any metric. The metrics which were disabled before and are
+// not filtered out anymore are enabled again.
+func (m *Metrics) filter(allowed, denied []string) []string {
+	byName := make(map[string]prometheus.Collector)
+	for _, c := range m.allCollectors() {
+		byName[collectorName(c)] = c
+	}
+	var unknown []string
+	lookup := func(names []string) map[prometheus.Collector]bool {
+		found := make(map[prometheus.Collector]bool)
+		for _, name := range names {
+			c, ok := byName[name]
+			if !ok {
+				c, ok = byName[m.namespace+"_"+name]
+			}
+			if !ok {
+				unknown = append(unknown, name)
+				continue
+			}
+			found[c] = true
+		}
+		return found
+	}
+	disabled := make(map[prometheus.Collector]bool)
+	if allowed != nil {
+		allow := lookup(allowed)
+		for _, c := range byName {
+			if !allow[c] {
+				disabled[c] = true
+			}
+		}
+	}
+	for c := range lookup(denied) {
+		disabled[c] = true
+	}
+	m.disabled.set(disabled)
+	return unknown
+}
+
+// fqNameRegex matches the full name of the metric in the string of its
+// descriptor, prometheus.Desc doesn't export the name.
+var fqNameRegex = regexp.MustCompile(`^Desc{fqName: ("(?:[^"\\]|\\.)*")`)
+
+// collectorName returns the full name of the metric described by c.
+func collectorName(c prometheus.Collector) string {
+	ch := make(chan *prometheus.Desc, 1)
+	go func() {
+		c.Describe(ch)
+		close(ch)
+	}()
+	var name string
+	for desc := range ch {
+		if match := fqNameRegex.FindStringSubmatch(desc.String()); match != nil {
+			name, _ = strconv.Unquote(match[1])
+		}
+	}
+	return name
+}
+
+// describe sends the descriptors of all the metrics to the provided
+// channel, the disabled metrics are described as well so that these can
+// be enabled again once the exporter is registered.
+func (m *Metrics) describe(ch chan<- *prometheus.Desc) {
+	for _, c := range m.allCollectors() {
+		c.Describe(ch)
+	}
+}
+
+// collect sends all the metrics to the provided channel. The metrics set
+// from the cached stats are sent with the time of the collection of the
+// stats, see statsCollectors.
+func (m *Metrics) collect(ch chan<- prometheus.Metric) {
+	t, stamped := m.collectedAt.load()
+	stats := m.statsCollectors()
+	for _, c := range m.collectorsList() {
+		if stamped && stats[c] {
+			collectWithTimestamp(c, t, ch)
+			continue
+		}
+		c.Collect(ch)
 	}
 }
 
@@ -295,12 +1478,20 @@ func (v *VolumeStatsExporter) countersList() []prometheus.Collector {
 
 // Describe describes all the registered stats metrics from the OpenEBS volumes.
 func (v *VolumeStatsExporter) Describe(ch chan<- *prometheus.Desc) {
-	for _, gauge := range v.gaugesList() {
-		gauge.Describe(ch)
+	v.initTargets()
+	v.scrapeSummary.Describe(ch)
+	if len(v.targets) == 0 {
+		v.Metrics.describe(ch)
+		if v.LegacyMetricNames {
+			v.Metrics.describeLegacy(ch)
+		}
+		return
 	}
-
-	for _, counter := range v.countersList() {
-		counter.Describe(ch)
+	for _, t := range v.targets {
+		t.Metrics.describe(ch)
+		if v.LegacyMetricNames {
+			t.Metrics.describeLegacy(ch)
+		}
 	}
 }
 
@@ -317,22 +1508,425 @@ func (v *VolumeStatsExporter) Describe(ch chan<- *prometheus.Desc) {
 
 // Collect collects all the registered stats metrics from the OpenEBS volumes.
 // It tries to reconnect with the volume if there is any error via a goroutine.
+// If multiple volumes are configured, the failure in collecting the metrics
+// of one volume doesn't stop the collection of the others.
 func (v *VolumeStatsExporter) Collect(ch chan<- prometheus.Metric) {
+	v.initTargets()
+	v.collectMu.Lock()
+	defer v.collectMu.Unlock()
+	if len(v.targets) == 0 {
+		v.collect(&v.Jiva, &v.Metrics)
+		// collect the metrics extracted by collect method
+		v.Metrics.collect(ch)
+		if v.LegacyMetricNames {
+			v.Metrics.collectLegacy(ch)
+		}
+		v.collectScrapeSummary(ch)
+		return
+	}
+	v.collectTargets()
+	// metrics are sent once the stats of all the volumes are collected
+	// so that they are always sent in the same order.
+	for _, t := range v.targets {
+		t.Metrics.collect(ch)
+		if v.LegacyMetricNames {
+			t.Metrics.collectLegacy(ch)
+		}
+	}
+	v.collectScrapeSummary(ch)
+}
+
+// collectScrapeSummary sends the summary of the scrape durations unless it
+// is disabled.
+func (v *VolumeStatsExporter) collectScrapeSummary(ch chan<- prometheus.Metric) {
+	if v.scrapeSummaryEnabled() {
+		ch <- v.scrapeSummary
+	}
+}
+
+// maxConcurrency returns the max no of volumes whose stats are collected
+// concurrently.
+func (v *VolumeStatsExporter) maxConcurrency() int {
+	if v.MaxConcurrency <= 0 {
+		return DefaultMaxConcurrency
+	}
+	return v.MaxConcurrency
+}
+
+// collectTargets collects the stats of all the targets using a pool of
+// maxConcurrency workers and returns once all of them are collected.
+func (v *VolumeStatsExporter) collectTargets() {
+	var wg sync.WaitGroup
+	targets := make(chan *target)
+	workers := v.maxConcurrency()
+	if workers > len(v.targets) {
+		workers = len(v.targets)
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range targets {
+				v.collect(&t.Jiva, &t.Metrics)
+			}
+		}()
+	}
+	for _, t := range v.targets {
+		targets <- t
+	}
+	close(targets)
+	wg.Wait()
+}
+
+// LastStats returns the stats of the last successful collection of the
+// volumes by their names, it doesn't make any request to the volume
+// controllers. The name of the volume is taken from its stats if only a
+// single volume is collected.
+func (v *VolumeStatsExporter) LastStats() map[string]v1.VolumeStats {
+	v.initTargets()
+	stats := map[string]v1.VolumeStats{}
+	if len(v.targets) == 0 {
+		if s, ok := v.Metrics.lastStats.load(); ok {
+			name := s.Name
+			if len(name) == 0 {
+				name = s.Iqn
+			}
+			stats[name] = s
+		}
+		return stats
+	}
+	for _, t := range v.targets {
+		if s, ok := t.Metrics.lastStats.load(); ok {
+			stats[t.name] = s
+		}
+	}
+	return stats
+}
+
+// SetNamespace changes the prefix of the names of the metrics of the
+// exporter, it must be called before the exporter is registered. The
+// trailing "_" of namespace is trimmed and DefaultNamespace is used if it
+// is empty, the names of all the metrics are derived from Namespace.
+func (v *VolumeStatsExporter) SetNamespace(namespace string) {
+	v.Namespace = normalizeNamespace(namespace)
+	v.initMetrics()
+}
+
+// SetLatencyBuckets changes the buckets of the latency histograms of the
+// exporter, these are in seconds. It must be called before the exporter
+// is registered, prometheus.DefBuckets are used if it is not called.
+func (v *VolumeStatsExporter) SetLatencyBuckets(buckets []float64) error {
+	if err := ValidateLatencyBuckets(buckets); err != nil {
+		return err
+	}
+	v.latencyBuckets = buckets
+	v.initMetrics()
+	return nil
+}
+
+// SetCounterMode exports the cumulative totals of the volumes, e.g. the
+// reads, the read time and the read bytes, as counters rather than the
+// gauges if enabled is set, so that rate() can be used on them. The
+// counters are increased by the increase of the totals of the controller
+// and so never go down, even if the controller is restarted. These start
+// over at the totals of the controller on a restart of the exporter, which
+// rate() treats as a counter reset, and the names of the metrics stay the
+// same, so a dashboard must switch to rate() along with the mode. It must
+// be called before the exporter is registered.
+func (v *VolumeStatsExporter) SetCounterMode(enabled bool) {
+	v.counterMode = enabled
+	v.initMetrics()
+}
+
+// The names of the labels which identify the pod of the exporter and the
+// persistent volume in kubernetes, see SetConstLabels.
+const (
+	NamespaceLabel = "namespace"
+	PodLabel       = "pod"
+	PVLabel        = "pv"
+)
+
+// SetConstLabels adds the given labels to all the metrics of the exporter,
+// e.g. the namespace and the name of the pod of the exporter. The labels
+// with empty values are omitted. It must be called before the exporter is
+// registered.
+func (v *VolumeStatsExporter) SetConstLabels(labels prometheus.Labels) {
+	v.constLabels = prometheus.Labels{}
+	for name, value := range labels {
+		if len(value) != 0 {
+			v.constLabels[name] = value
+		}
+	}
+	v.initMetrics()
+}
+
+// initMetrics creates the metrics of the exporter again with its
+// namespace, constant labels, latency buckets and counter mode.
+func (v *VolumeStatsExporter) initMetrics() {
+	var labels prometheus.Labels
+	if len(v.constLabels) != 0 {
+		labels = v.constLabels
+	}
+	v.Metrics = *newMetrics(v.CASType, v.Namespace, labels, v.latencyBuckets, v.counterMode)
+	v.Metrics.filter(v.allowedMetrics, v.disabledMetrics)
+}
+
+// targetLabels returns the constant labels of the metrics of the volume,
+// i.e. the constant labels of the exporter and the name of the volume.
+func (v *VolumeStatsExporter) targetLabels(volume string) prometheus.Labels {
+	labels := prometheus.Labels{"volume": volume}
+	for name, value := range v.constLabels {
+		labels[name] = value
+	}
+	return labels
+}
+
+// DisableMetrics disables the metrics with the given names, see
+// Metrics.filter. A warning is logged for the names which don't match
+// any metric. It can be called after the exporter is registered to
+// change the disabled metrics, the scrapes in progress are not affected.
+func (v *VolumeStatsExporter) DisableMetrics(names []string) {
+	v.disabledMu.Lock()
+	defer v.disabledMu.Unlock()
+	v.disabledMetrics = names
+	for _, name := range v.filterMetrics(names) {
+		logger.Warningf("unknown metric %q can't be disabled", name)
+	}
+}
+
+// AllowMetrics disables all the metrics except the ones with the given
+// names, the metrics are filtered like DisableMetrics does and a metric
+// disabled by DisableMetrics is disabled even if it is allowed. All the
+// metrics are allowed again if names is nil.
+func (v *VolumeStatsExporter) AllowMetrics(names []string) {
+	v.disabledMu.Lock()
+	defer v.disabledMu.Unlock()
+	v.allowedMetrics = names
+	for _, name := range v.filterMetrics(names) {
+		logger.Warningf("unknown metric %q can't be allowed", name)
+	}
+}
+
+// filterMetrics disables the metrics of the exporter and of its targets
+// which are not allowed or are disabled, disabledMu must be held. It
+// returns the names which don't match any metric among the given ones.
+func (v *VolumeStatsExporter) filterMetrics(names []string) []string {
+	unknown := make(map[string]bool)
+	for _, name := range v.Metrics.filter(v.allowedMetrics, v.disabledMetrics) {
+		unknown[name] = true
+	}
+	for _, t := range v.targets {
+		t.Metrics.filter(v.allowedMetrics, v.disabledMetrics)
+	}
+	var unknownNames []string
+	for _, name := range names {
+		if unknown[name] {
+			unknownNames = append(unknownNames, name)
+		}
+	}
+	return v.filterScrapeSummary(unknownNames)
+}
+
+// SetContext sets the context of the requests made to the volume
+// controllers, the in-flight requests are aborted once it is done. It
+// must be called before the exporter is registered.
+func (v *VolumeStatsExporter) SetContext(ctx context.Context) {
+	v.ctx = ctx
+}
+
+// context returns the context of the requests made to the volume
+// controllers.
+func (v *VolumeStatsExporter) context() context.Context {
+	if v.ctx == nil {
+		return context.Background()
+	}
+	return v.ctx
+}
+
+// Ready returns true if the stats have been collected successfully at
+// least once.
+func (v *VolumeStatsExporter) Ready() bool {
+	return atomic.LoadInt32(&v.ready) == 1
+}
+
+// MetricNames returns the full names of the metrics of the exporter which
+// are not disabled.
+func (v *VolumeStatsExporter) MetricNames() []string {
+	var names []string
+	for _, c := range v.Metrics.collectorsList() {
+		names = append(names, collectorName(c))
+	}
+	if v.scrapeSummaryEnabled() {
+		names = append(names, v.scrapeSummaryFullName())
+	}
+	return names
+}
+
+// ParseErrors returns the total no of stats fields of the volumes which
+// were missing or could not be parsed, see the parse_errors_total counter.
+func (v *VolumeStatsExporter) ParseErrors() float64 {
+	// the targets are appended by initTargets with disabledMu held.
+	v.disabledMu.Lock()
+	defer v.disabledMu.Unlock()
+	total := counterValue(v.Metrics.parseErrorsCounter)
+	for _, t := range v.targets {
+		total += counterValue(t.Metrics.parseErrorsCounter)
+	}
+	return total
+}
+
+// counterValue returns the current value of the counter.
+func counterValue(counter prometheus.Counter) float64 {
+	var metric dto.Metric
+	if err := counter.Write(&metric); err != nil {
+		return 0
+	}
+	return metric.GetCounter().GetValue()
+}
+
+// collect collects the stats of a volume into the given metrics.
+func (v *VolumeStatsExporter) collect(j *Jiva, m *Metrics) {
 	// no need to catch the error as exporter should work even if
 	// there are failures in collecting the metrics due to connection
 	// issues or anything else.
+	var err error
+	start := time.Now()
+	if !m.breaker.allow(start, v.BreakerThreshold, v.breakerCooldown()) {
+		// the volume is reported as down without waiting for the
+		// controller which has been failing.
+		m.volumeUp.Set(0)
+		m.circuitBreakerOpen.Set(1)
+		m.scrapeDuration.Set(time.Since(start).Seconds())
+		v.observeScrapeDuration(time.Since(start))
+		return
+	}
+	m.scrapesCounter.Inc()
+	switch v.CASType {
+	case "cstor":
+		err = v.Cstor.collector(m)
+	case "jiva":
+		err = j.collector(v.context(), m)
+	case "replica":
+		err = v.Replica.collector(v.context(), m)
+	}
+	if err != nil {
+		m.scrapeErrorsCounter.Inc()
+		v.logError(j, m, err)
+		if m.breaker.failed(time.Now(), v.BreakerThreshold) {
+			logger.WithVolume(v.volume(j)).Warningf("circuit breaker is open after %d failures in a row, the stats of the volume are not collected for %v",
+				v.BreakerThreshold, v.breakerCooldown())
+		}
+	} else {
+		atomic.StoreInt32(&v.ready, 1)
+		if m.errorLog.recovered() {
+			logger.WithVolume(v.volume(j)).Info("collected the stats of the volume after the failures")
+		}
+		if m.breaker.succeeded(v.BreakerThreshold) {
+			logger.WithVolume(v.volume(j)).Info("circuit breaker is closed")
+		}
+		v.checkAnomalies(j, m)
+	}
+	circuitBreakerOpen := 0.0
+	if m.breaker.open(v.BreakerThreshold) {
+		circuitBreakerOpen = 1
+	}
+	m.circuitBreakerOpen.Set(circuitBreakerOpen)
+	// duration is set even if the collection of metrics has failed.
+	duration := time.Since(start)
+	m.scrapeDuration.Set(duration.Seconds())
+	v.observeScrapeDuration(duration)
+}
+
+// logError logs the failure in collecting the stats of a volume unless
+// a failure of the volume has already been logged in the last
+// logErrorInterval.
+func (v *VolumeStatsExporter) logError(j *Jiva, m *Metrics, err error) {
+	log, suppressed := m.errorLog.failed(time.Now(), v.logErrorInterval())
+	if !log {
+		return
+	}
+	entry := logger.WithVolume(v.volume(j)).WithError(err)
+	if suppressed > 0 {
+		entry.Warningf("could not collect the stats of the volume, %d failures were not logged", suppressed)
+		return
+	}
+	entry.Warning("could not collect the stats of the volume")
+}
+
+// logErrorInterval returns the min interval between the logs of the
+// failures in collecting the stats of a volume.
+func (v *VolumeStatsExporter) logErrorInterval() time.Duration {
+	if v.LogErrorInterval <= 0 {
+		return DefaultLogErrorInterval
+	}
+	return v.LogErrorInterval
+}
+
+// The values of the stat label of the stats_anomaly metric.
+const (
+	anomalyReadLatency  = "read_latency"
+	anomalyWriteLatency = "write_latency"
+)
+
+// checkAnomalies flags the stats of the last collection whose values are
+// implausible, the values of the stats are emitted as they are.
+func (v *VolumeStatsExporter) checkAnomalies(j *Jiva, m *Metrics) {
+	stats, ok := m.lastStats.load()
+	if !ok {
+		return
+	}
+	var volStats VolumeStats
 	switch v.CASType {
 	case "cstor":
-		_ = v.Cstor.collector(&v.Metrics)
+		volStats = v.Cstor.parser(stats)
 	case "jiva":
-		_ = v.Jiva.collector(&v.Metrics)
+		volStats = j.parser(stats)
 	}
+	threshold := v.latencyAnomalyThreshold().Seconds()
+	for stat, latency := range map[string]float64{
+		anomalyReadLatency:  volStats.avgReadLatency,
+		anomalyWriteLatency: volStats.avgWriteLatency,
+	} {
+		anomaly := 0.0
+		if latency > threshold {
+			anomaly = 1
+			logger.WithVolume(v.volume(j)).Warningf("average %s of %vs is above %vs, the stats of the volume may be corrupt", stat, latency, threshold)
+		}
+		m.statsAnomaly.WithLabelValues(stat).Set(anomaly)
+	}
+}
 
-	// collect the metrics extracted by collect method
-	for _, gauge := range v.gaugesList() {
-		gauge.Collect(ch)
+// latencyAnomalyThreshold returns the average latency above which the
+// stats of a volume are flagged as an anomaly.
+func (v *VolumeStatsExporter) latencyAnomalyThreshold() time.Duration {
+	if v.LatencyAnomalyThreshold <= 0 {
+		return DefaultLatencyAnomalyThreshold
 	}
-	for _, counter := range v.countersList() {
-		counter.Collect(ch)
+	return v.LatencyAnomalyThreshold
+}
+
+// breakerCooldown returns the time after which a volume whose circuit
+// breaker is open is probed again.
+func (v *VolumeStatsExporter) breakerCooldown() time.Duration {
+	if v.BreakerCooldown <= 0 {
+		return DefaultBreakerCooldown
 	}
+	return v.BreakerCooldown
+}
+
+// volume returns the address of the volume whose stats are collected
+// by j, it is used in the logs.
+func (v *VolumeStatsExporter) volume(j *Jiva) string {
+	switch v.CASType {
+	case "cstor":
+		return SocketPath
+	case "replica":
+		return v.ReplicaURL
+	}
+	return j.VolumeControllerURL
+}
+
+// unixSeconds returns t as the no of seconds since the Unix epoch.
+func unixSeconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / 1e9
 }
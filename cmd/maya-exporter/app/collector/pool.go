@@ -0,0 +1,172 @@
+package collector
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/openebs/maya/cmd/maya-exporter/app/logger"
+	"github.com/openebs/maya/pkg/util"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PoolOperator is the tool used to get the stats of the cstor pools.
+const PoolOperator = "zpool"
+
+// PoolStats keeps the capacity of a cstor pool in bytes.
+type PoolStats struct {
+	Name string
+	Size float64
+	Used float64
+	Free float64
+}
+
+// PoolLister lists the cstor pools along with their stats. It is
+// implemented by ZpoolLister, tests can implement it to inject fake
+// pools.
+type PoolLister interface {
+	ListPools() ([]PoolStats, error)
+}
+
+// ZpoolLister lists the cstor pools using "zpool list".
+type ZpoolLister struct {
+	Runner util.Runner
+}
+
+// ListPools runs "zpool list" and parses the name, size, allocated and
+// free space of the pools from its output.
+func (z ZpoolLister) ListPools() ([]PoolStats, error) {
+	out, err := z.Runner.RunCombinedOutput(PoolOperator, "list", "-Hp", "-o", "name,size,alloc,free")
+	if err != nil {
+		return nil, fmt.Errorf("%s list failed: %v, out: %s", PoolOperator, err, out)
+	}
+	return parsePoolList(string(out))
+}
+
+// parsePoolList parses the output of "zpool list -Hp -o
+// name,size,alloc,free" which has a line per pool with the tab
+// separated fields, for example "cstor-pool1\t10737418240\t1024\t10737417216".
+func parsePoolList(out string) ([]PoolStats, error) {
+	var pools []PoolStats
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("invalid pool stats %q", line)
+		}
+		pool := PoolStats{Name: fields[0]}
+		for i, value := range []*float64{&pool.Size, &pool.Used, &pool.Free} {
+			v, err := strconv.ParseFloat(fields[i+1], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pool stats %q: %v", line, err)
+			}
+			*value = v
+		}
+		pools = append(pools, pool)
+	}
+	return pools, nil
+}
+
+// PoolCollector collects the capacity of the cstor pools listed by the
+// Lister, it implements the prometheus.Collector interface.
+type PoolCollector struct {
+	Lister          PoolLister
+	poolSize        *prometheus.GaugeVec
+	poolUsed        *prometheus.GaugeVec
+	poolFree        *prometheus.GaugeVec
+	poolListErrors  prometheus.Counter
+	poolListSuccess prometheus.Gauge
+}
+
+// NewPoolCollector returns the PoolCollector which collects the stats
+// of the pools listed by the given lister, the names of its metrics are
+// prefixed with the given namespace.
+func NewPoolCollector(namespace string, lister PoolLister) *PoolCollector {
+	namespace = normalizeNamespace(namespace)
+	return &PoolCollector{
+		Lister: lister,
+		poolSize: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "pool_size_bytes",
+				Help:      "Size of the pool in bytes",
+			},
+			[]string{"pool"},
+		),
+		poolUsed: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "pool_used_bytes",
+				Help:      "Used space of the pool in bytes",
+			},
+			[]string{"pool"},
+		),
+		poolFree: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "pool_free_bytes",
+				Help:      "Free space of the pool in bytes",
+			},
+			[]string{"pool"},
+		),
+		poolListErrors: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "pool_list_errors_total",
+				Help:      "Total no of failures in listing the pools",
+			}),
+		poolListSuccess: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "pool_list_success",
+				Help:      "Whether the pools were listed successfully in the last scrape",
+			}),
+	}
+}
+
+// collectors returns all the metrics of the PoolCollector.
+func (p *PoolCollector) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		p.poolSize,
+		p.poolUsed,
+		p.poolFree,
+		p.poolListErrors,
+		p.poolListSuccess,
+	}
+}
+
+// Describe is implementation of Describe method of prometheus.Collector
+// interface.
+func (p *PoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, col := range p.collectors() {
+		col.Describe(ch)
+	}
+}
+
+// Collect is implementation of prometheus's prometheus.Collector
+// interface, the stats of the pools which are not listed anymore are
+// not emitted.
+func (p *PoolCollector) Collect(ch chan<- prometheus.Metric) {
+	p.poolSize.Reset()
+	p.poolUsed.Reset()
+	p.poolFree.Reset()
+	pools, err := p.Lister.ListPools()
+	if err != nil {
+		logger.WithError(err).Error("could not list the cstor pools")
+		p.poolListErrors.Inc()
+		p.poolListSuccess.Set(0)
+	} else {
+		p.poolListSuccess.Set(1)
+	}
+	for _, pool := range pools {
+		p.poolSize.WithLabelValues(pool.Name).Set(pool.Size)
+		p.poolUsed.WithLabelValues(pool.Name).Set(pool.Used)
+		p.poolFree.WithLabelValues(pool.Name).Set(pool.Free)
+	}
+	for _, col := range p.collectors() {
+		col.Collect(ch)
+	}
+}
@@ -0,0 +1,139 @@
+package collector
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+// fakePoolLister returns the given pools or error.
+type fakePoolLister struct {
+	pools []PoolStats
+	err   error
+}
+
+func (f *fakePoolLister) ListPools() ([]PoolStats, error) {
+	return f.pools, f.err
+}
+
+// fakeRunner returns the given output and error on running any command.
+type fakeRunner struct {
+	out []byte
+	err error
+}
+
+func (f fakeRunner) RunCombinedOutput(string, ...string) ([]byte, error) {
+	return f.out, f.err
+}
+
+func (f fakeRunner) RunStdoutPipe(string, ...string) ([]byte, error) {
+	return f.out, f.err
+}
+
+func TestPoolCollector(t *testing.T) {
+	cases := map[string]struct {
+		namespace      string
+		lister         *fakePoolLister
+		match, unmatch []*regexp.Regexp
+	}{
+		"[Success] pools are listed": {
+			lister: &fakePoolLister{
+				pools: []PoolStats{
+					{Name: "cstor-pool1", Size: 10737418240, Used: 1073741824, Free: 9663676416},
+					{Name: "cstor-pool2", Size: 2048, Used: 0, Free: 2048},
+				},
+			},
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_pool_size_bytes{pool="cstor-pool1"} 1.073741824e\+10`),
+				regexp.MustCompile(`openebs_pool_used_bytes{pool="cstor-pool1"} 1.073741824e\+09`),
+				regexp.MustCompile(`openebs_pool_free_bytes{pool="cstor-pool1"} 9.663676416e\+09`),
+				regexp.MustCompile(`openebs_pool_size_bytes{pool="cstor-pool2"} 2048`),
+				regexp.MustCompile(`openebs_pool_list_success 1`),
+				regexp.MustCompile(`openebs_pool_list_errors_total 0`),
+			},
+		},
+		"[Failure] pools can't be listed": {
+			lister: &fakePoolLister{err: errors.New("zpool list failed")},
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_pool_list_success 0`),
+				regexp.MustCompile(`openebs_pool_list_errors_total 1`),
+			},
+			unmatch: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_pool_size_bytes{`),
+			},
+		},
+		"[Success] pools are listed with a custom namespace": {
+			namespace: "maya_cstor_",
+			lister: &fakePoolLister{
+				pools: []PoolStats{{Name: "cstor-pool1", Size: 2048, Used: 0, Free: 2048}},
+			},
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`maya_cstor_pool_size_bytes{pool="cstor-pool1"} 2048`),
+				regexp.MustCompile(`maya_cstor_pool_list_success 1`),
+				regexp.MustCompile(`maya_cstor_pool_list_errors_total 0`),
+			},
+			unmatch: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_`),
+				regexp.MustCompile(`maya_cstor__`),
+			},
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			buf := scrape(t, NewPoolCollector(tt.namespace, tt.lister))
+			for _, re := range tt.match {
+				if !re.Match(buf) {
+					t.Errorf("failed matching: %q", re)
+				}
+			}
+			for _, re := range tt.unmatch {
+				if re.Match(buf) {
+					t.Errorf("unexpected match: %q", re)
+				}
+			}
+		})
+	}
+}
+
+func TestZpoolLister(t *testing.T) {
+	cases := map[string]struct {
+		runner fakeRunner
+		pools  []PoolStats
+		isErr  bool
+	}{
+		"Valid output": {
+			runner: fakeRunner{out: []byte("cstor-pool1\t10737418240\t1024\t10737417216\ncstor-pool2\t2048\t0\t2048\n")},
+			pools: []PoolStats{
+				{Name: "cstor-pool1", Size: 10737418240, Used: 1024, Free: 10737417216},
+				{Name: "cstor-pool2", Size: 2048, Used: 0, Free: 2048},
+			},
+		},
+		"No pools": {
+			runner: fakeRunner{out: []byte("")},
+		},
+		"Invalid no of fields": {
+			runner: fakeRunner{out: []byte("cstor-pool1\t10737418240\n")},
+			isErr:  true,
+		},
+		"Invalid size": {
+			runner: fakeRunner{out: []byte("cstor-pool1\tten\t1024\t10737417216\n")},
+			isErr:  true,
+		},
+		"zpool fails": {
+			runner: fakeRunner{out: []byte("no pools available"), err: errors.New("exit status 1")},
+			isErr:  true,
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			pools, err := ZpoolLister{Runner: tt.runner}.ListPools()
+			if (err != nil) != tt.isErr {
+				t.Fatalf("ListPools() => got error %v, want error %v", err, tt.isErr)
+			}
+			if !reflect.DeepEqual(pools, tt.pools) {
+				t.Fatalf("ListPools() => got %v, want %v", pools, tt.pools)
+			}
+		})
+	}
+}
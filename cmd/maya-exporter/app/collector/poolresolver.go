@@ -0,0 +1,52 @@
+package collector
+
+import (
+	"fmt"
+
+	apis "github.com/openebs/maya/pkg/apis/openebs.io/v1alpha1"
+	clientset "github.com/openebs/maya/pkg/client/generated/clientset/internalclientset"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PoolLabel is the name of the label of the metrics with the storage pool
+// of the volume, see SetConstLabels and ResolveCStorPool.
+const PoolLabel = "pool"
+
+// The labels of the cstor volume replicas and of the cstor pools which map
+// a cstor volume to its storage pool.
+const (
+	persistentVolumeLabel = "openebs.io/persistent-volume"
+	cstorPoolNameLabel    = "cstorpool.openebs.io/name"
+)
+
+// ResolveCStorPool returns the storage pool claim of the pools of the
+// replicas of the given cstor persistent volume using the kubernetes api.
+// The replicas of a volume are on the different cstor pools of a storage
+// pool claim, so it is the pool whose capacity the volume uses. It
+// returns empty if the volume has no replicas or if the pools of the
+// replicas don't belong to the same claim.
+func ResolveCStorPool(client clientset.Interface, pv string) (string, error) {
+	cvrs, err := client.OpenebsV1alpha1().CStorVolumeReplicas(metav1.NamespaceAll).List(metav1.ListOptions{
+		LabelSelector: persistentVolumeLabel + "=" + pv,
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not list the cstor volume replicas of %s: %v", pv, err)
+	}
+	var claim string
+	for _, cvr := range cvrs.Items {
+		name := cvr.Labels[cstorPoolNameLabel]
+		if len(name) == 0 {
+			return "", nil
+		}
+		pool, err := client.OpenebsV1alpha1().CStorPools().Get(name, metav1.GetOptions{})
+		if err != nil {
+			return "", fmt.Errorf("could not get the cstor pool %s of %s: %v", name, pv, err)
+		}
+		poolClaim := pool.Labels[string(apis.StoragePoolClaimCPK)]
+		if len(poolClaim) == 0 || (len(claim) != 0 && poolClaim != claim) {
+			return "", nil
+		}
+		claim = poolClaim
+	}
+	return claim, nil
+}
@@ -0,0 +1,89 @@
+package collector
+
+import (
+	"errors"
+	"testing"
+
+	apis "github.com/openebs/maya/pkg/apis/openebs.io/v1alpha1"
+	openebsfake "github.com/openebs/maya/pkg/client/generated/clientset/internalclientset/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// fakeCVR returns a replica of the given persistent volume on the given
+// cstor pool.
+func fakeCVR(pv, pool string) *apis.CStorVolumeReplica {
+	return &apis.CStorVolumeReplica{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pv + "-" + pool,
+			Namespace: "openebs",
+			Labels:    map[string]string{persistentVolumeLabel: pv, cstorPoolNameLabel: pool},
+		},
+	}
+}
+
+// fakeCSP returns a cstor pool of the given storage pool claim.
+func fakeCSP(name, claim string) *apis.CStorPool {
+	return &apis.CStorPool{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{string(apis.StoragePoolClaimCPK): claim},
+		},
+	}
+}
+
+func TestResolveCStorPool(t *testing.T) {
+	cases := map[string]struct {
+		objects []runtime.Object
+		listErr error
+		want    string
+		isErr   bool
+	}{
+		"[Success] replicas are on the pools of the same claim": {
+			objects: []runtime.Object{
+				fakeCVR("pvc-1", "pool-a"), fakeCVR("pvc-1", "pool-b"),
+				fakeCVR("pvc-2", "pool-c"),
+				fakeCSP("pool-a", "sparse"), fakeCSP("pool-b", "sparse"), fakeCSP("pool-c", "ssd"),
+			},
+			want: "sparse",
+		},
+		"[Success] pool is unknown if the replicas are on the pools of different claims": {
+			objects: []runtime.Object{
+				fakeCVR("pvc-1", "pool-a"), fakeCVR("pvc-1", "pool-c"),
+				fakeCSP("pool-a", "sparse"), fakeCSP("pool-c", "ssd"),
+			},
+		},
+		"[Success] pool is unknown if the volume has no replicas": {
+			objects: []runtime.Object{fakeCVR("pvc-2", "pool-c"), fakeCSP("pool-c", "ssd")},
+		},
+		"[Success] pool is unknown if the pool has no claim": {
+			objects: []runtime.Object{fakeCVR("pvc-1", "pool-a"), fakeCSP("pool-a", "")},
+		},
+		"[Failure] pool of the replica is missing": {
+			objects: []runtime.Object{fakeCVR("pvc-1", "pool-a")},
+			isErr:   true,
+		},
+		"[Failure] replicas can't be listed": {
+			listErr: errors.New("forbidden"),
+			isErr:   true,
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			client := openebsfake.NewSimpleClientset(tt.objects...)
+			if tt.listErr != nil {
+				client.PrependReactor("list", "cstorvolumereplicas", func(k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, tt.listErr
+				})
+			}
+			got, err := ResolveCStorPool(client, "pvc-1")
+			if (err != nil) != tt.isErr {
+				t.Fatalf("ResolveCStorPool() => got error %v, want error %v", err, tt.isErr)
+			}
+			if got != tt.want {
+				t.Fatalf("ResolveCStorPool() => got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
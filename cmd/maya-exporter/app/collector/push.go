@@ -0,0 +1,176 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+)
+
+const (
+	// DefaultPushInterval is the default interval between the pushes of
+	// the metrics to the Pushgateway.
+	DefaultPushInterval = 15 * time.Second
+	// DefaultPushJob is the default job label of the pushed metrics.
+	DefaultPushJob = "maya-exporter"
+)
+
+// Pusher pushes the metrics of a collector to a Prometheus Pushgateway,
+// for the volumes whose lifetime is too short to be scraped. The
+// vendored client_golang doesn't have the push package, so the metrics
+// are pushed using the api of the Pushgateway, i.e. a PUT of the metrics
+// in the text format to /metrics/job/<job>/instance/<instance> which
+// replaces the metrics previously pushed by the same job and instance.
+type Pusher struct {
+	// Job is the job label of the pushed metrics.
+	Job string
+	// Instance returns the instance label of the pushed metrics, it is
+	// called once the metrics are gathered so that it can be derived
+	// from the collected stats. It defaults to the hostname.
+	Instance func() string
+	// Retries is the no of retries of a failed push, the delay between
+	// the retries starts at RetryBackoff and is doubled after each one.
+	Retries      int
+	RetryBackoff time.Duration
+	// Timeout is the timeout of each push.
+	Timeout time.Duration
+	// gatewayURL is the url of the Pushgateway.
+	gatewayURL *url.URL
+	// registry gathers the metrics of the collector along with the
+	// errors counter which are pushed.
+	registry *prometheus.Registry
+	// errors counts the pushes which failed after all the retries.
+	errors prometheus.Counter
+	client *http.Client
+}
+
+// NewPusher returns a Pusher which pushes the metrics of c to the
+// Pushgateway of the given url, the name of its errors counter is
+// prefixed with the namespace, DefaultNamespace is used if it is empty.
+func NewPusher(gatewayURL string, namespace string, c prometheus.Collector) (*Pusher, error) {
+	u, err := url.Parse(gatewayURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("%w: %q must be an http or https url", ErrInvalidURL, gatewayURL)
+	}
+	if err := validateHost(u); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidURL, err)
+	}
+	p := &Pusher{
+		Job:          DefaultPushJob,
+		Instance:     hostname,
+		Retries:      DefaultRetries,
+		RetryBackoff: DefaultRetryBackoff,
+		Timeout:      DefaultTimeout,
+		gatewayURL:   u,
+		registry:     prometheus.NewRegistry(),
+		errors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: normalizeNamespace(namespace),
+			Name:      "push_errors_total",
+			Help:      "Total no of pushes of the metrics to the Pushgateway which failed after all the retries",
+		}),
+		client: &http.Client{},
+	}
+	if err := p.registry.Register(c); err != nil {
+		return nil, err
+	}
+	if err := p.registry.Register(p.errors); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// ErrorsCounter returns the counter of the failed pushes, so that it can
+// be served on /metrics as well.
+func (p *Pusher) ErrorsCounter() prometheus.Counter {
+	return p.errors
+}
+
+// hostname returns the hostname, it is empty if it is unknown.
+func hostname() string {
+	name, _ := os.Hostname()
+	return name
+}
+
+// pushURL returns the url to which the metrics of the instance are
+// pushed.
+func (p *Pusher) pushURL(instance string) string {
+	u := *p.gatewayURL
+	// the labels are escaped as these may have slashes.
+	u.RawPath = strings.TrimSuffix(u.EscapedPath(), "/") + "/metrics/job/" + url.PathEscape(p.Job) + "/instance/" + url.PathEscape(instance)
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/metrics/job/" + p.Job + "/instance/" + instance
+	return u.String()
+}
+
+// Push collects the metrics once and pushes them with the job and the
+// instance labels, the push is retried on failure. The errors counter is
+// incremented if all the attempts fail, it is pushed on the next push.
+func (p *Pusher) Push(ctx context.Context) error {
+	mfs, err := p.registry.Gather()
+	if err != nil {
+		p.errors.Inc()
+		return fmt.Errorf("could not gather the metrics: %w", err)
+	}
+	instance := p.Instance()
+	if len(instance) == 0 {
+		p.errors.Inc()
+		return errors.New("instance label of the pushed metrics is empty")
+	}
+	var body bytes.Buffer
+	encoder := expfmt.NewEncoder(&body, expfmt.FmtText)
+	for _, mf := range mfs {
+		if err := encoder.Encode(mf); err != nil {
+			p.errors.Inc()
+			return err
+		}
+	}
+	backoff := p.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		err = p.push(ctx, p.pushURL(instance), body.Bytes())
+		if err == nil || attempt >= p.Retries || ctx.Err() != nil {
+			break
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+	if err != nil {
+		p.errors.Inc()
+	}
+	return err
+}
+
+// push sends the metrics in the text format to the Pushgateway.
+func (p *Pusher) push(ctx context.Context, pushURL string, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, p.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, pushURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", string(expfmt.FmtText))
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("got status code %d from %s: %s", resp.StatusCode, p.gatewayURL, bytes.TrimSpace(msg))
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestNewPusher(t *testing.T) {
+	cases := map[string]struct {
+		url   string
+		isErr bool
+	}{
+		"Valid url":             {url: "http://pushgateway:9091"},
+		"Valid url with a path": {url: "https://gateway.example.com/prometheus/"},
+		"Unix socket":           {url: "unix:///var/run/pushgateway.sock", isErr: true},
+		"Missing host":          {url: "http://:9091", isErr: true},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewPusher(tt.url, DefaultNamespace, prometheus.NewGauge(prometheus.GaugeOpts{Name: "up", Help: "Up"}))
+			if (err != nil) != tt.isErr {
+				t.Fatalf("NewPusher(%q) => %v, want error %v", tt.url, err, tt.isErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidURL) {
+				t.Fatalf("NewPusher(%q) => %v, want ErrInvalidURL", tt.url, err)
+			}
+		})
+	}
+}
+
+func TestPusherPush(t *testing.T) {
+	var (
+		failures int32
+		path     atomic.Value
+		body     atomic.Value
+	)
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&failures, -1) >= 0 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		if r.Method != http.MethodPut {
+			t.Errorf("got method %s, want PUT", r.Method)
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		path.Store(r.URL.EscapedPath())
+		body.Store(string(b))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer gateway.Close()
+
+	controller := fakeJivaController(validControllerResp)
+	defer controller.Close()
+	control := mustParseURL(t, controller.URL)
+
+	cases := map[string]struct {
+		failures int32
+		retries  int
+		isErr    bool
+		match    []*regexp.Regexp
+	}{
+		"Pushed on the first attempt": {
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_reads 5`),
+				regexp.MustCompile(`openebs_push_errors_total 0`),
+			},
+		},
+		"Pushed after the retries": {
+			failures: 2,
+			retries:  2,
+			match:    []*regexp.Regexp{regexp.MustCompile(`openebs_push_errors_total 0`)},
+		},
+		"Push fails after the retries": {
+			failures: 2,
+			retries:  1,
+			isErr:    true,
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			pusher, err := NewPusher(gateway.URL+"/", DefaultNamespace, NewJivaStatsExporter(control, "jiva"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			pusher.Retries = tt.retries
+			pusher.RetryBackoff = time.Millisecond
+			atomic.StoreInt32(&failures, tt.failures)
+			pusher.Instance = func() string { return "pvc-1/a" }
+			err = pusher.Push(context.Background())
+			if (err != nil) != tt.isErr {
+				t.Fatalf("Push() => %v, want error %v", err, tt.isErr)
+			}
+			if tt.isErr {
+				if got := counterValue(pusher.ErrorsCounter()); got != 1 {
+					t.Fatalf("push_errors_total => %v, want 1", got)
+				}
+				return
+			}
+			if got := path.Load(); got != "/metrics/job/maya-exporter/instance/pvc-1%2Fa" {
+				t.Fatalf("pushed to %v, want /metrics/job/maya-exporter/instance/pvc-1%%2Fa", got)
+			}
+			for _, re := range tt.match {
+				if !re.MatchString(body.Load().(string)) {
+					t.Errorf("failed matching: %q", re)
+				}
+			}
+		})
+	}
+}
+
+func TestPusherPushEmptyInstance(t *testing.T) {
+	pusher, err := NewPusher("http://127.0.0.2:1", DefaultNamespace, prometheus.NewGauge(prometheus.GaugeOpts{Name: "up", Help: "Up"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pusher.Instance = func() string { return "" }
+	if err := pusher.Push(context.Background()); err == nil {
+		t.Fatal("Push() => nil, want error for the empty instance")
+	}
+	if got := counterValue(pusher.ErrorsCounter()); got != 1 {
+		t.Fatalf("push_errors_total => %v, want 1", got)
+	}
+}
+
+func TestPusherNamespace(t *testing.T) {
+	cases := map[string]struct {
+		namespace string
+		match     *regexp.Regexp
+	}{
+		"Namespace has a trailing _": {"maya_jiva_", regexp.MustCompile(`(?m)^maya_jiva_push_errors_total 0$`)},
+		"Namespace is empty":         {"", regexp.MustCompile(`(?m)^openebs_push_errors_total 0$`)},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			pusher, err := NewPusher("http://127.0.0.2:1", tt.namespace, prometheus.NewGauge(prometheus.GaugeOpts{Name: "up", Help: "Up"}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if buf := scrape(t, pusher.ErrorsCounter()); !tt.match.Match(buf) {
+				t.Fatalf("failed matching: %q in %s", tt.match, buf)
+			}
+		})
+	}
+}
+
+func TestPusherPushConcurrentScrape(t *testing.T) {
+	var inflight, maxInflight int32
+	controller := fakeJivaController(validControllerResp)
+	defer controller.Close()
+	handler := controller.Config.Handler
+	controller.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inflight, 1)
+		defer atomic.AddInt32(&inflight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInflight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInflight, max, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		handler.ServeHTTP(w, r)
+	})
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer gateway.Close()
+	col := NewJivaStatsExporter(mustParseURL(t, controller.URL), "jiva")
+	pusher, err := NewPusher(gateway.URL, DefaultNamespace, col)
+	if err != nil {
+		t.Fatalf("NewPusher(%q) : unexpected error %v", gateway.URL, err)
+	}
+	done := make(chan error)
+	go func() {
+		done <- pusher.Push(context.Background())
+	}()
+	scrape(t, col)
+	if err := <-done; err != nil {
+		t.Fatalf("Push() : unexpected error %v", err)
+	}
+	// the push and the scrape share the collector of the volume, so these
+	// don't collect its stats at the same time.
+	if got := atomic.LoadInt32(&maxInflight); got != 1 {
+		t.Fatalf("concurrent push and scrape => %d requests in flight, want 1", got)
+	}
+}
@@ -0,0 +1,50 @@
+package collector
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// registeredMu guards registered.
+	registeredMu sync.Mutex
+	// registered keeps the collectors registered using RegisterCollector
+	// by their names.
+	registered = map[string]prometheus.Collector{}
+)
+
+// Register registers the exporter with the default prometheus registerer.
+// The exporter previously registered for the same CASType is unregistered
+// first, so that it can be called again on reconfiguration without
+// panicking on duplicate registration.
+func Register(exporter *VolumeStatsExporter) error {
+	return RegisterCollector(exporter.CASType, exporter)
+}
+
+// RegisterCollector registers the collector with the default prometheus
+// registerer by the given name, the collector previously registered by
+// the same name is unregistered first.
+func RegisterCollector(name string, c prometheus.Collector) error {
+	registeredMu.Lock()
+	defer registeredMu.Unlock()
+	if old, ok := registered[name]; ok {
+		prometheus.Unregister(old)
+		delete(registered, name)
+	}
+	if err := prometheus.Register(c); err != nil {
+		return err
+	}
+	registered[name] = c
+	return nil
+}
+
+// Reset unregisters all the collectors registered using RegisterCollector.
+func Reset() {
+	registeredMu.Lock()
+	defer registeredMu.Unlock()
+	for name, c := range registered {
+		prometheus.Unregister(c)
+		delete(registered, name)
+	}
+}
@@ -0,0 +1,37 @@
+package collector
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestRegister(t *testing.T) {
+	defer Reset()
+	controllerURL, _ := url.Parse("http://localhost:9501")
+	for i := 0; i < 2; i++ {
+		exporter := NewJivaStatsExporter(controllerURL, "jiva")
+		if err := Register(exporter); err != nil {
+			t.Fatalf("Register() => got error %v on attempt %d, want nil", err, i+1)
+		}
+	}
+	if err := RegisterCollector("build_info", NewBuildInfoCollector(DefaultNamespace)); err != nil {
+		t.Fatalf("RegisterCollector() => got error %v, want nil", err)
+	}
+	if len(registered) != 2 {
+		t.Fatalf("got %d registered collectors, want 2", len(registered))
+	}
+
+	Reset()
+	if len(registered) != 0 {
+		t.Fatalf("got %d registered collectors after Reset(), want 0", len(registered))
+	}
+	// all the collectors must have been unregistered by Reset, so the
+	// same metrics can be registered again directly.
+	exporter := NewJivaStatsExporter(controllerURL, "jiva")
+	if err := prometheus.Register(exporter); err != nil {
+		t.Fatalf("Register() after Reset() => got error %v, want nil", err)
+	}
+	prometheus.Unregister(exporter)
+}
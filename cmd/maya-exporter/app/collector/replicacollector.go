@@ -0,0 +1,100 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/openebs/maya/types/v1"
+)
+
+// Replica exposes the metrics of a jiva replica whose stats are collected
+// from the replica itself rather than via the controller, e.g. to monitor
+// a replica which is not connected with the controller. The metrics are
+// labelled with the host of the replica.
+type Replica struct {
+	// ReplicaURL is the url of the stats api of the replica.
+	ReplicaURL string
+	// ReplicaTimeout is the timeout of the requests to the replica,
+	// DefaultTimeout is used if it is not set.
+	ReplicaTimeout time.Duration
+	client         *http.Client
+}
+
+// NewJivaReplicaStatsExporter returns the exporter which collects the
+// stats of the jiva replica at the given url.
+func NewJivaReplicaStatsExporter(replicaURL *url.URL, casType string) *VolumeStatsExporter {
+	return &VolumeStatsExporter{
+		CASType:   casType,
+		Namespace: DefaultNamespace,
+		Replica: Replica{
+			ReplicaURL: replicaStatsURL(replicaURL),
+		},
+		Metrics: *MetricsInitializer(casType, DefaultNamespace),
+	}
+}
+
+// replicaStatsURL returns the url of the stats api of the replica.
+func replicaStatsURL(replicaURL *url.URL) string {
+	replicaURL.Path = "v1/stats"
+	return replicaURL.String()
+}
+
+// httpClient returns the client used for the requests to the replica.
+func (r *Replica) httpClient() *http.Client {
+	if r.client != nil {
+		return r.client
+	}
+	timeout := r.ReplicaTimeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	r.client = &http.Client{Timeout: timeout}
+	return r.client
+}
+
+// getVolumeStats is used to get the stats of the replica.
+func (r *Replica) getVolumeStats(ctx context.Context, obj *v1.ReplicaStats) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.ReplicaURL, nil)
+	if err != nil {
+		return err
+	}
+	return getJSON(r.httpClient(), req, obj)
+}
+
+// collector sets the metrics of the replica, the volume is reported as
+// down if the stats of the replica can't be retrieved.
+func (r *Replica) collector(ctx context.Context, m *Metrics) error {
+	var obj v1.ReplicaStats
+	if err := r.getVolumeStats(ctx, &obj); err != nil {
+		if isTimeout(err) {
+			m.scrapeTimeoutCounter.Inc()
+		}
+		m.connectionErrorCounter.WithLabelValues(err.Error()).Inc()
+		m.volumeUp.Set(0)
+		return fmt.Errorf("%w: %w", ErrCollectMetrics, err)
+	}
+	name := "unknown"
+	if u, err := url.Parse(r.ReplicaURL); err == nil {
+		name = u.Hostname()
+	}
+	m.replicaReadIOPS.Reset()
+	m.replicaWriteIOPS.Reset()
+	m.replicaRebuilding.Reset()
+	m.replicaRebuildProgress.Reset()
+	m.rebuildBytesRemaining.Reset()
+	m.replicaRevisionCounter.Reset()
+	readIOPS, _ := obj.ReadIOPS.Float64()
+	writeIOPS, _ := obj.WriteIOPS.Float64()
+	m.replicaReadIOPS.WithLabelValues(name).Set(readIOPS)
+	m.replicaWriteIOPS.WithLabelValues(name).Set(writeIOPS)
+	setRebuildStats(m, name, obj)
+	if revision, err := obj.RevisionCounter.Float64(); err == nil {
+		m.replicaRevisionCounter.WithLabelValues(name).Set(revision)
+	}
+	m.volumeUp.Set(1)
+	m.lastScrapeSuccess.Set(unixSeconds(time.Now()))
+	return nil
+}
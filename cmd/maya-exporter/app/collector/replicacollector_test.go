@@ -0,0 +1,84 @@
+package collector
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+)
+
+func TestReplicaCollector(t *testing.T) {
+	cases := map[string]struct {
+		status         int
+		response       string
+		match, unmatch []*regexp.Regexp
+	}{
+		"[Success] stats of the replica": {
+			status:   http.StatusOK,
+			response: `{"ReadIOPS":"7","WriteIOPS":"9","revisioncounter":"100","rebuilding":true,"rebuildProgress":"40"}`,
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_replica_read_iops{replica="127.0.0.1"} 7`),
+				regexp.MustCompile(`openebs_replica_write_iops{replica="127.0.0.1"} 9`),
+				regexp.MustCompile(`openebs_replica_revision_counter{replica="127.0.0.1"} 100`),
+				regexp.MustCompile(`openebs_replica_rebuilding{replica="127.0.0.1"} 1`),
+				regexp.MustCompile(`openebs_volume_up 1`),
+			},
+		},
+		"[Success] rebuild stats are not reported": {
+			status:   http.StatusOK,
+			response: `{"ReadIOPS":"7","WriteIOPS":"9"}`,
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_replica_read_iops{replica="127.0.0.1"} 7`),
+				regexp.MustCompile(`openebs_volume_up 1`),
+			},
+			unmatch: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_replica_rebuilding{`),
+				regexp.MustCompile(`openebs_replica_revision_counter{`),
+			},
+		},
+		"[Failure] replica is not healthy": {
+			status:   http.StatusInternalServerError,
+			response: `{}`,
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_volume_up 0`),
+			},
+			unmatch: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_replica_read_iops{`),
+			},
+		},
+		"[Failure] invalid response of the replica": {
+			status:   http.StatusOK,
+			response: `{"ReadIOPS":`,
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_volume_up 0`),
+			},
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			replica := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/v1/stats" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.response)
+			}))
+			defer replica.Close()
+			replicaURL, _ := url.Parse(replica.URL)
+			buf := scrape(t, NewJivaReplicaStatsExporter(replicaURL, "replica"))
+			for _, re := range tt.match {
+				if !re.Match(buf) {
+					t.Errorf("failed matching: %q", re)
+				}
+			}
+			for _, re := range tt.unmatch {
+				if re.Match(buf) {
+					t.Errorf("unexpected match: %q", re)
+				}
+			}
+		})
+	}
+}
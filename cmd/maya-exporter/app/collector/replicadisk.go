@@ -0,0 +1,201 @@
+package collector
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/openebs/maya/cmd/maya-exporter/app/logger"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// DefaultReplicaDiskMaxFiles is the default max no of files walked in
+	// the data directory of a replica to compute its disk usage.
+	DefaultReplicaDiskMaxFiles = 10000
+	// DefaultReplicaDiskRefreshInterval is the default min interval
+	// between the walks of the data directories of the replicas.
+	DefaultReplicaDiskRefreshInterval = time.Minute
+)
+
+// errMaxFiles stops the walk of a data directory once the max no of
+// files are walked.
+var errMaxFiles = errors.New("max no of files walked")
+
+// ReplicaPath is the data directory of a jiva replica on the host.
+type ReplicaPath struct {
+	Replica string
+	Path    string
+}
+
+// replicaDiskUsage is the disk usage of the data directory of a replica
+// computed by the last walk.
+type replicaDiskUsage struct {
+	bytes     float64
+	truncated bool
+	err       error
+}
+
+// ReplicaDiskCollector collects the space used on the disk by the data
+// directories of the jiva replicas on the host, which differs from the
+// logical size reported by the controller as the data files are sparse.
+// The directories are walked at most once per RefreshInterval and at
+// most MaxFiles files are walked per directory, so that the scrapes
+// don't hammer the filesystem. It implements the prometheus.Collector
+// interface.
+type ReplicaDiskCollector struct {
+	Paths []ReplicaPath
+	// MaxFiles is the max no of files walked per directory, the usage
+	// of the directories with more files is only the usage of the
+	// files walked. DefaultReplicaDiskMaxFiles is used if it is not set.
+	MaxFiles int
+	// RefreshInterval is the min interval between the walks, the usage
+	// of the last walk is emitted in between. DefaultReplicaDiskRefreshInterval
+	// is used if it is not set.
+	RefreshInterval time.Duration
+	// mu guards the usage and the time of the last walk.
+	mu       sync.Mutex
+	lastWalk time.Time
+	usage    map[string]replicaDiskUsage
+
+	diskUsed      *prometheus.GaugeVec
+	walkTruncated *prometheus.GaugeVec
+	walkErrors    *prometheus.CounterVec
+}
+
+// NewReplicaDiskCollector returns the ReplicaDiskCollector which collects
+// the disk usage of the given data directories of the replicas, the names
+// of its metrics are prefixed with the given namespace.
+func NewReplicaDiskCollector(namespace string, paths []ReplicaPath) *ReplicaDiskCollector {
+	namespace = normalizeNamespace(namespace)
+	return &ReplicaDiskCollector{
+		Paths: paths,
+		diskUsed: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "replica_disk_used_bytes",
+				Help:      "Space used on the disk by the data directory of the replica in bytes",
+			},
+			[]string{"replica"},
+		),
+		walkTruncated: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "replica_disk_walk_truncated",
+				Help:      "Whether the walk of the data directory of the replica stopped at the max no of files, i.e. the used space is underestimated (1 for yes, 0 for no)",
+			},
+			[]string{"replica"},
+		),
+		walkErrors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "replica_disk_walk_errors_total",
+				Help:      "Total no of failures in walking the data directory of the replica",
+			},
+			[]string{"replica"},
+		),
+	}
+}
+
+// maxFiles returns the max no of files walked per directory.
+func (r *ReplicaDiskCollector) maxFiles() int {
+	if r.MaxFiles <= 0 {
+		return DefaultReplicaDiskMaxFiles
+	}
+	return r.MaxFiles
+}
+
+// refreshInterval returns the min interval between the walks.
+func (r *ReplicaDiskCollector) refreshInterval() time.Duration {
+	if r.RefreshInterval <= 0 {
+		return DefaultReplicaDiskRefreshInterval
+	}
+	return r.RefreshInterval
+}
+
+// collectors returns all the metrics of the ReplicaDiskCollector.
+func (r *ReplicaDiskCollector) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		r.diskUsed,
+		r.walkTruncated,
+		r.walkErrors,
+	}
+}
+
+// Describe is implementation of Describe method of prometheus.Collector
+// interface.
+func (r *ReplicaDiskCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, col := range r.collectors() {
+		col.Describe(ch)
+	}
+}
+
+// Collect is implementation of prometheus's prometheus.Collector
+// interface, the directories are walked again only if the last walk is
+// older than the refresh interval. The usage of the replicas whose
+// directory can't be walked is not emitted.
+func (r *ReplicaDiskCollector) Collect(ch chan<- prometheus.Metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.usage == nil || time.Since(r.lastWalk) >= r.refreshInterval() {
+		r.walk()
+	}
+	r.diskUsed.Reset()
+	r.walkTruncated.Reset()
+	for replica, usage := range r.usage {
+		if usage.err != nil {
+			continue
+		}
+		truncated := 0.0
+		if usage.truncated {
+			truncated = 1
+		}
+		r.diskUsed.WithLabelValues(replica).Set(usage.bytes)
+		r.walkTruncated.WithLabelValues(replica).Set(truncated)
+	}
+	for _, col := range r.collectors() {
+		col.Collect(ch)
+	}
+}
+
+// walk computes the disk usage of all the data directories, it must be
+// called with mu held.
+func (r *ReplicaDiskCollector) walk() {
+	r.usage = map[string]replicaDiskUsage{}
+	r.lastWalk = time.Now()
+	for _, path := range r.Paths {
+		bytes, truncated, err := diskUsage(path.Path, r.maxFiles())
+		if err != nil {
+			logger.WithError(err).Errorf("could not walk the data directory %s of replica %s", path.Path, path.Replica)
+			r.walkErrors.WithLabelValues(path.Replica).Inc()
+		}
+		r.usage[path.Replica] = replicaDiskUsage{bytes: bytes, truncated: truncated, err: err}
+	}
+}
+
+// diskUsage returns the space used on the disk by the files in the given
+// directory, it walks at most maxFiles files and returns true if it
+// stopped before walking all of them.
+func diskUsage(path string, maxFiles int) (float64, bool, error) {
+	var (
+		used  int64
+		files int
+	)
+	err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if files >= maxFiles {
+			return errMaxFiles
+		}
+		files++
+		used += diskUsedBytes(info)
+		return nil
+	})
+	if err == errMaxFiles {
+		return float64(used), true, nil
+	}
+	return float64(used), false, err
+}
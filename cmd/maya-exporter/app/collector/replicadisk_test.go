@@ -0,0 +1,120 @@
+package collector
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// writeReplicaFiles writes the given no of files of the given size in a
+// new temp dir and returns its path, the dir is removed by the returned
+// func.
+func writeReplicaFiles(t *testing.T, files, size int) (string, func()) {
+	dir, err := ioutil.TempDir("", "replica")
+	if err != nil {
+		t.Fatalf("failed creating temp dir: %v", err)
+	}
+	for i := 0; i < files; i++ {
+		path := filepath.Join(dir, "volume-snap-"+string(rune('a'+i))+".img")
+		if err := ioutil.WriteFile(path, make([]byte, size), 0644); err != nil {
+			t.Fatalf("failed writing %s: %v", path, err)
+		}
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func TestDiskUsage(t *testing.T) {
+	dir, cleanup := writeReplicaFiles(t, 3, 8192)
+	defer cleanup()
+	cases := map[string]struct {
+		maxFiles  int
+		truncated bool
+	}{
+		"All the files are walked": {maxFiles: 10},
+		// the dir itself is one of the files walked.
+		"Walk stops at the max no of files": {maxFiles: 2, truncated: true},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			used, truncated, err := diskUsage(dir, tt.maxFiles)
+			if err != nil {
+				t.Fatalf("diskUsage() => %v", err)
+			}
+			if truncated != tt.truncated {
+				t.Fatalf("diskUsage() => truncated %v, want %v", truncated, tt.truncated)
+			}
+			if used <= 0 {
+				t.Fatalf("diskUsage() => %v bytes used, want more than 0", used)
+			}
+		})
+	}
+
+	if _, _, err := diskUsage(filepath.Join(dir, "missing"), 10); err == nil {
+		t.Fatal("diskUsage() => no error for a missing dir")
+	}
+}
+
+func TestReplicaDiskCollector(t *testing.T) {
+	dir, cleanup := writeReplicaFiles(t, 2, 4096)
+	defer cleanup()
+	col := NewReplicaDiskCollector(DefaultNamespace, []ReplicaPath{
+		{Replica: "rep1", Path: dir},
+		{Replica: "rep2", Path: filepath.Join(dir, "missing")},
+	})
+	col.RefreshInterval = time.Hour
+	buf := scrape(t, col)
+	for _, re := range []*regexp.Regexp{
+		regexp.MustCompile(`openebs_replica_disk_used_bytes{replica="rep1"} [1-9]`),
+		regexp.MustCompile(`openebs_replica_disk_walk_truncated{replica="rep1"} 0`),
+		regexp.MustCompile(`openebs_replica_disk_walk_errors_total{replica="rep2"} 1`),
+	} {
+		if !re.Match(buf) {
+			t.Errorf("failed matching: %q", re)
+		}
+	}
+	if regexp.MustCompile(`openebs_replica_disk_used_bytes{replica="rep2"}`).Match(buf) {
+		t.Errorf("unexpected usage of the replica which can't be walked")
+	}
+
+	// the usage of the last walk is emitted until the refresh interval.
+	if err := os.Mkdir(filepath.Join(dir, "missing"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	buf = scrape(t, col)
+	for _, re := range []*regexp.Regexp{
+		regexp.MustCompile(`openebs_replica_disk_walk_errors_total{replica="rep2"} 1`),
+	} {
+		if !re.Match(buf) {
+			t.Errorf("failed matching: %q", re)
+		}
+	}
+	if regexp.MustCompile(`openebs_replica_disk_used_bytes{replica="rep2"}`).Match(buf) {
+		t.Errorf("replica is walked again before the refresh interval")
+	}
+
+	col.RefreshInterval = time.Nanosecond
+	buf = scrape(t, col)
+	if !regexp.MustCompile(`openebs_replica_disk_used_bytes{replica="rep2"} `).Match(buf) {
+		t.Errorf("replica is not walked again after the refresh interval")
+	}
+}
+
+func TestReplicaDiskCollectorNamespace(t *testing.T) {
+	dir, cleanup := writeReplicaFiles(t, 1, 4096)
+	defer cleanup()
+	buf := scrape(t, NewReplicaDiskCollector("maya_jiva_", []ReplicaPath{{Replica: "rep1", Path: dir}}))
+	for _, re := range []*regexp.Regexp{
+		regexp.MustCompile(`maya_jiva_replica_disk_used_bytes{replica="rep1"} [1-9]`),
+		regexp.MustCompile(`maya_jiva_replica_disk_walk_truncated{replica="rep1"} 0`),
+	} {
+		if !re.Match(buf) {
+			t.Errorf("failed matching: %q", re)
+		}
+	}
+	if regexp.MustCompile(`openebs_|maya_jiva__`).Match(buf) {
+		t.Errorf("unexpected namespace of the metrics: %s", buf)
+	}
+}
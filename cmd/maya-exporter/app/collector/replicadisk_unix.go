@@ -0,0 +1,20 @@
+//go:build !windows
+// +build !windows
+
+package collector
+
+import (
+	"os"
+	"syscall"
+)
+
+// diskUsedBytes returns the space used on the disk by the file, which is
+// less than its size if the file is sparse.
+func diskUsedBytes(info os.FileInfo) int64 {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		// the blocks are of 512 bytes irrespective of the block size
+		// of the filesystem.
+		return int64(stat.Blocks) * 512
+	}
+	return info.Size()
+}
@@ -0,0 +1,9 @@
+package collector
+
+import "os"
+
+// diskUsedBytes returns the size of the file, the space used on the disk
+// by the sparse files is not known on windows.
+func diskUsedBytes(info os.FileInfo) int64 {
+	return info.Size()
+}
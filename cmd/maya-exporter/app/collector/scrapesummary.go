@@ -0,0 +1,88 @@
+package collector
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// scrapeSummaryName is the name of the summary of the scrape durations
+// of the volumes without the namespace.
+const scrapeSummaryName = "volume_scrape_duration_seconds"
+
+// scrapeSummaryObjectives are the quantiles of the summary of the scrape
+// durations along with their allowed errors.
+var scrapeSummaryObjectives = map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001}
+
+// newScrapeSummary returns the summary of the durations of the scrapes of
+// the volumes of the given cas type. It is shared by all the volumes of
+// the exporter, unlike the collector_scrape_duration_seconds gauge of
+// each volume, so that the quantiles are computed across the volumes.
+func newScrapeSummary(namespace, casType string, labels prometheus.Labels) prometheus.Summary {
+	constLabels := prometheus.Labels{"cas_type": casType}
+	for name, value := range labels {
+		constLabels[name] = value
+	}
+	return prometheus.NewSummary(
+		prometheus.SummaryOpts{
+			Namespace:   namespace,
+			ConstLabels: constLabels,
+			Name:        scrapeSummaryName,
+			Help:        "Duration of the scrapes of the stats of each volume in seconds",
+			Objectives:  scrapeSummaryObjectives,
+		})
+}
+
+// initScrapeSummary creates the summary of the scrape durations, it is
+// called only once by initTargets.
+func (v *VolumeStatsExporter) initScrapeSummary() {
+	v.scrapeSummary = newScrapeSummary(normalizeNamespace(v.Namespace), v.CASType, v.constLabels)
+}
+
+// scrapeSummaryFullName returns the name of the summary of the scrape
+// durations with the namespace of the exporter.
+func (v *VolumeStatsExporter) scrapeSummaryFullName() string {
+	return normalizeNamespace(v.Namespace) + "_" + scrapeSummaryName
+}
+
+// observeScrapeDuration adds the duration of the scrape of a volume to the
+// summary, it can be called concurrently for the volumes.
+func (v *VolumeStatsExporter) observeScrapeDuration(d time.Duration) {
+	if v.scrapeSummary != nil {
+		v.scrapeSummary.Observe(d.Seconds())
+	}
+}
+
+// scrapeSummaryEnabled returns true if the summary of the scrape durations
+// is not disabled, see filterScrapeSummary.
+func (v *VolumeStatsExporter) scrapeSummaryEnabled() bool {
+	v.disabledMu.Lock()
+	defer v.disabledMu.Unlock()
+	return !v.scrapeSummaryDisabled
+}
+
+// filterScrapeSummary disables the summary of the scrape durations like
+// Metrics.filter does for the metrics of the volumes, disabledMu must be
+// held. It returns the names among the given ones which are not the name
+// of the summary.
+func (v *VolumeStatsExporter) filterScrapeSummary(names []string) []string {
+	isSummary := func(name string) bool {
+		return name == scrapeSummaryName || name == v.scrapeSummaryFullName()
+	}
+	contains := func(names []string) bool {
+		for _, name := range names {
+			if isSummary(name) {
+				return true
+			}
+		}
+		return false
+	}
+	v.scrapeSummaryDisabled = (v.allowedMetrics != nil && !contains(v.allowedMetrics)) || contains(v.disabledMetrics)
+	var others []string
+	for _, name := range names {
+		if !isSummary(name) {
+			others = append(others, name)
+		}
+	}
+	return others
+}
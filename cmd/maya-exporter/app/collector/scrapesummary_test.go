@@ -0,0 +1,132 @@
+package collector
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestScrapeSummary(t *testing.T) {
+	controller := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/stats" {
+			fmt.Fprintln(w, `{"data":[]}`)
+			return
+		}
+		fmt.Fprintln(w, validControllerResp)
+	}))
+	defer controller.Close()
+
+	cases := map[string]struct {
+		disabled       []string
+		allowed        []string
+		match, unmatch []*regexp.Regexp
+	}{
+		"[Success] the scrapes of all the volumes are observed": {
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`# TYPE openebs_volume_scrape_duration_seconds summary`),
+				regexp.MustCompile(`openebs_volume_scrape_duration_seconds{cas_type="jiva",quantile="0.5"} `),
+				regexp.MustCompile(`openebs_volume_scrape_duration_seconds{cas_type="jiva",quantile="0.99"} `),
+				regexp.MustCompile(`openebs_volume_scrape_duration_seconds_count{cas_type="jiva"} 2`),
+				// the gauge of each volume is kept.
+				regexp.MustCompile(`openebs_collector_scrape_duration_seconds{volume="vol1"} `),
+			},
+		},
+		"[Success] summary is disabled": {
+			disabled: []string{"volume_scrape_duration_seconds"},
+			unmatch: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_volume_scrape_duration_seconds`),
+			},
+		},
+		"[Success] summary is not allowed": {
+			allowed: []string{"openebs_volume_up"},
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_volume_up{volume="vol1"} 1`),
+			},
+			unmatch: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_volume_scrape_duration_seconds`),
+			},
+		},
+		"[Success] summary is allowed": {
+			allowed: []string{"openebs_volume_scrape_duration_seconds"},
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`openebs_volume_scrape_duration_seconds_count{cas_type="jiva"} 2`),
+			},
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			exporter, err := NewJivaVolumesStatsExporter([]VolumeTarget{
+				{Name: "vol1", URL: controller.URL},
+				{Name: "vol2", URL: controller.URL},
+			}, "jiva")
+			if err != nil {
+				t.Fatalf("NewJivaVolumesStatsExporter() : unexpected error %v", err)
+			}
+			if tt.allowed != nil {
+				exporter.AllowMetrics(tt.allowed)
+			}
+			exporter.DisableMetrics(tt.disabled)
+			buf := scrape(t, exporter)
+			for _, re := range tt.match {
+				if !re.Match(buf) {
+					t.Errorf("failed matching: %q", re)
+				}
+			}
+			for _, re := range tt.unmatch {
+				if re.Match(buf) {
+					t.Errorf("unexpected match: %q", re)
+				}
+			}
+		})
+	}
+}
+
+func TestScrapeSummaryNamespace(t *testing.T) {
+	controller := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/stats" {
+			fmt.Fprintln(w, `{"data":[]}`)
+			return
+		}
+		fmt.Fprintln(w, validControllerResp)
+	}))
+	defer controller.Close()
+
+	cases := map[string]struct {
+		namespace, summary string
+	}{
+		"Namespace has a trailing _": {"maya_jiva_", "maya_jiva_volume_scrape_duration_seconds"},
+		"Namespace is empty":         {"", "openebs_volume_scrape_duration_seconds"},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			newExporter := func() *VolumeStatsExporter {
+				exporter, err := NewJivaVolumesStatsExporter([]VolumeTarget{{Name: "vol1", URL: controller.URL}}, "jiva")
+				if err != nil {
+					t.Fatalf("NewJivaVolumesStatsExporter() : unexpected error %v", err)
+				}
+				exporter.SetNamespace(tt.namespace)
+				return exporter
+			}
+			exporter := newExporter()
+			count := regexp.MustCompile(regexp.QuoteMeta(tt.summary + `_count{cas_type="jiva"} 1`))
+			if buf := scrape(t, exporter); !count.Match(buf) {
+				t.Errorf("failed matching: %q", count)
+			}
+			found := false
+			for _, metric := range exporter.MetricNames() {
+				found = found || metric == tt.summary
+			}
+			if !found {
+				t.Errorf("MetricNames() => got %v, want %s", exporter.MetricNames(), tt.summary)
+			}
+
+			exporter = newExporter()
+			exporter.DisableMetrics([]string{tt.summary})
+			if buf := scrape(t, exporter); regexp.MustCompile(tt.summary).Match(buf) {
+				t.Errorf("unexpected match of the disabled %s", tt.summary)
+			}
+		})
+	}
+}
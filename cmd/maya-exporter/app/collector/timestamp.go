@@ -0,0 +1,101 @@
+package collector
+
+import (
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// collectedAt keeps the time at which the stats served from the cache
+// were collected, it is safe for concurrent use.
+type collectedAt struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+// store keeps the given time, the zero time clears it.
+func (c *collectedAt) store(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = t
+}
+
+// load returns the stored time and false if no time is stored.
+func (c *collectedAt) load() (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t, !c.t.IsZero()
+}
+
+// statsCollectors returns the metrics which are set from the stats of the
+// volume controller. These carry the time at which the stats were
+// collected if the stats are cached, the rest of the metrics describe the
+// scrape itself and carry the time of the scrape.
+func (m *Metrics) statsCollectors() map[prometheus.Collector]bool {
+	stats := map[prometheus.Collector]bool{
+		m.avgReadLatency:      true,
+		m.avgWriteLatency:     true,
+		m.actualUsed:          true,
+		m.logicalSize:         true,
+		m.sectorSize:          true,
+		m.sectorSizeValid:     true,
+		m.sizeOfVolume:        true,
+		m.volumeUptimeSeconds: true,
+		m.revisionCounter:     true,
+		m.replicaCount:        true,
+		m.volumeUsedPercent:   true,
+		m.controllerInfo:      true,
+		m.scsiIOCount:         true,
+		m.pendingIO:           true,
+		m.queueDepth:          true,
+		m.activeSessions:      true,
+		m.readsByPattern:      true,
+		m.writesByPattern:     true,
+		m.volumeUpTime:        true,
+		m.readErrors:          true,
+		m.writeErrors:         true,
+	}
+	for _, cumulative := range m.cumulativesList() {
+		stats[cumulative] = true
+	}
+	return stats
+}
+
+// timestampedMetric is a metric with an explicit timestamp. It is the
+// equivalent of prometheus.NewMetricWithTimestamp which the vendored
+// client_golang predates.
+type timestampedMetric struct {
+	prometheus.Metric
+	t time.Time
+}
+
+// Write implements prometheus.Metric.
+func (m timestampedMetric) Write(pb *dto.Metric) error {
+	if err := m.Metric.Write(pb); err != nil {
+		return err
+	}
+	pb.TimestampMs = proto.Int64(m.t.UnixNano() / int64(time.Millisecond))
+	return nil
+}
+
+// newMetricWithTimestamp returns the metric which is exposed with the
+// given timestamp rather than the time of the scrape.
+func newMetricWithTimestamp(t time.Time, m prometheus.Metric) prometheus.Metric {
+	return timestampedMetric{Metric: m, t: t}
+}
+
+// collectWithTimestamp sends the metrics of c to ch with the given
+// timestamp.
+func collectWithTimestamp(c prometheus.Collector, t time.Time, ch chan<- prometheus.Metric) {
+	metrics := make(chan prometheus.Metric)
+	go func() {
+		c.Collect(metrics)
+		close(metrics)
+	}()
+	for metric := range metrics {
+		ch <- newMetricWithTimestamp(t, metric)
+	}
+}
@@ -0,0 +1,41 @@
+package collector
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+)
+
+// NewTLSConfig returns the tls configuration which verifies the
+// certificate of the volume controller using the CA certificate(s)
+// from caFile. System CAs are used if caFile is empty. The client
+// certificate and key from certFile and keyFile are presented to the
+// controllers which require mutual TLS, these must be set together.
+func NewTLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	config := &tls.Config{}
+	if (len(certFile) == 0) != (len(keyFile) == 0) {
+		return nil, errors.New("client certificate and key must be set together")
+	}
+	if len(certFile) != 0 {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not load the client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if len(caFile) == 0 {
+		return config, nil
+	}
+	caCert, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caCert) {
+		return nil, errors.New("no valid CA certificate found in " + caFile)
+	}
+	config.RootCAs = pool
+	return config, nil
+}
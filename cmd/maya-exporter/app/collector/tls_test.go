@@ -0,0 +1,294 @@
+package collector
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCA writes the certificate in PEM format into a file in dir and
+// returns its path.
+func writeCA(t *testing.T, dir string, cert []byte) string {
+	caFile := filepath.Join(dir, "ca.crt")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert})
+	if err := ioutil.WriteFile(caFile, data, 0644); err != nil {
+		t.Fatalf("failed writing CA file: %v", err)
+	}
+	return caFile
+}
+
+// newSelfSignedCert returns a self signed certificate in DER format
+// which is not related to the certificate of the test servers.
+func newSelfSignedCert(t *testing.T) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "unknown"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+// writeClientCert writes a self signed client certificate and its key in
+// PEM format into files in dir, it returns their paths and the parsed
+// certificate which the servers trust to verify the client.
+func writeClientCert(t *testing.T, dir string) (string, string, *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(2),
+		Subject:               pkix.Name{CommonName: "maya-exporter"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile := filepath.Join(dir, "client.crt")
+	keyFile := filepath.Join(dir, "client.key")
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		t.Fatalf("failed writing client certificate: %v", err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatalf("failed writing client key: %v", err)
+	}
+	return certFile, keyFile, cert
+}
+
+func TestJivaGetVolumeStatsTLS(t *testing.T) {
+	controller := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, validControllerResp)
+	}))
+	defer controller.Close()
+
+	dir, err := ioutil.TempDir("", "maya-exporter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	knownDir := filepath.Join(dir, "known")
+	unknownDir := filepath.Join(dir, "unknown")
+	os.Mkdir(knownDir, 0755)
+	os.Mkdir(unknownDir, 0755)
+
+	cases := map[string]struct {
+		caFile string
+		err    bool
+	}{
+		"[Success] controller certificate is signed by the provided CA": {
+			caFile: writeCA(t, knownDir, controller.Certificate().Raw),
+			err:    false,
+		},
+		"[Failure] controller certificate is signed by an unknown CA": {
+			caFile: writeCA(t, unknownDir, newSelfSignedCert(t)),
+			err:    true,
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			config, err := NewTLSConfig(tt.caFile, "", "")
+			if err != nil {
+				t.Fatalf("NewTLSConfig(%v) : unexpected error %v", tt.caFile, err)
+			}
+			control, _ := url.Parse(controller.URL)
+			exporter := NewJivaStatsExporter(control, "jiva")
+			exporter.TLSConfig = config
+			exporter.Retries = 0
+
+			res, err := exporter.Jiva.getVolumeStats(context.Background())
+			if (err != nil) != tt.err {
+				t.Fatalf("getVolumeStats() : unexpected error %v", err)
+			}
+			if err == nil && res.stats.Name != "vol1" {
+				t.Fatalf("getVolumeStats() : expected volume vol1, got %v", res.stats.Name)
+			}
+		})
+	}
+}
+
+func TestJivaGetVolumeStatsMutualTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "maya-exporter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile, clientCert := writeClientCert(t, dir)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+
+	var commonName string
+	controller := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		commonName = r.TLS.PeerCertificates[0].Subject.CommonName
+		fmt.Fprintln(w, validControllerResp)
+	}))
+	controller.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+	}
+	controller.StartTLS()
+	defer controller.Close()
+	caFile := writeCA(t, dir, controller.Certificate().Raw)
+
+	cases := map[string]struct {
+		certFile, keyFile string
+		err               bool
+	}{
+		"[Success] client certificate is presented": {
+			certFile: certFile,
+			keyFile:  keyFile,
+		},
+		"[Failure] client certificate is not presented": {
+			err: true,
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			commonName = ""
+			config, err := NewTLSConfig(caFile, tt.certFile, tt.keyFile)
+			if err != nil {
+				t.Fatalf("NewTLSConfig() : unexpected error %v", err)
+			}
+			control, _ := url.Parse(controller.URL)
+			exporter := NewJivaStatsExporter(control, "jiva")
+			exporter.TLSConfig = config
+			exporter.Retries = 0
+
+			_, err = exporter.Jiva.getVolumeStats(context.Background())
+			if (err != nil) != tt.err {
+				t.Fatalf("getVolumeStats() : unexpected error %v", err)
+			}
+			if err == nil && commonName != "maya-exporter" {
+				t.Fatalf("getVolumeStats() : expected client certificate maya-exporter, got %q", commonName)
+			}
+		})
+	}
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "maya-exporter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	invalid := filepath.Join(dir, "invalid.crt")
+	ioutil.WriteFile(invalid, []byte("invalid"), 0644)
+	certFile, keyFile, _ := writeClientCert(t, dir)
+
+	cases := map[string]struct {
+		caFile, certFile, keyFile string
+		certificates              int
+		err                       bool
+	}{
+		"CA file is not set":                 {caFile: "", err: false},
+		"CA file doesn't exist":              {caFile: filepath.Join(dir, "missing.crt"), err: true},
+		"CA file has invalid PEM":            {caFile: invalid, err: true},
+		"Client certificate and key":         {certFile: certFile, keyFile: keyFile, certificates: 1},
+		"Client certificate without key":     {certFile: certFile, err: true},
+		"Client key without certificate":     {keyFile: keyFile, err: true},
+		"Client certificate has invalid PEM": {certFile: invalid, keyFile: keyFile, err: true},
+		"Client key doesn't match":           {certFile: certFile, keyFile: certFile, err: true},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			config, err := NewTLSConfig(tt.caFile, tt.certFile, tt.keyFile)
+			if (err != nil) != tt.err {
+				t.Fatalf("NewTLSConfig(%v, %v, %v) : unexpected error %v", tt.caFile, tt.certFile, tt.keyFile, err)
+			}
+			if err == nil && len(config.Certificates) != tt.certificates {
+				t.Fatalf("NewTLSConfig(%v, %v, %v) : expected %d certificates, got %d", tt.caFile, tt.certFile, tt.keyFile, tt.certificates, len(config.Certificates))
+			}
+		})
+	}
+}
+
+func TestJivaGetVolumeStatsHTTP2(t *testing.T) {
+	var proto string
+	controller := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proto = r.Proto
+		fmt.Fprintln(w, validControllerResp)
+	}))
+	controller.EnableHTTP2 = true
+	controller.StartTLS()
+	defer controller.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(controller.Certificate())
+
+	cases := map[string]struct {
+		forceHTTP2, disableHTTP2 bool
+		proto                    string
+	}{
+		"[Success] HTTP/1.1 is used by default": {
+			proto: "HTTP/1.1",
+		},
+		"[Success] HTTP/2 is negotiated if it is forced": {
+			forceHTTP2: true,
+			proto:      "HTTP/2.0",
+		},
+		"[Success] HTTP/2 is not negotiated if it is disabled": {
+			forceHTTP2:   true,
+			disableHTTP2: true,
+			proto:        "HTTP/1.1",
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			config := &tls.Config{RootCAs: pool}
+			control, _ := url.Parse(controller.URL)
+			exporter := NewJivaStatsExporter(control, "jiva")
+			exporter.TLSConfig = config
+			exporter.ForceHTTP2 = tt.forceHTTP2
+			exporter.DisableHTTP2 = tt.disableHTTP2
+			exporter.Retries = 0
+
+			if _, err := exporter.Jiva.getVolumeStats(context.Background()); err != nil {
+				t.Fatalf("getVolumeStats() : unexpected error %v", err)
+			}
+			if proto != tt.proto {
+				t.Fatalf("getVolumeStats() : expected protocol %s, got %s", tt.proto, proto)
+			}
+			if len(config.NextProtos) != 0 {
+				t.Fatalf("getVolumeStats() : expected the tls config to be unchanged, got protocols %v", config.NextProtos)
+			}
+		})
+	}
+}
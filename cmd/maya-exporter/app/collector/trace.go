@@ -0,0 +1,79 @@
+package collector
+
+import (
+	"context"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// The phases of a request to the jiva controller, these are the values of
+// the phase label of controller_request_duration_seconds.
+const (
+	phaseDNS     = "dns"
+	phaseConnect = "connect"
+	phaseTTFB    = "ttfb"
+	phaseTotal   = "total"
+)
+
+// requestTrace records the time taken by the phases of a request to the
+// jiva controller using httptrace. The callbacks of httptrace may be
+// called concurrently, so it is safe for concurrent use.
+type requestTrace struct {
+	mu                        sync.Mutex
+	start, done               time.Time
+	dnsStart, dnsDone         time.Time
+	connectStart, connectDone time.Time
+	firstByte                 time.Time
+}
+
+// newRequestTrace returns a trace started now.
+func newRequestTrace() *requestTrace {
+	return &requestTrace{start: time.Now()}
+}
+
+// withClientTrace returns ctx with the httptrace hooks which record the
+// phases of the requests made with it. The retries of a request are
+// recorded in the same trace, the first time of a phase is kept.
+func (t *requestTrace) withClientTrace(ctx context.Context) context.Context {
+	record := func(at *time.Time) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if at.IsZero() {
+			*at = time.Now()
+		}
+	}
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { record(&t.dnsStart) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { record(&t.dnsDone) },
+		ConnectStart:         func(_, _ string) { record(&t.connectStart) },
+		ConnectDone:          func(_, _ string, _ error) { record(&t.connectDone) },
+		GotFirstResponseByte: func() { record(&t.firstByte) },
+	})
+}
+
+// finish records the end of the request, i.e. once its body is read.
+func (t *requestTrace) finish() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.done = time.Now()
+}
+
+// phases returns the durations of the phases of the request which have
+// completed, e.g. dns and connect are missing if an idle connection was
+// reused.
+func (t *requestTrace) phases() map[string]time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	phases := map[string]time.Duration{}
+	add := func(phase string, start, end time.Time) {
+		if !start.IsZero() && !end.IsZero() {
+			phases[phase] = end.Sub(start)
+		}
+	}
+	add(phaseDNS, t.dnsStart, t.dnsDone)
+	add(phaseConnect, t.connectStart, t.connectDone)
+	add(phaseTTFB, t.start, t.firstByte)
+	add(phaseTotal, t.start, t.done)
+	return phases
+}
@@ -0,0 +1,81 @@
+package collector
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// ParseControllerURL parses and normalizes the address of a volume
+// controller, see normalizeURL.
+func ParseControllerURL(address string) (*url.URL, error) {
+	return normalizeURL(address)
+}
+
+// normalizeURL parses the address of a volume controller into a url. The
+// scheme defaults to http if it is missing and the trailing slashes of
+// the path are removed, e.g. "localhost:9501" and "http://localhost:9501/"
+// are both normalized to "http://localhost:9501". IPv6 hosts without
+// brackets are bracketed, e.g. "fd00::1" is normalized to
+// "http://[fd00::1]", an IPv6 host with a port must be bracketed. It
+// returns error if the scheme is not supported or the host is not valid,
+// see validateHost.
+func normalizeURL(address string) (*url.URL, error) {
+	address = strings.TrimSpace(address)
+	if len(address) == 0 {
+		return nil, fmt.Errorf("%w: empty url", ErrInvalidURL)
+	}
+	if !strings.Contains(address, "://") {
+		address = "http://" + address
+	}
+	address = bracketIPv6(address)
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidURL, err)
+	}
+	switch u.Scheme {
+	case "http", "https":
+	case unixScheme:
+		if len(u.Path) == 0 {
+			return nil, fmt.Errorf("%w: missing socket path in %q", ErrInvalidURL, address)
+		}
+		return u, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported scheme %q in %q", ErrInvalidURL, u.Scheme, address)
+	}
+	if err := validateHost(u); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidURL, err)
+	}
+	u.Path = strings.TrimRight(u.Path, "/")
+	return u, nil
+}
+
+// validateHost returns ErrInvalidControllerHost if the host of the url is
+// empty or an unspecified address, i.e. 0.0.0.0 or ::, which is the
+// address the controller listens on rather than the one to reach it at.
+func validateHost(u *url.URL) error {
+	host := u.Hostname()
+	if len(host) == 0 {
+		return fmt.Errorf("%w: missing host in %q", ErrInvalidControllerHost, u.String())
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		return fmt.Errorf("%w: unspecified address %q in %q", ErrInvalidControllerHost, host, u.String())
+	}
+	return nil
+}
+
+// bracketIPv6 encloses the host of the address in brackets if it is an
+// IPv6 address without brackets, e.g. "http://fd00::1/v1" is returned as
+// "http://[fd00::1]/v1". The address must have a scheme.
+func bracketIPv6(address string) string {
+	i := strings.Index(address, "://") + len("://")
+	host := address[i:]
+	if j := strings.Index(host, "/"); j >= 0 {
+		host = host[:j]
+	}
+	if !strings.Contains(host, ":") || strings.HasPrefix(host, "[") || net.ParseIP(host) == nil {
+		return address
+	}
+	return address[:i] + "[" + host + "]" + address[i+len(host):]
+}
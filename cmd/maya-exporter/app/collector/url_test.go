@@ -0,0 +1,73 @@
+package collector
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	cases := map[string]struct {
+		address string
+		url     string
+		isErr   bool
+	}{
+		"Host and port without scheme":           {address: "localhost:9500", url: "http://localhost:9500"},
+		"Trailing slash":                         {address: "http://host:9500/", url: "http://host:9500"},
+		"Https without port":                     {address: "https://host", url: "https://host"},
+		"Spaces around":                          {address: " http://10.42.0.1:9501 ", url: "http://10.42.0.1:9501"},
+		"IPv6 host":                              {address: "[::1]:9501", url: "http://[::1]:9501"},
+		"IPv6 host with scheme":                  {address: "http://[fd00::1]:9500", url: "http://[fd00::1]:9500"},
+		"IPv6 host without port":                 {address: "http://[fd00::1]", url: "http://[fd00::1]"},
+		"IPv6 host without brackets":             {address: "fd00::1", url: "http://[fd00::1]"},
+		"IPv6 host without brackets with scheme": {address: "https://fd00::1/", url: "https://[fd00::1]"},
+		"IPv6 host with zone":                    {address: "http://[fe80::1%25eth0]:9500", url: "http://[fe80::1%25eth0]:9500"},
+		"IPv6 host with invalid port":            {address: "http://[fd00::1]:port", isErr: true},
+		"Unix socket":                            {address: "unix:///var/run/jiva.sock", url: "unix:///var/run/jiva.sock"},
+		"Empty address":                          {address: "", isErr: true},
+		"Missing host":                           {address: "http://:9501", isErr: true},
+		"Unspecified IPv4 host":                  {address: "0.0.0.0:9501", isErr: true},
+		"Unspecified IPv6 host":                  {address: "http://[::]:9501", isErr: true},
+		"Unsupported scheme":                     {address: "ftp://host:9501", isErr: true},
+		"Invalid port":                           {address: "host:port", isErr: true},
+		"Unix socket without path":               {address: "unix://", isErr: true},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := normalizeURL(tt.address)
+			if (err != nil) != tt.isErr {
+				t.Fatalf("normalizeURL(%q) => got error %v, want error %v", tt.address, err, tt.isErr)
+			}
+			if err == nil && got.String() != tt.url {
+				t.Fatalf("normalizeURL(%q) => got %q, want %q", tt.address, got, tt.url)
+			}
+		})
+	}
+}
+
+func TestValidateHost(t *testing.T) {
+	cases := map[string]struct {
+		url   string
+		isErr bool
+	}{
+		"Valid host":            {url: "http://10.42.0.1:9501"},
+		"Empty host":            {url: "http://:9501", isErr: true},
+		"Unspecified IPv4 host": {url: "http://0.0.0.0:9501", isErr: true},
+		"Unspecified IPv6 host": {url: "http://[::]:9501", isErr: true},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = validateHost(u)
+			if (err != nil) != tt.isErr {
+				t.Fatalf("validateHost(%q) => got error %v, want error %v", tt.url, err, tt.isErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidControllerHost) {
+				t.Fatalf("validateHost(%q) => %v, want it to wrap %v", tt.url, err, ErrInvalidControllerHost)
+			}
+		})
+	}
+}
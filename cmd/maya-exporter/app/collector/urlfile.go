@@ -0,0 +1,69 @@
+package collector
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/openebs/maya/cmd/maya-exporter/app/logger"
+)
+
+// ReadControllerURLFile reads the address of the volume controller from
+// the given file, e.g. a file of the kubernetes downward api, and parses
+// it using ParseControllerURL.
+func ReadControllerURLFile(path string) (*url.URL, error) {
+	address, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	u, err := ParseControllerURL(string(address))
+	if err != nil {
+		return nil, fmt.Errorf("invalid controller url in %s: %w", path, err)
+	}
+	return u, nil
+}
+
+// reloadControllerURL re-reads the address of the volume controller from
+// ControllerURLFile, so that the scrapes target the new controller once
+// the file is changed e.g. on a failover. The last known address is kept
+// if the file can't be read or is not valid.
+func (j *Jiva) reloadControllerURL() {
+	if len(j.ControllerURLFile) == 0 {
+		return
+	}
+	u, err := ReadControllerURLFile(j.ControllerURLFile)
+	if err != nil {
+		logger.Warningf("could not reload the controller url, using %s: %v", j.VolumeControllerURL, err)
+		return
+	}
+	statsURL := controllerStatsURL(u)
+	if statsURL == j.VolumeControllerURL {
+		return
+	}
+	logger.Infof("controller url changed from %s to %s", j.VolumeControllerURL, statsURL)
+	j.VolumeControllerURL = statsURL
+	// neither the connections with the old controller nor its cached
+	// stats are reused, the unix socket, if any, is dialled by the new
+	// transport.
+	j.resetClient()
+	if j.cache != nil {
+		j.cache = &statsCache{}
+	}
+}
+
+// resetClient closes the idle connections of the http clients, which are
+// then created again for the next request.
+func (j *Jiva) resetClient() {
+	clientMu.Lock()
+	defer clientMu.Unlock()
+	for _, client := range []*http.Client{j.client, j.replicaClient} {
+		if client == nil {
+			continue
+		}
+		if transport, ok := client.Transport.(*http.Transport); ok {
+			transport.CloseIdleConnections()
+		}
+	}
+	j.client, j.replicaClient = nil, nil
+}
@@ -0,0 +1,85 @@
+package collector
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync/atomic"
+	"testing"
+)
+
+func TestJivaCollectorControllerURLFile(t *testing.T) {
+	var oldRequests, newRequests int32
+	oldController := countingJivaController(&oldRequests)
+	defer oldController.Close()
+	newController := countingJivaController(&newRequests)
+	defer newController.Close()
+
+	dir, err := ioutil.TempDir("", "maya-exporter")
+	if err != nil {
+		t.Fatalf("failed creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "controller-url")
+	write := func(content string) {
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatalf("failed writing %s: %v", file, err)
+		}
+	}
+	write(oldController.URL + "\n")
+	controllerURL, err := ReadControllerURLFile(file)
+	if err != nil {
+		t.Fatalf("ReadControllerURLFile(%s) => %v", file, err)
+	}
+	col := NewJivaStatsExporter(controllerURL, "jiva")
+	col.ControllerURLFile = file
+
+	// cases are run in order since the file is rewritten between them.
+	cases := []struct {
+		name        string
+		content     string
+		oldRequests int32
+		newRequests int32
+	}{
+		{
+			name:        "[Success] scrape the controller in the file",
+			oldRequests: 1,
+		},
+		{
+			name:        "[Success] scrape the new controller once the file is rewritten",
+			content:     newController.URL,
+			oldRequests: 1,
+			newRequests: 1,
+		},
+		{
+			name:        "[Success] keep the last controller if the file is not valid",
+			content:     "ftp://localhost",
+			oldRequests: 1,
+			newRequests: 2,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if len(tt.content) != 0 {
+				write(tt.content)
+			}
+			buf := scrape(t, col)
+			if re := regexp.MustCompile(`openebs_volume_up 1`); !re.Match(buf) {
+				t.Errorf("failed matching: %q", re)
+			}
+			if got := atomic.LoadInt32(&oldRequests); got != tt.oldRequests {
+				t.Errorf("got %d requests to the old controller, want %d", got, tt.oldRequests)
+			}
+			if got := atomic.LoadInt32(&newRequests); got != tt.newRequests {
+				t.Errorf("got %d requests to the new controller, want %d", got, tt.newRequests)
+			}
+		})
+	}
+}
+
+func TestReadControllerURLFile(t *testing.T) {
+	if _, err := ReadControllerURLFile("/nonexistent/controller-url"); !os.IsNotExist(err) {
+		t.Fatalf("ReadControllerURLFile() => %v, want not exist error", err)
+	}
+}
@@ -1,16 +1,31 @@
 package command
 
 import (
+	"context"
 	"errors"
 	goflag "flag"
-	"log"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
 	"net/url"
+	"os"
+	"os/signal"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
-	"github.com/golang/glog"
 	"github.com/openebs/maya/cmd/maya-exporter/app/collector"
+	"github.com/openebs/maya/cmd/maya-exporter/app/logger"
+	clientset "github.com/openebs/maya/pkg/client/generated/clientset/internalclientset"
+	k8s "github.com/openebs/maya/pkg/client/k8s/v1alpha1"
 	"github.com/openebs/maya/pkg/util"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"k8s.io/client-go/kubernetes"
 )
 
 // Constants defined here are the default value of the flags. Which can be
@@ -26,6 +41,47 @@ const (
 	// casType is the type of container attached storage (CAS) from which
 	// the metrics need to be exported. Default is Jiva"
 	casType = "jiva"
+	// scrapeTimeout is the timeout of the requests made to the jiva
+	// controller while collecting the metrics.
+	scrapeTimeout = 5 * time.Second
+	// scrapeRetries is the no of times a failed request to the jiva
+	// controller is retried.
+	scrapeRetries = collector.DefaultRetries
+	// scrapeRetryBackoff is the initial delay between the retries.
+	scrapeRetryBackoff = collector.DefaultRetryBackoff
+	// metricsNamespace is the prefix of the names of the metrics.
+	metricsNamespace = collector.DefaultNamespace
+	// maxConcurrency is the max no of volumes whose stats are collected
+	// concurrently.
+	maxConcurrency = collector.DefaultMaxConcurrency
+	// logErrorInterval is the min interval between the logs of the
+	// failures in collecting the stats of a volume.
+	logErrorInterval = collector.DefaultLogErrorInterval
+	// breakerThreshold is the no of failed collections of the stats of a
+	// volume in a row after which these are skipped for breakerCooldown.
+	breakerThreshold = 5
+	breakerCooldown  = collector.DefaultBreakerCooldown
+	// latencyAnomalyThreshold is the average latency above which the
+	// stats of a volume are flagged as an anomaly.
+	latencyAnomalyThreshold = collector.DefaultLatencyAnomalyThreshold
+	// serverReadTimeout and serverWriteTimeout are the timeouts of
+	// reading a request and writing its response by the http server of
+	// the exporter. The write timeout covers the collection of the stats
+	// made while serving /metrics, so it must exceed scrapeTimeout which
+	// bounds all the requests of a scrape including the retries.
+	serverReadTimeout  = 10 * time.Second
+	serverWriteTimeout = 30 * time.Second
+	// serverWriteMargin is the time left by the write timeout after the
+	// scrape timeout below which a warning is logged, as the response
+	// may be cut off while it is written after a slow scrape.
+	serverWriteMargin = time.Second
+	// logFormat is the format of the logs of the exporter.
+	logFormat = logger.TextFormat
+	// podNamespaceEnv and podNameEnv are the env variables, usually set
+	// using the downward API, from which the default namespace and name
+	// of the pod of the exporter are read.
+	podNamespaceEnv = "POD_NAMESPACE"
+	podNameEnv      = "POD_NAME"
 )
 
 // VolumeExporterOptions is used to create flags for the monitoring command
@@ -33,13 +89,111 @@ type VolumeExporterOptions struct {
 	ListenAddress     string
 	MetricsPath       string
 	ControllerAddress string
+	ControllerURLFile string
 	CASType           string
+	MetricsNamespace  string
+	ScrapeTimeout     time.Duration
+	CacheTTL          time.Duration
+	DisableKeepAlives bool
+	ScrapeRetries     int
+	RetryBackoff      time.Duration
+	CAFile            string
+	ProxyURL          string
+	Username          string
+	Password          string
+	PasswordFile      string
+	Volumes           []string
+	DisabledMetrics   []string
+	MaxConcurrency    int
+	LogErrorInterval  time.Duration
+	BreakerThreshold  int
+	BreakerCooldown   time.Duration
+	// LatencyAnomalyThreshold is the average latency of the reads or
+	// writes above which the stats of a volume are flagged.
+	LatencyAnomalyThreshold time.Duration
+	EnableDebug             bool
+	EnableRequestTrace      bool
+	StrictDecode            bool
+	LegacyMetricNames       bool
+	LatencyBuckets          string
+	PodNamespace            string
+	PodName                 string
+	PV                      string
+	Validate                bool
+	LogFormat               string
+	ConfigFile              string
+	// ReplicaPaths are the data directories of the jiva replicas on the
+	// host whose disk usage is collected, in the form of replica=path.
+	ReplicaPaths               []string
+	ReplicaDiskMaxFiles        int
+	ReplicaDiskRefreshInterval time.Duration
+	// ServerReadTimeout and ServerWriteTimeout are the timeouts of the
+	// http server of the exporter, see validateServerTimeouts.
+	ServerReadTimeout  time.Duration
+	ServerWriteTimeout time.Duration
+	// ForceHTTP2 and DisableHTTP2 control the negotiation of HTTP/2 with
+	// the volume controllers served over https.
+	ForceHTTP2   bool
+	DisableHTTP2 bool
+	// MetricsAllowlistFile is the file with the names of the only metrics
+	// which are exported, see readMetricsAllowlist.
+	MetricsAllowlistFile string
+	// PushGatewayURL is the url of the Pushgateway to which the metrics
+	// are pushed every PushInterval, in addition to being served.
+	PushGatewayURL string
+	PushInterval   time.Duration
+	// ControllerHeaders are the headers added to the requests to the
+	// volume controller in the form of key=value, see parseHeaders.
+	ControllerHeaders []string
+	// CounterMode exports the cumulative totals of the volumes as
+	// counters rather than gauges, see SetCounterMode.
+	CounterMode bool
+	// ClientCertFile and ClientKeyFile are the client certificate and
+	// key presented to the volume controllers which require mutual TLS.
+	ClientCertFile string
+	ClientKeyFile  string
+	// CredentialsFile is the file with the credentials of the volume
+	// controller, e.g. a mounted kubernetes secret, which is re-read
+	// before each scrape, see collector.ReadCredentialsFile.
+	CredentialsFile string
+	// MaxRedirects is the max no of redirects followed by a request to
+	// the volume controller.
+	MaxRedirects int
+	// ProbeAllowedTargets are the hosts, host:port or CIDRs of the jiva
+	// controllers which may be probed using /probe, which is disabled if
+	// it is empty, see probeAllowed.
+	ProbeAllowedTargets []string
+	// StatsProtocol is the protocol used to fetch the stats of the cstor
+	// volume, statsProtocolGRPC to use the gRPC stats api at GRPCAddr
+	// rather than the unix socket.
+	StatsProtocol string
+	GRPCAddr      string
+	// Pool is the storage pool of the volume added as the pool label to
+	// the metrics, it is resolved using the kubernetes api from the PV
+	// if it is not set and PoolFromAPI is set.
+	Pool        string
+	PoolFromAPI bool
+	// poolClient is the client used to resolve the pool, it is created
+	// from the in-cluster config or the kubeconfig of the env if nil.
+	poolClient clientset.Interface
+	// commandLineFlags are the flags set on the command line and
+	// settings are the settings read from the ConfigFile at the start.
+	commandLineFlags map[string]bool
+	settings         map[string]string
+	// exporter is the registered exporter, it is used to know whether
+	// the exporter is ready.
+	exporter *collector.VolumeStatsExporter
+	// ctx is cancelled when the exporter receives SIGTERM, it aborts
+	// the in-flight scrapes and shuts down the http server.
+	ctx context.Context
 }
 
 // AddListenAddressFlag is used to create flag to pass the listen address of exporter.
 func AddListenAddressFlag(cmd *cobra.Command, value *string) {
 	cmd.Flags().StringVarP(value, "listen.addr", "a", *value,
 		"Address on which to expose metrics and web interface.)")
+	cmd.Flags().StringVar(value, "listen-address", *value,
+		"Address on which to expose metrics and web interface, same as listen.addr")
 }
 
 // AddMetricsPathFlag is used to create flag to pass the listen path where volume
@@ -47,19 +201,323 @@ func AddListenAddressFlag(cmd *cobra.Command, value *string) {
 func AddMetricsPathFlag(cmd *cobra.Command, value *string) {
 	cmd.Flags().StringVarP(value, "listen.path", "m", *value,
 		"Path under which to expose metrics.")
+	cmd.Flags().StringVar(value, "metrics-path", *value,
+		"Path under which to expose metrics, same as listen.path")
 }
 
 // AddControllerAddressFlag is used to create flag to pass the Jiva volume
 // controllers IP.
 func AddControllerAddressFlag(cmd *cobra.Command, value *string) {
 	cmd.Flags().StringVarP(value, "controller.addr", "c", *value,
-		"IP address from where metrics to be exported")
+		"IP address from where metrics to be exported, use unix://<path> for a unix socket")
+}
+
+// AddControllerURLFileFlag is used to create flag to pass the file from
+// which the address of the Jiva volume controller is read.
+func AddControllerURLFileFlag(cmd *cobra.Command, value *string) {
+	cmd.Flags().StringVar(value, "controller-url-file", *value,
+		"File from which the address of the volume controller is read instead of controller.addr, it is re-read before each scrape")
 }
 
 // AddCASTypeFlag is used to create flag to pass the storage engine name
 func AddCASTypeFlag(cmd *cobra.Command, value *string) {
 	cmd.Flags().StringVarP(value, "cas.type", "e", *value,
-		"Type of container attached storage engine")
+		"Type of container attached storage engine, jiva, cstor or pool, replica to collect the stats of the jiva replica at controller.addr directly, or cluster to count the volumes of the cluster by their state")
+}
+
+// AddMetricsNamespaceFlag is used to create flag to pass the prefix of
+// the names of the metrics.
+func AddMetricsNamespaceFlag(cmd *cobra.Command, value *string) {
+	cmd.Flags().StringVar(value, "metrics.namespace", *value,
+		"Prefix of the names of the metrics")
+}
+
+// AddScrapeTimeoutFlag is used to create flag to pass the timeout of the
+// requests made to the volume controller.
+func AddScrapeTimeoutFlag(cmd *cobra.Command, value *time.Duration) {
+	cmd.Flags().DurationVarP(value, "scrape.timeout", "t", *value,
+		"Timeout of the requests made to the volume controller")
+}
+
+// AddServerTimeoutsFlag is used to create flags to pass the timeouts of
+// reading the requests and writing the responses by the http server of
+// the exporter.
+func AddServerTimeoutsFlag(cmd *cobra.Command, read, write *time.Duration) {
+	cmd.Flags().DurationVar(read, "server-read-timeout", *read,
+		"Max duration of reading a request by the http server of the exporter, which protects it from the slow clients, 0 disables it")
+	cmd.Flags().DurationVar(write, "server-write-timeout", *write,
+		"Max duration of serving a request by the http server of the exporter, 0 disables it. The stats are collected while serving /metrics, so it must exceed scrape.timeout which bounds all the requests of a scrape including the retries")
+}
+
+// AddCacheTTLFlag is used to create flag to pass the duration for which
+// the stats of the volume controller are cached.
+func AddCacheTTLFlag(cmd *cobra.Command, value *time.Duration) {
+	cmd.Flags().DurationVar(value, "cache-ttl", *value,
+		"Serve the stats of the volume controller from the cache if they are younger than the ttl, disabled if 0. The metrics of the cached stats carry the time of their collection")
+}
+
+// AddDisableKeepAlivesFlag is used to create flag to close the connection
+// with the volume controller after each request.
+func AddDisableKeepAlivesFlag(cmd *cobra.Command, value *bool) {
+	cmd.Flags().BoolVar(value, "disable-keep-alives", *value,
+		"Close the connection with the volume controller after each request instead of reusing it across scrapes")
+}
+
+// AddHTTP2Flags is used to create flags to force or disable the use of
+// HTTP/2 with the volume controller.
+func AddHTTP2Flags(cmd *cobra.Command, force, disable *bool) {
+	cmd.Flags().BoolVar(force, "force-http2", *force,
+		"Negotiate HTTP/2 with the volume controller served over https, HTTP/1.1 is used by default if controller.ca-file is set")
+	cmd.Flags().BoolVar(disable, "disable-http2", *disable,
+		"Never negotiate HTTP/2 with the volume controller, for the controllers with a buggy HTTP/2 support")
+}
+
+// AddScrapeRetriesFlag is used to create flags to pass the no of retries
+// of a failed request to the volume controller and the delay between them.
+func AddScrapeRetriesFlag(cmd *cobra.Command, retries *int, backoff *time.Duration) {
+	cmd.Flags().IntVar(retries, "scrape.retries", *retries,
+		"No of retries of a failed request to the volume controller")
+	cmd.Flags().DurationVar(backoff, "scrape.retry-backoff", *backoff,
+		"Initial delay between the retries which is doubled after each retry")
+}
+
+// AddMaxRedirectsFlag is used to create flag to pass the max no of
+// redirects followed by a request to the volume controller.
+func AddMaxRedirectsFlag(cmd *cobra.Command, value *int) {
+	cmd.Flags().IntVar(value, "scrape.max-redirects", *value,
+		"Max no of redirects followed by a request to the volume controller, e.g. issued by a load balancer, a negative value disables the redirects")
+}
+
+// AddMaxConcurrencyFlag is used to create flag to pass the max no of
+// volumes whose stats are collected concurrently.
+func AddMaxConcurrencyFlag(cmd *cobra.Command, value *int) {
+	cmd.Flags().IntVar(value, "max-concurrency", *value,
+		"Max no of volumes whose stats are collected concurrently")
+}
+
+// AddLogErrorIntervalFlag is used to create flag to pass the min interval
+// between the logs of the failures in collecting the stats of a volume.
+func AddLogErrorIntervalFlag(cmd *cobra.Command, value *time.Duration) {
+	cmd.Flags().DurationVar(value, "log-error-interval", *value,
+		"Min interval between the logs of the failures in collecting the stats of a volume, the first failure is always logged")
+}
+
+// AddCircuitBreakerFlags is used to create flags to pass the no of failed
+// collections in a row after which the stats of a volume are not collected
+// and the time after which the volume is probed again.
+func AddCircuitBreakerFlags(cmd *cobra.Command, threshold *int, cooldown *time.Duration) {
+	cmd.Flags().IntVar(threshold, "circuit-breaker-threshold", *threshold,
+		"No of failed collections of the stats of a volume in a row after which the volume is reported as down without calling its controller, 0 disables it")
+	cmd.Flags().DurationVar(cooldown, "circuit-breaker-cooldown", *cooldown,
+		"Time after which a volume whose circuit breaker is open is probed again")
+}
+
+// AddLatencyAnomalyThresholdFlag is used to create flag to pass the
+// average latency above which the stats of a volume are flagged as an
+// anomaly.
+func AddLatencyAnomalyThresholdFlag(cmd *cobra.Command, value *time.Duration) {
+	cmd.Flags().DurationVar(value, "latency-anomaly-threshold", *value,
+		"Average latency of the reads or writes of a volume above which stats_anomaly is set, e.g. due to a stuck queue")
+}
+
+// AddEnableDebugFlag is used to create flag to enable the debug endpoints
+// of the exporter.
+func AddEnableDebugFlag(cmd *cobra.Command, value *bool) {
+	cmd.Flags().BoolVar(value, "enable-debug", *value,
+		"Expose the last collected stats of the volumes as JSON on /debug/stats")
+}
+
+// AddEnableRequestTraceFlag is used to create flag to record the time
+// taken by the phases of the requests made to the volume controller.
+func AddEnableRequestTraceFlag(cmd *cobra.Command, value *bool) {
+	cmd.Flags().BoolVar(value, "enable-request-trace", *value,
+		"Record the duration of the dns, connect and ttfb phases of the requests made to the volume controller, disabled by default due to the overhead")
+}
+
+// AddStrictDecodeFlag is used to create flag to fail the scrapes if the
+// response of the volume controller has unexpected fields.
+func AddStrictDecodeFlag(cmd *cobra.Command, value *bool) {
+	cmd.Flags().BoolVar(value, "strict-decode", *value,
+		"Fail the scrape and count it in strict_decode_errors_total if the response of the jiva controller has unknown fields, the unknown fields are ignored by default")
+}
+
+// AddLegacyMetricNamesFlag is used to create flag to emit the core
+// metrics under their legacy names without the namespace as well.
+func AddLegacyMetricNamesFlag(cmd *cobra.Command, value *bool) {
+	cmd.Flags().BoolVar(value, "legacy-metric-names", *value,
+		"Emit the core volume metrics under their legacy names without the namespace as well, e.g. reads along with openebs_reads. It doubles the no of these metrics, disable it once the dashboards are migrated")
+}
+
+// AddCounterModeFlag is used to create flag to export the cumulative
+// totals of the volumes as counters rather than gauges.
+func AddCounterModeFlag(cmd *cobra.Command, value *bool) {
+	cmd.Flags().BoolVar(value, "counter-mode", *value,
+		"Export the cumulative totals of the volumes, e.g. reads, read_time and read_block_count, as counters rather than gauges so that rate() can be used on them. The counters start over on a restart of the exporter, which rate() treats as a counter reset, disabled by default so that the dashboards using the gauges keep working")
+}
+
+// AddLatencyBucketsFlag is used to create flag to pass the buckets of the
+// read and write latency histograms.
+func AddLatencyBucketsFlag(cmd *cobra.Command, value *string) {
+	cmd.Flags().StringVar(value, "latency-buckets", *value,
+		"Comma separated list of the upper bounds in seconds of the buckets of the read and write latency histograms in increasing order, the prometheus default buckets are used if it is not set")
+}
+
+// AddPodLabelsFlags is used to create flags to pass the namespace and
+// the name of the pod of the exporter and the persistent volume, these
+// are added as labels to the metrics.
+func AddPodLabelsFlags(cmd *cobra.Command, namespace, pod, pv *string) {
+	cmd.Flags().StringVar(namespace, "pod-namespace", *namespace,
+		"Namespace of the pod of the exporter added as the namespace label to the metrics, read from "+podNamespaceEnv+" by default")
+	cmd.Flags().StringVar(pod, "pod-name", *pod,
+		"Name of the pod of the exporter added as the pod label to the metrics, read from "+podNameEnv+" by default")
+	cmd.Flags().StringVar(pv, "pv", *pv,
+		"Name of the persistent volume added as the pv label to the metrics")
+}
+
+// The values of the stats-protocol flag.
+const (
+	statsProtocolDefault = ""
+	statsProtocolGRPC    = "grpc"
+)
+
+// AddStatsProtocolFlags is used to create flags to pass the protocol used
+// to fetch the stats of the cstor volume and the address of the gRPC
+// stats api of the cstor target.
+func AddStatsProtocolFlags(cmd *cobra.Command, protocol, addr *string) {
+	cmd.Flags().StringVar(protocol, "stats-protocol", *protocol,
+		"Protocol used to fetch the stats of the cstor volume, grpc to use the gRPC stats api of the newer cstor targets at grpc.addr rather than the unix socket. The stats of jiva are always fetched using http")
+	cmd.Flags().StringVar(addr, "grpc.addr", *addr,
+		"Address of the gRPC stats api of the cstor target used if stats-protocol is grpc")
+}
+
+// AddPoolLabelFlags is used to create flags to pass the storage pool of
+// the volume added as the pool label to the metrics, or to resolve it from
+// the cstor volume replicas of the persistent volume.
+func AddPoolLabelFlags(cmd *cobra.Command, pool *string, fromAPI *bool) {
+	cmd.Flags().StringVar(pool, "pool", *pool,
+		"Name of the storage pool of the volume added as the pool label to the metrics, the label is omitted if the pool is unknown")
+	cmd.Flags().BoolVar(fromAPI, "pool.from-api", *fromAPI,
+		"Resolve the storage pool claim of the cstor pools of the replicas of the volume passed using --pv with the kubernetes api if --pool is not set")
+}
+
+// AddValidateFlag is used to create flag to validate the configuration
+// of the exporter by collecting the metrics once without starting the
+// http server.
+func AddValidateFlag(cmd *cobra.Command, value *bool) {
+	cmd.Flags().BoolVar(value, "validate", *value,
+		"Collect the metrics once, print them and exit with status 1 if the collection fails, the http server is not started")
+}
+
+// AddCAFileFlag is used to create flag to pass the CA certificate used to
+// verify the certificate of the volume controller served over https.
+func AddCAFileFlag(cmd *cobra.Command, value *string) {
+	cmd.Flags().StringVar(value, "controller.ca-file", *value,
+		"CA certificate file to verify the volume controller's certificate")
+}
+
+// AddClientCertFlags is used to create flags to pass the client
+// certificate and key presented to the volume controller served over
+// https which requires mutual TLS.
+func AddClientCertFlags(cmd *cobra.Command, certFile, keyFile *string) {
+	cmd.Flags().StringVar(certFile, "client-cert", *certFile,
+		"Client certificate file presented to the volume controller for mutual TLS, client-key must be set as well")
+	cmd.Flags().StringVar(keyFile, "client-key", *keyFile,
+		"Private key file of the client-cert")
+}
+
+// AddCredentialsFileFlag is used to create flag to pass the file with the
+// credentials of the volume controller which is reloaded once changed.
+func AddCredentialsFileFlag(cmd *cobra.Command, value *string) {
+	cmd.Flags().StringVar(value, "credentials-file", *value,
+		"File with the credentials of the volume controller as key=value lines of username, password, ca-file, client-cert and client-key, e.g. a mounted secret, it is re-read before each scrape")
+}
+
+// AddProxyURLFlag is used to create flag to pass the proxy through which
+// the requests to the volume controller are sent.
+func AddProxyURLFlag(cmd *cobra.Command, value *string) {
+	cmd.Flags().StringVar(value, "proxy-url", *value,
+		"URL of the proxy of the requests to the volume controller, overrides the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables")
+}
+
+// AddControllerHeaderFlag is used to create flag to pass the headers added
+// to the requests to the volume controller.
+func AddControllerHeaderFlag(cmd *cobra.Command, value *[]string) {
+	cmd.Flags().StringArrayVar(value, "controller-header", *value,
+		"Header added to the requests to the volume controller in the form of key=value, e.g. x-request-id=maya-exporter, can be repeated")
+}
+
+// AddControllerCredentialsFlag is used to create flags to pass the basic
+// auth credentials of the volume controller, the password can be read
+// from a file so that it is not visible in the process list.
+func AddControllerCredentialsFlag(cmd *cobra.Command, username, password, passwordFile *string) {
+	cmd.Flags().StringVar(username, "controller-username", *username,
+		"Username of the basic auth of the volume controller")
+	cmd.Flags().StringVar(password, "controller-password", *password,
+		"Password of the basic auth of the volume controller")
+	cmd.Flags().StringVar(passwordFile, "controller-password-file", *passwordFile,
+		"File from which the password of the basic auth of the volume controller is read")
+}
+
+// AddVolumesFlag is used to create flag to pass the volumes whose stats
+// are collected by the same exporter.
+func AddVolumesFlag(cmd *cobra.Command, value *[]string) {
+	cmd.Flags().StringSliceVar(value, "volumes", *value,
+		"Comma separated list of volumes in the form of name=controller address")
+}
+
+// AddReplicaDiskFlags is used to create flags to pass the data
+// directories of the replicas whose disk usage is collected and to bound
+// the walks of the directories.
+func AddReplicaDiskFlags(cmd *cobra.Command, paths *[]string, maxFiles *int, refreshInterval *time.Duration) {
+	cmd.Flags().StringSliceVar(paths, "replica-paths", *paths,
+		"Comma separated list of the data directories of the jiva replicas on the host in the form of replica=path, whose disk usage is collected")
+	cmd.Flags().IntVar(maxFiles, "replica-disk-max-files", *maxFiles,
+		"Max no of files walked per data directory of a replica to compute its disk usage")
+	cmd.Flags().DurationVar(refreshInterval, "replica-disk-refresh-interval", *refreshInterval,
+		"Min interval between the walks of the data directories of the replicas, the last disk usage is emitted in between")
+}
+
+// AddProbeAllowedTargetsFlag is used to create flag to pass the jiva
+// controllers which may be probed using /probe.
+func AddProbeAllowedTargetsFlag(cmd *cobra.Command, value *[]string) {
+	cmd.Flags().StringSliceVar(value, "probe-allowed-targets", *value,
+		"Comma separated list of the hosts, host:port or CIDRs of the jiva controllers which may be probed using /probe?target=, /probe is disabled if it is not set")
+}
+
+// AddDisableMetricsFlag is used to create flag to pass the names of the
+// metrics which are not exported.
+func AddDisableMetricsFlag(cmd *cobra.Command, value *[]string) {
+	cmd.Flags().StringSliceVar(value, "disable-metrics", *value,
+		"Comma separated list of the metrics which are not exported, e.g. replica_read_iops,replica_write_iops")
+}
+
+// AddMetricsAllowlistFileFlag is used to create flag to pass the file with
+// the names of the only metrics which are exported.
+func AddMetricsAllowlistFileFlag(cmd *cobra.Command, value *string) {
+	cmd.Flags().StringVar(value, "metrics-allowlist-file", *value,
+		"File with the names of the only metrics which are exported, one per line. The metrics of disable-metrics are not exported even if these are listed")
+}
+
+// AddPushFlags is used to create flags to pass the Pushgateway to which
+// the metrics are pushed and the interval between the pushes.
+func AddPushFlags(cmd *cobra.Command, gatewayURL *string, interval *time.Duration) {
+	cmd.Flags().StringVar(gatewayURL, "push-gateway-url", *gatewayURL,
+		"URL of the Pushgateway to which the metrics are pushed as well, with the job label "+collector.DefaultPushJob+" and the name of the volume as the instance label, e.g. http://pushgateway:9091")
+	cmd.Flags().DurationVar(interval, "push-interval", *interval,
+		"Interval between the pushes of the metrics to the Pushgateway")
+}
+
+// AddConfigFileFlag is used to create flag to pass the file from which
+// the settings of the exporter are read.
+func AddConfigFileFlag(cmd *cobra.Command, value *string) {
+	cmd.Flags().StringVar(value, "config-file", *value,
+		"File with a flag per line in the form of name=value, the flags set on the command line take precedence. disable-metrics, metrics-allowlist-file and v are reloaded on SIGHUP, the others require a restart")
+}
+
+// AddLogFormatFlag is used to create flag to pass the format of the logs.
+func AddLogFormatFlag(cmd *cobra.Command, value *string) {
+	cmd.Flags().StringVar(value, "log-format", *value,
+		"Format of the logs, either text or json")
 }
 
 // NewCmdVolumeExporter is used to create command monitoring and it initialize
@@ -72,6 +530,25 @@ func NewCmdVolumeExporter() (*cobra.Command, error) {
 	options.ListenAddress = listenAddress
 	options.MetricsPath = metricsPath
 	options.CASType = casType
+	options.MetricsNamespace = metricsNamespace
+	options.LogFormat = logFormat
+	options.MaxConcurrency = maxConcurrency
+	options.LogErrorInterval = logErrorInterval
+	options.BreakerThreshold = breakerThreshold
+	options.BreakerCooldown = breakerCooldown
+	options.LatencyAnomalyThreshold = latencyAnomalyThreshold
+	options.ReplicaDiskMaxFiles = collector.DefaultReplicaDiskMaxFiles
+	options.ReplicaDiskRefreshInterval = collector.DefaultReplicaDiskRefreshInterval
+	options.ScrapeTimeout = scrapeTimeout
+	options.ServerReadTimeout = serverReadTimeout
+	options.ServerWriteTimeout = serverWriteTimeout
+	options.PushInterval = collector.DefaultPushInterval
+	options.ScrapeRetries = scrapeRetries
+	options.RetryBackoff = scrapeRetryBackoff
+	options.MaxRedirects = collector.DefaultMaxRedirects
+	options.GRPCAddr = collector.DefaultGRPCStatsAddr
+	options.PodNamespace = os.Getenv(podNamespaceEnv)
+	options.PodName = os.Getenv(podNameEnv)
 	cmd := &cobra.Command{
 		Short: "Collect metrics from OpenEBS volumes",
 		Long: `maya-exporter can be used to monitor openebs volumes and pools.
@@ -85,29 +562,151 @@ It can be deployed alongside the openebs volume or pool containers as sidecars.`
 	cmd.Flags().AddGoFlagSet(goflag.CommandLine)
 	goflag.CommandLine.Parse([]string{})
 	AddControllerAddressFlag(cmd, &options.ControllerAddress)
+	AddControllerURLFileFlag(cmd, &options.ControllerURLFile)
 	AddListenAddressFlag(cmd, &options.ListenAddress)
 	AddMetricsPathFlag(cmd, &options.MetricsPath)
 	AddCASTypeFlag(cmd, &options.CASType)
+	AddMetricsNamespaceFlag(cmd, &options.MetricsNamespace)
+	AddScrapeTimeoutFlag(cmd, &options.ScrapeTimeout)
+	AddServerTimeoutsFlag(cmd, &options.ServerReadTimeout, &options.ServerWriteTimeout)
+	AddCacheTTLFlag(cmd, &options.CacheTTL)
+	AddDisableKeepAlivesFlag(cmd, &options.DisableKeepAlives)
+	AddHTTP2Flags(cmd, &options.ForceHTTP2, &options.DisableHTTP2)
+	AddScrapeRetriesFlag(cmd, &options.ScrapeRetries, &options.RetryBackoff)
+	AddMaxRedirectsFlag(cmd, &options.MaxRedirects)
+	AddVolumesFlag(cmd, &options.Volumes)
+	AddProbeAllowedTargetsFlag(cmd, &options.ProbeAllowedTargets)
+	AddReplicaDiskFlags(cmd, &options.ReplicaPaths, &options.ReplicaDiskMaxFiles, &options.ReplicaDiskRefreshInterval)
+	AddDisableMetricsFlag(cmd, &options.DisabledMetrics)
+	AddMetricsAllowlistFileFlag(cmd, &options.MetricsAllowlistFile)
+	AddPushFlags(cmd, &options.PushGatewayURL, &options.PushInterval)
+	AddMaxConcurrencyFlag(cmd, &options.MaxConcurrency)
+	AddCAFileFlag(cmd, &options.CAFile)
+	AddClientCertFlags(cmd, &options.ClientCertFile, &options.ClientKeyFile)
+	AddCredentialsFileFlag(cmd, &options.CredentialsFile)
+	AddProxyURLFlag(cmd, &options.ProxyURL)
+	AddControllerCredentialsFlag(cmd, &options.Username, &options.Password, &options.PasswordFile)
+	AddControllerHeaderFlag(cmd, &options.ControllerHeaders)
+	AddLogFormatFlag(cmd, &options.LogFormat)
+	AddLogErrorIntervalFlag(cmd, &options.LogErrorInterval)
+	AddCircuitBreakerFlags(cmd, &options.BreakerThreshold, &options.BreakerCooldown)
+	AddLatencyAnomalyThresholdFlag(cmd, &options.LatencyAnomalyThreshold)
+	AddEnableDebugFlag(cmd, &options.EnableDebug)
+	AddEnableRequestTraceFlag(cmd, &options.EnableRequestTrace)
+	AddStrictDecodeFlag(cmd, &options.StrictDecode)
+	AddLegacyMetricNamesFlag(cmd, &options.LegacyMetricNames)
+	AddCounterModeFlag(cmd, &options.CounterMode)
+	AddLatencyBucketsFlag(cmd, &options.LatencyBuckets)
+	AddPodLabelsFlags(cmd, &options.PodNamespace, &options.PodName, &options.PV)
+	AddPoolLabelFlags(cmd, &options.Pool, &options.PoolFromAPI)
+	AddStatsProtocolFlags(cmd, &options.StatsProtocol, &options.GRPCAddr)
+	AddValidateFlag(cmd, &options.Validate)
+	AddConfigFileFlag(cmd, &options.ConfigFile)
+	cmd.AddCommand(NewCmdDump(&options), NewCmdParse(&options))
 	return cmd, nil
 }
 
 // Run used to process commands,args and call openebs exporter and it returns
 // nil on successful execution.
 func Run(cmd *cobra.Command, options *VolumeExporterOptions) error {
-	glog.Infof("Starting maya-exporter ...")
+	if len(options.ConfigFile) != 0 {
+		if err := options.applyConfigFile(cmd); err != nil {
+			return err
+		}
+	}
+	if err := logger.SetFormat(options.LogFormat); err != nil {
+		return err
+	}
+	logger.Infof("Starting maya-exporter ...")
+	if err := options.validateListenAddress(); err != nil {
+		return err
+	}
+	if err := options.validateServerTimeouts(); err != nil {
+		return err
+	}
+	if err := options.validateProbeAllowedTargets(); err != nil {
+		return err
+	}
+	if err := options.validateStatsProtocol(); err != nil {
+		return err
+	}
+	if _, err := options.latencyBuckets(); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	options.ctx = ctx
+	go cancelOnSignal(cancel, syscall.SIGTERM, os.Interrupt)
 	option := Initialize(options)
 	if len(option) == 0 {
-		glog.Fatal("maya-exporter only supports jiva and cstor as storage engine")
+		logger.Fatal("maya-exporter only supports jiva, cstor, pool, replica and cluster as storage engine")
 		return nil
 	}
+	if err := collector.RegisterCollector("build_info", collector.NewBuildInfoCollector(options.MetricsNamespace)); err != nil {
+		logger.Error(err)
+	}
+	if len(options.ReplicaPaths) != 0 {
+		if err := options.registerReplicaDiskCollector(); err != nil {
+			if options.Validate {
+				return err
+			}
+			logger.Fatal(err)
+			return nil
+		}
+	}
 	if option == "cstor" {
-		glog.Infof("initialising maya-exporter for the cstor")
+		logger.Infof("initialising maya-exporter for the cstor")
 		options.RegisterCstorStatsExporter()
 	}
+	if option == "pool" {
+		logger.Info("Initialising maya-exporter for the cstor pools")
+		if err := options.RegisterPoolCollector(); err != nil {
+			if options.Validate {
+				return err
+			}
+			logger.Fatal(err)
+			return nil
+		}
+	}
+	if option == "cluster" {
+		logger.Info("Initialising maya-exporter for the volumes of the cluster")
+		if err := options.RegisterClusterCollector(); err != nil {
+			if options.Validate {
+				return err
+			}
+			logger.Fatal(err)
+			return nil
+		}
+	}
 	if option == "jiva" {
-		log.Println("Initialising maya-exporter for the jiva")
+		logger.Info("Initialising maya-exporter for the jiva")
 		if err := options.RegisterJivaStatsExporter(); err != nil {
-			glog.Fatal(err)
+			if options.Validate {
+				return err
+			}
+			logger.Fatal(err)
+			return nil
+		}
+	}
+	if option == "replica" {
+		logger.Info("Initialising maya-exporter for the jiva replica")
+		if err := options.RegisterJivaReplicaStatsExporter(); err != nil {
+			if options.Validate {
+				return err
+			}
+			logger.Fatal(err)
+			return nil
+		}
+	}
+	if options.Validate {
+		return options.validate(os.Stdout, prometheus.DefaultGatherer)
+	}
+	if len(options.ConfigFile) != 0 {
+		go options.reloadOnSignal(ctx, syscall.SIGHUP)
+	}
+	if len(options.PushGatewayURL) != 0 {
+		if err := options.startPusher(); err != nil {
+			logger.Fatal(err)
 			return nil
 		}
 	}
@@ -115,17 +714,84 @@ func Run(cmd *cobra.Command, options *VolumeExporterOptions) error {
 	return nil
 }
 
+// cancelOnSignal calls cancel once any of the given signals is received.
+func cancelOnSignal(cancel context.CancelFunc, sig ...os.Signal) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, sig...)
+	s := <-signals
+	logger.Infof("Received %v, shutting down maya-exporter", s)
+	cancel()
+}
+
+// context returns the context of the exporter, it is never done if the
+// exporter is not started using Run.
+func (o *VolumeExporterOptions) context() context.Context {
+	if o.ctx == nil {
+		return context.Background()
+	}
+	return o.ctx
+}
+
 // RegisterJivaStatsExporter parses the jiva controller URL and
 // initialises an instance of JivaStatsExporter.This returns err
 // if the URL is not correct.
 func (o *VolumeExporterOptions) RegisterJivaStatsExporter() error {
-	controllerURL, err := url.ParseRequestURI(o.ControllerAddress)
+	if len(o.Volumes) != 0 {
+		return o.registerJivaVolumesStatsExporter()
+	}
+	controllerURL, err := o.controllerURL()
 	if err != nil {
-		glog.Error(err)
-		return errors.New("Error in parsing the URI")
+		logger.Error(err)
+		return fmt.Errorf("Error in parsing the URI: %w", err)
 	}
 	exporter := collector.NewJivaStatsExporter(controllerURL, o.CASType)
-	prometheus.MustRegister(exporter)
+	exporter.ControllerURLFile = o.ControllerURLFile
+	if err := o.configureExporter(exporter); err != nil {
+		logger.Error(err)
+		return err
+	}
+	exporter.MaxConcurrency = o.MaxConcurrency
+	if err := o.configureJiva(&exporter.Jiva); err != nil {
+		logger.Error(err)
+		return err
+	}
+	if err := collector.Register(exporter); err != nil {
+		logger.Error(err)
+		return err
+	}
+	o.exporter = exporter
+	return nil
+}
+
+// controllerURL returns the url of the jiva controller, it is read from
+// ControllerURLFile if it is set.
+func (o *VolumeExporterOptions) controllerURL() (*url.URL, error) {
+	if len(o.ControllerURLFile) != 0 {
+		return collector.ReadControllerURLFile(o.ControllerURLFile)
+	}
+	return collector.ParseControllerURL(o.ControllerAddress)
+}
+
+// RegisterJivaReplicaStatsExporter registers the exporter which collects
+// the stats of the jiva replica at ControllerAddress directly, e.g.
+// "http://10.42.0.3:9502". This returns err if the URL is not correct.
+func (o *VolumeExporterOptions) RegisterJivaReplicaStatsExporter() error {
+	replicaURL, err := collector.ParseControllerURL(o.ControllerAddress)
+	if err != nil {
+		logger.Error(err)
+		return fmt.Errorf("Error in parsing the URI: %w", err)
+	}
+	exporter := collector.NewJivaReplicaStatsExporter(replicaURL, o.CASType)
+	if err := o.configureExporter(exporter); err != nil {
+		logger.Error(err)
+		return err
+	}
+	exporter.ReplicaTimeout = o.ScrapeTimeout
+	if err := collector.Register(exporter); err != nil {
+		logger.Error(err)
+		return err
+	}
+	o.exporter = exporter
 	return nil
 }
 
@@ -133,13 +799,435 @@ func (o *VolumeExporterOptions) RegisterJivaStatsExporter() error {
 // the exporter with Prometheus for collecting the metrics.This doesn't returns
 // error because that case is handled in InitiateConnection().
 func (o *VolumeExporterOptions) RegisterCstorStatsExporter() {
+	if o.StatsProtocol == statsProtocolGRPC {
+		o.registerCstorGRPCStatsExporter()
+		return
+	}
 	var c collector.Cstor
 	c.InitiateConnection()
 	if c.Conn == nil {
-		glog.Error("Connection is not established with the cstor.")
+		logger.Error("Connection is not established with the cstor.")
 	}
 	exporter := collector.NewCstorStatsExporter(c.Conn, o.CASType)
-	prometheus.MustRegister(exporter)
-	glog.Info("Registered the exporter")
+	if err := o.configureExporter(exporter); err != nil {
+		logger.Error(err)
+		return
+	}
+	if err := collector.Register(exporter); err != nil {
+		logger.Error(err)
+		return
+	}
+	o.exporter = exporter
+	logger.Info("Registered the exporter")
 	return
 }
+
+// registerCstorGRPCStatsExporter registers the exporter of the stats of the
+// cstor volume fetched using the gRPC stats api at GRPCAddr. The
+// connection is established in the background and re-established by grpc
+// if it is lost, so the exporter is registered even if the target is not
+// reachable yet.
+func (o *VolumeExporterOptions) registerCstorGRPCStatsExporter() {
+	conn, err := grpc.Dial(o.GRPCAddr, grpc.WithInsecure())
+	if err != nil {
+		logger.Errorf("could not dial the gRPC stats api at %s: %v", o.GRPCAddr, err)
+		return
+	}
+	exporter := collector.NewCstorGRPCStatsExporter(collector.NewVolumeStatsClient(conn), o.CASType)
+	exporter.StatsTimeout = o.ScrapeTimeout
+	if err := o.configureExporter(exporter); err != nil {
+		logger.Error(err)
+		return
+	}
+	if err := collector.Register(exporter); err != nil {
+		logger.Error(err)
+		return
+	}
+	o.exporter = exporter
+	logger.Infof("Registered the exporter of the stats fetched using the gRPC stats api at %s", o.GRPCAddr)
+}
+
+// RegisterPoolCollector registers the collector of the stats of the
+// cstor pools which are listed using zpool.
+func (o *VolumeExporterOptions) RegisterPoolCollector() error {
+	poolCollector := collector.NewPoolCollector(o.MetricsNamespace, collector.ZpoolLister{Runner: util.RealRunner{}})
+	if err := collector.RegisterCollector("pool", poolCollector); err != nil {
+		logger.Error(err)
+		return err
+	}
+	return nil
+}
+
+// RegisterClusterCollector registers the collector of the no of volumes
+// of the cluster by their state, these are listed using the kubernetes
+// api with the in-cluster config or the kubeconfig of the env.
+func (o *VolumeExporterOptions) RegisterClusterCollector() error {
+	config, err := k8s.Config().Get()
+	if err != nil {
+		return err
+	}
+	config.Timeout = o.ScrapeTimeout
+	kubeClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	openebsClient, err := clientset.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	lister := collector.KubeVolumeLister{KubeClient: kubeClient, OpenebsClient: openebsClient}
+	if err := collector.RegisterCollector("cluster", collector.NewClusterCollector(o.MetricsNamespace, lister)); err != nil {
+		logger.Error(err)
+		return err
+	}
+	return nil
+}
+
+// registerReplicaDiskCollector registers the collector of the disk usage
+// of the data directories of the replicas passed using the replica-paths
+// flag.
+func (o *VolumeExporterOptions) registerReplicaDiskCollector() error {
+	var paths []collector.ReplicaPath
+	for _, path := range o.ReplicaPaths {
+		parts := strings.SplitN(path, "=", 2)
+		if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+			return fmt.Errorf("Invalid replica path %q, must be in the form of replica=path", path)
+		}
+		paths = append(paths, collector.ReplicaPath{Replica: parts[0], Path: parts[1]})
+	}
+	diskCollector := collector.NewReplicaDiskCollector(o.MetricsNamespace, paths)
+	diskCollector.MaxFiles = o.ReplicaDiskMaxFiles
+	diskCollector.RefreshInterval = o.ReplicaDiskRefreshInterval
+	if err := collector.RegisterCollector("replica_disk", diskCollector); err != nil {
+		logger.Error(err)
+		return err
+	}
+	return nil
+}
+
+// registerJivaVolumesStatsExporter initialises an instance of
+// JivaStatsExporter which collects the stats of all the volumes passed
+// using the volumes flag.
+func (o *VolumeExporterOptions) registerJivaVolumesStatsExporter() error {
+	var volumes []collector.VolumeTarget
+	for _, volume := range o.Volumes {
+		parts := strings.SplitN(volume, "=", 2)
+		if len(parts) != 2 || len(parts[0]) == 0 {
+			return fmt.Errorf("Invalid volume %q, must be in the form of name=controller address", volume)
+		}
+		volumes = append(volumes, collector.VolumeTarget{Name: parts[0], URL: parts[1]})
+	}
+	exporter, err := collector.NewJivaVolumesStatsExporter(volumes, o.CASType)
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+	if err := o.configureExporter(exporter); err != nil {
+		logger.Error(err)
+		return err
+	}
+	exporter.MaxConcurrency = o.MaxConcurrency
+	if err := o.configureJiva(&exporter.Jiva); err != nil {
+		logger.Error(err)
+		return err
+	}
+	if err := collector.Register(exporter); err != nil {
+		logger.Error(err)
+		return err
+	}
+	o.exporter = exporter
+	return nil
+}
+
+// configureExporter sets the configuration passed using the flags to the
+// exporter, it must be called before the exporter is registered.
+func (o *VolumeExporterOptions) configureExporter(exporter *collector.VolumeStatsExporter) error {
+	exporter.SetNamespace(o.MetricsNamespace)
+	o.resolvePool()
+	exporter.SetConstLabels(o.constLabels())
+	exporter.SetCounterMode(o.CounterMode)
+	if err := o.setLatencyBuckets(exporter); err != nil {
+		return err
+	}
+	if err := o.allowMetrics(exporter); err != nil {
+		return err
+	}
+	exporter.DisableMetrics(o.DisabledMetrics)
+	exporter.SetContext(o.context())
+	exporter.LogErrorInterval = o.LogErrorInterval
+	exporter.BreakerThreshold = o.BreakerThreshold
+	exporter.BreakerCooldown = o.BreakerCooldown
+	exporter.LatencyAnomalyThreshold = o.LatencyAnomalyThreshold
+	exporter.LegacyMetricNames = o.LegacyMetricNames
+	return nil
+}
+
+// allowMetrics allows only the metrics listed in the MetricsAllowlistFile,
+// all the metrics are allowed if it is not set.
+func (o *VolumeExporterOptions) allowMetrics(exporter *collector.VolumeStatsExporter) error {
+	if len(o.MetricsAllowlistFile) == 0 {
+		exporter.AllowMetrics(nil)
+		return nil
+	}
+	names, err := readMetricsAllowlist(o.MetricsAllowlistFile)
+	if err != nil {
+		return err
+	}
+	exporter.AllowMetrics(names)
+	return nil
+}
+
+// configureJiva sets the configuration passed using the flags to the
+// jiva collector.
+func (o *VolumeExporterOptions) configureJiva(j *collector.Jiva) error {
+	if err := o.configureJivaTransport(j); err != nil {
+		return err
+	}
+	j.Username = o.Username
+	j.Password = o.Password
+	headers, err := parseHeaders(o.ControllerHeaders)
+	if err != nil {
+		return err
+	}
+	j.Headers = headers
+	if len(o.PasswordFile) != 0 {
+		password, err := ioutil.ReadFile(o.PasswordFile)
+		if err != nil {
+			// the error only has the path of the file, not the password.
+			return err
+		}
+		j.Password = strings.TrimSpace(string(password))
+	}
+	if len(o.CAFile) != 0 || len(o.ClientCertFile) != 0 || len(o.ClientKeyFile) != 0 {
+		config, err := collector.NewTLSConfig(o.CAFile, o.ClientCertFile, o.ClientKeyFile)
+		if err != nil {
+			return err
+		}
+		j.TLSConfig = config
+	}
+	if len(o.CredentialsFile) != 0 {
+		return o.configureCredentialsFile(j)
+	}
+	return nil
+}
+
+// configureJivaTransport sets the configuration of the requests passed
+// using the flags to the jiva collector, i.e. all of it but the
+// credentials, the headers and the tls configuration.
+func (o *VolumeExporterOptions) configureJivaTransport(j *collector.Jiva) error {
+	j.Timeout = o.ScrapeTimeout
+	j.CacheTTL = o.CacheTTL
+	j.DisableKeepAlives = o.DisableKeepAlives
+	if o.ForceHTTP2 && o.DisableHTTP2 {
+		return errors.New("force-http2 and disable-http2 can't be set together")
+	}
+	j.ForceHTTP2 = o.ForceHTTP2
+	j.DisableHTTP2 = o.DisableHTTP2
+	j.EnableRequestTrace = o.EnableRequestTrace
+	j.StrictDecode = o.StrictDecode
+	j.Retries = o.ScrapeRetries
+	j.RetryBackoff = o.RetryBackoff
+	j.MaxRedirects = o.MaxRedirects
+	if len(o.ProxyURL) != 0 {
+		proxyURL, err := url.Parse(o.ProxyURL)
+		if err != nil {
+			return fmt.Errorf("Invalid proxy url %q: %w", o.ProxyURL, err)
+		}
+		if len(proxyURL.Host) == 0 {
+			return fmt.Errorf("Invalid proxy url %q, must be in the form of scheme://host:port", o.ProxyURL)
+		}
+		j.ProxyURL = proxyURL
+	}
+	return nil
+}
+
+// configureCredentialsFile sets the credentials of the jiva collector from
+// CredentialsFile, which is then reloaded by the collector. It can't be
+// used along with the other flags of the credentials.
+func (o *VolumeExporterOptions) configureCredentialsFile(j *collector.Jiva) error {
+	if len(o.Username) != 0 || len(o.Password) != 0 || len(o.PasswordFile) != 0 ||
+		len(o.CAFile) != 0 || len(o.ClientCertFile) != 0 || len(o.ClientKeyFile) != 0 {
+		return errors.New("credentials-file can't be set along with controller-username, controller-password, controller-password-file, controller.ca-file, client-cert or client-key")
+	}
+	credentials, err := collector.ReadCredentialsFile(o.CredentialsFile)
+	if err != nil {
+		return err
+	}
+	if err := j.SetCredentials(credentials); err != nil {
+		return err
+	}
+	j.CredentialsFile = o.CredentialsFile
+	return nil
+}
+
+// headerNameRegex matches the valid names of the http headers, i.e. the
+// tokens of RFC 7230.
+var headerNameRegex = regexp.MustCompile("^[!#$%&'*+.^_`|~0-9A-Za-z-]+$")
+
+// parseHeaders parses the headers in the form of key=value, the value
+// may be empty. It returns error if a header is not in this form or its
+// name or value is not valid.
+func parseHeaders(headers []string) (http.Header, error) {
+	if len(headers) == 0 {
+		return nil, nil
+	}
+	parsed := http.Header{}
+	for _, header := range headers {
+		parts := strings.SplitN(header, "=", 2)
+		name := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || !headerNameRegex.MatchString(name) {
+			return nil, fmt.Errorf("Invalid controller header %q, must be in the form of key=value", header)
+		}
+		if strings.ContainsAny(parts[1], "\r\n") {
+			return nil, fmt.Errorf("Invalid controller header %q, the value must not have line breaks", name)
+		}
+		parsed.Add(name, strings.TrimSpace(parts[1]))
+	}
+	return parsed, nil
+}
+
+// constLabels returns the labels added to all the metrics, the empty ones
+// are omitted by the exporter.
+func (o *VolumeExporterOptions) constLabels() prometheus.Labels {
+	return prometheus.Labels{
+		collector.NamespaceLabel: o.PodNamespace,
+		collector.PodLabel:       o.PodName,
+		collector.PVLabel:        o.PV,
+		collector.PoolLabel:      o.Pool,
+	}
+}
+
+// resolvePool resolves Pool from the PV using the kubernetes api if it is
+// not set and PoolFromAPI is set. The pool label is omitted if the pool
+// can't be resolved, the exporter is started anyway.
+func (o *VolumeExporterOptions) resolvePool() {
+	if len(o.Pool) != 0 || !o.PoolFromAPI {
+		return
+	}
+	if len(o.PV) == 0 {
+		logger.Warning("the pool can't be resolved without the persistent volume, pass it using --pv")
+		return
+	}
+	client := o.poolClient
+	if client == nil {
+		config, err := k8s.Config().Get()
+		if err == nil {
+			config.Timeout = o.ScrapeTimeout
+			client, err = clientset.NewForConfig(config)
+		}
+		if err != nil {
+			logger.Warningf("could not resolve the pool of %s: %v", o.PV, err)
+			return
+		}
+	}
+	pool, err := collector.ResolveCStorPool(client, o.PV)
+	if err != nil {
+		logger.Warningf("could not resolve the pool of %s: %v", o.PV, err)
+		return
+	}
+	if len(pool) == 0 {
+		logger.Warningf("the pool of %s is unknown, the pool label is omitted", o.PV)
+		return
+	}
+	o.Pool = pool
+}
+
+// latencyBuckets parses LatencyBuckets, it returns nil if it is not set so
+// that the default buckets are used.
+func (o *VolumeExporterOptions) latencyBuckets() ([]float64, error) {
+	var buckets []float64
+	for _, value := range splitList(o.LatencyBuckets) {
+		bucket, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid latency bucket %q: %w", value, err)
+		}
+		buckets = append(buckets, bucket)
+	}
+	if err := collector.ValidateLatencyBuckets(buckets); err != nil {
+		return nil, fmt.Errorf("Invalid latency buckets %q: %w", o.LatencyBuckets, err)
+	}
+	return buckets, nil
+}
+
+// setLatencyBuckets sets the buckets of the latency histograms of the
+// exporter if LatencyBuckets is set.
+func (o *VolumeExporterOptions) setLatencyBuckets(exporter *collector.VolumeStatsExporter) error {
+	buckets, err := o.latencyBuckets()
+	if err != nil || buckets == nil {
+		return err
+	}
+	return exporter.SetLatencyBuckets(buckets)
+}
+
+// validateStatsProtocol returns error if StatsProtocol is unknown or if it
+// is grpc for a cas type other than cstor, only the cstor targets expose
+// the gRPC stats api.
+func (o *VolumeExporterOptions) validateStatsProtocol() error {
+	switch o.StatsProtocol {
+	case statsProtocolDefault:
+		return nil
+	case statsProtocolGRPC:
+		if o.CASType != "cstor" {
+			return fmt.Errorf("Invalid stats protocol %q: only supported for cstor, not %s", o.StatsProtocol, o.CASType)
+		}
+		if len(o.GRPCAddr) == 0 {
+			return errors.New("Invalid stats protocol \"grpc\": grpc.addr is not set")
+		}
+		return nil
+	}
+	return fmt.Errorf("Invalid stats protocol %q: must be empty or grpc", o.StatsProtocol)
+}
+
+// validateServerTimeouts returns error if the write timeout of the http
+// server doesn't exceed the scrape timeout, as the scrapes would always
+// be cut off if the volume controller is slow. The scrape timeout bounds
+// all the requests of a scrape including the retries, so it only logs a
+// warning if the write timeout doesn't leave serverWriteMargin after it
+// for writing the response.
+func (o *VolumeExporterOptions) validateServerTimeouts() error {
+	if o.ServerReadTimeout < 0 || o.ServerWriteTimeout < 0 {
+		return fmt.Errorf("Invalid server timeouts %v and %v: must not be negative", o.ServerReadTimeout, o.ServerWriteTimeout)
+	}
+	if o.ServerWriteTimeout == 0 {
+		return nil
+	}
+	if o.ServerWriteTimeout <= o.ScrapeTimeout {
+		return fmt.Errorf("Invalid server write timeout %v: must exceed the scrape timeout %v", o.ServerWriteTimeout, o.ScrapeTimeout)
+	}
+	if o.ServerWriteTimeout < o.ScrapeTimeout+serverWriteMargin {
+		logger.Warningf("The server write timeout %v leaves less than %v for writing the response after the scrape timeout %v", o.ServerWriteTimeout, serverWriteMargin, o.ScrapeTimeout)
+	}
+	return nil
+}
+
+// validateProbeAllowedTargets returns error if one of ProbeAllowedTargets is
+// empty, or has a prefix length but is not a valid CIDR.
+func (o *VolumeExporterOptions) validateProbeAllowedTargets() error {
+	for _, allowed := range o.ProbeAllowedTargets {
+		if len(allowed) == 0 {
+			return errors.New("Invalid probe-allowed-targets: empty target")
+		}
+		if strings.Contains(allowed, "/") {
+			if _, _, err := net.ParseCIDR(allowed); err != nil {
+				return fmt.Errorf("Invalid probe-allowed-targets %q: %w", allowed, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateListenAddress returns error if the listen address is not in the
+// form of host:port or the metrics path is not an absolute path.
+func (o *VolumeExporterOptions) validateListenAddress() error {
+	_, port, err := net.SplitHostPort(o.ListenAddress)
+	if err != nil {
+		return fmt.Errorf("Invalid listen address %q: %v", o.ListenAddress, err)
+	}
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return fmt.Errorf("Invalid listen address %q: invalid port %q", o.ListenAddress, port)
+	}
+	if !strings.HasPrefix(o.MetricsPath, "/") {
+		return fmt.Errorf("Invalid metrics path %q: must start with /", o.MetricsPath)
+	}
+	return nil
+}
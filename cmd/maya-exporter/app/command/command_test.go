@@ -2,10 +2,20 @@ package command
 
 import (
 	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
+	"github.com/openebs/maya/cmd/maya-exporter/app/collector"
+	apis "github.com/openebs/maya/pkg/apis/openebs.io/v1alpha1"
+	openebsfake "github.com/openebs/maya/pkg/client/generated/clientset/internalclientset/fake"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 var (
@@ -18,6 +28,7 @@ It can be deployed alongside the openebs volume or pool containers as sidecars.`
 )
 
 func TestRegisterJivaStatsExporter(t *testing.T) {
+	defer collector.Reset()
 	cases := map[string]struct {
 		option *VolumeExporterOptions
 		output error
@@ -28,27 +39,535 @@ func TestRegisterJivaStatsExporter(t *testing.T) {
 			},
 			output: nil,
 		},
+		"URL without scheme": {
+			option: &VolumeExporterOptions{
+				ControllerAddress: "localhost:9501",
+			},
+			output: nil,
+		},
 		"InvalidURL": {
 			option: &VolumeExporterOptions{
-				ControllerAddress: "localhost",
+				ControllerAddress: "http://:9501",
 			},
-			output: errors.New("Error in parsing the URI"),
+			output: collector.ErrInvalidURL,
 		},
 		"EmptyURL": {
 			option: &VolumeExporterOptions{
 				ControllerAddress: "",
 			},
-			output: errors.New("Error in parsing the URI"),
+			output: collector.ErrInvalidURL,
 		},
 	}
 
 	for name, tt := range cases {
 		t.Run(name, func(t *testing.T) {
 			got := tt.option.RegisterJivaStatsExporter()
-			if !reflect.DeepEqual(got, tt.output) {
+			if !errors.Is(got, tt.output) {
 				t.Fatalf("RegisterJivaStatsExporter() => [%v], want [%v]", got, tt.output)
 			}
 		})
 	}
 
 }
+
+func TestRegisterJivaVolumesStatsExporter(t *testing.T) {
+	cases := map[string]struct {
+		option *VolumeExporterOptions
+	}{
+		"Volume without name": {
+			option: &VolumeExporterOptions{
+				Volumes: []string{"http://localhost:9501"},
+			},
+		},
+		"Volume with empty name": {
+			option: &VolumeExporterOptions{
+				Volumes: []string{"=http://localhost:9501"},
+			},
+		},
+		"Volume with invalid URL": {
+			option: &VolumeExporterOptions{
+				Volumes: []string{"vol1=ftp://localhost"},
+			},
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := tt.option.RegisterJivaStatsExporter(); got == nil {
+				t.Fatalf("RegisterJivaStatsExporter() => nil, want error")
+			}
+		})
+	}
+}
+
+func TestRegisterJivaStatsExporterTwice(t *testing.T) {
+	defer collector.Reset()
+	option := &VolumeExporterOptions{
+		CASType:           "jiva",
+		ControllerAddress: "http://localhost:9501",
+	}
+	for i := 0; i < 2; i++ {
+		if err := option.RegisterJivaStatsExporter(); err != nil {
+			t.Fatalf("RegisterJivaStatsExporter() => got error %v on attempt %d, want nil", err, i+1)
+		}
+	}
+}
+
+func TestValidateListenAddress(t *testing.T) {
+	cases := map[string]struct {
+		address, path string
+		isErr         bool
+	}{
+		"Port only":          {":9500", "/metrics", false},
+		"Host and port":      {"127.0.0.1:9500", "/metrics", false},
+		"IPv6 host and port": {"[::1]:9500", "/metrics", false},
+		"Missing port":       {"127.0.0.1", "/metrics", true},
+		"Invalid port":       {":port", "/metrics", true},
+		"Port out of range":  {":95000", "/metrics", true},
+		"Relative path":      {":9500", "metrics", true},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			o := &VolumeExporterOptions{ListenAddress: tt.address, MetricsPath: tt.path}
+			err := o.validateListenAddress()
+			if (err != nil) != tt.isErr {
+				t.Fatalf("validateListenAddress(%q, %q) => got %v, want error %v", tt.address, tt.path, err, tt.isErr)
+			}
+		})
+	}
+}
+
+func TestValidateProbeAllowedTargets(t *testing.T) {
+	cases := map[string]struct {
+		targets []string
+		isErr   bool
+	}{
+		"Probe is disabled":     {},
+		"Hosts and CIDRs":       {targets: []string{"jiva-ctrl", "jiva-ctrl:9501", "10.0.0.0/8"}},
+		"Empty target":          {targets: []string{"jiva-ctrl", ""}, isErr: true},
+		"Invalid prefix length": {targets: []string{"10.0.0.0/33"}, isErr: true},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			o := &VolumeExporterOptions{ProbeAllowedTargets: tt.targets}
+			err := o.validateProbeAllowedTargets()
+			if (err != nil) != tt.isErr {
+				t.Fatalf("validateProbeAllowedTargets(%q) => got %v, want error %v", tt.targets, err, tt.isErr)
+			}
+		})
+	}
+}
+
+func TestValidateServerTimeouts(t *testing.T) {
+	cases := map[string]struct {
+		read, write time.Duration
+		isErr       bool
+	}{
+		"Default timeouts":                  {read: serverReadTimeout, write: serverWriteTimeout},
+		"Timeouts are disabled":             {read: 0, write: 0},
+		"Write timeout below the margin":    {read: serverReadTimeout, write: scrapeTimeout + serverWriteMargin/2},
+		"Write timeout equal to the scrape": {read: serverReadTimeout, write: scrapeTimeout, isErr: true},
+		"Write timeout below the scrape":    {read: serverReadTimeout, write: time.Second, isErr: true},
+		"Negative read timeout":             {read: -time.Second, write: serverWriteTimeout, isErr: true},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			o := &VolumeExporterOptions{
+				ScrapeTimeout:      scrapeTimeout,
+				ServerReadTimeout:  tt.read,
+				ServerWriteTimeout: tt.write,
+			}
+			err := o.validateServerTimeouts()
+			if (err != nil) != tt.isErr {
+				t.Fatalf("validateServerTimeouts(%v, %v) => got %v, want error %v", tt.read, tt.write, err, tt.isErr)
+			}
+		})
+	}
+}
+
+func TestListenFlags(t *testing.T) {
+	cmd, _ := NewCmdVolumeExporter()
+	if err := cmd.Flags().Parse([]string{"--listen-address=:9600", "--metrics-path=/stats"}); err != nil {
+		t.Fatalf("failed parsing the flags: %v", err)
+	}
+	address, _ := cmd.Flags().GetString("listen.addr")
+	path, _ := cmd.Flags().GetString("listen.path")
+	if address != ":9600" || path != "/stats" {
+		t.Fatalf("got listen address %q and metrics path %q, want %q and %q", address, path, ":9600", "/stats")
+	}
+}
+
+func TestConfigureJivaCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "maya-exporter")
+	if err != nil {
+		t.Fatalf("failed creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	passwordFile := filepath.Join(dir, "password")
+	if err := ioutil.WriteFile(passwordFile, []byte("secret\n"), 0600); err != nil {
+		t.Fatalf("failed writing password file: %v", err)
+	}
+
+	cases := map[string]struct {
+		option   *VolumeExporterOptions
+		password string
+		isErr    bool
+	}{
+		"Password passed using flag": {
+			option:   &VolumeExporterOptions{Username: "admin", Password: "secret"},
+			password: "secret",
+		},
+		"Password read from file": {
+			option:   &VolumeExporterOptions{Username: "admin", PasswordFile: passwordFile},
+			password: "secret",
+		},
+		"Password file doesn't exist": {
+			option: &VolumeExporterOptions{Username: "admin", PasswordFile: filepath.Join(dir, "missing")},
+			isErr:  true,
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			var j collector.Jiva
+			err := tt.option.configureJiva(&j)
+			if (err != nil) != tt.isErr {
+				t.Fatalf("configureJiva() => got error %v, want error %v", err, tt.isErr)
+			}
+			if err == nil && (j.Username != "admin" || j.Password != tt.password) {
+				t.Fatalf("configureJiva() => got credentials %q, %q, want %q, %q", j.Username, j.Password, "admin", tt.password)
+			}
+		})
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	cases := map[string]struct {
+		headers []string
+		parsed  http.Header
+		isErr   bool
+	}{
+		"No headers": {},
+		"Repeated headers": {
+			headers: []string{"x-request-id=maya-exporter", "X-Route = a=b", "x-route=c", "x-empty="},
+			parsed:  http.Header{"X-Request-Id": {"maya-exporter"}, "X-Route": {"a=b", "c"}, "X-Empty": {""}},
+		},
+		"Header without value": {headers: []string{"x-request-id"}, isErr: true},
+		"Header without name":  {headers: []string{"=value"}, isErr: true},
+		"Invalid name":         {headers: []string{"x request=value"}, isErr: true},
+		"Value with new line":  {headers: []string{"x-request-id=a\r\nX-Injected: b"}, isErr: true},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			parsed, err := parseHeaders(tt.headers)
+			if (err != nil) != tt.isErr {
+				t.Fatalf("parseHeaders(%q) => got error %v, want error %v", tt.headers, err, tt.isErr)
+			}
+			if !reflect.DeepEqual(parsed, tt.parsed) {
+				t.Fatalf("parseHeaders(%q) => got %v, want %v", tt.headers, parsed, tt.parsed)
+			}
+		})
+	}
+}
+
+func TestConfigureJivaProxyURL(t *testing.T) {
+	cases := map[string]struct {
+		proxyURL string
+		host     string
+		isErr    bool
+	}{
+		"Proxy is not set":      {},
+		"Proxy url is valid":    {proxyURL: "http://proxy:3128", host: "proxy:3128"},
+		"Proxy url has no host": {proxyURL: "proxy", isErr: true},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			var j collector.Jiva
+			err := (&VolumeExporterOptions{ProxyURL: tt.proxyURL}).configureJiva(&j)
+			if (err != nil) != tt.isErr {
+				t.Fatalf("configureJiva() => got error %v, want error %v", err, tt.isErr)
+			}
+			if tt.isErr {
+				return
+			}
+			if len(tt.host) == 0 {
+				if j.ProxyURL != nil {
+					t.Fatalf("configureJiva() => got proxy %v, want none", j.ProxyURL)
+				}
+				return
+			}
+			if j.ProxyURL == nil || j.ProxyURL.Host != tt.host {
+				t.Fatalf("configureJiva() => got proxy %v, want host %q", j.ProxyURL, tt.host)
+			}
+		})
+	}
+}
+
+func TestConfigureJivaHTTP2(t *testing.T) {
+	cases := map[string]struct {
+		force, disable bool
+		isErr          bool
+	}{
+		"HTTP/2 is not configured":           {},
+		"HTTP/2 is forced":                   {force: true},
+		"HTTP/2 is disabled":                 {disable: true},
+		"HTTP/2 is both forced and disabled": {force: true, disable: true, isErr: true},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			var j collector.Jiva
+			err := (&VolumeExporterOptions{ForceHTTP2: tt.force, DisableHTTP2: tt.disable}).configureJiva(&j)
+			if (err != nil) != tt.isErr {
+				t.Fatalf("configureJiva() => got error %v, want error %v", err, tt.isErr)
+			}
+			if err == nil && (j.ForceHTTP2 != tt.force || j.DisableHTTP2 != tt.disable) {
+				t.Fatalf("configureJiva() => got force %v and disable %v, want %v and %v", j.ForceHTTP2, j.DisableHTTP2, tt.force, tt.disable)
+			}
+		})
+	}
+}
+
+func TestConfigureJivaClientCert(t *testing.T) {
+	cases := map[string]struct {
+		certFile, keyFile string
+		isErr             bool
+	}{
+		"Client certificate is not set":    {},
+		"Client key is not set":            {certFile: "client.crt", isErr: true},
+		"Client certificate doesn't exist": {certFile: "missing.crt", keyFile: "missing.key", isErr: true},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			var j collector.Jiva
+			err := (&VolumeExporterOptions{ClientCertFile: tt.certFile, ClientKeyFile: tt.keyFile}).configureJiva(&j)
+			if (err != nil) != tt.isErr {
+				t.Fatalf("configureJiva() => got error %v, want error %v", err, tt.isErr)
+			}
+			if err == nil && j.TLSConfig != nil {
+				t.Fatalf("configureJiva() => got tls config %v, want none", j.TLSConfig)
+			}
+		})
+	}
+}
+
+func TestConfigureJivaCredentialsFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "maya-exporter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "credentials")
+	if err := ioutil.WriteFile(path, []byte("username=admin\npassword=s3cret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]struct {
+		option *VolumeExporterOptions
+		isErr  bool
+	}{
+		"Credentials are read from the file": {
+			option: &VolumeExporterOptions{CredentialsFile: path},
+		},
+		"Credentials file doesn't exist": {
+			option: &VolumeExporterOptions{CredentialsFile: filepath.Join(dir, "missing")},
+			isErr:  true,
+		},
+		"Credentials file along with the username": {
+			option: &VolumeExporterOptions{CredentialsFile: path, Username: "admin"},
+			isErr:  true,
+		},
+		"Credentials file along with the client certificate": {
+			option: &VolumeExporterOptions{CredentialsFile: path, ClientCertFile: "client.crt", ClientKeyFile: "client.key"},
+			isErr:  true,
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			var j collector.Jiva
+			err := tt.option.configureJiva(&j)
+			if (err != nil) != tt.isErr {
+				t.Fatalf("configureJiva() => got error %v, want error %v", err, tt.isErr)
+			}
+			if err != nil {
+				return
+			}
+			if j.Username != "admin" || j.Password != "s3cret" || j.CredentialsFile != path {
+				t.Fatalf("configureJiva() => got credentials %q, %q from %q, want %q, %q from %q", j.Username, j.Password, j.CredentialsFile, "admin", "s3cret", path)
+			}
+		})
+	}
+}
+
+func TestLatencyBuckets(t *testing.T) {
+	cases := map[string]struct {
+		latencyBuckets string
+		buckets        []float64
+		isErr          bool
+	}{
+		"Buckets are not set":      {},
+		"Buckets are valid":        {latencyBuckets: "0.0001, 0.001,0.01", buckets: []float64{0.0001, 0.001, 0.01}},
+		"Bucket is not a number":   {latencyBuckets: "0.001,1ms", isErr: true},
+		"Buckets are not in order": {latencyBuckets: "0.01,0.001", isErr: true},
+		"Bucket is not positive":   {latencyBuckets: "0,0.001", isErr: true},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			buckets, err := (&VolumeExporterOptions{LatencyBuckets: tt.latencyBuckets}).latencyBuckets()
+			if (err != nil) != tt.isErr {
+				t.Fatalf("latencyBuckets() => got error %v, want error %v", err, tt.isErr)
+			}
+			if !reflect.DeepEqual(buckets, tt.buckets) {
+				t.Fatalf("latencyBuckets() => got %v, want %v", buckets, tt.buckets)
+			}
+		})
+	}
+}
+
+func TestPodLabelsFlags(t *testing.T) {
+	defer os.Unsetenv(podNamespaceEnv)
+	defer os.Unsetenv(podNameEnv)
+	os.Setenv(podNamespaceEnv, "openebs")
+	os.Setenv(podNameEnv, "vol1-ctrl-0")
+
+	cases := map[string]struct {
+		args   []string
+		labels prometheus.Labels
+	}{
+		"Labels are read from the env": {
+			labels: prometheus.Labels{"namespace": "openebs", "pod": "vol1-ctrl-0", "pv": "", "pool": ""},
+		},
+		"Flags take precedence over the env": {
+			args:   []string{"--pod-namespace=default", "--pv=pvc-1"},
+			labels: prometheus.Labels{"namespace": "default", "pod": "vol1-ctrl-0", "pv": "pvc-1", "pool": ""},
+		},
+		"Pool is set": {
+			args:   []string{"--pv=pvc-1", "--pool=cstor-sparse-pool"},
+			labels: prometheus.Labels{"namespace": "openebs", "pod": "vol1-ctrl-0", "pv": "pvc-1", "pool": "cstor-sparse-pool"},
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			cmd, _ := NewCmdVolumeExporter()
+			if err := cmd.Flags().Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			options := VolumeExporterOptions{
+				PodNamespace: cmd.Flags().Lookup("pod-namespace").Value.String(),
+				PodName:      cmd.Flags().Lookup("pod-name").Value.String(),
+				PV:           cmd.Flags().Lookup("pv").Value.String(),
+				Pool:         cmd.Flags().Lookup("pool").Value.String(),
+			}
+			if got := options.constLabels(); !reflect.DeepEqual(got, tt.labels) {
+				t.Fatalf("constLabels() => got %v, want %v", got, tt.labels)
+			}
+		})
+	}
+}
+
+func TestResolvePool(t *testing.T) {
+	cases := map[string]struct {
+		options VolumeExporterOptions
+		want    string
+	}{
+		"Pool is resolved from the api": {
+			options: VolumeExporterOptions{PV: "pvc-1", PoolFromAPI: true},
+			want:    "cstor-sparse-pool",
+		},
+		"Pool passed using the flag is kept": {
+			options: VolumeExporterOptions{PV: "pvc-1", Pool: "pool1", PoolFromAPI: true},
+			want:    "pool1",
+		},
+		"Pool is not resolved if it is not enabled": {
+			options: VolumeExporterOptions{PV: "pvc-1"},
+		},
+		"Pool is not resolved without the pv": {
+			options: VolumeExporterOptions{PoolFromAPI: true},
+		},
+		"Pool of an unknown pv is omitted": {
+			options: VolumeExporterOptions{PV: "pvc-2", PoolFromAPI: true},
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			tt.options.poolClient = openebsfake.NewSimpleClientset(
+				&apis.CStorVolumeReplica{ObjectMeta: metav1.ObjectMeta{
+					Name:      "pvc-1-pool-a",
+					Namespace: "openebs",
+					Labels: map[string]string{
+						"openebs.io/persistent-volume": "pvc-1",
+						"cstorpool.openebs.io/name":    "pool-a",
+					},
+				}},
+				&apis.CStorPool{ObjectMeta: metav1.ObjectMeta{
+					Name:   "pool-a",
+					Labels: map[string]string{"openebs.io/storage-pool-claim": "cstor-sparse-pool"},
+				}},
+			)
+			tt.options.resolvePool()
+			if tt.options.Pool != tt.want {
+				t.Fatalf("resolvePool() => got pool %q, want %q", tt.options.Pool, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateStatsProtocol(t *testing.T) {
+	cases := map[string]struct {
+		casType  string
+		protocol string
+		addr     string
+		isErr    bool
+	}{
+		"Default protocol of jiva":  {casType: "jiva"},
+		"Default protocol of cstor": {casType: "cstor"},
+		"grpc with cstor":           {casType: "cstor", protocol: "grpc", addr: "localhost:7777"},
+		"grpc without the address":  {casType: "cstor", protocol: "grpc", isErr: true},
+		"grpc with jiva":            {casType: "jiva", protocol: "grpc", addr: "localhost:7777", isErr: true},
+		"Unknown protocol":          {casType: "cstor", protocol: "thrift", isErr: true},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			o := &VolumeExporterOptions{CASType: tt.casType, StatsProtocol: tt.protocol, GRPCAddr: tt.addr}
+			if err := o.validateStatsProtocol(); (err != nil) != tt.isErr {
+				t.Fatalf("validateStatsProtocol() => got error %v, want error %v", err, tt.isErr)
+			}
+		})
+	}
+}
+
+func TestRegisterReplicaDiskCollector(t *testing.T) {
+	defer collector.Reset()
+	cases := map[string]struct {
+		paths []string
+		isErr bool
+	}{
+		"Valid replica paths":          {paths: []string{"rep1=/var/openebs/rep1", "rep2=/var/openebs/rep2"}},
+		"Replica path without name":    {paths: []string{"/var/openebs/rep1"}, isErr: true},
+		"Replica path with empty name": {paths: []string{"=/var/openebs/rep1"}, isErr: true},
+		"Replica without path":         {paths: []string{"rep1="}, isErr: true},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			option := &VolumeExporterOptions{ReplicaPaths: tt.paths}
+			if err := option.registerReplicaDiskCollector(); (err != nil) != tt.isErr {
+				t.Fatalf("registerReplicaDiskCollector() => got error %v, want error %v", err, tt.isErr)
+			}
+		})
+	}
+}
+
+func TestRegisterReplicaDiskCollectorNamespace(t *testing.T) {
+	defer collector.Reset()
+	option := &VolumeExporterOptions{MetricsNamespace: "maya_jiva", ReplicaPaths: []string{"rep1=/var/openebs/missing"}}
+	if err := option.registerReplicaDiskCollector(); err != nil {
+		t.Fatalf("registerReplicaDiskCollector() : unexpected error %v", err)
+	}
+	mfs, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("Gather() : unexpected error %v", err)
+	}
+	for _, mf := range mfs {
+		if mf.GetName() == "maya_jiva_replica_disk_walk_errors_total" {
+			return
+		}
+	}
+	t.Fatal("registerReplicaDiskCollector() => maya_jiva_replica_disk_walk_errors_total is not registered")
+}
@@ -0,0 +1,210 @@
+package command
+
+import (
+	"bufio"
+	"context"
+	goflag "flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/openebs/maya/cmd/maya-exporter/app/logger"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// reloadableSetting is a setting of the config file which is applied
+// again when the config file is reloaded on SIGHUP.
+type reloadableSetting struct {
+	// def is the value applied if the setting is removed from the
+	// config file.
+	def   string
+	apply func(o *VolumeExporterOptions, value string) error
+}
+
+// reloadableSettings are the settings of the config file which are
+// reloaded on SIGHUP, i.e. the disabled metrics, the metrics allowlist
+// whose file is read again and the verbosity of the logs. All the other settings are applied only at the start and require
+// a restart of the exporter.
+var reloadableSettings = map[string]reloadableSetting{
+	"disable-metrics": {
+		apply: func(o *VolumeExporterOptions, value string) error {
+			o.DisabledMetrics = splitList(value)
+			if o.exporter != nil {
+				o.exporter.DisableMetrics(o.DisabledMetrics)
+			}
+			return nil
+		},
+	},
+	"metrics-allowlist-file": {
+		apply: func(o *VolumeExporterOptions, value string) error {
+			o.MetricsAllowlistFile = value
+			if o.exporter != nil {
+				return o.allowMetrics(o.exporter)
+			}
+			return nil
+		},
+	},
+	"v": {
+		def: "0",
+		apply: func(o *VolumeExporterOptions, value string) error {
+			return goflag.Set("v", value)
+		},
+	},
+}
+
+// readConfigFile reads the settings of the exporter from the given file.
+// A setting is a flag of the exporter in the form of name=value per line,
+// e.g. disable-metrics=replica_read_iops, the leading dashes of the name
+// are optional. Empty lines and lines starting with # are ignored.
+func readConfigFile(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	settings := map[string]string{}
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		name := strings.TrimLeft(strings.TrimSpace(parts[0]), "-")
+		if len(parts) != 2 || len(name) == 0 {
+			return nil, fmt.Errorf("Invalid setting %q at line %d of %s, must be in the form of name=value", line, n, path)
+		}
+		settings[name] = strings.TrimSpace(parts[1])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// applyConfigFile sets the flags of cmd to the settings of ConfigFile,
+// the flags set on the command line take precedence over the settings.
+func (o *VolumeExporterOptions) applyConfigFile(cmd *cobra.Command) error {
+	settings, err := readConfigFile(o.ConfigFile)
+	if err != nil {
+		return err
+	}
+	o.commandLineFlags = map[string]bool{}
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		o.commandLineFlags[f.Name] = true
+	})
+	for name, value := range settings {
+		if cmd.Flags().Lookup(name) == nil {
+			return fmt.Errorf("Unknown setting %q in %s", name, o.ConfigFile)
+		}
+		if o.commandLineFlags[name] {
+			logger.Infof("setting %q of %s is ignored, it is set on the command line", name, o.ConfigFile)
+			continue
+		}
+		if err := cmd.Flags().Set(name, value); err != nil {
+			return fmt.Errorf("Invalid setting %q in %s: %w", name, o.ConfigFile, err)
+		}
+	}
+	o.settings = settings
+	return nil
+}
+
+// reloadConfigFile reads ConfigFile again and applies the reloadable
+// settings which are not set on the command line. A warning is logged for
+// the other settings which are changed, as these require a restart.
+func (o *VolumeExporterOptions) reloadConfigFile() error {
+	settings, err := readConfigFile(o.ConfigFile)
+	if err != nil {
+		return err
+	}
+	for name, setting := range reloadableSettings {
+		if o.commandLineFlags[name] {
+			continue
+		}
+		value, ok := settings[name]
+		if !ok {
+			value = setting.def
+		}
+		if err := setting.apply(o, value); err != nil {
+			return fmt.Errorf("Invalid setting %q in %s: %w", name, o.ConfigFile, err)
+		}
+	}
+	changed := map[string]bool{}
+	for name, value := range settings {
+		if old, ok := o.settings[name]; !ok || old != value {
+			changed[name] = true
+		}
+	}
+	for name := range o.settings {
+		if _, ok := settings[name]; !ok {
+			changed[name] = true
+		}
+	}
+	for name := range changed {
+		if _, ok := reloadableSettings[name]; !ok {
+			logger.Warningf("setting %q of %s is changed, restart the exporter to apply it", name, o.ConfigFile)
+		}
+	}
+	return nil
+}
+
+// reloadOnSignal reloads ConfigFile on each of the given signals until
+// ctx is done. The scrapes in progress complete with the old settings.
+func (o *VolumeExporterOptions) reloadOnSignal(ctx context.Context, sig ...os.Signal) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, sig...)
+	defer signal.Stop(signals)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case s := <-signals:
+			logger.Infof("Received %v, reloading %s", s, o.ConfigFile)
+			if err := o.reloadConfigFile(); err != nil {
+				logger.Errorf("could not reload %s: %v", o.ConfigFile, err)
+			}
+		}
+	}
+}
+
+// splitList splits the comma separated list of values, the empty values
+// are skipped.
+func splitList(list string) []string {
+	var values []string
+	for _, value := range strings.Split(list, ",") {
+		if value = strings.TrimSpace(value); len(value) != 0 {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// readMetricsAllowlist reads the names of the metrics from the given
+// file, one per line with or without the namespace. Empty lines and lines
+// starting with # are ignored. It returns error if no metric is listed as
+// none would be exported.
+func readMetricsAllowlist(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var names []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		name := strings.TrimSpace(scanner.Text())
+		if len(name) == 0 || strings.HasPrefix(name, "#") {
+			continue
+		}
+		names = append(names, name)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("No metric is listed in the allowlist %s", path)
+	}
+	return names, nil
+}
@@ -0,0 +1,213 @@
+package command
+
+import (
+	goflag "flag"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/openebs/maya/cmd/maya-exporter/app/collector"
+)
+
+// writeConfigFile writes the given config file in a new temp dir and
+// returns its path, the dir is removed by the returned func.
+func writeConfigFile(t *testing.T, content string) (string, func()) {
+	dir, err := ioutil.TempDir("", "maya-exporter")
+	if err != nil {
+		t.Fatalf("failed creating temp dir: %v", err)
+	}
+	path := filepath.Join(dir, "config")
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed writing %s: %v", path, err)
+	}
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func TestReadConfigFile(t *testing.T) {
+	cases := map[string]struct {
+		content  string
+		settings map[string]string
+		isErr    bool
+	}{
+		"Settings with comments and empty lines": {
+			content:  "# exporter settings\n\ndisable-metrics=reads,writes\n--scrape.timeout = 10s\n",
+			settings: map[string]string{"disable-metrics": "reads,writes", "scrape.timeout": "10s"},
+		},
+		"Empty value": {
+			content:  "disable-metrics=",
+			settings: map[string]string{"disable-metrics": ""},
+		},
+		"Setting without value": {
+			content: "disable-metrics",
+			isErr:   true,
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			path, cleanup := writeConfigFile(t, tt.content)
+			defer cleanup()
+			settings, err := readConfigFile(path)
+			if (err != nil) != tt.isErr {
+				t.Fatalf("readConfigFile() => got error %v, want error %v", err, tt.isErr)
+			}
+			if err == nil && !reflect.DeepEqual(settings, tt.settings) {
+				t.Fatalf("readConfigFile() => got %v, want %v", settings, tt.settings)
+			}
+		})
+	}
+}
+
+func TestApplyConfigFile(t *testing.T) {
+	path, cleanup := writeConfigFile(t, "disable-metrics=reads\nscrape.timeout=10s\ncas.type=cstor\n")
+	defer cleanup()
+	cmd, _ := NewCmdVolumeExporter()
+	if err := cmd.Flags().Parse([]string{"--cas.type=jiva", "--config-file=" + path}); err != nil {
+		t.Fatal(err)
+	}
+	options := VolumeExporterOptions{ConfigFile: path}
+	if err := options.applyConfigFile(cmd); err != nil {
+		t.Fatalf("applyConfigFile() => %v", err)
+	}
+	// the flag set on the command line takes precedence over the file.
+	if got := cmd.Flags().Lookup("cas.type").Value.String(); got != "jiva" {
+		t.Errorf("got cas.type %q, want %q", got, "jiva")
+	}
+	if got := cmd.Flags().Lookup("scrape.timeout").Value.String(); got != "10s" {
+		t.Errorf("got scrape.timeout %q, want %q", got, "10s")
+	}
+	if got := cmd.Flags().Lookup("disable-metrics").Value.String(); got != "[reads]" {
+		t.Errorf("got disable-metrics %q, want %q", got, "[reads]")
+	}
+}
+
+func TestApplyConfigFileUnknownSetting(t *testing.T) {
+	path, cleanup := writeConfigFile(t, "unknown=1\n")
+	defer cleanup()
+	cmd, _ := NewCmdVolumeExporter()
+	options := VolumeExporterOptions{ConfigFile: path}
+	if err := options.applyConfigFile(cmd); err == nil {
+		t.Fatal("applyConfigFile() => no error for an unknown setting")
+	}
+}
+
+func TestReloadConfigFile(t *testing.T) {
+	path, cleanup := writeConfigFile(t, "disable-metrics=reads\n")
+	defer cleanup()
+	controllerURL, _ := url.Parse("http://localhost:9501")
+	options := VolumeExporterOptions{
+		ConfigFile: path,
+		settings:   map[string]string{"disable-metrics": "reads"},
+		exporter:   collector.NewJivaStatsExporter(controllerURL, "jiva"),
+	}
+	defer goflag.Set("v", goflag.Lookup("v").Value.String())
+
+	cases := []struct {
+		name     string
+		content  string
+		disabled []string
+		v        string
+	}{
+		{name: "Disabled metrics are changed", content: "disable-metrics=writes,reads\nv=4\n", disabled: []string{"writes", "reads"}, v: "4"},
+		{name: "Settings which require a restart are not applied", content: "disable-metrics=writes\nscrape.timeout=1s\n", disabled: []string{"writes"}, v: "0"},
+		{name: "Settings are removed", content: "", v: "0"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ioutil.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := options.reloadConfigFile(); err != nil {
+				t.Fatalf("reloadConfigFile() => %v", err)
+			}
+			if !reflect.DeepEqual(options.DisabledMetrics, tt.disabled) {
+				t.Errorf("got disabled metrics %v, want %v", options.DisabledMetrics, tt.disabled)
+			}
+			if got := goflag.Lookup("v").Value.String(); got != tt.v {
+				t.Errorf("got v %q, want %q", got, tt.v)
+			}
+		})
+	}
+}
+
+func TestReloadConfigFileCommandLineFlags(t *testing.T) {
+	path, cleanup := writeConfigFile(t, "disable-metrics=writes\n")
+	defer cleanup()
+	options := VolumeExporterOptions{
+		ConfigFile:       path,
+		DisabledMetrics:  []string{"reads"},
+		commandLineFlags: map[string]bool{"disable-metrics": true, "v": true},
+	}
+	if err := options.reloadConfigFile(); err != nil {
+		t.Fatalf("reloadConfigFile() => %v", err)
+	}
+	if !reflect.DeepEqual(options.DisabledMetrics, []string{"reads"}) {
+		t.Fatalf("got disabled metrics %v, want the ones set on the command line", options.DisabledMetrics)
+	}
+}
+
+func TestReadMetricsAllowlist(t *testing.T) {
+	cases := map[string]struct {
+		content string
+		names   []string
+		isErr   bool
+	}{
+		"Names with comments and empty lines": {
+			content: "# volume metrics\nreads\n\n  openebs_writes  \nvolume_up\n",
+			names:   []string{"reads", "openebs_writes", "volume_up"},
+		},
+		"No metric is listed": {
+			content: "# none\n\n",
+			isErr:   true,
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			path, cleanup := writeConfigFile(t, tt.content)
+			defer cleanup()
+			names, err := readMetricsAllowlist(path)
+			if (err != nil) != tt.isErr {
+				t.Fatalf("readMetricsAllowlist() => got error %v, want error %v", err, tt.isErr)
+			}
+			if !reflect.DeepEqual(names, tt.names) {
+				t.Fatalf("readMetricsAllowlist() => got %v, want %v", names, tt.names)
+			}
+		})
+	}
+}
+
+func TestReloadMetricsAllowlist(t *testing.T) {
+	allowlist, cleanup := writeConfigFile(t, "reads\n")
+	defer cleanup()
+	path, cleanupConfig := writeConfigFile(t, "metrics-allowlist-file="+allowlist+"\n")
+	defer cleanupConfig()
+	controllerURL, _ := url.Parse("http://localhost:9501")
+	options := VolumeExporterOptions{
+		ConfigFile: path,
+		exporter:   collector.NewJivaStatsExporter(controllerURL, "jiva"),
+	}
+	if err := options.reloadConfigFile(); err != nil {
+		t.Fatalf("reloadConfigFile() => %v", err)
+	}
+	if options.MetricsAllowlistFile != allowlist {
+		t.Fatalf("got metrics allowlist file %q, want %q", options.MetricsAllowlistFile, allowlist)
+	}
+	// the allowlist is read again on each reload.
+	if err := ioutil.WriteFile(allowlist, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := options.reloadConfigFile(); err == nil {
+		t.Fatalf("reloadConfigFile() => nil, want error for the empty allowlist")
+	}
+	if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := options.reloadConfigFile(); err != nil {
+		t.Fatalf("reloadConfigFile() => %v", err)
+	}
+	if len(options.MetricsAllowlistFile) != 0 {
+		t.Fatalf("got metrics allowlist file %q, want none", options.MetricsAllowlistFile)
+	}
+}
@@ -0,0 +1,120 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/openebs/maya/cmd/maya-exporter/app/collector"
+	"github.com/openebs/maya/pkg/util"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+	"github.com/spf13/cobra"
+)
+
+// NewCmdDump is used to create the dump command which collects the stats
+// of a jiva controller once and writes the metrics in the prometheus text
+// format, without starting the http server.
+func NewCmdDump(options *VolumeExporterOptions) *cobra.Command {
+	var output string
+	cmd := &cobra.Command{
+		Use:   "dump",
+		Short: "Write a snapshot of the metrics of a jiva controller",
+		Long: `dump collects the stats of a jiva controller once and writes the
+metrics in the prometheus text format to stdout or a file, then exits. It
+exits with status 1 if the stats could not be collected.`,
+		Example: `maya-exporter dump --controller=http://10.0.0.5:9501 -o metrics.txt`,
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			util.CheckErr(options.runDump(cmd.OutOrStdout(), output), util.Fatal)
+		},
+	}
+	cmd.Flags().StringVar(&options.ControllerAddress, "controller", options.ControllerAddress,
+		"Address of the jiva controller whose stats are collected, use unix://<path> for a unix socket")
+	cmd.Flags().StringVarP(&output, "output", "o", output,
+		"File to which the metrics are written, stdout if it is not set")
+	AddMetricsNamespaceFlag(cmd, &options.MetricsNamespace)
+	AddCounterModeFlag(cmd, &options.CounterMode)
+	AddScrapeTimeoutFlag(cmd, &options.ScrapeTimeout)
+	AddScrapeRetriesFlag(cmd, &options.ScrapeRetries, &options.RetryBackoff)
+	AddMaxRedirectsFlag(cmd, &options.MaxRedirects)
+	AddCAFileFlag(cmd, &options.CAFile)
+	AddClientCertFlags(cmd, &options.ClientCertFile, &options.ClientKeyFile)
+	AddCredentialsFileFlag(cmd, &options.CredentialsFile)
+	AddHTTP2Flags(cmd, &options.ForceHTTP2, &options.DisableHTTP2)
+	AddProxyURLFlag(cmd, &options.ProxyURL)
+	AddControllerCredentialsFlag(cmd, &options.Username, &options.Password, &options.PasswordFile)
+	AddControllerHeaderFlag(cmd, &options.ControllerHeaders)
+	AddDisableMetricsFlag(cmd, &options.DisabledMetrics)
+	AddMetricsAllowlistFileFlag(cmd, &options.MetricsAllowlistFile)
+	AddPodLabelsFlags(cmd, &options.PodNamespace, &options.PodName, &options.PV)
+	AddPoolLabelFlags(cmd, &options.Pool, &options.PoolFromAPI)
+	return cmd
+}
+
+// runDump writes the metrics of the jiva controller to the output file,
+// or to w if output is empty. The metrics are written even if the stats
+// could not be collected so that volume_up and the error counters can be
+// inspected, the error is returned afterwards.
+func (o *VolumeExporterOptions) runDump(w io.Writer, output string) error {
+	var buf bytes.Buffer
+	dumpErr := o.dump(&buf)
+	if len(output) == 0 {
+		if _, err := buf.WriteTo(w); err != nil {
+			return err
+		}
+		return dumpErr
+	}
+	if err := ioutil.WriteFile(output, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	return dumpErr
+}
+
+// dump collects the stats of the jiva controller of ControllerAddress
+// using a transient exporter configured with the flags, and writes its
+// metrics to w in the text format. It returns error if the stats could
+// not be collected.
+func (o *VolumeExporterOptions) dump(w io.Writer) error {
+	controllerURL, err := collector.ParseControllerURL(o.ControllerAddress)
+	if err != nil {
+		return fmt.Errorf("Error in parsing the URI: %w", err)
+	}
+	exporter := collector.NewJivaStatsExporter(controllerURL, "jiva")
+	if err := o.configureExporter(exporter); err != nil {
+		return err
+	}
+	if err := o.configureJiva(&exporter.Jiva); err != nil {
+		return err
+	}
+	// the exporter is dropped after a single scrape.
+	exporter.DisableKeepAlives = true
+	if err := writeMetrics(w, exporter); err != nil {
+		return err
+	}
+	if !exporter.Ready() {
+		return fmt.Errorf("could not collect the stats of the jiva controller %s", controllerURL)
+	}
+	return nil
+}
+
+// writeMetrics collects the metrics of the exporter once and writes them
+// to w in the text format.
+func writeMetrics(w io.Writer, exporter *collector.VolumeStatsExporter) error {
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(exporter); err != nil {
+		return err
+	}
+	mfs, err := registry.Gather()
+	if err != nil {
+		return fmt.Errorf("could not gather the metrics: %w", err)
+	}
+	encoder := expfmt.NewEncoder(w, expfmt.FmtText)
+	for _, mf := range mfs {
+		if err := encoder.Encode(mf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
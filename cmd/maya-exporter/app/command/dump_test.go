@@ -0,0 +1,84 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDump(t *testing.T) {
+	controller := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"Name":"vol1","ReadIOPS":"5","SectorSize":"4096","Size":"1073741824"}`)
+	}))
+	defer controller.Close()
+
+	cases := map[string]struct {
+		address string
+		isErr   bool
+		output  []string
+	}{
+		"Controller is reachable": {
+			address: controller.URL,
+			output:  []string{"openebs_reads 5", "openebs_volume_up 1"},
+		},
+		"Controller is not reachable": {
+			address: "http://127.0.0.2:1",
+			isErr:   true,
+			output:  []string{"openebs_volume_up 0"},
+		},
+		"Controller address is not valid": {
+			address: "ftp://host:9501",
+			isErr:   true,
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			options := &VolumeExporterOptions{ControllerAddress: tt.address, MetricsNamespace: metricsNamespace}
+			var buf bytes.Buffer
+			err := options.dump(&buf)
+			if (err != nil) != tt.isErr {
+				t.Fatalf("dump() => %v, want error %v", err, tt.isErr)
+			}
+			for _, want := range tt.output {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("dump() => %q, want it to contain %q", buf.String(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestDumpCommand(t *testing.T) {
+	controller := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"Name":"vol1","ReadIOPS":"5"}`)
+	}))
+	defer controller.Close()
+	dir, err := ioutil.TempDir("", "maya-exporter")
+	if err != nil {
+		t.Fatalf("failed creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	output := filepath.Join(dir, "metrics.txt")
+
+	cmd, err := NewCmdVolumeExporter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd.SetArgs([]string{"dump", "--controller", controller.URL, "-o", output, "--metrics.namespace", "jiva"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() => %v, want nil", err)
+	}
+	metrics, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatalf("failed reading the output: %v", err)
+	}
+	if !strings.Contains(string(metrics), "jiva_reads 5") {
+		t.Fatalf("dump wrote %q, want it to contain %q", metrics, "jiva_reads 5")
+	}
+}
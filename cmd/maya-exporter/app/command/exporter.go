@@ -5,12 +5,27 @@
 package command
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
+	"net/url"
+	"time"
 
-	"github.com/golang/glog"
+	"github.com/openebs/maya/cmd/maya-exporter/app/collector"
+	"github.com/openebs/maya/cmd/maya-exporter/app/logger"
+	"github.com/openebs/maya/types/v1"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
 )
 
+// shutdownTimeout is the time given to the http server to complete the
+// in-flight requests on shutdown.
+const shutdownTimeout = 5 * time.Second
+
 // Initialize returns the valid flags such as jiva and cstor and returns
 // null string otherwise.
 func Initialize(options *VolumeExporterOptions) string {
@@ -19,6 +34,12 @@ func Initialize(options *VolumeExporterOptions) string {
 		return "jiva"
 	case "cstor":
 		return "cstor"
+	case "pool":
+		return "pool"
+	case "cluster":
+		return "cluster"
+	case "replica":
+		return "replica"
 	default:
 		return ""
 	}
@@ -31,11 +52,68 @@ func Initialize(options *VolumeExporterOptions) string {
 // info.
 
 // StartMayaExporter starts an HTTP server that exposes the metrics on
-// "/metrics" endpoint.
+// "/metrics" endpoint. The server is shut down once the context of the
+// exporter is done.
 func (options *VolumeExporterOptions) StartMayaExporter() error {
-	glog.Info("Starting http server....")
-	http.Handle(options.MetricsPath, promhttp.Handler())
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+	logger.Info("Starting http server....")
+	server := &http.Server{
+		Addr:         options.ListenAddress,
+		Handler:      options.handler(),
+		ReadTimeout:  options.ServerReadTimeout,
+		WriteTimeout: options.ServerWriteTimeout,
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-options.context().Done():
+		case <-done:
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			logger.Error(err)
+		}
+	}()
+	err := server.ListenAndServe()
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	if err != nil {
+		logger.Error(err)
+	}
+	return err
+}
+
+// handler returns the handler which serves the metrics, the homepage
+// and the health endpoints of the exporter.
+func (options *VolumeExporterOptions) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle(options.MetricsPath, promhttp.Handler())
+	// healthz only tells that the exporter is alive, it doesn't
+	// depend on the volume controller.
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	// readyz tells whether the stats have been collected successfully
+	// at least once.
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if options.exporter == nil || !options.exporter.Ready() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("not ready"))
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+	if options.EnableDebug {
+		mux.HandleFunc("/debug/stats", options.debugStats)
+	}
+	if options.CASType == "jiva" && len(options.ProbeAllowedTargets) != 0 {
+		mux.HandleFunc(probePath, options.probe)
+	}
+	mux.HandleFunc("/selftest", options.selfTest)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		homepage := `<html>
 <head><title>OpenEBS Exporter</title></head>
 <body>
@@ -46,9 +124,205 @@ func (options *VolumeExporterOptions) StartMayaExporter() error {
 `
 		w.Write([]byte(homepage))
 	})
-	err := http.ListenAndServe(options.ListenAddress, nil)
+	return mux
+}
+
+// probePath is the path of the endpoint which serves the metrics of the
+// jiva controller passed in the target query parameter, e.g.
+// /probe?target=http://10.0.0.5:9501, following the multi-target
+// exporter pattern of the blackbox exporter.
+const probePath = "/probe"
+
+// probe collects the stats of the jiva controller passed in the target
+// query parameter using a transient exporter configured with the flags
+// of the exporter, and serves its metrics. The target must be an http or
+// https url without credentials allowed by ProbeAllowedTargets, unix
+// sockets can't be probed. The target is chosen by the client of the
+// probe, so neither the credentials, the headers nor the client
+// certificate of the volume controllers are sent to it and its replicas
+// are not requested.
+func (options *VolumeExporterOptions) probe(w http.ResponseWriter, r *http.Request) {
+	target, err := probeTarget(r.URL.Query().Get("target"))
 	if err != nil {
-		glog.Error(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
-	return err
+	if !options.probeAllowed(target) {
+		http.Error(w, fmt.Sprintf("Target %q is not allowed by probe-allowed-targets", target.Host), http.StatusForbidden)
+		return
+	}
+	exporter := collector.NewJivaStatsExporter(target, "jiva")
+	if err := options.configureExporter(exporter); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// the scrape is aborted if the prometheus server gives up.
+	exporter.SetContext(r.Context())
+	if err := options.configureJivaTransport(&exporter.Jiva); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// the CA only verifies the certificate of the target.
+	if len(options.CAFile) != 0 {
+		config, err := collector.NewTLSConfig(options.CAFile, "", "")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		exporter.TLSConfig = config
+	}
+	exporter.DisableReplicaStats = true
+	// the transient exporter is dropped after the probe, so its
+	// connections must not be kept alive.
+	exporter.DisableKeepAlives = true
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(exporter); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+}
+
+// probeTarget parses the target of a probe into the url of the jiva
+// controller, see collector.ParseControllerURL. It returns error if the
+// target is missing, is a unix socket or has credentials.
+func probeTarget(target string) (*url.URL, error) {
+	if len(target) == 0 {
+		return nil, errors.New("target parameter is missing")
+	}
+	u, err := collector.ParseControllerURL(target)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid target %q: %w", target, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("Invalid target %q, must be an http or https url", target)
+	}
+	if u.User != nil {
+		return nil, fmt.Errorf("Invalid target %q, must not have credentials", target)
+	}
+	return u, nil
+}
+
+// probeAllowed returns true if the host of the target or its host:port is
+// one of the ProbeAllowedTargets, or if its host is an IP address in one
+// of the CIDRs of the ProbeAllowedTargets. The host names are not resolved,
+// so that a name can't be pointed at an address which isn't allowed.
+func (options *VolumeExporterOptions) probeAllowed(target *url.URL) bool {
+	ip := net.ParseIP(target.Hostname())
+	for _, allowed := range options.ProbeAllowedTargets {
+		if _, cidr, err := net.ParseCIDR(allowed); err == nil {
+			if ip != nil && cidr.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if allowed == target.Hostname() || allowed == target.Host {
+			return true
+		}
+	}
+	return false
+}
+
+// debugStats writes the stats of the last collection of the volumes as
+// indented JSON, it doesn't trigger a new collection.
+func (options *VolumeExporterOptions) debugStats(w http.ResponseWriter, r *http.Request) {
+	stats := map[string]v1.VolumeStats{}
+	if options.exporter != nil {
+		stats = options.exporter.LastStats()
+	}
+	body, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+// selfTestResult is the verdict of the self-test of the exporter. Metrics
+// tells for each metric of the exporter which is not disabled whether it
+// has been collected, the optional ones are missing if the controller
+// doesn't report them so these don't fail the self-test.
+type selfTestResult struct {
+	OK          bool            `json:"ok"`
+	Errors      []string        `json:"errors,omitempty"`
+	ParseErrors float64         `json:"parseErrors"`
+	Metrics     map[string]bool `json:"metrics"`
+}
+
+// selfTest collects the metrics of the registered exporter once and writes
+// the verdict as JSON, with status 200 if the stats of all the volumes
+// have been collected without parse errors and 503 otherwise. The parse
+// errors of the scrapes of /metrics made meanwhile are counted as well.
+func (options *VolumeExporterOptions) selfTest(w http.ResponseWriter, r *http.Request) {
+	result := options.runSelfTest()
+	body, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if !result.OK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	w.Write(body)
+}
+
+// runSelfTest gathers the metrics of the registered exporter using a new
+// registry, so that the other collectors are not run, and checks that
+// volume_up is 1 for all the volumes and no stats field failed to parse.
+func (options *VolumeExporterOptions) runSelfTest() selfTestResult {
+	result := selfTestResult{Metrics: map[string]bool{}}
+	exporter := options.exporter
+	if exporter == nil {
+		result.Errors = append(result.Errors, "the exporter of the volume is not registered")
+		return result
+	}
+	for _, name := range exporter.MetricNames() {
+		result.Metrics[name] = false
+	}
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(exporter); err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result
+	}
+	parseErrors := exporter.ParseErrors()
+	mfs, err := registry.Gather()
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("could not gather the metrics: %v", err))
+	}
+	result.ParseErrors = exporter.ParseErrors() - parseErrors
+	if result.ParseErrors != 0 {
+		result.Errors = append(result.Errors, fmt.Sprintf("%v stats field(s) could not be parsed", result.ParseErrors))
+	}
+	volumeUp := exporter.Namespace + "_volume_up"
+	for _, mf := range mfs {
+		result.Metrics[mf.GetName()] = len(mf.GetMetric()) != 0
+		if mf.GetName() != volumeUp {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			if m.GetGauge().GetValue() != 1 {
+				result.Errors = append(result.Errors, fmt.Sprintf("could not collect the stats of the volume%s", volumeLabel(m)))
+			}
+		}
+	}
+	if _, ok := result.Metrics[volumeUp]; !ok && !exporter.Ready() {
+		// volume_up is disabled, Ready tells whether any collection
+		// has ever succeeded.
+		result.Errors = append(result.Errors, "could not collect the stats of the volume")
+	}
+	result.OK = len(result.Errors) == 0
+	return result
+}
+
+// volumeLabel returns the volume label of the metric as " <volume>", it is
+// empty if the metric doesn't have it i.e. there's a single volume.
+func volumeLabel(m *dto.Metric) string {
+	for _, pair := range m.GetLabel() {
+		if pair.GetName() == "volume" {
+			return " " + pair.GetValue()
+		}
+	}
+	return ""
 }
@@ -1,10 +1,25 @@
 package command
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io/ioutil"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"reflect"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/openebs/maya/cmd/maya-exporter/app/collector"
+	"github.com/openebs/maya/cmd/maya-exporter/app/collector/collectortest"
+	"github.com/openebs/maya/types/v1"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 func TestInitialize(t *testing.T) {
@@ -24,6 +39,24 @@ func TestInitialize(t *testing.T) {
 			},
 			output: "jiva",
 		},
+		"Storage engine is cstor pool": {
+			cmdOptions: &VolumeExporterOptions{
+				CASType: "pool",
+			},
+			output: "pool",
+		},
+		"Volumes of the cluster": {
+			cmdOptions: &VolumeExporterOptions{
+				CASType: "cluster",
+			},
+			output: "cluster",
+		},
+		"Jiva replica": {
+			cmdOptions: &VolumeExporterOptions{
+				CASType: "replica",
+			},
+			output: "replica",
+		},
 		"storage engine is other": {
 			cmdOptions: &VolumeExporterOptions{
 				CASType: "other",
@@ -77,3 +110,391 @@ func startTestServer(t *testing.T, options *VolumeExporterOptions, errMsg chan e
 		errMsg <- options.StartMayaExporter()
 	}()
 }
+
+func TestHealthEndpoints(t *testing.T) {
+	controller := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"Name":"vol1","ReadIOPS":"5","SectorSize":"4096","Size":"1073741824"}`)
+	}))
+	defer controller.Close()
+	control, _ := url.Parse(controller.URL)
+
+	cases := map[string]struct {
+		scraped         bool
+		healthz, readyz int
+	}{
+		"Stats are not collected yet": {
+			scraped: false,
+			healthz: http.StatusOK,
+			readyz:  http.StatusServiceUnavailable,
+		},
+		"Stats are collected successfully": {
+			scraped: true,
+			healthz: http.StatusOK,
+			readyz:  http.StatusOK,
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			options := &VolumeExporterOptions{
+				MetricsPath: "/metrics",
+				exporter:    collector.NewJivaStatsExporter(control, "jiva"),
+			}
+			if tt.scraped {
+				registry := prometheus.NewRegistry()
+				registry.MustRegister(options.exporter)
+				if _, err := registry.Gather(); err != nil {
+					t.Fatalf("Gather() : unexpected error %v", err)
+				}
+			}
+			server := httptest.NewServer(options.handler())
+			defer server.Close()
+			for path, code := range map[string]int{"/healthz": tt.healthz, "/readyz": tt.readyz} {
+				resp, err := http.Get(server.URL + path)
+				if err != nil {
+					t.Fatalf("GET %s : unexpected error %v", path, err)
+				}
+				resp.Body.Close()
+				if resp.StatusCode != code {
+					t.Fatalf("GET %s : expected %d, got %d", path, code, resp.StatusCode)
+				}
+			}
+		})
+	}
+}
+
+func TestStartMayaExporterShutdown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	options := &VolumeExporterOptions{
+		MetricsPath:   "/metrics",
+		ListenAddress: "127.0.0.1:0",
+		ctx:           ctx,
+	}
+	errMsg := make(chan error)
+	go func() {
+		errMsg <- options.StartMayaExporter()
+	}()
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-errMsg:
+		if err != nil {
+			t.Fatalf("StartMayaExporter() : expected nil after shutdown, got %v", err)
+		}
+	case <-time.After(shutdownTimeout):
+		t.Fatalf("StartMayaExporter() : server is not shut down after cancellation")
+	}
+}
+
+func TestDebugStatsEndpoint(t *testing.T) {
+	controller := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"Name":"vol1","ReadIOPS":"5","SectorSize":"4096","Size":"1073741824"}`)
+	}))
+	defer controller.Close()
+	control, _ := url.Parse(controller.URL)
+
+	cases := map[string]struct {
+		enableDebug bool
+	}{
+		"Debug is disabled": {enableDebug: false},
+		"Debug is enabled":  {enableDebug: true},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			options := &VolumeExporterOptions{
+				MetricsPath: "/metrics",
+				EnableDebug: tt.enableDebug,
+				exporter:    collector.NewJivaStatsExporter(control, "jiva"),
+			}
+			registry := prometheus.NewRegistry()
+			registry.MustRegister(options.exporter)
+			if _, err := registry.Gather(); err != nil {
+				t.Fatalf("Gather() : unexpected error %v", err)
+			}
+			server := httptest.NewServer(options.handler())
+			defer server.Close()
+			resp, err := http.Get(server.URL + "/debug/stats")
+			if err != nil {
+				t.Fatalf("GET /debug/stats : unexpected error %v", err)
+			}
+			defer resp.Body.Close()
+			var stats map[string]v1.VolumeStats
+			err = json.NewDecoder(resp.Body).Decode(&stats)
+			if !tt.enableDebug {
+				// the request is served by the homepage
+				if err == nil {
+					t.Fatalf("GET /debug/stats : expected homepage, got stats %v", stats)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GET /debug/stats : failed decoding stats %v", err)
+			}
+			if stats["vol1"].Reads != "5" {
+				t.Fatalf("GET /debug/stats : expected reads 5 of vol1, got %v", stats)
+			}
+		})
+	}
+}
+
+func TestProbe(t *testing.T) {
+	controller := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"Name":"vol1","ReadIOPS":"5","SectorSize":"4096","Size":"1073741824"}`)
+	}))
+	defer controller.Close()
+	options := &VolumeExporterOptions{
+		CASType:     "jiva",
+		MetricsPath: "/metrics",
+		// ScrapeRetries is not set, so that the unreachable target
+		// fails fast.
+		ScrapeTimeout:       time.Second,
+		ProbeAllowedTargets: []string{"127.0.0.0/8"},
+	}
+	server := httptest.NewServer(options.handler())
+	defer server.Close()
+
+	cases := map[string]struct {
+		target string
+		code   int
+		body   string
+	}{
+		"Target not allowed":      {target: "http://10.0.0.5:9501", code: http.StatusForbidden},
+		"Target name not allowed": {target: "http://localhost:9501", code: http.StatusForbidden},
+		"Reachable target":        {target: controller.URL, code: http.StatusOK, body: "openebs_reads 5"},
+		"Target without scheme":   {target: strings.TrimPrefix(controller.URL, "http://"), code: http.StatusOK, body: "openebs_reads 5"},
+		"Unreachable target":      {target: "http://127.0.0.1:1", code: http.StatusOK, body: "openebs_volume_up 0"},
+		"Missing target":          {code: http.StatusBadRequest, body: "target parameter is missing"},
+		"Target with credentials": {target: "http://admin:secret@" + strings.TrimPrefix(controller.URL, "http://"), code: http.StatusBadRequest},
+		"Unix socket target":      {target: "unix:///var/run/jiva.sock", code: http.StatusBadRequest},
+		"Unsupported scheme":      {target: "ftp://localhost:9501", code: http.StatusBadRequest},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			resp, err := http.Get(server.URL + "/probe?target=" + url.QueryEscape(tt.target))
+			if err != nil {
+				t.Fatalf("GET /probe : unexpected error %v", err)
+			}
+			defer resp.Body.Close()
+			body, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.code {
+				t.Fatalf("GET /probe : expected %d, got %d: %s", tt.code, resp.StatusCode, body)
+			}
+			if !strings.Contains(string(body), tt.body) {
+				t.Fatalf("GET /probe : expected the body to contain %q, got %s", tt.body, body)
+			}
+		})
+	}
+}
+
+func TestProbeDisabled(t *testing.T) {
+	controller := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to the target %s", r.URL)
+	}))
+	defer controller.Close()
+	options := &VolumeExporterOptions{CASType: "jiva", MetricsPath: "/metrics"}
+	server := httptest.NewServer(options.handler())
+	defer server.Close()
+	resp, err := http.Get(server.URL + "/probe?target=" + url.QueryEscape(controller.URL))
+	if err != nil {
+		t.Fatalf("GET /probe : unexpected error %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if strings.Contains(string(body), "openebs_") {
+		t.Fatalf("GET /probe : expected the probe to be disabled, got %s", body)
+	}
+}
+
+func TestProbeCredentials(t *testing.T) {
+	var replicaRequests int32
+	replica := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&replicaRequests, 1)
+	}))
+	defer replica.Close()
+	var header atomic.Value
+	controller := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/replicas" {
+			fmt.Fprintf(w, `{"data":[{"address":"tcp://%s","mode":"RW"}]}`, strings.TrimPrefix(replica.URL, "http://"))
+			return
+		}
+		header.Store(r.Header.Clone())
+		fmt.Fprintln(w, `{"Name":"vol1","ReadIOPS":"5"}`)
+	}))
+	defer controller.Close()
+	options := &VolumeExporterOptions{
+		CASType:             "jiva",
+		MetricsPath:         "/metrics",
+		ScrapeTimeout:       time.Second,
+		Username:            "admin",
+		Password:            "secret",
+		ControllerHeaders:   []string{"X-Token=secret"},
+		ProbeAllowedTargets: []string{"127.0.0.1"},
+	}
+	server := httptest.NewServer(options.handler())
+	defer server.Close()
+	resp, err := http.Get(server.URL + "/probe?target=" + url.QueryEscape(controller.URL))
+	if err != nil {
+		t.Fatalf("GET /probe : unexpected error %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if !strings.Contains(string(body), "openebs_reads 5") {
+		t.Fatalf("GET /probe : expected the body to contain the stats of the target, got %s", body)
+	}
+	got, _ := header.Load().(http.Header)
+	if len(got.Get("Authorization")) != 0 || len(got.Get("X-Token")) != 0 {
+		t.Fatalf("GET /probe : expected no credentials sent to the target, got headers %v", got)
+	}
+	if n := atomic.LoadInt32(&replicaRequests); n != 0 {
+		t.Fatalf("GET /probe : expected no requests to the replicas of the target, got %d", n)
+	}
+}
+
+func TestProbeAllowed(t *testing.T) {
+	options := &VolumeExporterOptions{
+		ProbeAllowedTargets: []string{"10.0.0.0/24", "jiva-ctrl", "jiva-ctrl2:9501", "fd00::1"},
+	}
+	cases := map[string]struct {
+		target  string
+		allowed bool
+	}{
+		"IP in the CIDR":            {target: "http://10.0.0.5:9501", allowed: true},
+		"IP out of the CIDR":        {target: "http://10.0.1.5:9501"},
+		"Allowed host":              {target: "http://jiva-ctrl:9501", allowed: true},
+		"Allowed host and port":     {target: "https://jiva-ctrl2:9501", allowed: true},
+		"Allowed host other port":   {target: "http://jiva-ctrl2:9502"},
+		"Allowed IPv6 host":         {target: "http://[fd00::1]:9501", allowed: true},
+		"Host not in the allowlist": {target: "http://metadata.internal"},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			target, err := probeTarget(tt.target)
+			if err != nil {
+				t.Fatalf("probeTarget(%q) : unexpected error %v", tt.target, err)
+			}
+			if got := options.probeAllowed(target); got != tt.allowed {
+				t.Fatalf("probeAllowed(%q) => got %v, want %v", tt.target, got, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestSelfTest(t *testing.T) {
+	healthy := collectortest.NewFakeJivaController(v1.VolumeStats{Name: "vol1", Reads: "5", SectorSize: "4096", Size: "1073741824"})
+	defer healthy.Close()
+	partial := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"Name":"vol1","ReadIOPS":"5"}`)
+	}))
+	defer partial.Close()
+
+	cases := map[string]struct {
+		controller string
+		code       int
+		errors     []string
+		metrics    map[string]bool
+	}{
+		"Stats are collected": {
+			controller: healthy.URL,
+			code:       http.StatusOK,
+			metrics:    map[string]bool{"openebs_reads": true, "openebs_volume_up": true},
+		},
+		"Stats fields are missing": {
+			controller: partial.URL,
+			code:       http.StatusServiceUnavailable,
+			errors:     []string{"12 stats field(s) could not be parsed"},
+			metrics:    map[string]bool{"openebs_reads": true},
+		},
+		"Controller is not reachable": {
+			controller: "http://127.0.0.2:1",
+			code:       http.StatusServiceUnavailable,
+			errors:     []string{"could not collect the stats of the volume"},
+			metrics:    map[string]bool{"openebs_volume_up": true},
+		},
+		"Exporter is not registered": {
+			code:   http.StatusServiceUnavailable,
+			errors: []string{"the exporter of the volume is not registered"},
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			options := &VolumeExporterOptions{MetricsPath: "/metrics"}
+			if len(tt.controller) != 0 {
+				control, _ := url.Parse(tt.controller)
+				options.exporter = collector.NewJivaStatsExporter(control, "jiva")
+			}
+			server := httptest.NewServer(options.handler())
+			defer server.Close()
+			resp, err := http.Get(server.URL + "/selftest")
+			if err != nil {
+				t.Fatalf("GET /selftest : unexpected error %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.code {
+				t.Fatalf("GET /selftest : expected %d, got %d", tt.code, resp.StatusCode)
+			}
+			var result selfTestResult
+			if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+				t.Fatalf("GET /selftest : failed decoding the result %v", err)
+			}
+			if result.OK != (tt.code == http.StatusOK) {
+				t.Fatalf("GET /selftest : got ok %v with status %d", result.OK, resp.StatusCode)
+			}
+			if !reflect.DeepEqual(result.Errors, tt.errors) {
+				t.Fatalf("GET /selftest : expected errors %q, got %q", tt.errors, result.Errors)
+			}
+			for metric, present := range tt.metrics {
+				if got, ok := result.Metrics[metric]; !ok || got != present {
+					t.Errorf("GET /selftest : expected %s present %v, got %v", metric, present, result.Metrics)
+				}
+			}
+		})
+	}
+}
+
+func TestSelfTestNamespace(t *testing.T) {
+	healthy := collectortest.NewFakeJivaController(v1.VolumeStats{Name: "vol1", Reads: "5", SectorSize: "4096", Size: "1073741824"})
+	defer healthy.Close()
+	var down int32
+	controller := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&down) == 1 {
+			http.Error(w, "controller is down", http.StatusServiceUnavailable)
+			return
+		}
+		healthy.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer controller.Close()
+	control, _ := url.Parse(controller.URL)
+	options := &VolumeExporterOptions{MetricsPath: "/metrics"}
+	options.exporter = collector.NewJivaStatsExporter(control, "jiva")
+	options.exporter.SetNamespace("maya_jiva_")
+	server := httptest.NewServer(options.handler())
+	defer server.Close()
+
+	selfTest := func() selfTestResult {
+		resp, err := http.Get(server.URL + "/selftest")
+		if err != nil {
+			t.Fatalf("GET /selftest : unexpected error %v", err)
+		}
+		defer resp.Body.Close()
+		var result selfTestResult
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatalf("GET /selftest : failed decoding the result %v", err)
+		}
+		return result
+	}
+	if result := selfTest(); !result.OK || !result.Metrics["maya_jiva_volume_up"] {
+		t.Fatalf("GET /selftest with the controller up : expected ok with maya_jiva_volume_up, got %+v", result)
+	}
+	// the exporter is ready, the failure is known only from volume_up.
+	atomic.StoreInt32(&down, 1)
+	result := selfTest()
+	if result.OK {
+		t.Fatalf("GET /selftest with the controller down : expected not ok, got %+v", result)
+	}
+	want := []string{"could not collect the stats of the volume"}
+	if !reflect.DeepEqual(result.Errors, want) {
+		t.Fatalf("GET /selftest with the controller down : expected errors %q, got %q", want, result.Errors)
+	}
+}
@@ -0,0 +1,63 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/openebs/maya/cmd/maya-exporter/app/collector"
+	"github.com/openebs/maya/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+// NewCmdParse is used to create the parse command which parses a captured
+// response of the stats api of a jiva controller and writes the metrics
+// in the prometheus text format, without sending any request.
+func NewCmdParse(options *VolumeExporterOptions) *cobra.Command {
+	var file string
+	cmd := &cobra.Command{
+		Use:   "parse",
+		Short: "Write the metrics of a captured response of the stats api of a jiva controller",
+		Long: `parse reads a captured response of the /v1/stats api of a jiva controller
+from a file, parses it the same way as the exporter parses the responses of
+the controller and writes the resulting metrics in the prometheus text
+format to stdout. It exits with status 1 if the file can't be parsed.`,
+		Example: `maya-exporter parse --file stats.json`,
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			util.CheckErr(options.parse(cmd.OutOrStdout(), file), util.Fatal)
+		},
+	}
+	cmd.Flags().StringVar(&file, "file", file,
+		"File with the captured response of the stats api of the jiva controller")
+	AddMetricsNamespaceFlag(cmd, &options.MetricsNamespace)
+	AddCounterModeFlag(cmd, &options.CounterMode)
+	AddStrictDecodeFlag(cmd, &options.StrictDecode)
+	AddDisableMetricsFlag(cmd, &options.DisabledMetrics)
+	AddMetricsAllowlistFileFlag(cmd, &options.MetricsAllowlistFile)
+	AddPodLabelsFlags(cmd, &options.PodNamespace, &options.PodName, &options.PV)
+	AddPoolLabelFlags(cmd, &options.Pool, &options.PoolFromAPI)
+	return cmd
+}
+
+// parse writes the metrics of the stats read from the file to w in the
+// text format, see collector.NewJivaStatsFileExporter. The metrics are
+// written even if the stats could not be parsed, the error is returned
+// afterwards.
+func (o *VolumeExporterOptions) parse(w io.Writer, file string) error {
+	if len(file) == 0 {
+		return errors.New("the file with the stats is missing, pass it using --file")
+	}
+	exporter := collector.NewJivaStatsFileExporter(file)
+	if err := o.configureExporter(exporter); err != nil {
+		return err
+	}
+	exporter.StrictDecode = o.StrictDecode
+	if err := writeMetrics(w, exporter); err != nil {
+		return err
+	}
+	if !exporter.Ready() {
+		return fmt.Errorf("could not parse the stats of %s", file)
+	}
+	return nil
+}
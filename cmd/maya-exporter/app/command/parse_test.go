@@ -0,0 +1,63 @@
+package command
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	dir, err := ioutil.TempDir("", "maya-exporter")
+	if err != nil {
+		t.Fatalf("failed creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	stats := filepath.Join(dir, "stats.json")
+	if err := ioutil.WriteFile(stats, []byte(`{"Name":"vol1","ReadIOPS":"5","NewField":"1"}`), 0644); err != nil {
+		t.Fatalf("failed writing stats file: %v", err)
+	}
+
+	cases := map[string]struct {
+		file         string
+		strictDecode bool
+		isErr        bool
+		output       []string
+	}{
+		"Stats are parsed": {
+			file:   stats,
+			output: []string{"openebs_reads 5", "openebs_volume_up 1"},
+		},
+		"Unknown fields fail in strict mode": {
+			file:         stats,
+			strictDecode: true,
+			isErr:        true,
+			output:       []string{"openebs_volume_up 0", "openebs_strict_decode_errors_total 1"},
+		},
+		"File doesn't exist": {
+			file:   filepath.Join(dir, "missing.json"),
+			isErr:  true,
+			output: []string{"openebs_volume_up 0"},
+		},
+		"File is missing": {
+			isErr: true,
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			options := &VolumeExporterOptions{MetricsNamespace: metricsNamespace, StrictDecode: tt.strictDecode}
+			var buf bytes.Buffer
+			err := options.parse(&buf, tt.file)
+			if (err != nil) != tt.isErr {
+				t.Fatalf("parse() => %v, want error %v", err, tt.isErr)
+			}
+			for _, want := range tt.output {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("parse() => %q, want it to contain %q", buf.String(), want)
+				}
+			}
+		})
+	}
+}
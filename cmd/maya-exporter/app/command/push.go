@@ -0,0 +1,74 @@
+package command
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/openebs/maya/cmd/maya-exporter/app/collector"
+	"github.com/openebs/maya/cmd/maya-exporter/app/logger"
+)
+
+// startPusher pushes the metrics of the registered exporter to the
+// PushGatewayURL every PushInterval until the context of the exporter is
+// done, the first push is made right away.
+func (o *VolumeExporterOptions) startPusher() error {
+	if o.exporter == nil {
+		return errors.New("push-gateway-url is only supported for the jiva and cstor volumes")
+	}
+	if o.PushInterval <= 0 {
+		return errors.New("push-interval must be positive")
+	}
+	pusher, err := collector.NewPusher(o.PushGatewayURL, o.MetricsNamespace, o.exporter)
+	if err != nil {
+		return err
+	}
+	pusher.Instance = o.pushInstance
+	if err := collector.RegisterCollector("push_errors", pusher.ErrorsCounter()); err != nil {
+		return err
+	}
+	logger.Infof("Pushing the metrics to %s every %v", o.PushGatewayURL, o.PushInterval)
+	go o.pushOnInterval(pusher)
+	return nil
+}
+
+// pushOnInterval pushes the metrics every PushInterval until the context
+// of the exporter is done. The failed pushes are logged and counted in
+// push_errors_total, these are retried by the next push as well.
+func (o *VolumeExporterOptions) pushOnInterval(pusher *collector.Pusher) {
+	ticker := time.NewTicker(o.PushInterval)
+	defer ticker.Stop()
+	for {
+		if err := pusher.Push(o.context()); err != nil {
+			logger.Errorf("could not push the metrics to %s: %v", o.PushGatewayURL, err)
+		}
+		select {
+		case <-o.context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// pushInstance returns the instance label of the pushed metrics, i.e. the
+// name of the persistent volume if the pv flag is set, or else the name of
+// the volume reported by its controller, it is empty until the stats have
+// been collected once so that the metrics of a volume are always pushed
+// with the same label. The metrics of several volumes are pushed with the
+// name of the pod of the exporter, their volume label tells them apart.
+func (o *VolumeExporterOptions) pushInstance() string {
+	if len(o.PV) != 0 {
+		return o.PV
+	}
+	if len(o.Volumes) != 0 {
+		if len(o.PodName) != 0 {
+			return o.PodName
+		}
+		name, _ := os.Hostname()
+		return name
+	}
+	for name := range o.exporter.LastStats() {
+		return name
+	}
+	return ""
+}
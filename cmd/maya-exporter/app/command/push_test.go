@@ -0,0 +1,105 @@
+package command
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openebs/maya/cmd/maya-exporter/app/collector"
+	"github.com/openebs/maya/cmd/maya-exporter/app/collector/collectortest"
+	"github.com/openebs/maya/types/v1"
+)
+
+func TestPushOnInterval(t *testing.T) {
+	pushes := make(chan string, 10)
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		pushes <- r.URL.Path + "\n" + string(body)
+	}))
+	defer gateway.Close()
+	controller := collectortest.NewFakeJivaController(v1.VolumeStats{Name: "vol1", Reads: "5"})
+	defer controller.Close()
+	controllerURL, _ := url.Parse(controller.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	options := &VolumeExporterOptions{
+		MetricsNamespace: metricsNamespace,
+		PushGatewayURL:   gateway.URL,
+		PushInterval:     10 * time.Millisecond,
+		exporter:         collector.NewJivaStatsExporter(controllerURL, "jiva"),
+		ctx:              ctx,
+	}
+	if err := options.startPusher(); err != nil {
+		t.Fatalf("startPusher() => %v", err)
+	}
+	defer collector.Reset()
+	// the pushes are repeated on each interval.
+	for i := 0; i < 2; i++ {
+		select {
+		case push := <-pushes:
+			if !strings.HasPrefix(push, "/metrics/job/maya-exporter/instance/vol1\n") {
+				t.Fatalf("got push %q, want it to the instance vol1", push)
+			}
+			if !strings.Contains(push, "openebs_reads 5") {
+				t.Fatalf("got push %q, want it to contain openebs_reads 5", push)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("got %d push(es), want 2", i)
+		}
+	}
+}
+
+func TestStartPusherErrors(t *testing.T) {
+	controllerURL, _ := url.Parse("http://127.0.0.2:1")
+	cases := map[string]*VolumeExporterOptions{
+		"Exporter is not registered": {PushGatewayURL: "http://pushgateway:9091", PushInterval: time.Second},
+		"Interval is not positive": {
+			PushGatewayURL: "http://pushgateway:9091",
+			exporter:       collector.NewJivaStatsExporter(controllerURL, "jiva"),
+		},
+		"Gateway url is not valid": {
+			PushGatewayURL: "pushgateway:9091",
+			PushInterval:   time.Second,
+			exporter:       collector.NewJivaStatsExporter(controllerURL, "jiva"),
+		},
+	}
+	for name, options := range cases {
+		t.Run(name, func(t *testing.T) {
+			if err := options.startPusher(); err == nil {
+				t.Fatalf("startPusher() => nil, want error")
+			}
+		})
+	}
+}
+
+func TestPushInstance(t *testing.T) {
+	controller := collectortest.NewFakeJivaController(v1.VolumeStats{Name: "vol1"})
+	defer controller.Close()
+	controllerURL, _ := url.Parse(controller.URL)
+	exporter := collector.NewJivaStatsExporter(controllerURL, "jiva")
+
+	options := &VolumeExporterOptions{exporter: exporter}
+	if got := options.pushInstance(); got != "" {
+		t.Fatalf("pushInstance() before the collection => %q, want none", got)
+	}
+	if err := writeMetrics(ioutil.Discard, exporter); err != nil {
+		t.Fatal(err)
+	}
+	if got := options.pushInstance(); got != "vol1" {
+		t.Fatalf("pushInstance() => %q, want vol1", got)
+	}
+	options.PV = "pvc-1"
+	if got := options.pushInstance(); got != "pvc-1" {
+		t.Fatalf("pushInstance() with the pv => %q, want pvc-1", got)
+	}
+	options = &VolumeExporterOptions{Volumes: []string{"vol1=" + controller.URL}, PodName: "exporter-0", exporter: exporter}
+	if got := options.pushInstance(); got != "exporter-0" {
+		t.Fatalf("pushInstance() with several volumes => %q, want exporter-0", got)
+	}
+}
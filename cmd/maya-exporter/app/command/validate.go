@@ -0,0 +1,59 @@
+package command
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/openebs/maya/cmd/maya-exporter/app/collector"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+)
+
+// validate collects the metrics once from the gatherer and writes them
+// to w in the text format along with the resolved configuration of the
+// exporter. It returns error if the metrics can't be gathered or the
+// stats of the volume could not be collected.
+func (o *VolumeExporterOptions) validate(w io.Writer, gatherer prometheus.Gatherer) error {
+	fmt.Fprintf(w, "CAS type: %s\n", o.CASType)
+	o.printControllers(w)
+	mfs, err := gatherer.Gather()
+	if err != nil {
+		return fmt.Errorf("Validation failed, could not gather the metrics: %v", err)
+	}
+	encoder := expfmt.NewEncoder(w, expfmt.FmtText)
+	for _, mf := range mfs {
+		if err := encoder.Encode(mf); err != nil {
+			return err
+		}
+	}
+	if o.exporter != nil && !o.exporter.Ready() {
+		return fmt.Errorf("Validation failed, could not collect the stats of the %s volume", o.CASType)
+	}
+	fmt.Fprintln(w, "Validation succeeded")
+	return nil
+}
+
+// printControllers writes the resolved addresses of the volume
+// controllers from which the stats are collected.
+func (o *VolumeExporterOptions) printControllers(w io.Writer) {
+	if o.exporter == nil {
+		return
+	}
+	switch o.exporter.CASType {
+	case "cstor":
+		fmt.Fprintf(w, "Controller: %s\n", collector.SocketPath)
+	case "jiva":
+		if len(o.exporter.Volumes) == 0 {
+			fmt.Fprintf(w, "Controller: %s\n", o.exporter.VolumeControllerURL)
+			return
+		}
+		for _, volume := range o.exporter.Volumes {
+			controllerURL, err := collector.ParseControllerURL(volume.URL)
+			if err != nil {
+				fmt.Fprintf(w, "Controller of %s: invalid URL %q\n", volume.Name, volume.URL)
+				continue
+			}
+			fmt.Fprintf(w, "Controller of %s: %s\n", volume.Name, controllerURL)
+		}
+	}
+}
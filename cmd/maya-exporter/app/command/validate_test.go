@@ -0,0 +1,86 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/openebs/maya/cmd/maya-exporter/app/collector"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestValidate(t *testing.T) {
+	controller := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"Name":"vol1","ReadIOPS":"5","SectorSize":"4096","Size":"1073741824"}`)
+	}))
+	defer controller.Close()
+
+	cases := map[string]struct {
+		address string
+		isErr   bool
+		output  []string
+	}{
+		"Controller is reachable": {
+			address: controller.URL,
+			output: []string{
+				"CAS type: jiva",
+				"Controller: " + controller.URL + "/v1/stats",
+				"openebs_reads 5",
+				"openebs_volume_up 1",
+				"Validation succeeded",
+			},
+		},
+		"Controller is not reachable": {
+			address: "http://127.0.0.2:1",
+			isErr:   true,
+			output: []string{
+				"CAS type: jiva",
+				"Controller: http://127.0.0.2:1/v1/stats",
+				"openebs_volume_up 0",
+			},
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			controllerURL, _ := url.Parse(tt.address)
+			options := &VolumeExporterOptions{
+				CASType:  "jiva",
+				exporter: collector.NewJivaStatsExporter(controllerURL, "jiva"),
+			}
+			options.exporter.Retries = 0
+			registry := prometheus.NewRegistry()
+			registry.MustRegister(options.exporter)
+			var buf bytes.Buffer
+			err := options.validate(&buf, registry)
+			if (err != nil) != tt.isErr {
+				t.Fatalf("validate() => %v, want error %v", err, tt.isErr)
+			}
+			for _, want := range tt.output {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("validate() => %q, want it to contain %q", buf.String(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateVolumes(t *testing.T) {
+	exporter, err := collector.NewJivaVolumesStatsExporter([]collector.VolumeTarget{
+		{Name: "vol1", URL: "localhost:9501"},
+		{Name: "vol2", URL: "http://[fd00::1]:9501/"},
+	}, "jiva")
+	if err != nil {
+		t.Fatalf("NewJivaVolumesStatsExporter() : unexpected error %v", err)
+	}
+	options := &VolumeExporterOptions{CASType: "jiva", exporter: exporter}
+	var buf bytes.Buffer
+	options.printControllers(&buf)
+	want := "Controller of vol1: http://localhost:9501\nController of vol2: http://[fd00::1]:9501\n"
+	if buf.String() != want {
+		t.Fatalf("printControllers() => %q, want %q", buf.String(), want)
+	}
+}
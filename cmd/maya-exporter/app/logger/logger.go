@@ -0,0 +1,236 @@
+// Package logger is used by maya-exporter to log either in the text
+// format of glog or in JSON with the level, msg, volume and error
+// fields, which can be parsed by log pipelines.
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+const (
+	// TextFormat logs using glog, it is the default format.
+	TextFormat = "text"
+	// JSONFormat logs a JSON object per line.
+	JSONFormat = "json"
+)
+
+// severity is the level of a log.
+type severity string
+
+const (
+	debugLevel   severity = "debug"
+	infoLevel    severity = "info"
+	warningLevel severity = "warning"
+	errorLevel   severity = "error"
+	fatalLevel   severity = "fatal"
+)
+
+var (
+	// mu guards format and output.
+	mu     sync.Mutex
+	format = TextFormat
+	// output is where the logs are written in JSON format.
+	output io.Writer = os.Stderr
+	// std is the entry used by the package level functions.
+	std = &Entry{}
+)
+
+// debugVerbosity is the glog verbosity (-v) from which the debug logs
+// are printed.
+const debugVerbosity glog.Level = 4
+
+// SetFormat sets the format of the logs, it returns error if the format
+// is neither text nor json.
+func SetFormat(f string) error {
+	if f != TextFormat && f != JSONFormat {
+		return fmt.Errorf("Invalid log format %q, must be either %s or %s", f, TextFormat, JSONFormat)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	format = f
+	return nil
+}
+
+// Entry is a log with the optional volume and error fields.
+type Entry struct {
+	volume string
+	err    error
+}
+
+// jsonEntry is the JSON encoding of an Entry.
+type jsonEntry struct {
+	Time   string   `json:"time"`
+	Level  severity `json:"level"`
+	Msg    string   `json:"msg"`
+	Volume string   `json:"volume,omitempty"`
+	Error  string   `json:"error,omitempty"`
+}
+
+// WithVolume returns an entry with the volume field.
+func WithVolume(volume string) *Entry {
+	return std.WithVolume(volume)
+}
+
+// WithError returns an entry with the error field.
+func WithError(err error) *Entry {
+	return std.WithError(err)
+}
+
+// WithVolume returns a copy of the entry with the volume field.
+func (e *Entry) WithVolume(volume string) *Entry {
+	entry := *e
+	entry.volume = volume
+	return &entry
+}
+
+// WithError returns a copy of the entry with the error field.
+func (e *Entry) WithError(err error) *Entry {
+	entry := *e
+	entry.err = err
+	return &entry
+}
+
+// Debugf logs at debug level if the verbosity is at least 4.
+func (e *Entry) Debugf(f string, args ...interface{}) {
+	if !glog.V(debugVerbosity) {
+		return
+	}
+	e.print(debugLevel, fmt.Sprintf(f, args...))
+}
+
+// Info logs at info level.
+func (e *Entry) Info(args ...interface{}) {
+	e.print(infoLevel, fmt.Sprint(args...))
+}
+
+// Infof logs at info level.
+func (e *Entry) Infof(f string, args ...interface{}) {
+	e.print(infoLevel, fmt.Sprintf(f, args...))
+}
+
+// Warning logs at warning level.
+func (e *Entry) Warning(args ...interface{}) {
+	e.print(warningLevel, fmt.Sprint(args...))
+}
+
+// Warningf logs at warning level.
+func (e *Entry) Warningf(f string, args ...interface{}) {
+	e.print(warningLevel, fmt.Sprintf(f, args...))
+}
+
+// Error logs at error level.
+func (e *Entry) Error(args ...interface{}) {
+	e.print(errorLevel, fmt.Sprint(args...))
+}
+
+// Errorf logs at error level.
+func (e *Entry) Errorf(f string, args ...interface{}) {
+	e.print(errorLevel, fmt.Sprintf(f, args...))
+}
+
+// Fatal logs at fatal level and exits.
+func (e *Entry) Fatal(args ...interface{}) {
+	e.print(fatalLevel, fmt.Sprint(args...))
+}
+
+// Debugf logs at debug level if the verbosity is at least 4.
+func Debugf(f string, args ...interface{}) {
+	if !glog.V(debugVerbosity) {
+		return
+	}
+	std.print(debugLevel, fmt.Sprintf(f, args...))
+}
+
+// Info logs at info level.
+func Info(args ...interface{}) {
+	std.print(infoLevel, fmt.Sprint(args...))
+}
+
+// Infof logs at info level.
+func Infof(f string, args ...interface{}) {
+	std.print(infoLevel, fmt.Sprintf(f, args...))
+}
+
+// Warning logs at warning level.
+func Warning(args ...interface{}) {
+	std.print(warningLevel, fmt.Sprint(args...))
+}
+
+// Warningf logs at warning level.
+func Warningf(f string, args ...interface{}) {
+	std.print(warningLevel, fmt.Sprintf(f, args...))
+}
+
+// Error logs at error level.
+func Error(args ...interface{}) {
+	std.print(errorLevel, fmt.Sprint(args...))
+}
+
+// Errorf logs at error level.
+func Errorf(f string, args ...interface{}) {
+	std.print(errorLevel, fmt.Sprintf(f, args...))
+}
+
+// Errorln logs at error level.
+func Errorln(args ...interface{}) {
+	std.print(errorLevel, strings.TrimSuffix(fmt.Sprintln(args...), "\n"))
+}
+
+// Fatal logs at fatal level and exits.
+func Fatal(args ...interface{}) {
+	std.print(fatalLevel, fmt.Sprint(args...))
+}
+
+// print logs the msg with the fields of the entry in the configured
+// format. It must be called directly by the logging functions so that
+// glog reports the file and line of their caller.
+func (e *Entry) print(level severity, msg string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if format == JSONFormat {
+		e.printJSON(level, msg)
+		return
+	}
+	if len(e.volume) != 0 {
+		msg += " volume=" + e.volume
+	}
+	if e.err != nil {
+		msg += " error=" + e.err.Error()
+	}
+	switch level {
+	case debugLevel, infoLevel:
+		glog.InfoDepth(2, msg)
+	case warningLevel:
+		glog.WarningDepth(2, msg)
+	case errorLevel:
+		glog.ErrorDepth(2, msg)
+	case fatalLevel:
+		glog.FatalDepth(2, msg)
+	}
+}
+
+// printJSON writes the entry as a JSON object on a line to the output.
+func (e *Entry) printJSON(level severity, msg string) {
+	entry := jsonEntry{
+		Time:   time.Now().UTC().Format(time.RFC3339Nano),
+		Level:  level,
+		Msg:    msg,
+		Volume: e.volume,
+	}
+	if e.err != nil {
+		entry.Error = e.err.Error()
+	}
+	line, _ := json.Marshal(entry)
+	output.Write(append(line, '\n'))
+	if level == fatalLevel {
+		os.Exit(255)
+	}
+}
@@ -0,0 +1,93 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestSetFormat(t *testing.T) {
+	defer SetFormat(TextFormat)
+	cases := map[string]struct {
+		format string
+		isErr  bool
+	}{
+		"Text format":    {TextFormat, false},
+		"JSON format":    {JSONFormat, false},
+		"Invalid format": {"xml", true},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			if err := SetFormat(tt.format); (err != nil) != tt.isErr {
+				t.Fatalf("SetFormat(%q) => got %v, want error %v", tt.format, err, tt.isErr)
+			}
+		})
+	}
+}
+
+func TestJSONFormat(t *testing.T) {
+	var buf bytes.Buffer
+	stderr := output
+	output = &buf
+	SetFormat(JSONFormat)
+	defer func() {
+		SetFormat(TextFormat)
+		output = stderr
+	}()
+
+	cases := map[string]struct {
+		log  func()
+		want jsonEntry
+	}{
+		"Info without fields": {
+			log:  func() { Infof("Starting %s", "maya-exporter") },
+			want: jsonEntry{Level: infoLevel, Msg: "Starting maya-exporter"},
+		},
+		"Warning with volume and error": {
+			log: func() {
+				WithVolume("http://10.42.0.1:9501/v1/stats").WithError(errors.New("timeout")).Warning("could not retrieve stats")
+			},
+			want: jsonEntry{
+				Level:  warningLevel,
+				Msg:    "could not retrieve stats",
+				Volume: "http://10.42.0.1:9501/v1/stats",
+				Error:  "timeout",
+			},
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			buf.Reset()
+			tt.log()
+			var got jsonEntry
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed decoding log %q: %v", buf.String(), err)
+			}
+			if len(got.Time) == 0 {
+				t.Fatalf("log %q => time is missing", buf.String())
+			}
+			got.Time = ""
+			if got != tt.want {
+				t.Fatalf("log => got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDebugfVerbosity(t *testing.T) {
+	var buf bytes.Buffer
+	stderr := output
+	output = &buf
+	SetFormat(JSONFormat)
+	defer func() {
+		SetFormat(TextFormat)
+		output = stderr
+	}()
+
+	Debugf("got stats from %s", "/v1/stats")
+	WithVolume("vol1").Debugf("got stats from %s", "/v1/stats")
+	if buf.Len() != 0 {
+		t.Fatalf("Debugf() at default verbosity => %q, want no logs", buf.String())
+	}
+}
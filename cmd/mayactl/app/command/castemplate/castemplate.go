@@ -0,0 +1,72 @@
+/*
+Copyright 2018 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package castemplate
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var (
+	options = &CmdCASTemplateOptions{}
+)
+
+// CmdCASTemplateOptions holds information of the CAS templates being
+// operated
+type CmdCASTemplateOptions struct {
+	files      []string
+	valuesFile string
+}
+
+var (
+	casTemplateCommandHelpText = `
+Command provides operations related to the CAS templates and their run
+tasks defined in YAML files, without connecting to maya-apiserver.
+
+Usage: mayactl cas-template <subcommand> [options] [args]
+
+Examples:
+  # Validates a CAS template and its run tasks:
+    $ mayactl cas-template validate --file jiva-volume-create.yaml
+
+  # Validates a CAS template whose run tasks are in another file:
+    $ mayactl cas-template validate --file cast.yaml --file runtasks.yaml
+
+  # Renders the run tasks of a CAS template against the values of a file:
+    $ mayactl cas-template render --file jiva-volume-create.yaml --values values.yaml
+`
+)
+
+// NewCmdCASTemplate adds command for operating on CAS templates
+func NewCmdCASTemplate() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cas-template",
+		Short: "Provides operations related to a CAS template",
+		Long:  casTemplateCommandHelpText,
+		// the templates are read from the files, so the connection
+		// with maya-apiserver checked by mayactl is not needed.
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+	}
+
+	cmd.AddCommand(
+		NewCmdCASTemplateValidate(),
+		NewCmdCASTemplateRender(),
+	)
+	cmd.PersistentFlags().StringSliceVarP(&options.files, "file", "f", options.files,
+		"YAML file with the CAS templates and run tasks, can be repeated.")
+
+	return cmd
+}
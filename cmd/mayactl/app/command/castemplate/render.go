@@ -0,0 +1,169 @@
+/*
+Copyright 2018 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package castemplate
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/openebs/maya/pkg/apis/openebs.io/v1alpha1"
+	"github.com/openebs/maya/pkg/engine"
+	"github.com/openebs/maya/pkg/template"
+	"github.com/openebs/maya/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+var (
+	casTemplateRenderCommandHelpText = `
+This command renders the run tasks of the CAS templates defined in the given
+YAML files against the values of the values file, and prints the rendered
+meta and task of each run task in the order of the CAS template followed by
+its output task. Nothing is applied to the cluster.
+
+The top level properties of the values file e.g. Volume, Config or
+TaskResult are set as the values of the templates. Config defaults to the
+defaultConfig of the CAS template, and since the post templates are not
+run, the results of the previous run tasks can be set under TaskResult.
+
+Usage: mayactl cas-template render --file <file> [--values <file>]
+`
+)
+
+// NewCmdCASTemplateRender renders the CAS templates of the files
+func NewCmdCASTemplateRender() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "render",
+		Short:   "Renders the run tasks of CAS templates without applying them",
+		Long:    casTemplateRenderCommandHelpText,
+		Example: ` mayactl cas-template render --file jiva-volume-create.yaml --values values.yaml`,
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			util.CheckErr(options.validateFiles(), util.Fatal)
+			util.CheckErr(options.RunCASTemplateRender(os.Stdout), util.Fatal)
+		},
+	}
+	cmd.Flags().StringVar(&options.valuesFile, "values", options.valuesFile,
+		"YAML file with the values the templates are rendered against.")
+	return cmd
+}
+
+// RunCASTemplateRender validates the documents of the files and writes
+// the rendered run tasks of each CAS template to w.
+func (c *CmdCASTemplateOptions) RunCASTemplateRender(w io.Writer) error {
+	docs, err := readDocuments(c.files)
+	if err != nil {
+		return err
+	}
+	v := validateDocuments(docs)
+	if len(v.problems) != 0 {
+		for _, p := range v.problems {
+			fmt.Fprintln(w, p)
+		}
+		return fmt.Errorf("error: validation failed, found %d problem(s)", len(v.problems))
+	}
+	if len(v.templates) == 0 {
+		return fmt.Errorf("error: no CAS template found")
+	}
+	values, err := readValues(c.valuesFile)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	for _, t := range v.templates {
+		if err := v.render(&buf, t.template, values); err != nil {
+			return err
+		}
+	}
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+// readValues returns the values of the values file, there are none if
+// file is empty.
+func readValues(file string) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	if len(file) == 0 {
+		return values, nil
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error: %v", err)
+	}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("error: invalid values file %s: %v", file, err)
+	}
+	return values, nil
+}
+
+// templateValues returns the values the run tasks of the CAS template are
+// rendered against, they are set like the cas template engine does and
+// overridden by the given values.
+func templateValues(cast v1alpha1.CASTemplate, values map[string]interface{}) (map[string]interface{}, error) {
+	config, err := engine.ConfigToMap(cast.Spec.Defaults)
+	if err != nil {
+		return nil, fmt.Errorf("error: CASTemplate %q: %v", cast.Name, err)
+	}
+	v := map[string]interface{}{
+		string(v1alpha1.ConfigTLP):      config,
+		string(v1alpha1.CASTOptionsTLP): cast.Labels,
+		string(v1alpha1.ListItemsTLP):   map[string]interface{}{},
+		string(v1alpha1.TaskResultTLP):  map[string]interface{}{},
+	}
+	for key, value := range values {
+		v[key] = value
+	}
+	return v, nil
+}
+
+// render writes the rendered meta and task of the run tasks of the CAS
+// template to w.
+func (v *validator) render(w io.Writer, cast v1alpha1.CASTemplate, values map[string]interface{}) error {
+	values, err := templateValues(cast, values)
+	if err != nil {
+		return err
+	}
+	tasks := cast.Spec.RunTasks.Tasks
+	if len(cast.Spec.OutputTask) != 0 {
+		tasks = append(append([]string(nil), tasks...), cast.Spec.OutputTask)
+	}
+	for _, name := range tasks {
+		runTask := v.runTasks[name]
+		for _, field := range []struct {
+			key, value string
+		}{
+			{"meta", runTask.Spec.Meta},
+			{"task", runTask.Spec.Task},
+		} {
+			if len(strings.TrimSpace(field.value)) == 0 {
+				continue
+			}
+			b, err := template.AsTemplatedBytes(name, field.value, values)
+			if err != nil {
+				return fmt.Errorf("error: CASTemplate %q: failed to render spec.%s of RunTask %q: %v", cast.Name, field.key, name, err)
+			}
+			fmt.Fprintf(w, "---\n# CASTemplate: %s, RunTask: %s, spec.%s\n", cast.Name, name, field.key)
+			w.Write(bytes.TrimRight(b, "\n"))
+			fmt.Fprintln(w)
+		}
+	}
+	return nil
+}
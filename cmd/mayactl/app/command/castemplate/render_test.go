@@ -0,0 +1,133 @@
+package castemplate
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunCASTemplateRender(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mayactl")
+	if err != nil {
+		t.Fatalf("failed creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name, content string) string {
+		file := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+	cast := write("cast.yaml", `apiVersion: openebs.io/v1alpha1
+kind: CASTemplate
+metadata:
+  name: jiva-volume-create-default
+spec:
+  defaultConfig:
+  - name: ReplicaCount
+    value: "3"
+  run:
+    tasks:
+    - jiva-volume-create-puttargetservice-default
+---
+apiVersion: openebs.io/v1alpha1
+kind: RunTask
+metadata:
+  name: jiva-volume-create-puttargetservice-default
+spec:
+  meta: |
+    id: createputsvc
+    runNamespace: {{ .Volume.runNamespace }}
+    apiVersion: v1
+    kind: Service
+    action: put
+  task: |
+    apiVersion: v1
+    kind: Service
+    metadata:
+      name: {{ .Volume.owner | lower }}-ctrl-svc
+      labels:
+        replicas: "{{ .Config.ReplicaCount.value }}"
+`)
+	invalid := write("invalid.yaml", `apiVersion: openebs.io/v1alpha1
+kind: CASTemplate
+metadata:
+  name: cast
+spec:
+  run:
+    tasks:
+    - task1
+---
+apiVersion: openebs.io/v1alpha1
+kind: RunTask
+metadata:
+  name: task1
+spec:
+  meta: |
+    id: {{ if not .Volume.id }}{{ fail "id is required" }}{{ end }}
+`)
+	values := write("values.yaml", `Volume:
+  runNamespace: openebs
+  owner: PVC-1
+`)
+	config := write("config.yaml", `Volume:
+  owner: pvc-2
+Config:
+  ReplicaCount:
+    value: "1"
+`)
+
+	cases := map[string]struct {
+		files  []string
+		values string
+		isErr  bool
+		output []string
+	}{
+		"Rendered with the values": {
+			files:  []string{cast},
+			values: values,
+			output: []string{
+				"---\n# CASTemplate: jiva-volume-create-default, RunTask: jiva-volume-create-puttargetservice-default, spec.meta\nid: createputsvc\nrunNamespace: openebs\n",
+				"  name: pvc-1-ctrl-svc\n",
+				`replicas: "3"`,
+			},
+		},
+		"Config overridden by the values": {
+			files:  []string{cast},
+			values: config,
+			output: []string{"  name: pvc-2-ctrl-svc\n", `replicas: "1"`},
+		},
+		"Template fails": {
+			files: []string{invalid},
+			isErr: true,
+		},
+		"Values file doesn't exist": {
+			files:  []string{cast},
+			values: filepath.Join(dir, "missing.yaml"),
+			isErr:  true,
+		},
+		"Validation fails": {
+			files:  []string{write("nometa.yaml", "apiVersion: openebs.io/v1alpha1\nkind: RunTask\nmetadata:\n  name: task1\n")},
+			isErr:  true,
+			output: []string{"spec.meta is missing"},
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := (&CmdCASTemplateOptions{files: tt.files, valuesFile: tt.values}).RunCASTemplateRender(&buf)
+			if (err != nil) != tt.isErr {
+				t.Fatalf("RunCASTemplateRender() => %v, want error %v", err, tt.isErr)
+			}
+			for _, want := range tt.output {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("RunCASTemplateRender() => %q, want it to contain %q", buf.String(), want)
+				}
+			}
+		})
+	}
+}
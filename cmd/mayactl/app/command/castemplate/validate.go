@@ -0,0 +1,366 @@
+/*
+Copyright 2018 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package castemplate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/openebs/maya/pkg/apis/openebs.io/v1alpha1"
+	"github.com/openebs/maya/pkg/template"
+	"github.com/openebs/maya/pkg/util"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var (
+	casTemplateValidateCommandHelpText = `
+This command validates the CAS templates and the run tasks defined in the
+given YAML files. The documents are parsed into the CASTemplate and RunTask
+types, the required fields are checked as well as the syntax of the go
+templates of the run tasks, and the run tasks referred by the CAS templates
+must be defined in the given files. The problems are reported along with
+their line in the file, it exits with status 1 if there are any.
+
+Usage: mayactl cas-template validate --file <file> [--file <file>]
+`
+)
+
+// The kinds of the documents which are validated.
+const (
+	kindCASTemplate = "CASTemplate"
+	kindRunTask     = "RunTask"
+)
+
+// NewCmdCASTemplateValidate validates the CAS templates of the files
+func NewCmdCASTemplateValidate() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "validate",
+		Short:   "Validates the CAS templates and run tasks of YAML files",
+		Long:    casTemplateValidateCommandHelpText,
+		Example: ` mayactl cas-template validate --file jiva-volume-create.yaml`,
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			util.CheckErr(options.validateFiles(), util.Fatal)
+			util.CheckErr(options.RunCASTemplateValidate(os.Stdout), util.Fatal)
+		},
+	}
+	return cmd
+}
+
+// validateFiles returns error if no file is given
+func (c *CmdCASTemplateOptions) validateFiles() error {
+	if len(c.files) == 0 {
+		return fmt.Errorf("error: --file not specified")
+	}
+	return nil
+}
+
+// RunCASTemplateValidate validates the documents of the files and writes
+// the problems found to w, it returns error if there are any.
+func (c *CmdCASTemplateOptions) RunCASTemplateValidate(w io.Writer) error {
+	docs, err := readDocuments(c.files)
+	if err != nil {
+		return err
+	}
+	v := validateDocuments(docs)
+	for _, p := range v.problems {
+		fmt.Fprintln(w, p)
+	}
+	if len(v.problems) != 0 {
+		return fmt.Errorf("error: validation failed, found %d problem(s)", len(v.problems))
+	}
+	fmt.Fprintf(w, "Validated %d CAS template(s) and %d run task(s)\n", len(v.templates), len(v.runTasks))
+	return nil
+}
+
+// document is a YAML document of a file, line is the line of the file at
+// which the document starts.
+type document struct {
+	file string
+	line int
+	data []byte
+}
+
+// readDocuments returns the YAML documents of the files in their order.
+func readDocuments(files []string) ([]document, error) {
+	var docs []document
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("error: %v", err)
+		}
+		docs = append(docs, splitDocuments(file, data)...)
+	}
+	return docs, nil
+}
+
+// splitDocuments splits the content of the file into its YAML documents
+// separated by "---", the empty documents are skipped.
+func splitDocuments(file string, data []byte) []document {
+	var docs []document
+	var buf bytes.Buffer
+	start := 1
+	add := func() {
+		if len(bytes.TrimSpace(buf.Bytes())) != 0 {
+			docs = append(docs, document{file: file, line: start, data: append([]byte(nil), buf.Bytes()...)})
+		}
+		buf.Reset()
+	}
+	for i, line := range strings.SplitAfter(string(data), "\n") {
+		if strings.TrimRight(line, " \t\r\n") == "---" {
+			add()
+			start = i + 2
+			continue
+		}
+		buf.WriteString(line)
+	}
+	add()
+	return docs
+}
+
+// lineOf returns the line of the file of the first line of the document
+// which matches, the first line of the document is returned if none does.
+func (d document) lineOf(match func(line string) bool) int {
+	for i, line := range strings.Split(string(d.data), "\n") {
+		if match(strings.TrimSpace(line)) {
+			return d.line + i
+		}
+	}
+	return d.line
+}
+
+// lineOfKey returns the line of the file at which the key is set.
+func (d document) lineOfKey(key string) int {
+	return d.lineOf(func(line string) bool {
+		line = strings.TrimPrefix(line, "- ")
+		return strings.HasPrefix(line, key+":")
+	})
+}
+
+// lineOfItem returns the line of the file of the list item with the
+// given value.
+func (d document) lineOfItem(value string) int {
+	return d.lineOf(func(line string) bool {
+		return line == "- "+value || line == `- "`+value+`"` || line == "- '"+value+"'"
+	})
+}
+
+// problem is a validation failure of a document, line is the line of the
+// file to which it relates and context is the content of the line.
+type problem struct {
+	file    string
+	line    int
+	msg     string
+	context string
+}
+
+// String returns the problem prefixed with the file and line, followed
+// by the content of the line.
+func (p problem) String() string {
+	s := fmt.Sprintf("%s:%d: %s", p.file, p.line, p.msg)
+	if len(strings.TrimSpace(p.context)) != 0 {
+		s += fmt.Sprintf("\n    %d | %s", p.line, p.context)
+	}
+	return s
+}
+
+// validator keeps the problems found in the documents along with the CAS
+// templates and the run tasks defined in them.
+type validator struct {
+	problems  []problem
+	templates []casTemplate
+	runTasks  map[string]*v1alpha1.RunTask
+}
+
+// casTemplate is a CAS template along with the document defining it.
+type casTemplate struct {
+	doc      document
+	template v1alpha1.CASTemplate
+}
+
+// addProblem adds the problem at the given line of the document.
+func (v *validator) addProblem(d document, line int, format string, args ...interface{}) {
+	lines := strings.Split(string(d.data), "\n")
+	var context string
+	if i := line - d.line; i >= 0 && i < len(lines) {
+		context = strings.TrimRight(lines[i], "\r")
+	}
+	v.problems = append(v.problems, problem{file: d.file, line: line, msg: fmt.Sprintf(format, args...), context: context})
+}
+
+// validateDocuments validates each document and then the references of
+// the CAS templates to their run tasks.
+func validateDocuments(docs []document) *validator {
+	v := &validator{runTasks: map[string]*v1alpha1.RunTask{}}
+	for _, d := range docs {
+		var meta metav1.TypeMeta
+		if !v.decode(d, &meta, false) {
+			continue
+		}
+		switch meta.Kind {
+		case kindCASTemplate:
+			v.validateCASTemplate(d, meta)
+		case kindRunTask:
+			v.validateRunTask(d, meta)
+		case "":
+			v.addProblem(d, d.line, "kind is missing")
+		default:
+			v.addProblem(d, d.lineOfKey("kind"), "unsupported kind %q, only %s and %s are validated", meta.Kind, kindCASTemplate, kindRunTask)
+		}
+	}
+	for _, t := range v.templates {
+		for _, task := range t.template.Spec.RunTasks.Tasks {
+			if v.runTasks[task] == nil {
+				v.addProblem(t.doc, t.doc.lineOfItem(task), "CASTemplate %q: run task %q is not defined", t.template.Name, task)
+			}
+		}
+		if output := t.template.Spec.OutputTask; len(output) != 0 && v.runTasks[output] == nil {
+			v.addProblem(t.doc, t.doc.lineOfKey("output"), "CASTemplate %q: output task %q is not defined", t.template.Name, output)
+		}
+	}
+	return v
+}
+
+// validateCASTemplate validates the required fields of the CAS template.
+func (v *validator) validateCASTemplate(d document, meta metav1.TypeMeta) {
+	var cast v1alpha1.CASTemplate
+	if !v.decode(d, &cast, true) {
+		return
+	}
+	v.validateMeta(d, meta, cast.ObjectMeta)
+	tasks := cast.Spec.RunTasks.Tasks
+	if len(tasks) == 0 {
+		v.addProblem(d, d.lineOfKey("spec"), "CASTemplate %q: spec.run.tasks is missing", cast.Name)
+	}
+	seen := map[string]bool{}
+	for i, task := range tasks {
+		switch {
+		case len(strings.TrimSpace(task)) == 0:
+			v.addProblem(d, d.lineOfKey("tasks"), "CASTemplate %q: spec.run.tasks[%d] is empty", cast.Name, i)
+		case seen[task]:
+			v.addProblem(d, d.lineOfItem(task), "CASTemplate %q: run task %q is listed more than once", cast.Name, task)
+		}
+		seen[task] = true
+	}
+	v.templates = append(v.templates, casTemplate{doc: d, template: cast})
+}
+
+// validateRunTask validates the required fields of the run task and the
+// syntax of its go templates.
+func (v *validator) validateRunTask(d document, meta metav1.TypeMeta) {
+	var runTask v1alpha1.RunTask
+	if !v.decode(d, &runTask, true) {
+		return
+	}
+	v.validateMeta(d, meta, runTask.ObjectMeta)
+	if len(runTask.Name) != 0 {
+		if v.runTasks[runTask.Name] != nil {
+			v.addProblem(d, d.lineOfKey("name"), "RunTask %q is defined more than once", runTask.Name)
+		}
+		v.runTasks[runTask.Name] = &runTask
+	}
+	if len(strings.TrimSpace(runTask.Spec.Meta)) == 0 {
+		v.addProblem(d, d.lineOfKey("spec"), "RunTask %q: spec.meta is missing", runTask.Name)
+	}
+	for _, field := range []struct {
+		key, value string
+	}{
+		{"meta", runTask.Spec.Meta},
+		{"task", runTask.Spec.Task},
+		{"post", runTask.Spec.PostRun},
+	} {
+		if err := template.Parse(runTask.Name, field.value); err != nil {
+			v.addProblem(d, templateErrorLine(d, field.key, err), "RunTask %q: invalid template in spec.%s: %v", runTask.Name, field.key, err)
+		}
+	}
+}
+
+// validateMeta validates the api version and the name of the document.
+func (v *validator) validateMeta(d document, meta metav1.TypeMeta, object metav1.ObjectMeta) {
+	if meta.APIVersion != v1alpha1.SchemeGroupVersion.String() {
+		v.addProblem(d, d.lineOfKey("apiVersion"), "%s: apiVersion must be %s, got %q", meta.Kind, v1alpha1.SchemeGroupVersion, meta.APIVersion)
+	}
+	if len(object.Name) == 0 {
+		v.addProblem(d, d.lineOfKey("metadata"), "%s: metadata.name is missing", meta.Kind)
+	}
+}
+
+var (
+	// yamlErrorLine matches the line of a YAML syntax error.
+	yamlErrorLine = regexp.MustCompile(`line (\d+): `)
+	// jsonUnknownField matches the name of an unknown field.
+	jsonUnknownField = regexp.MustCompile(`unknown field "([^"]+)"`)
+	// jsonFieldType matches the path of a field of a wrong type.
+	jsonFieldType = regexp.MustCompile(`Go struct field \S*?([^.\s]+) of type`)
+	// templateErrLine matches the line of a go template syntax error.
+	templateErrLine = regexp.MustCompile(`:(\d+): `)
+)
+
+// decode decodes the document into obj and adds the problem if it fails,
+// the unknown fields are not allowed if strict is set. It returns true if
+// the document is decoded.
+func (v *validator) decode(d document, obj interface{}, strict bool) bool {
+	data, err := yaml.YAMLToJSON(d.data)
+	if err != nil {
+		line := d.line
+		msg := err.Error()
+		if m := yamlErrorLine.FindStringSubmatch(msg); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			line = d.line + n - 1
+			msg = strings.Replace(msg, m[0], "", 1)
+		}
+		v.addProblem(d, line, "invalid YAML: %s", msg)
+		return false
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if strict {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(obj); err != nil {
+		line := d.line
+		if m := jsonUnknownField.FindStringSubmatch(err.Error()); m != nil {
+			line = d.lineOfKey(m[1])
+		} else if m := jsonFieldType.FindStringSubmatch(err.Error()); m != nil {
+			line = d.lineOfKey(m[1])
+		}
+		v.addProblem(d, line, "invalid document: %v", err)
+		return false
+	}
+	return true
+}
+
+// templateErrorLine returns the line of the file of the syntax error of
+// the go template set in the field with the given key, the template is
+// expected to be a block scalar starting on the line following the key.
+func templateErrorLine(d document, key string, err error) int {
+	line := d.lineOfKey(key)
+	m := templateErrLine.FindStringSubmatch(err.Error())
+	if m == nil {
+		return line
+	}
+	n, _ := strconv.Atoi(m[1])
+	return line + n
+}
@@ -0,0 +1,221 @@
+package castemplate
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	validCASTemplate = `---
+apiVersion: openebs.io/v1alpha1
+kind: CASTemplate
+metadata:
+  name: jiva-volume-read-default
+spec:
+  run:
+    tasks:
+    - jiva-volume-read-listtargetservice-default
+  output: jiva-volume-read-output-default
+`
+	validRunTasks = `---
+apiVersion: openebs.io/v1alpha1
+kind: RunTask
+metadata:
+  name: jiva-volume-read-listtargetservice-default
+spec:
+  meta: |
+    id: readlistsvc
+    runNamespace: {{ .Volume.runNamespace }}
+    apiVersion: v1
+    kind: Service
+    action: list
+  post: |
+    {{- jsonpath .JsonResult "{.items[*].metadata.name}" | trim | saveAs "readlistsvc.items" .TaskResult | noop -}}
+---
+apiVersion: openebs.io/v1alpha1
+kind: RunTask
+metadata:
+  name: jiva-volume-read-output-default
+spec:
+  meta: |
+    action: output
+    id: readoutput
+    kind: CASVolume
+    apiVersion: v1alpha1
+  task: |
+    kind: CASVolume
+    apiVersion: v1alpha1
+`
+)
+
+func TestSplitDocuments(t *testing.T) {
+	docs := splitDocuments("t.yaml", []byte("---\nkind: CASTemplate\n---\n\n---\nkind: RunTask\nmetadata:\n"))
+	if len(docs) != 2 {
+		t.Fatalf("splitDocuments() => got %d documents, want 2", len(docs))
+	}
+	if docs[0].line != 2 || docs[1].line != 6 {
+		t.Fatalf("splitDocuments() => got documents at lines %d and %d, want 2 and 6", docs[0].line, docs[1].line)
+	}
+}
+
+func TestValidateDocuments(t *testing.T) {
+	cases := map[string]struct {
+		yaml     string
+		problems []string
+	}{
+		"Valid template and run tasks": {
+			yaml: validCASTemplate + validRunTasks,
+		},
+		"Run task is not defined": {
+			yaml: validCASTemplate,
+			problems: []string{
+				`t.yaml:9: CASTemplate "jiva-volume-read-default": run task "jiva-volume-read-listtargetservice-default" is not defined` +
+					"\n    9 |     - jiva-volume-read-listtargetservice-default",
+				`t.yaml:10: CASTemplate "jiva-volume-read-default": output task "jiva-volume-read-output-default" is not defined`,
+			},
+		},
+		"Required fields are missing": {
+			yaml: `apiVersion: openebs.io/v1alpha1
+kind: CASTemplate
+metadata:
+  labels:
+    app: jiva
+spec:
+  taskNamespace: openebs
+`,
+			problems: []string{
+				`t.yaml:3: CASTemplate: metadata.name is missing`,
+				`t.yaml:6: CASTemplate "": spec.run.tasks is missing`,
+			},
+		},
+		"Unknown field": {
+			yaml: `apiVersion: openebs.io/v1alpha1
+kind: CASTemplate
+metadata:
+  name: cast
+spec:
+  run:
+    taks:
+    - task1
+`,
+			problems: []string{`t.yaml:7: invalid document: json: unknown field "taks"`},
+		},
+		"Invalid YAML": {
+			yaml: `apiVersion: openebs.io/v1alpha1
+kind: RunTask
+metadata:
+  name: task1
+  labels: a: b
+`,
+			problems: []string{`t.yaml:5: invalid YAML:`},
+		},
+		"Invalid run task template": {
+			yaml: `apiVersion: openebs.io/v1alpha1
+kind: RunTask
+metadata:
+  name: task1
+spec:
+  meta: |
+    id: task1
+    runNamespace: {{ .Volume.runNamespace | unknownFunc }}
+`,
+			problems: []string{`t.yaml:8: RunTask "task1": invalid template in spec.meta:`},
+		},
+		"Run task without meta": {
+			yaml: `apiVersion: openebs.io/v1alpha1
+kind: RunTask
+metadata:
+  name: task1
+spec:
+  task: |
+    kind: Service
+`,
+			problems: []string{`t.yaml:5: RunTask "task1": spec.meta is missing`},
+		},
+		"Wrong api version and kind": {
+			yaml: `apiVersion: openebs.io/v1
+kind: RunTask
+metadata:
+  name: task1
+spec:
+  meta: "id: task1"
+---
+apiVersion: v1
+kind: Service
+`,
+			problems: []string{
+				`t.yaml:1: RunTask: apiVersion must be openebs.io/v1alpha1, got "openebs.io/v1"`,
+				`t.yaml:9: unsupported kind "Service"`,
+			},
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			v := validateDocuments(splitDocuments("t.yaml", []byte(tt.yaml)))
+			var got []string
+			for _, p := range v.problems {
+				got = append(got, p.String())
+			}
+			if len(got) != len(tt.problems) {
+				t.Fatalf("validateDocuments() => got problems %q, want %q", got, tt.problems)
+			}
+			for i, want := range tt.problems {
+				if !strings.HasPrefix(got[i], want) {
+					t.Errorf("validateDocuments() => got problem %q, want it to start with %q", got[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestRunCASTemplateValidate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mayactl")
+	if err != nil {
+		t.Fatalf("failed creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	cast := filepath.Join(dir, "cast.yaml")
+	runTasks := filepath.Join(dir, "runtasks.yaml")
+	if err := ioutil.WriteFile(cast, []byte(validCASTemplate), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(runTasks, []byte(validRunTasks), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]struct {
+		files  []string
+		isErr  bool
+		output string
+	}{
+		"Run tasks in another file": {
+			files:  []string{cast, runTasks},
+			output: "Validated 1 CAS template(s) and 2 run task(s)",
+		},
+		"Run tasks are missing": {
+			files:  []string{cast},
+			isErr:  true,
+			output: cast + ":9: ",
+		},
+		"File doesn't exist": {
+			files: []string{filepath.Join(dir, "missing.yaml")},
+			isErr: true,
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := (&CmdCASTemplateOptions{files: tt.files}).RunCASTemplateValidate(&buf)
+			if (err != nil) != tt.isErr {
+				t.Fatalf("RunCASTemplateValidate() => %v, want error %v", err, tt.isErr)
+			}
+			if !strings.Contains(buf.String(), tt.output) {
+				t.Fatalf("RunCASTemplateValidate() => %q, want it to contain %q", buf.String(), tt.output)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2018 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pool
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/template"
+
+	"github.com/openebs/maya/pkg/client/mapiserver"
+	"github.com/openebs/maya/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+var (
+	poolDescribeCommandHelpText = `
+This command displays the details of a cStor pool.
+
+Usage: mayactl pool describe <pool> [-o json]
+`
+)
+
+const poolTemplate = `
+Pool Details :
+--------------
+Name      :   {{.Name}}
+Status    :   {{.Status}}
+Capacity  :   {{.Capacity}}
+Replicas  :   {{.Replicas}}
+`
+
+// NewCmdPoolDescribe displays the details of a cStor pool
+func NewCmdPoolDescribe() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "describe <pool>",
+		Short: "Displays the details of a cStor pool",
+		Long:  poolDescribeCommandHelpText,
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			options.poolName = args[0]
+			util.CheckErr(options.validateOutput(), util.Fatal)
+			util.CheckErr(options.RunPoolDescribe(os.Stdout), util.Fatal)
+		},
+	}
+	return cmd
+}
+
+// RunPoolDescribe makes pool-read API request to maya-apiserver and
+// displays the pool to w
+func (c *CmdPoolOptions) RunPoolDescribe(w io.Writer) error {
+	var pool mapiserver.PoolInfo
+	if err := mapiserver.ReadPool(c.poolName, &pool); err != nil {
+		return fmt.Errorf("Error describing pool %s: %v", c.poolName, err)
+	}
+	if c.output == "json" {
+		return displayJSON(w, pool)
+	}
+	tmpl := template.Must(template.New("pool").Parse(poolTemplate))
+	return tmpl.Execute(w, pool)
+}
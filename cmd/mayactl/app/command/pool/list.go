@@ -0,0 +1,75 @@
+/*
+Copyright 2018 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pool
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/openebs/maya/pkg/client/mapiserver"
+	"github.com/openebs/maya/pkg/util"
+	"github.com/openebs/maya/types/v1"
+	"github.com/spf13/cobra"
+)
+
+var (
+	poolListCommandHelpText = `
+This command displays the cStor pools and their health.
+
+Usage: mayactl pool list [-o json]
+`
+)
+
+// NewCmdPoolList displays the list of cStor pools
+func NewCmdPoolList() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "Lists all the cStor pools",
+		Long:  poolListCommandHelpText,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			util.CheckErr(options.validateOutput(), util.Fatal)
+			util.CheckErr(options.RunPoolList(os.Stdout), util.Fatal)
+		},
+	}
+	return cmd
+}
+
+// RunPoolList makes pool-list API request to maya-apiserver and displays
+// the pools to w
+func (c *CmdPoolOptions) RunPoolList(w io.Writer) error {
+	var pools mapiserver.PoolList
+	if err := mapiserver.ListPools(&pools); err != nil {
+		return fmt.Errorf("Error listing pools: %v", err)
+	}
+	if c.output == "json" {
+		return displayJSON(w, pools)
+	}
+	if len(pools.Items) == 0 {
+		_, err := fmt.Fprintln(w, "No pools are found")
+		return err
+	}
+	tw := tabwriter.NewWriter(w, v1.MinWidth, v1.MaxWidth, v1.Padding, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tSTATUS\tCAPACITY\tREPLICAS")
+	fmt.Fprintln(tw, "----\t------\t--------\t--------")
+	for _, pool := range pools.Items {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\n", pool.Name, pool.Status, pool.Capacity, pool.Replicas)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2018 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pool
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	options = &CmdPoolOptions{}
+)
+
+// CmdPoolOptions holds information of pools being operated
+type CmdPoolOptions struct {
+	poolName string
+	output   string
+}
+
+var (
+	poolCommandHelpText = `
+Command provides operations related to a cStor pool.
+
+Usage: mayactl pool <subcommand> [options] [args]
+
+Examples:
+  # Lists the pools:
+    $ mayactl pool list
+
+  # Describes a pool:
+    $ mayactl pool describe <pool>
+
+  # Describes a pool in JSON:
+    $ mayactl pool describe <pool> -o json
+`
+)
+
+// NewCmdPool adds command for operating on cStor pools
+func NewCmdPool() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "pool",
+		Short: "Provides operations related to a cStor pool",
+		Long:  poolCommandHelpText,
+	}
+
+	cmd.AddCommand(
+		NewCmdPoolList(),
+		NewCmdPoolDescribe(),
+	)
+	cmd.PersistentFlags().StringVarP(&options.output, "output", "o", options.output,
+		"output format, json displays the pool(s) in JSON.")
+
+	return cmd
+}
+
+// validateOutput validates the output format
+func (c *CmdPoolOptions) validateOutput() error {
+	if c.output != "" && c.output != "json" {
+		return fmt.Errorf("error: invalid output format %q, only json is supported", c.output)
+	}
+	return nil
+}
+
+// displayJSON writes v to w as indented JSON.
+func displayJSON(w io.Writer, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
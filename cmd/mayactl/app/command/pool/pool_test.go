@@ -0,0 +1,143 @@
+package pool
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"testing"
+
+	utiltesting "k8s.io/client-go/util/testing"
+)
+
+const (
+	poolListResponse = `{"items":[{"name":"pool1","status":"Online","capacity":"10G","replicas":2},{"name":"pool2","status":"Offline","capacity":"5G","replicas":0}]}`
+	poolResponse     = `{"name":"pool1","status":"Online","capacity":"10G","replicas":2}`
+)
+
+// fakeMapiServer starts a fake maya-apiserver replying with the given
+// response and sets MAPI_ADDR to it, the returned func stops it.
+func fakeMapiServer(t *testing.T, code int, response string) func() {
+	server := httptest.NewServer(&utiltesting.FakeHandler{
+		StatusCode:   code,
+		ResponseBody: response,
+		T:            t,
+	})
+	os.Setenv("MAPI_ADDR", server.URL)
+	return func() {
+		os.Unsetenv("MAPI_ADDR")
+		server.Close()
+	}
+}
+
+func TestRunPoolList(t *testing.T) {
+	cases := map[string]struct {
+		output   string
+		code     int
+		response string
+		match    []*regexp.Regexp
+		isErr    bool
+	}{
+		"Table": {
+			code:     200,
+			response: poolListResponse,
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`NAME\s+STATUS\s+CAPACITY\s+REPLICAS`),
+				regexp.MustCompile(`pool1\s+Online\s+10G\s+2`),
+				regexp.MustCompile(`pool2\s+Offline\s+5G\s+0`),
+			},
+		},
+		"JSON": {
+			output:   "json",
+			code:     200,
+			response: poolListResponse,
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`"name": "pool1"`),
+				regexp.MustCompile(`"replicas": 2`),
+			},
+		},
+		"No pools": {
+			code:     200,
+			response: `{"items":[]}`,
+			match:    []*regexp.Regexp{regexp.MustCompile(`No pools are found`)},
+		},
+		"Server error": {
+			code:     500,
+			response: "internal error",
+			isErr:    true,
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			defer fakeMapiServer(t, tt.code, tt.response)()
+			var buf bytes.Buffer
+			c := &CmdPoolOptions{output: tt.output}
+			err := c.RunPoolList(&buf)
+			if (err != nil) != tt.isErr {
+				t.Fatalf("RunPoolList() => got error %v, want error %v", err, tt.isErr)
+			}
+			for _, re := range tt.match {
+				if !re.Match(buf.Bytes()) {
+					t.Errorf("failed matching %q in %q", re, buf.String())
+				}
+			}
+		})
+	}
+}
+
+func TestRunPoolDescribe(t *testing.T) {
+	cases := map[string]struct {
+		output   string
+		code     int
+		response string
+		match    []*regexp.Regexp
+		isErr    bool
+	}{
+		"Details": {
+			code:     200,
+			response: poolResponse,
+			match: []*regexp.Regexp{
+				regexp.MustCompile(`Name\s+:\s+pool1`),
+				regexp.MustCompile(`Status\s+:\s+Online`),
+				regexp.MustCompile(`Capacity\s+:\s+10G`),
+				regexp.MustCompile(`Replicas\s+:\s+2`),
+			},
+		},
+		"JSON": {
+			output:   "json",
+			code:     200,
+			response: poolResponse,
+			match:    []*regexp.Regexp{regexp.MustCompile(`"status": "Online"`)},
+		},
+		"Not found": {
+			code:     404,
+			response: "HTTP Error 404 : Not Found",
+			isErr:    true,
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			defer fakeMapiServer(t, tt.code, tt.response)()
+			var buf bytes.Buffer
+			c := &CmdPoolOptions{poolName: "pool1", output: tt.output}
+			err := c.RunPoolDescribe(&buf)
+			if (err != nil) != tt.isErr {
+				t.Fatalf("RunPoolDescribe() => got error %v, want error %v", err, tt.isErr)
+			}
+			for _, re := range tt.match {
+				if !re.Match(buf.Bytes()) {
+					t.Errorf("failed matching %q in %q", re, buf.String())
+				}
+			}
+		})
+	}
+}
+
+func TestValidateOutput(t *testing.T) {
+	for output, isErr := range map[string]bool{"": false, "json": false, "yaml": true} {
+		c := &CmdPoolOptions{output: output}
+		if err := c.validateOutput(); (err != nil) != isErr {
+			t.Errorf("validateOutput(%q) => got error %v, want error %v", output, err, isErr)
+		}
+	}
+}
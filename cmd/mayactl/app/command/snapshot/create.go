@@ -19,7 +19,10 @@ package snapshot
 import (
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
+	client "github.com/openebs/maya/pkg/client/jiva"
 	"github.com/openebs/maya/pkg/client/mapiserver"
 	"github.com/openebs/maya/pkg/util"
 	"github.com/spf13/cobra"
@@ -31,10 +34,20 @@ This command creates a new snapshot.
 
 Usage: mayactl snapshot create [options]
 
-$ mayactl snapshot create --volname <vol> --snapname <snap>
+$ mayactl snapshot create --volname <vol> --snapname <snap> [--timeout <duration>]
 `
 )
 
+const (
+	// defaultCreateTimeout is the default time to wait for a snapshot to
+	// be created.
+	defaultCreateTimeout = time.Minute
+)
+
+// snapshotPollInterval is the interval between the checks of the snapshots
+// of the volume while waiting for a snapshot to be created.
+var snapshotPollInterval = time.Second
+
 // NewCmdSnapshotCreate creates a snapshot of OpenEBS Volume
 func NewCmdSnapshotCreate() *cobra.Command {
 	cmd := &cobra.Command{
@@ -53,6 +66,8 @@ func NewCmdSnapshotCreate() *cobra.Command {
 	cmd.Flags().StringVarP(&options.snapName, "snapname", "s", options.snapName,
 		"unique snapshot name")
 	cmd.MarkPersistentFlagRequired("snapname")
+	cmd.Flags().DurationVarP(&options.timeout, "timeout", "", options.timeout,
+		"time to wait for the snapshot to be created.")
 	return cmd
 }
 
@@ -64,19 +79,64 @@ func (c *CmdSnaphotOptions) Validate(cmd *cobra.Command) error {
 	if len(c.snapName) == 0 {
 		return errors.New("--snapname is missing. Please specify an unique name")
 	}
+	if c.timeout <= 0 {
+		return fmt.Errorf("--timeout %v is invalid. Please specify a positive duration", c.timeout)
+	}
 
 	return nil
 }
 
-// RunSnapshotCreate does tasks related to mayaserver.
+// RunSnapshotCreate makes snapshot-create API request to maya-apiserver and
+// waits for the snapshot to be created.
 func (c *CmdSnaphotOptions) RunSnapshotCreate(cmd *cobra.Command) error {
 	fmt.Println("Executing volume snapshot create...")
 
-	resp := mapiserver.CreateSnapshot(c.volName, c.snapName, c.namespace)
-	if resp != nil {
-		return fmt.Errorf("Snapshot creation failed: %v", resp)
+	_, found, err := mapiserver.GetSnapshot(c.volName, c.snapName, c.namespace)
+	if err != nil {
+		return fmt.Errorf("Snapshot creation failed: %v", err)
+	}
+	if found {
+		return c.errSnapshotExists()
+	}
+	if err := mapiserver.CreateSnapshot(c.volName, c.snapName, c.namespace); err != nil {
+		if strings.Contains(err.Error(), "already exists") {
+			return c.errSnapshotExists()
+		}
+		return fmt.Errorf("Snapshot creation failed: %v", err)
+	}
+
+	snap, err := c.waitForSnapshot()
+	if err != nil {
+		return fmt.Errorf("Snapshot creation failed: %v", err)
 	}
 
 	fmt.Printf("Volume snapshot created for volume %s : '%s'\n", c.volName, c.snapName)
+	fmt.Printf("Created : %s\nSize    : %s\n", snap.Created, snap.Size)
 	return nil
 }
+
+// errSnapshotExists returns the error of creating a snapshot which already
+// exists.
+func (c *CmdSnaphotOptions) errSnapshotExists() error {
+	return fmt.Errorf("Snapshot creation failed: '%s' of volume %s: %w, please specify another name",
+		c.snapName, c.volName, mapiserver.ErrSnapshotExists)
+}
+
+// waitForSnapshot waits until the snapshot is listed in the snapshots of
+// the volume, for at most the timeout.
+func (c *CmdSnaphotOptions) waitForSnapshot() (client.DiskInfo, error) {
+	deadline := time.Now().Add(c.timeout)
+	for {
+		snap, found, err := mapiserver.GetSnapshot(c.volName, c.snapName, c.namespace)
+		if err == nil && found {
+			return snap, nil
+		}
+		if time.Now().Add(snapshotPollInterval).After(deadline) {
+			if err != nil {
+				return snap, fmt.Errorf("timed out after %v waiting for snapshot '%s': %v", c.timeout, c.snapName, err)
+			}
+			return snap, fmt.Errorf("timed out after %v waiting for snapshot '%s'", c.timeout, c.snapName)
+		}
+		time.Sleep(snapshotPollInterval)
+	}
+}
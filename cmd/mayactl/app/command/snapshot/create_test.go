@@ -0,0 +1,119 @@
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/openebs/maya/pkg/client/mapiserver"
+)
+
+const (
+	headOnlyList = `{"volume-head-001.img": {"name": "volume-head-001.img", "parent": "", "children":[], "created": "2018-06-10T19:33:34Z", "size": "0"}}`
+	snap1List    = `{"volume-snap-snap1.img": {"name": "volume-snap-snap1.img", "parent": "", "children":["volume-head-002.img"], "usercreated":true, "created": "2018-06-12T19:33:34Z", "size": "4096"}, "volume-head-002.img": {"name": "volume-head-002.img", "parent": "volume-snap-snap1.img", "children":[], "created": "2018-06-12T19:33:34Z", "size": "0"}}`
+)
+
+// fakeSnapshotServer starts a fake maya-apiserver, the snapshots of the
+// volume are listed once the given number of lists are made after the
+// create request. The create request fails with createErr if set.
+func fakeSnapshotServer(t *testing.T, existing bool, listsUntilCreated int32, createErr string) func() {
+	var created, lists int32
+	if existing {
+		created = 1
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/latest/snapshots/create/", func(w http.ResponseWriter, r *http.Request) {
+		if len(createErr) != 0 {
+			http.Error(w, createErr, http.StatusInternalServerError)
+			return
+		}
+		atomic.StoreInt32(&created, 1)
+	})
+	mux.HandleFunc("/latest/snapshots/list/vol1", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&created) == 1 && (existing || atomic.AddInt32(&lists, 1) > listsUntilCreated) {
+			fmt.Fprint(w, snap1List)
+			return
+		}
+		fmt.Fprint(w, headOnlyList)
+	})
+	server := httptest.NewServer(mux)
+	os.Setenv("MAPI_ADDR", server.URL)
+	return func() {
+		os.Unsetenv("MAPI_ADDR")
+		server.Close()
+	}
+}
+
+func TestRunSnapshotCreate(t *testing.T) {
+	defer func(interval time.Duration) { snapshotPollInterval = interval }(snapshotPollInterval)
+	snapshotPollInterval = time.Millisecond
+
+	cases := map[string]struct {
+		existing          bool
+		listsUntilCreated int32
+		createErr         string
+		timeout           time.Duration
+		isErr             bool
+		errExists         bool
+	}{
+		"Created at once": {
+			timeout: time.Second,
+		},
+		"Created after a while": {
+			listsUntilCreated: 3,
+			timeout:           time.Second,
+		},
+		"Already exists": {
+			existing:  true,
+			timeout:   time.Second,
+			isErr:     true,
+			errExists: true,
+		},
+		"Already exists on the server": {
+			createErr: "snapshot snap1 already exists",
+			timeout:   time.Second,
+			isErr:     true,
+			errExists: true,
+		},
+		"Creation failed": {
+			createErr: "controller not reachable",
+			timeout:   time.Second,
+			isErr:     true,
+		},
+		"Timed out": {
+			listsUntilCreated: 1000,
+			timeout:           10 * time.Millisecond,
+			isErr:             true,
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			stop := fakeSnapshotServer(t, tt.existing, tt.listsUntilCreated, tt.createErr)
+			defer stop()
+			c := &CmdSnaphotOptions{volName: "vol1", snapName: "snap1", timeout: tt.timeout}
+			err := c.RunSnapshotCreate(nil)
+			if (err != nil) != tt.isErr {
+				t.Fatalf("RunSnapshotCreate() => got error %v, want error %v", err, tt.isErr)
+			}
+			if errors.Is(err, mapiserver.ErrSnapshotExists) != tt.errExists {
+				t.Fatalf("RunSnapshotCreate() => got error %v, want already exists %v", err, tt.errExists)
+			}
+		})
+	}
+}
+
+func TestValidateTimeout(t *testing.T) {
+	c := &CmdSnaphotOptions{volName: "vol1", snapName: "snap1"}
+	if err := c.Validate(nil); err == nil {
+		t.Fatal("Validate() => no error for a zero timeout")
+	}
+	c.timeout = time.Second
+	if err := c.Validate(nil); err != nil {
+		t.Fatalf("Validate() => %v", err)
+	}
+}
@@ -19,6 +19,7 @@ package snapshot
 import (
 	"errors"
 	"fmt"
+	"os"
 
 	"github.com/openebs/maya/pkg/client/mapiserver"
 	"github.com/openebs/maya/pkg/util"
@@ -31,7 +32,7 @@ This command displays available snapshots on a volume.
 
 Usage: mayactl snapshot list [options]
 
-$ mayactl snapshot list --volname <vol>
+$ mayactl snapshot list --volname <vol> [-o json]
 `
 )
 
@@ -43,6 +44,7 @@ func NewCmdSnapshotList() *cobra.Command {
 		Long:  snapshotListCommandHelpText,
 		Run: func(cmd *cobra.Command, args []string) {
 			util.CheckErr(options.ValidateList(cmd), util.Fatal)
+			util.CheckErr(options.validateOutput(), util.Fatal)
 			util.CheckErr(options.RunSnapshotList(cmd), util.Fatal)
 		},
 	}
@@ -50,6 +52,8 @@ func NewCmdSnapshotList() *cobra.Command {
 	cmd.Flags().StringVarP(&options.volName, "volname", "", options.volName,
 		"unique volume name.")
 	cmd.MarkPersistentFlagRequired("volname")
+	cmd.Flags().StringVarP(&options.output, "output", "o", options.output,
+		"output format, json displays the snapshots in JSON.")
 	return cmd
 }
 
@@ -63,6 +67,13 @@ func (c *CmdSnaphotOptions) ValidateList(cmd *cobra.Command) error {
 
 // RunSnapshotList makes snapshot-list API request to maya-apiserver
 func (c *CmdSnaphotOptions) RunSnapshotList(cmd *cobra.Command) error {
+	if c.output == "json" {
+		snapshots, err := mapiserver.GetSnapshots(c.volName, c.namespace)
+		if err != nil {
+			return fmt.Errorf("Error list available snapshot: %v", err)
+		}
+		return displayJSON(os.Stdout, snapshots)
+	}
 
 	resp := mapiserver.ListSnapshot(c.volName, c.namespace)
 	if resp != nil {
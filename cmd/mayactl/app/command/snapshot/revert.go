@@ -17,7 +17,11 @@ limitations under the License.
 package snapshot
 
 import (
+	"bufio"
 	"fmt"
+	"io"
+	"os"
+	"strings"
 
 	"github.com/openebs/maya/pkg/client/mapiserver"
 	"github.com/openebs/maya/pkg/util"
@@ -33,11 +37,21 @@ the current state of data.
 
 Usage: mayactl snapshot revert [options]
 
-$ mayactl snapshot revert --volname <vol> --snapname <snap>
+$ mayactl snapshot revert --volname <vol> --snapname <snap> [--force] [-o json]
 
 `
 )
 
+// stdin is read for the confirmation of a revert.
+var stdin io.Reader = os.Stdin
+
+// revertResult is the result of a revert displayed in JSON.
+type revertResult struct {
+	Volume   string `json:"volume"`
+	Snapshot string `json:"snapshot"`
+	Reverted bool   `json:"reverted"`
+}
+
 // NewCmdSnapshotRevert reverts a snapshot of OpenEBS Volume
 func NewCmdSnapshotRevert() *cobra.Command {
 	cmd := &cobra.Command{
@@ -46,6 +60,7 @@ func NewCmdSnapshotRevert() *cobra.Command {
 		Long:  snapshotrevertHelpText,
 		Run: func(cmd *cobra.Command, args []string) {
 			util.CheckErr(options.Validate(cmd), util.Fatal)
+			util.CheckErr(options.validateOutput(), util.Fatal)
 			util.CheckErr(options.RunSnapshotRevert(cmd), util.Fatal)
 		},
 	}
@@ -55,17 +70,52 @@ func NewCmdSnapshotRevert() *cobra.Command {
 	cmd.Flags().StringVarP(&options.snapName, "snapname", "s", options.snapName,
 		"unique snapshot name")
 	cmd.MarkPersistentFlagRequired("snapname")
+	cmd.Flags().BoolVarP(&options.force, "force", "f", options.force,
+		"revert without asking for confirmation.")
+	cmd.Flags().StringVarP(&options.output, "output", "o", options.output,
+		"output format, json displays the result of the revert in JSON.")
 	return cmd
 }
 
-// RunSnapshotRevert does tasks related to mayaserver.
+// RunSnapshotRevert makes snapshot-revert API request to maya-apiserver once
+// confirmed by the user, or at once with --force.
 func (c *CmdSnaphotOptions) RunSnapshotRevert(cmd *cobra.Command) error {
-	fmt.Println("Executing volume snapshot revert ...")
+	if !c.force {
+		confirmed, err := confirmRevert(stdin, os.Stderr, c.volName, c.snapName)
+		if err != nil {
+			return fmt.Errorf("Snapshot revert failed: %v", err)
+		}
+		if !confirmed {
+			return fmt.Errorf("Snapshot revert of volume %s is cancelled", c.volName)
+		}
+	}
+	if c.output != "json" {
+		fmt.Println("Executing volume snapshot revert ...")
+	}
 
 	resp := mapiserver.RevertSnapshot(c.volName, c.snapName, c.namespace)
 	if resp != nil {
 		return fmt.Errorf("Snapshot revert failed: %v", resp)
 	}
+	if c.output == "json" {
+		return displayJSON(os.Stdout, revertResult{Volume: c.volName, Snapshot: c.snapName, Reverted: true})
+	}
 	fmt.Printf("Reverting to snapshot [%s] of volume [%s]\n", c.snapName, c.volName)
 	return nil
 }
+
+// confirmRevert asks on w whether to revert the volume to the snapshot and
+// reads the answer from r, only y or yes confirms the revert.
+func confirmRevert(r io.Reader, w io.Writer, volName, snapName string) (bool, error) {
+	fmt.Fprintf(w, "All the data changes of volume %s made after snapshot %s will be lost.\n", volName, snapName)
+	fmt.Fprint(w, "Are you sure you want to revert? [y/N]: ")
+	answer, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
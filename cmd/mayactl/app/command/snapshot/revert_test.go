@@ -0,0 +1,75 @@
+package snapshot
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestConfirmRevert(t *testing.T) {
+	cases := map[string]bool{
+		"y\n":   true,
+		"YES\n": true,
+		" yes ": true,
+		"n\n":   false,
+		"\n":    false,
+		"":      false,
+		"maybe": false,
+	}
+	for answer, want := range cases {
+		var prompt bytes.Buffer
+		got, err := confirmRevert(strings.NewReader(answer), &prompt, "vol1", "snap1")
+		if err != nil {
+			t.Fatalf("confirmRevert(%q) => %v", answer, err)
+		}
+		if got != want {
+			t.Errorf("confirmRevert(%q) => got %v, want %v", answer, got, want)
+		}
+		if !strings.Contains(prompt.String(), "[y/N]") {
+			t.Errorf("confirmRevert(%q) => got prompt %q", answer, prompt.String())
+		}
+	}
+}
+
+func TestRunSnapshotRevert(t *testing.T) {
+	defer func(r io.Reader) { stdin = r }(stdin)
+
+	cases := map[string]struct {
+		force    bool
+		answer   string
+		reverted bool
+		isErr    bool
+	}{
+		"Forced":    {force: true, reverted: true},
+		"Confirmed": {answer: "y\n", reverted: true},
+		"Declined":  {answer: "n\n", isErr: true},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			var reverts int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/latest/snapshots/revert/" {
+					atomic.AddInt32(&reverts, 1)
+				}
+			}))
+			defer server.Close()
+			os.Setenv("MAPI_ADDR", server.URL)
+			defer os.Unsetenv("MAPI_ADDR")
+			stdin = strings.NewReader(tt.answer)
+
+			c := &CmdSnaphotOptions{volName: "vol1", snapName: "snap1", force: tt.force}
+			err := c.RunSnapshotRevert(nil)
+			if (err != nil) != tt.isErr {
+				t.Fatalf("RunSnapshotRevert() => got error %v, want error %v", err, tt.isErr)
+			}
+			if got := atomic.LoadInt32(&reverts) == 1; got != tt.reverted {
+				t.Fatalf("RunSnapshotRevert() => got reverted %v, want %v", got, tt.reverted)
+			}
+		})
+	}
+}
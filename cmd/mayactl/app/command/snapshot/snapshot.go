@@ -17,12 +17,18 @@ limitations under the License.
 package snapshot
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
 	"github.com/spf13/cobra"
 )
 
 var (
 	options = &CmdSnaphotOptions{
 		namespace: "default",
+		timeout:   defaultCreateTimeout,
 	}
 )
 
@@ -31,6 +37,11 @@ type CmdSnaphotOptions struct {
 	volName   string
 	snapName  string
 	namespace string
+	// timeout is the time to wait for a snapshot to be created
+	timeout time.Duration
+	output  string
+	// force skips the confirmation of a revert
+	force bool
 }
 
 var (
@@ -50,15 +61,24 @@ Examples:
   # Create a snapshot for a volume created in 'test' namespace
     $ mayactl snapshot create --volname <vol> --snapname <snap> --namespace test
 
+  # Create a snapshot and wait at most 2 minutes for it to be created
+    $ mayactl snapshot create --volname <vol> --snapname <snap> --timeout 2m
+
   # Lists snapshot:
     $ mayactl snapshot list --volname <vol>
 
   # Lists snapshots for a volume created in 'test' namespace
     $ mayactl snapshot list --volname <vol> --namespace test
 
+  # Lists snapshots in JSON:
+    $ mayactl snapshot list --volname <vol> -o json
+
   # Reverts a snapshot:
     $ mayactl snapshot revert --volname <vol> --snapname <snap>
 
+  # Reverts a snapshot without confirmation:
+    $ mayactl snapshot revert --volname <vol> --snapname <snap> --force
+
   # Revert a snapshot for a volume created in 'test' namespace
     $ mayactl snapshot revert --volname <vol> --snapname <snap> --namespace test
 `
@@ -82,3 +102,21 @@ func NewCmdSnapshot() *cobra.Command {
 
 	return cmd
 }
+
+// validateOutput validates the output format
+func (c *CmdSnaphotOptions) validateOutput() error {
+	if c.output != "" && c.output != "json" {
+		return fmt.Errorf("error: invalid output format %q, only json is supported", c.output)
+	}
+	return nil
+}
+
+// displayJSON writes v to w as indented JSON.
+func displayJSON(w io.Writer, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
@@ -44,6 +44,20 @@ type CmdVolumeOptions struct {
 	size             string
 	namespace        string
 	json             string
+	output           string
+	watch            bool
+	follow           bool
+	interval         time.Duration
+	// capacityThreshold is the percentage of the capacity of the volume
+	// above which the capacity check of volume check fails.
+	capacityThreshold float64
+	// controllerA and controllerB are the addresses of the controllers
+	// whose stats are compared by volume diff, see tolerance.
+	controllerA string
+	controllerB string
+	// tolerance is the difference in percentage above which a field of
+	// the stats compared by volume diff is divergent.
+	tolerance float64
 }
 
 // CASType is engine type
@@ -86,6 +100,15 @@ Examples:
  # Info of a Volume created in 'test' namespace:
    $ mayactl volume info --volname <vol> --namespace test
 
+ # Events of a Volume:
+   $ mayactl volume events <vol> --follow
+
+ # Health check of a Volume:
+   $ mayactl volume check <vol>
+
+ # Compare the stats of two volume controllers:
+   $ mayactl volume diff --a <url> --b <url>
+
  # Delete a Volume:
    $ mayactl volume delete --volname <vol>
 
@@ -111,6 +134,9 @@ func NewCmdVolume() *cobra.Command {
 		NewCmdVolumeDelete(),
 		NewCmdVolumeStats(),
 		NewCmdVolumeInfo(),
+		NewCmdVolumeEvents(),
+		NewCmdVolumeCheck(),
+		NewCmdVolumeDiff(),
 	)
 	cmd.PersistentFlags().StringVarP(&options.namespace, "namespace", "n", options.namespace,
 		"namespace name, required if volume is not in the default namespace")
@@ -0,0 +1,227 @@
+/*
+Copyright 2018 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	client "github.com/openebs/maya/pkg/client/jiva"
+	"github.com/openebs/maya/pkg/client/mapiserver"
+	"github.com/openebs/maya/pkg/util"
+	"github.com/openebs/maya/types/v1"
+	"github.com/spf13/cobra"
+)
+
+var (
+	volumeCheckCommandHelpText = `
+This command checks the health of a Volume, i.e. whether its controller
+is reachable, the expected no of replicas are connected, the revision
+counters of the replicas are in sync and the used capacity is below the
+threshold. It exits with a nonzero code if any check fails.
+
+Usage: mayactl volume check <vol> [--capacity-threshold <percent>]
+`
+)
+
+// defaultCapacityThreshold is the percentage of the capacity of the
+// volume above which the capacity check fails.
+const defaultCapacityThreshold = 80.0
+
+// The colors of the results of the checks.
+const (
+	colorGreen = "\033[32m"
+	colorRed   = "\033[31m"
+	colorReset = "\033[0m"
+)
+
+// checkResult is the result of a health check of a volume.
+type checkResult struct {
+	Name    string
+	Passed  bool
+	Message string
+}
+
+// volumeCheckClient fetches the stats of the controller and the replicas
+// of a volume, replica is the address of the replica without the port.
+type volumeCheckClient struct {
+	controllerStats func() (v1.VolumeMetrics, error)
+	replicaStats    func(replica string) (v1.VolStatus, error)
+}
+
+// NewCmdVolumeCheck checks the health of a OpenEBS Volume.
+func NewCmdVolumeCheck() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "check [volname]",
+		Short: "Checks the health of a Volume",
+		Long:  volumeCheckCommandHelpText,
+		Example: ` mayactl volume check vol
+ mayactl volume check vol --capacity-threshold=90`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 1 && len(options.volName) == 0 {
+				options.volName = args[0]
+			}
+			util.CheckErr(options.Validate(cmd, false, false, true), util.Fatal)
+			util.CheckErr(options.RunVolumeCheck(cmd), util.Fatal)
+		},
+	}
+	cmd.Flags().StringVarP(&options.volName, "volname", "", options.volName,
+		"unique volume name.")
+	cmd.Flags().Float64VarP(&options.capacityThreshold, "capacity-threshold", "", defaultCapacityThreshold,
+		"percentage of the capacity of the volume above which the capacity check fails.")
+	return cmd
+}
+
+// RunVolumeCheck runs the health checks of the volume and displays their
+// results, it returns error if any check fails.
+func (c *CmdVolumeOptions) RunVolumeCheck(cmd *cobra.Command) error {
+	if c.capacityThreshold <= 0 || c.capacityThreshold > 100 {
+		return fmt.Errorf("error: invalid capacity threshold %v, must be in (0, 100]", c.capacityThreshold)
+	}
+	volumeInfo, err := NewVolumeInfo(mapiserver.GetURL()+VolumeAPIPath+c.volName, c.volName, c.namespace)
+	if err != nil {
+		return fmt.Errorf("error: unable to get the volume %s: %v", c.volName, err)
+	}
+	if volumeInfo.GetCASType() != string(JivaStorageEngine) {
+		return fmt.Errorf("error: volume check is supported only for jiva volumes, %s is a %s volume", c.volName, volumeInfo.GetCASType())
+	}
+	checkClient := volumeCheckClient{
+		controllerStats: func() (v1.VolumeMetrics, error) {
+			var stats v1.VolumeMetrics
+			controllerClient := client.ControllerClient{}
+			_, err := controllerClient.GetVolumeStats(volumeInfo.GetClusterIP()+v1.ControllerPort, v1.StatsAPI, &stats)
+			return stats, err
+		},
+		replicaStats: func(replica string) (v1.VolStatus, error) {
+			var status v1.VolStatus
+			replicaClient := client.ReplicaClient{}
+			_, err := replicaClient.GetVolumeStats(replica+v1.ReplicaPort, &status)
+			return status, err
+		},
+	}
+	results := checkVolume(volumeInfo, checkClient, c.capacityThreshold)
+	if !displayCheckResults(os.Stdout, c.volName, results) {
+		return fmt.Errorf("error: volume %s is not healthy", c.volName)
+	}
+	return nil
+}
+
+// checkVolume runs the health checks of the volume. The checks which
+// need the stats of the controller fail if the controller is not
+// reachable.
+func checkVolume(v *VolumeInfo, c volumeCheckClient, capacityThreshold float64) []checkResult {
+	stats, err := c.controllerStats()
+	if err != nil {
+		return []checkResult{
+			{"Controller", false, fmt.Sprintf("not reachable at %s: %v", v.GetClusterIP()+v1.ControllerPort, err)},
+			{"Replicas", false, "unknown as the controller is not reachable"},
+			checkRevisions(v, c),
+			{"Capacity", false, "unknown as the controller is not reachable"},
+		}
+	}
+	return []checkResult{
+		{"Controller", true, fmt.Sprintf("reachable at %s", v.GetClusterIP()+v1.ControllerPort)},
+		checkReplicas(v, stats),
+		checkRevisions(v, c),
+		checkCapacity(stats, capacityThreshold),
+	}
+}
+
+// checkReplicas checks if the no of replicas connected with the
+// controller is the replica count of the volume.
+func checkReplicas(v *VolumeInfo, stats v1.VolumeMetrics) checkResult {
+	result := checkResult{Name: "Replicas"}
+	expected, err := strconv.ParseInt(v.GetReplicaCount(), 10, 64)
+	if err != nil {
+		result.Message = fmt.Sprintf("invalid replica count %q", v.GetReplicaCount())
+		return result
+	}
+	result.Passed = stats.ReplicaCounter == expected
+	result.Message = fmt.Sprintf("%d of %d connected", stats.ReplicaCounter, expected)
+	return result
+}
+
+// checkRevisions checks if the revision counters of all the replicas of
+// the volume are the same, it fails if any replica is not reachable.
+func checkRevisions(v *VolumeInfo, c volumeCheckClient) checkResult {
+	result := checkResult{Name: "Revision counters"}
+	var replicas []string
+	if ips := v.GetReplicaIP(); len(ips) != 0 {
+		replicas = strings.Split(ips, ",")
+	}
+	if len(replicas) == 0 {
+		result.Message = "no replicas"
+		return result
+	}
+	var revision string
+	inSync := true
+	var list []string
+	for _, replica := range replicas {
+		status, err := c.replicaStats(replica)
+		if err != nil {
+			result.Message = fmt.Sprintf("replica %s is not reachable: %v", replica, err)
+			return result
+		}
+		if len(list) == 0 {
+			revision = status.RevisionCounter
+		}
+		inSync = inSync && status.RevisionCounter == revision
+		list = append(list, fmt.Sprintf("%s=%s", replica, status.RevisionCounter))
+	}
+	if !inSync {
+		result.Message = "out of sync: " + strings.Join(list, ", ")
+		return result
+	}
+	result.Passed = true
+	result.Message = fmt.Sprintf("in sync at %s", revision)
+	return result
+}
+
+// checkCapacity checks if the percentage of the capacity of the volume
+// which is used is below the threshold.
+func checkCapacity(stats v1.VolumeMetrics, threshold float64) checkResult {
+	used := newIOStats(stats).UsedPercent
+	return checkResult{
+		Name:    "Capacity",
+		Passed:  used < threshold,
+		Message: fmt.Sprintf("%.2f%% used, threshold is %.2f%%", used, threshold),
+	}
+}
+
+// displayCheckResults displays the results of the checks of the volume
+// followed by a summary, it returns true if all the checks have passed.
+func displayCheckResults(w io.Writer, volName string, results []checkResult) bool {
+	failed := 0
+	for _, result := range results {
+		status := colorGreen + "PASS" + colorReset
+		if !result.Passed {
+			status = colorRed + "FAIL" + colorReset
+			failed++
+		}
+		fmt.Fprintf(w, "[%s] %-18s %s\n", status, result.Name, result.Message)
+	}
+	if failed != 0 {
+		fmt.Fprintf(w, "%sVolume %s is not healthy, %d of %d checks failed%s\n", colorRed, volName, failed, len(results), colorReset)
+		return false
+	}
+	fmt.Fprintf(w, "%sVolume %s is healthy%s\n", colorGreen, volName, colorReset)
+	return true
+}
@@ -0,0 +1,124 @@
+package command
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/openebs/maya/pkg/apis/openebs.io/v1alpha1"
+	"github.com/openebs/maya/types/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCheckVolume(t *testing.T) {
+	volume := &VolumeInfo{
+		Volume: v1alpha1.CASVolume{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "vol1",
+				Annotations: map[string]string{
+					"openebs.io/cluster-ips":    "10.0.0.1",
+					"openebs.io/replica-count":  "2",
+					"openebs.io/replica-ips":    "10.1.0.3,10.1.0.4",
+					"openebs.io/replica-status": "running,running",
+				},
+			},
+		},
+	}
+	healthyStats := v1.VolumeMetrics{
+		ReplicaCounter:    2,
+		SectorSize:        "4096",
+		Size:              "1073741824",
+		UsedLogicalBlocks: "52428",
+	}
+	revisions := func(revisions map[string]string) func(string) (v1.VolStatus, error) {
+		return func(replica string) (v1.VolStatus, error) {
+			revision, ok := revisions[replica]
+			if !ok {
+				return v1.VolStatus{}, errors.New("connection refused")
+			}
+			return v1.VolStatus{RevisionCounter: revision}, nil
+		}
+	}
+	tests := map[string]struct {
+		stats     v1.VolumeMetrics
+		statsErr  error
+		revisions map[string]string
+		threshold float64
+		want      []bool
+		output    []string
+	}{
+		"Healthy volume": {
+			stats:     healthyStats,
+			revisions: map[string]string{"10.1.0.3": "100", "10.1.0.4": "100"},
+			threshold: 80,
+			want:      []bool{true, true, true, true},
+			output:    []string{"2 of 2 connected", "in sync at 100", "20.00% used", "Volume vol1 is healthy"},
+		},
+		"Controller is not reachable": {
+			statsErr:  errors.New("connection refused"),
+			revisions: map[string]string{"10.1.0.3": "100", "10.1.0.4": "100"},
+			threshold: 80,
+			want:      []bool{false, false, true, false},
+			output:    []string{"not reachable at 10.0.0.1:9501", "3 of 4 checks failed"},
+		},
+		"Replica is not connected": {
+			stats:     v1.VolumeMetrics{ReplicaCounter: 1, SectorSize: "4096", Size: "1073741824"},
+			revisions: map[string]string{"10.1.0.3": "100", "10.1.0.4": "100"},
+			threshold: 80,
+			want:      []bool{true, false, true, true},
+			output:    []string{"1 of 2 connected", "1 of 4 checks failed"},
+		},
+		"Revision counters are out of sync": {
+			stats:     healthyStats,
+			revisions: map[string]string{"10.1.0.3": "100", "10.1.0.4": "90"},
+			threshold: 80,
+			want:      []bool{true, true, false, true},
+			output:    []string{"out of sync: 10.1.0.3=100, 10.1.0.4=90"},
+		},
+		"Replica is not reachable": {
+			stats:     healthyStats,
+			revisions: map[string]string{"10.1.0.3": "100"},
+			threshold: 80,
+			want:      []bool{true, true, false, true},
+			output:    []string{"replica 10.1.0.4 is not reachable"},
+		},
+		"Capacity above the threshold": {
+			stats:     healthyStats,
+			revisions: map[string]string{"10.1.0.3": "100", "10.1.0.4": "100"},
+			threshold: 10,
+			want:      []bool{true, true, true, false},
+			output:    []string{"20.00% used, threshold is 10.00%"},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := volumeCheckClient{
+				controllerStats: func() (v1.VolumeMetrics, error) {
+					return test.stats, test.statsErr
+				},
+				replicaStats: revisions(test.revisions),
+			}
+			results := checkVolume(volume, c, test.threshold)
+			if len(results) != len(test.want) {
+				t.Fatalf("checkVolume() => %d results, want %d", len(results), len(test.want))
+			}
+			healthy := true
+			for i, result := range results {
+				if result.Passed != test.want[i] {
+					t.Errorf("check %q => passed %v, want %v: %s", result.Name, result.Passed, test.want[i], result.Message)
+				}
+				healthy = healthy && test.want[i]
+			}
+			var buf bytes.Buffer
+			if got := displayCheckResults(&buf, "vol1", results); got != healthy {
+				t.Errorf("displayCheckResults() => %v, want %v", got, healthy)
+			}
+			for _, s := range test.output {
+				if !strings.Contains(buf.String(), s) {
+					t.Errorf("output doesn't contain %q:\n%s", s, buf.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2018 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"text/tabwriter"
+
+	client "github.com/openebs/maya/pkg/client/jiva"
+	"github.com/openebs/maya/pkg/util"
+	"github.com/openebs/maya/types/v1"
+	"github.com/spf13/cobra"
+)
+
+var (
+	volumeDiffCommandHelpText = `
+This command compares the stats reported by two volume controllers, e.g.
+the old and the new controllers of a volume which is migrated, and
+displays the difference of each field. The fields which differ by more
+than the tolerance are highlighted and the command exits with a nonzero
+code if there is any.
+
+Usage: mayactl volume diff --a <url> --b <url> [--tolerance <percent>] [-o json]
+`
+)
+
+// statsFields are the fields of the stats of the controllers which are
+// compared, the uptime is not as it differs for any two controllers.
+var statsFields = []struct {
+	name  string
+	value func(v1.VolumeStats) v1.FlexNumber
+}{
+	{"ReadIOPS", func(s v1.VolumeStats) v1.FlexNumber { return s.Reads }},
+	{"WriteIOPS", func(s v1.VolumeStats) v1.FlexNumber { return s.Writes }},
+	{"TotalReadTime", func(s v1.VolumeStats) v1.FlexNumber { return s.TotalReadTime }},
+	{"TotalWriteTime", func(s v1.VolumeStats) v1.FlexNumber { return s.TotalWriteTime }},
+	{"TotalReadBlockCount", func(s v1.VolumeStats) v1.FlexNumber { return s.TotalReadBlockCount }},
+	{"TotalWriteBlockCount", func(s v1.VolumeStats) v1.FlexNumber { return s.TotalWriteBlockCount }},
+	{"TotalReadBytes", func(s v1.VolumeStats) v1.FlexNumber { return s.TotalReadBytes }},
+	{"TotalWriteBytes", func(s v1.VolumeStats) v1.FlexNumber { return s.TotalWriteBytes }},
+	{"UsedLogicalBlocks", func(s v1.VolumeStats) v1.FlexNumber { return s.UsedLogicalBlocks }},
+	{"UsedBlocks", func(s v1.VolumeStats) v1.FlexNumber { return s.UsedBlocks }},
+	{"SectorSize", func(s v1.VolumeStats) v1.FlexNumber { return s.SectorSize }},
+	{"Size", func(s v1.VolumeStats) v1.FlexNumber { return s.Size }},
+	{"RevisionCounter", func(s v1.VolumeStats) v1.FlexNumber { return s.RevisionCounter }},
+	{"ReplicaCounter", func(s v1.VolumeStats) v1.FlexNumber { return s.ReplicaCounter }},
+}
+
+// statsDiff is the difference of a field of the stats of two controllers,
+// A and B are empty if the field is not reported by the controller.
+type statsDiff struct {
+	Field       string  `json:"field"`
+	A           string  `json:"a"`
+	B           string  `json:"b"`
+	Diff        float64 `json:"diff"`
+	DiffPercent float64 `json:"diffPercent"`
+	Divergent   bool    `json:"divergent"`
+}
+
+// NewCmdVolumeDiff compares the stats of two volume controllers.
+func NewCmdVolumeDiff() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Compares the stats of two volume controllers",
+		Long:  volumeDiffCommandHelpText,
+		Example: ` mayactl volume diff --a 10.0.0.1:9501 --b 10.0.0.2:9501
+ mayactl volume diff --a 10.0.0.1:9501 --b 10.0.0.2:9501 --tolerance=5 -o json`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			util.CheckErr(options.RunVolumeDiff(cmd), util.Fatal)
+		},
+	}
+	cmd.Flags().StringVarP(&options.controllerA, "a", "", options.controllerA,
+		"address of the first volume controller, e.g. 10.0.0.1:9501.")
+	cmd.Flags().StringVarP(&options.controllerB, "b", "", options.controllerB,
+		"address of the second volume controller.")
+	cmd.Flags().Float64VarP(&options.tolerance, "tolerance", "", options.tolerance,
+		"difference in percentage of the larger value above which a field is divergent.")
+	cmd.Flags().StringVarP(&options.output, "output", "o", options.output,
+		"output format, json displays the differences as a JSON list.")
+	return cmd
+}
+
+// RunVolumeDiff fetches the stats of both the controllers and displays
+// their differences, it returns error if any field is divergent.
+func (c *CmdVolumeOptions) RunVolumeDiff(cmd *cobra.Command) error {
+	if len(c.controllerA) == 0 || len(c.controllerB) == 0 {
+		return errors.New("error: --a and --b must be specified")
+	}
+	if c.output != "" && c.output != "json" {
+		return fmt.Errorf("error: invalid output format %q, only json is supported", c.output)
+	}
+	if c.tolerance < 0 {
+		return fmt.Errorf("error: invalid tolerance %v", c.tolerance)
+	}
+	var statsA, statsB v1.VolumeStats
+	controllerClient := client.ControllerClient{}
+	if _, err := controllerClient.GetVolumeStats(c.controllerA, v1.StatsAPI, &statsA); err != nil {
+		return fmt.Errorf("error: unable to get the stats of %s: %v", c.controllerA, err)
+	}
+	if _, err := controllerClient.GetVolumeStats(c.controllerB, v1.StatsAPI, &statsB); err != nil {
+		return fmt.Errorf("error: unable to get the stats of %s: %v", c.controllerB, err)
+	}
+	diffs := diffVolumeStats(statsA, statsB, c.tolerance)
+	var err error
+	if c.output == "json" {
+		err = displayStatsDiffJSON(os.Stdout, diffs)
+	} else {
+		err = displayStatsDiff(os.Stdout, diffs)
+	}
+	if err != nil {
+		return err
+	}
+	for _, diff := range diffs {
+		if diff.Divergent {
+			return fmt.Errorf("error: the stats of %s and %s are divergent", c.controllerA, c.controllerB)
+		}
+	}
+	return nil
+}
+
+// diffVolumeStats returns the difference of each of the statsFields of b
+// from a. A field is divergent if its difference is more than tolerance
+// percent of the larger value, or if it is reported by only one of the
+// controllers.
+func diffVolumeStats(a, b v1.VolumeStats, tolerance float64) []statsDiff {
+	var diffs []statsDiff
+	for _, field := range statsFields {
+		diff := statsDiff{Field: field.name, A: field.value(a).String(), B: field.value(b).String()}
+		valueA, errA := field.value(a).Float64()
+		valueB, errB := field.value(b).Float64()
+		switch {
+		case errA != nil && errB != nil:
+		case errA != nil || errB != nil:
+			diff.Divergent = true
+		default:
+			diff.Diff = valueB - valueA
+			if max := math.Max(math.Abs(valueA), math.Abs(valueB)); max != 0 {
+				diff.DiffPercent = math.Abs(diff.Diff) * 100 / max
+			}
+			diff.Divergent = diff.DiffPercent > tolerance
+		}
+		diffs = append(diffs, diff)
+	}
+	return diffs
+}
+
+// displayStatsDiff displays the differences as a table, the divergent
+// fields are highlighted.
+func displayStatsDiff(w io.Writer, diffs []statsDiff) error {
+	tw := tabwriter.NewWriter(w, v1.MinWidth, v1.MaxWidth, v1.Padding, ' ', 0)
+	fmt.Fprintln(tw, "FIELD\t A\t B\t DIFF\t DIFF(%)\t")
+	fmt.Fprintln(tw, "------\t --\t --\t -----\t --------\t")
+	for _, diff := range diffs {
+		a, b := diff.A, diff.B
+		if len(a) == 0 {
+			a = "-"
+		}
+		if len(b) == 0 {
+			b = "-"
+		}
+		mark := ""
+		if diff.Divergent {
+			mark = colorRed + "DIVERGENT" + colorReset
+		}
+		fmt.Fprintf(tw, "%s\t %s\t %s\t %g\t %.2f\t %s\n", diff.Field, a, b, diff.Diff, diff.DiffPercent, mark)
+	}
+	return tw.Flush()
+}
+
+// displayStatsDiffJSON displays the differences as indented JSON.
+func displayStatsDiffJSON(w io.Writer, diffs []statsDiff) error {
+	data, err := json.MarshalIndent(diffs, "", "\t")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
@@ -0,0 +1,99 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/openebs/maya/types/v1"
+)
+
+func TestDiffVolumeStats(t *testing.T) {
+	a := v1.VolumeStats{
+		Reads:           "1000",
+		Writes:          "2000",
+		SectorSize:      "4096",
+		Size:            "1073741824",
+		RevisionCounter: "100",
+		ReplicaCounter:  "3",
+	}
+	cases := map[string]struct {
+		b         v1.VolumeStats
+		tolerance float64
+		divergent map[string]bool
+	}{
+		"Same stats": {
+			b: a,
+		},
+		"Difference within the tolerance": {
+			b: v1.VolumeStats{
+				Reads: "1010", Writes: "2000", SectorSize: "4096",
+				Size: "1073741824", RevisionCounter: "100", ReplicaCounter: "3",
+			},
+			tolerance: 1,
+		},
+		"Difference beyond the tolerance": {
+			b: v1.VolumeStats{
+				Reads: "1100", Writes: "2000", SectorSize: "4096",
+				Size: "1073741824", RevisionCounter: "100", ReplicaCounter: "2",
+			},
+			tolerance: 1,
+			divergent: map[string]bool{"ReadIOPS": true, "ReplicaCounter": true},
+		},
+		"Field reported by only one of the controllers": {
+			b: v1.VolumeStats{
+				Reads: "1000", Writes: "2000", SectorSize: "4096",
+				Size: "1073741824", ReplicaCounter: "3",
+			},
+			divergent: map[string]bool{"RevisionCounter": true},
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			diffs := diffVolumeStats(a, tt.b, tt.tolerance)
+			if len(diffs) != len(statsFields) {
+				t.Fatalf("diffVolumeStats() => got %d diffs, want %d", len(diffs), len(statsFields))
+			}
+			for _, diff := range diffs {
+				if diff.Divergent != tt.divergent[diff.Field] {
+					t.Errorf("diffVolumeStats() => got divergent %v for %s, want %v", diff.Divergent, diff.Field, tt.divergent[diff.Field])
+				}
+			}
+		})
+	}
+
+	diffs := diffVolumeStats(a, v1.VolumeStats{Reads: "1100"}, 0)
+	if diffs[0].Diff != 100 || diffs[0].DiffPercent <= 9 || diffs[0].DiffPercent >= 10 {
+		t.Fatalf("diffVolumeStats() => got %+v, want diff of 100 and 9.09%%", diffs[0])
+	}
+}
+
+func TestDisplayStatsDiff(t *testing.T) {
+	diffs := diffVolumeStats(v1.VolumeStats{Reads: "1000"}, v1.VolumeStats{Reads: "1100"}, 1)
+
+	var buf bytes.Buffer
+	if err := displayStatsDiff(&buf, diffs); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if strings.HasPrefix(line, "ReadIOPS") && !strings.Contains(line, "DIVERGENT") {
+			t.Errorf("displayStatsDiff() => got %q, want ReadIOPS highlighted", line)
+		}
+		if strings.HasPrefix(line, "WriteIOPS") && strings.Contains(line, "DIVERGENT") {
+			t.Errorf("displayStatsDiff() => got %q, want WriteIOPS not highlighted", line)
+		}
+	}
+
+	buf.Reset()
+	if err := displayStatsDiffJSON(&buf, diffs); err != nil {
+		t.Fatal(err)
+	}
+	var got []statsDiff
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("displayStatsDiffJSON() => got invalid json: %v", err)
+	}
+	if len(got) != len(diffs) || got[0] != diffs[0] {
+		t.Fatalf("displayStatsDiffJSON() => got %+v, want %+v", got, diffs)
+	}
+}
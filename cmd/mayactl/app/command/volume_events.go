@@ -0,0 +1,319 @@
+/*
+Copyright 2018 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package command
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	k8sclient "github.com/openebs/maya/pkg/client/k8s"
+	"github.com/openebs/maya/pkg/client/mapiserver"
+	"github.com/openebs/maya/pkg/util"
+	"github.com/spf13/cobra"
+	api_core_v1 "k8s.io/api/core/v1"
+	mach_apis_meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+var (
+	volumeEventsCommandHelpText = `
+This command displays the K8s events of the pods and other objects of a
+Volume along with the changes of the status of its controller and
+replicas, e.g. a replica which is connected or disconnected.
+
+Usage: mayactl volume events <vol> [--follow] [--interval <duration>]
+`
+)
+
+// The sources of the events of a volume.
+const (
+	eventSourceK8s    = "k8s"
+	eventSourceVolume = "volume"
+)
+
+// volumeEvent is an event of a volume, either a K8s event of the objects
+// of the volume or a change of the status of its controller or replicas.
+type volumeEvent struct {
+	Time    time.Time
+	Source  string
+	Reason  string
+	Message string
+}
+
+// String returns the event as a timestamped line.
+func (e volumeEvent) String() string {
+	return fmt.Sprintf("%s %-6s %s: %s", e.Time.Format(time.RFC3339), e.Source, e.Reason, e.Message)
+}
+
+// eventLister lists and watches the K8s events, it is implemented by
+// k8sclient.K8sClient.
+type eventLister interface {
+	ListEvents(opts mach_apis_meta_v1.ListOptions) (*api_core_v1.EventList, error)
+	WatchEvents(opts mach_apis_meta_v1.ListOptions) (watch.Interface, error)
+}
+
+// NewCmdVolumeEvents displays the events of a OpenEBS Volume.
+func NewCmdVolumeEvents() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "events [volname]",
+		Short: "Displays the events of a Volume",
+		Long:  volumeEventsCommandHelpText,
+		Example: ` mayactl volume events vol
+ mayactl volume events vol --follow --interval=5s`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 1 && len(options.volName) == 0 {
+				options.volName = args[0]
+			}
+			util.CheckErr(options.Validate(cmd, false, false, true), util.Fatal)
+			util.CheckErr(options.RunVolumeEvents(cmd), util.Fatal)
+		},
+	}
+	cmd.Flags().StringVarP(&options.volName, "volname", "", options.volName,
+		"unique volume name.")
+	cmd.Flags().BoolVarP(&options.follow, "follow", "f", options.follow,
+		"keep displaying the new events until interrupted.")
+	cmd.Flags().DurationVarP(&options.interval, "interval", "", defaultWatchInterval,
+		"interval between the checks of the status of the controller and replicas in the follow mode.")
+	return cmd
+}
+
+// RunVolumeEvents displays the K8s events of the volume and the status of
+// its controller and replicas, the new events and the changes of the
+// status are displayed until interrupted if follow is set.
+func (c *CmdVolumeOptions) RunVolumeEvents(cmd *cobra.Command) error {
+	if c.follow && c.interval <= 0 {
+		return fmt.Errorf("error: invalid interval %v", c.interval)
+	}
+	var events eventLister
+	k8sClient, err := k8sclient.NewK8sClient(c.namespace)
+	if err != nil {
+		fmt.Printf("Unable to get the K8s events, only the status of the volume is displayed: %v\n", err)
+	} else {
+		events = k8sClient
+	}
+	fetchStatus := func() (map[string]string, error) {
+		volumeInfo, err := NewVolumeInfo(mapiserver.GetURL()+VolumeAPIPath+c.volName, c.volName, c.namespace)
+		if err != nil {
+			return nil, err
+		}
+		return volumeStatus(volumeInfo), nil
+	}
+	done := make(chan struct{})
+	if c.follow {
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(sigCh)
+		go func() {
+			<-sigCh
+			close(done)
+		}()
+	}
+	return streamVolumeEvents(os.Stdout, c.volName, events, fetchStatus, c.follow, c.interval, done)
+}
+
+// streamVolumeEvents displays the K8s events of the volume sorted by time
+// and its current status. If follow is set, the new K8s events and the
+// changes of the status, which is fetched every interval, are displayed
+// until done is closed. The K8s events are skipped if events is nil.
+func streamVolumeEvents(w io.Writer, volName string, events eventLister, fetchStatus func() (map[string]string, error), follow bool, interval time.Duration, done <-chan struct{}) error {
+	var resourceVersion string
+	if events != nil {
+		list, err := events.ListEvents(mach_apis_meta_v1.ListOptions{})
+		if err != nil {
+			fmt.Fprintf(w, "Unable to list the K8s events: %v\n", err)
+			events = nil
+		} else {
+			resourceVersion = list.ResourceVersion
+			var recent []volumeEvent
+			for _, event := range list.Items {
+				if isVolumeEvent(volName, event) {
+					recent = append(recent, k8sVolumeEvent(event))
+				}
+			}
+			sort.SliceStable(recent, func(i, j int) bool {
+				return recent[i].Time.Before(recent[j].Time)
+			})
+			for _, event := range recent {
+				fmt.Fprintln(w, event)
+			}
+		}
+	}
+	status, err := fetchStatus()
+	if err != nil {
+		fmt.Fprintf(w, "Unable to get the status of the volume: %v\n", err)
+	}
+	for _, event := range currentStatus(status, time.Now()) {
+		fmt.Fprintln(w, event)
+	}
+	if !follow {
+		return nil
+	}
+
+	var results <-chan watch.Event
+	if events != nil {
+		watcher, err := events.WatchEvents(mach_apis_meta_v1.ListOptions{ResourceVersion: resourceVersion})
+		if err != nil {
+			fmt.Fprintf(w, "Unable to watch the K8s events: %v\n", err)
+		} else {
+			defer watcher.Stop()
+			results = watcher.ResultChan()
+		}
+	}
+	reachable := err == nil
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return nil
+		case result, ok := <-results:
+			if !ok {
+				fmt.Fprintln(w, "The watch of the K8s events is closed")
+				results = nil
+				continue
+			}
+			event, isEvent := result.Object.(*api_core_v1.Event)
+			if !isEvent || result.Type == watch.Deleted || !isVolumeEvent(volName, *event) {
+				continue
+			}
+			fmt.Fprintln(w, k8sVolumeEvent(*event))
+		case now := <-ticker.C:
+			cur, err := fetchStatus()
+			if err != nil {
+				// the failure is displayed only once until the
+				// volume is reachable again.
+				if reachable {
+					fmt.Fprintln(w, volumeEvent{now, eventSourceVolume, "Unreachable", err.Error()})
+				}
+				reachable = false
+				continue
+			}
+			reachable = true
+			for _, event := range statusChanges(status, cur, now) {
+				fmt.Fprintln(w, event)
+			}
+			status = cur
+		}
+	}
+}
+
+// isVolumeEvent returns true if the K8s event is of an object of the
+// volume, i.e. the object is named after the volume like the pods of its
+// controller and replicas.
+func isVolumeEvent(volName string, event api_core_v1.Event) bool {
+	name := event.InvolvedObject.Name
+	return name == volName || strings.HasPrefix(name, volName+"-")
+}
+
+// k8sVolumeEvent returns the volume event of the K8s event, the time of
+// the event is the time of its last occurrence.
+func k8sVolumeEvent(event api_core_v1.Event) volumeEvent {
+	at := event.LastTimestamp.Time
+	if at.IsZero() {
+		at = event.FirstTimestamp.Time
+	}
+	if at.IsZero() {
+		at = event.CreationTimestamp.Time
+	}
+	return volumeEvent{
+		Time:    at,
+		Source:  eventSourceK8s,
+		Reason:  event.Reason,
+		Message: fmt.Sprintf("%s/%s %s", event.InvolvedObject.Kind, event.InvolvedObject.Name, event.Message),
+	}
+}
+
+// volumeStatus returns the status of the controller and of each replica of
+// the volume indexed by the name of the component, e.g. "replica 10.1.0.3".
+func volumeStatus(v *VolumeInfo) map[string]string {
+	status := map[string]string{}
+	split := func(list string) []string {
+		if len(list) == 0 {
+			return nil
+		}
+		return strings.Split(list, ",")
+	}
+	switch v.GetCASType() {
+	case string(CstorStorageEngine):
+		// the status of the cstor replicas is kept in the controller
+		// status in the order of the replicas.
+		statuses := split(v.GetControllerStatus())
+		for i, name := range split(v.GetCVRName()) {
+			if i < len(statuses) {
+				status["replica "+name] = statuses[i]
+			}
+		}
+	default:
+		status["controller"] = v.GetControllerStatus()
+		statuses := split(v.GetReplicaStatus())
+		for i, ip := range split(v.GetReplicaIP()) {
+			if i < len(statuses) {
+				status["replica "+ip] = statuses[i]
+			}
+		}
+	}
+	return status
+}
+
+// currentStatus returns the events with the given status of each component
+// of the volume.
+func currentStatus(status map[string]string, now time.Time) []volumeEvent {
+	var events []volumeEvent
+	for _, name := range sortedKeys(status) {
+		events = append(events, volumeEvent{now, eventSourceVolume, "Status", fmt.Sprintf("%s is %s", name, status[name])})
+	}
+	return events
+}
+
+// statusChanges returns the events of the components of the volume which
+// are connected, disconnected or whose status has changed between prev
+// and cur.
+func statusChanges(prev, cur map[string]string, now time.Time) []volumeEvent {
+	var events []volumeEvent
+	for _, name := range sortedKeys(cur) {
+		old, ok := prev[name]
+		switch {
+		case !ok:
+			events = append(events, volumeEvent{now, eventSourceVolume, "Connected", fmt.Sprintf("%s is %s", name, cur[name])})
+		case old != cur[name]:
+			events = append(events, volumeEvent{now, eventSourceVolume, "StatusChanged", fmt.Sprintf("%s is %s, was %s", name, cur[name], old)})
+		}
+	}
+	for _, name := range sortedKeys(prev) {
+		if _, ok := cur[name]; !ok {
+			events = append(events, volumeEvent{now, eventSourceVolume, "Disconnected", fmt.Sprintf("%s was %s", name, prev[name])})
+		}
+	}
+	return events
+}
+
+// sortedKeys returns the keys of m in increasing order.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,207 @@
+package command
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openebs/maya/pkg/apis/openebs.io/v1alpha1"
+	api_core_v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// fakeEventLister lists the given events and watches the events sent on
+// the fake watcher.
+type fakeEventLister struct {
+	events  []api_core_v1.Event
+	watcher *watch.FakeWatcher
+	listErr error
+}
+
+func (f *fakeEventLister) ListEvents(opts metav1.ListOptions) (*api_core_v1.EventList, error) {
+	if f.listErr != nil {
+		return nil, f.listErr
+	}
+	return &api_core_v1.EventList{Items: f.events}, nil
+}
+
+func (f *fakeEventLister) WatchEvents(opts metav1.ListOptions) (watch.Interface, error) {
+	return f.watcher, nil
+}
+
+// newEvent returns a K8s event of the pod with the given name which last
+// occurred at the given time.
+func newEvent(pod, reason string, at time.Time) api_core_v1.Event {
+	return api_core_v1.Event{
+		InvolvedObject: api_core_v1.ObjectReference{Kind: "Pod", Name: pod},
+		Reason:         reason,
+		Message:        reason + " of " + pod,
+		LastTimestamp:  metav1.NewTime(at),
+	}
+}
+
+func TestIsVolumeEvent(t *testing.T) {
+	tests := map[string]struct {
+		name string
+		want bool
+	}{
+		"Event of the volume":            {name: "vol1", want: true},
+		"Event of the controller pod":    {name: "vol1-ctrl-6d5f7b9c8-x2xkz", want: true},
+		"Event of another volume":        {name: "vol10-ctrl-6d5f7b9c8-x2xkz", want: false},
+		"Event of an unrelated object":   {name: "maya-apiserver", want: false},
+		"Event of a similarly named pod": {name: "vol", want: false},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			event := api_core_v1.Event{InvolvedObject: api_core_v1.ObjectReference{Name: test.name}}
+			if got := isVolumeEvent("vol1", event); got != test.want {
+				t.Fatalf("isVolumeEvent(%q) => %v, want %v", test.name, got, test.want)
+			}
+		})
+	}
+}
+
+func TestVolumeStatus(t *testing.T) {
+	tests := map[string]struct {
+		annotations map[string]string
+		casType     string
+		want        map[string]string
+	}{
+		"Jiva volume": {
+			annotations: map[string]string{
+				"openebs.io/controller-status": "running",
+				"openebs.io/replica-ips":       "10.1.0.3,10.1.0.4",
+				"openebs.io/replica-status":    "running,pending",
+			},
+			want: map[string]string{
+				"controller":       "running",
+				"replica 10.1.0.3": "running",
+				"replica 10.1.0.4": "pending",
+			},
+		},
+		"Cstor volume": {
+			casType: "cstor",
+			annotations: map[string]string{
+				"openebs.io/controller-status": "healthy,offline",
+				"openebs.io/cvr-names":         "vol1-pool1,vol1-pool2",
+			},
+			want: map[string]string{
+				"replica vol1-pool1": "healthy",
+				"replica vol1-pool2": "offline",
+			},
+		},
+		"Jiva volume without replicas": {
+			annotations: map[string]string{
+				"openebs.io/controller-status": "running",
+			},
+			want: map[string]string{"controller": "running"},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			v := &VolumeInfo{Volume: v1alpha1.CASVolume{
+				ObjectMeta: metav1.ObjectMeta{Annotations: test.annotations},
+				Spec:       v1alpha1.CASVolumeSpec{CasType: test.casType},
+			}}
+			if got := volumeStatus(v); !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("volumeStatus() => %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestStatusChanges(t *testing.T) {
+	now := time.Date(2018, 7, 1, 10, 0, 0, 0, time.UTC)
+	prev := map[string]string{"controller": "running", "replica 10.1.0.3": "running", "replica 10.1.0.4": "running"}
+	cur := map[string]string{"controller": "running", "replica 10.1.0.3": "pending", "replica 10.1.0.5": "running"}
+	var got []string
+	for _, event := range statusChanges(prev, cur, now) {
+		got = append(got, event.String())
+	}
+	want := []string{
+		"2018-07-01T10:00:00Z volume StatusChanged: replica 10.1.0.3 is pending, was running",
+		"2018-07-01T10:00:00Z volume Connected: replica 10.1.0.5 is running",
+		"2018-07-01T10:00:00Z volume Disconnected: replica 10.1.0.4 was running",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("statusChanges() => %q, want %q", got, want)
+	}
+}
+
+func TestStreamVolumeEvents(t *testing.T) {
+	start := time.Date(2018, 7, 1, 10, 0, 0, 0, time.UTC)
+	events := &fakeEventLister{
+		events: []api_core_v1.Event{
+			newEvent("vol1-rep-1", "Pulled", start.Add(time.Minute)),
+			newEvent("vol1-ctrl-1", "Scheduled", start),
+			newEvent("vol2-ctrl-1", "Scheduled", start),
+		},
+		watcher: watch.NewFakeWithChanSize(2, false),
+	}
+	events.watcher.Add(&api_core_v1.Event{
+		InvolvedObject: api_core_v1.ObjectReference{Kind: "Pod", Name: "vol1-rep-2"},
+		Reason:         "Unhealthy",
+		Message:        "Readiness probe failed",
+	})
+	events.watcher.Add(&api_core_v1.Event{InvolvedObject: api_core_v1.ObjectReference{Name: "vol2-rep-1"}, Reason: "Killing"})
+
+	done := make(chan struct{})
+	var calls int
+	fetchStatus := func() (map[string]string, error) {
+		calls++
+		switch calls {
+		case 1:
+			return map[string]string{"controller": "running", "replica 10.1.0.3": "running"}, nil
+		case 2, 3:
+			return nil, errors.New("connection refused")
+		case 4:
+			return map[string]string{"controller": "running"}, nil
+		}
+		close(done)
+		return map[string]string{"controller": "running"}, nil
+	}
+	var buf bytes.Buffer
+	if err := streamVolumeEvents(&buf, "vol1", events, fetchStatus, true, time.Millisecond, done); err != nil {
+		t.Fatalf("streamVolumeEvents() => %v, want nil", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"2018-07-01T10:00:00Z k8s    Scheduled: Pod/vol1-ctrl-1 Scheduled of vol1-ctrl-1\n2018-07-01T10:01:00Z k8s    Pulled: Pod/vol1-rep-1 Pulled of vol1-rep-1",
+		"volume Status: replica 10.1.0.3 is running",
+		"k8s    Unhealthy: Pod/vol1-rep-2 Readiness probe failed",
+		"volume Unreachable: connection refused",
+		"volume Disconnected: replica 10.1.0.3 was running",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("streamVolumeEvents() => %q, want it to contain %q", out, want)
+		}
+	}
+	for _, unwanted := range []string{"vol2", "Killing"} {
+		if strings.Contains(out, unwanted) {
+			t.Errorf("streamVolumeEvents() => %q, want it not to contain %q", out, unwanted)
+		}
+	}
+	if n := strings.Count(out, "Unreachable"); n != 1 {
+		t.Errorf("streamVolumeEvents() => the failure is displayed %d times, want once", n)
+	}
+}
+
+func TestStreamVolumeEventsWithoutFollow(t *testing.T) {
+	events := &fakeEventLister{listErr: errors.New("forbidden")}
+	fetchStatus := func() (map[string]string, error) {
+		return map[string]string{"controller": "running"}, nil
+	}
+	var buf bytes.Buffer
+	if err := streamVolumeEvents(&buf, "vol1", events, fetchStatus, false, time.Millisecond, nil); err != nil {
+		t.Fatalf("streamVolumeEvents() => %v, want nil", err)
+	}
+	for _, want := range []string{"Unable to list the K8s events: forbidden", "volume Status: controller is running"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("streamVolumeEvents() => %q, want it to contain %q", buf.String(), want)
+		}
+	}
+}
@@ -20,9 +20,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"html/template"
+	"io"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+	"syscall"
 	"text/tabwriter"
 	"time"
 
@@ -38,9 +41,19 @@ var (
 This command queries the statisics of a volume.
 
 Usage: mayactl volume stats --volname <vol> [-size <size>]
+       mayactl volume stats <vol> [-o json]
+       mayactl volume stats <vol> --watch [--interval <duration>]
 `
 )
 
+// defaultWatchInterval is the interval between the refreshes of the
+// stats in the watch mode.
+const defaultWatchInterval = 2 * time.Second
+
+// clearScreen moves the cursor to the top left corner of the terminal
+// and clears the screen.
+const clearScreen = "\033[H\033[2J"
+
 // ReplicaStats keep info about the replicas.
 type ReplicaStats struct {
 	Replica         string
@@ -51,11 +64,17 @@ type ReplicaStats struct {
 // NewCmdVolumeStats displays the runtime statistics of volume
 func NewCmdVolumeStats() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:     "stats",
-		Short:   "Displays the runtime statisics of Volume",
-		Long:    volumeStatsCommandHelpText,
-		Example: ` mayactl volume stats --volname=vol -j=json`,
+		Use:   "stats [volname]",
+		Short: "Displays the runtime statisics of Volume",
+		Long:  volumeStatsCommandHelpText,
+		Example: ` mayactl volume stats --volname=vol -j=json
+ mayactl volume stats vol -o json
+ mayactl volume stats vol --watch --interval=5s`,
+		Args: cobra.MaximumNArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 1 && len(options.volName) == 0 {
+				options.volName = args[0]
+			}
 			util.CheckErr(options.Validate(cmd, false, false, true), util.Fatal)
 			util.CheckErr(options.RunVolumeStats(cmd), util.Fatal)
 		},
@@ -64,13 +83,30 @@ func NewCmdVolumeStats() *cobra.Command {
 	cmd.Flags().StringVarP(&options.volName, "volname", "", options.volName,
 		"unique volume name.")
 	cmd.Flags().StringVarP(&options.json, "json", "j", options.json, "display output in JSON.")
+	cmd.Flags().StringVarP(&options.output, "output", "o", options.output,
+		"output format, json displays the raw stats of the volume controller.")
+	cmd.Flags().BoolVarP(&options.watch, "watch", "w", options.watch,
+		"refresh the stats of the volume until interrupted.")
+	cmd.Flags().DurationVarP(&options.interval, "interval", "", defaultWatchInterval,
+		"interval between the refreshes in the watch mode.")
 	return cmd
 }
 
 // RunVolumeStats runs stats command and display the outputs in standard
 // I/O or in json format.
 func (c *CmdVolumeOptions) RunVolumeStats(cmd *cobra.Command) error {
-	fmt.Println("Executing volume stats...")
+	if c.output != "" && c.output != "json" {
+		return fmt.Errorf("error: invalid output format %q, only json is supported", c.output)
+	}
+	if c.watch && c.output != "" {
+		return fmt.Errorf("error: --watch can't be used with --output")
+	}
+	if c.watch && c.interval <= 0 {
+		return fmt.Errorf("error: invalid watch interval %v", c.interval)
+	}
+	if c.output != "json" && !c.watch {
+		fmt.Println("Executing volume stats...")
+	}
 	var (
 		status         v1.VolStatus
 		stats1, stats2 v1.VolumeMetrics
@@ -89,6 +125,24 @@ func (c *CmdVolumeOptions) RunVolumeStats(cmd *cobra.Command) error {
 		}
 	}
 
+	if c.watch {
+		controllerClient := client.ControllerClient{}
+		fetch := func() (v1.VolumeStats, error) {
+			var stats v1.VolumeStats
+			_, err := controllerClient.GetVolumeStats(volumeInfo.GetClusterIP()+v1.ControllerPort, v1.StatsAPI, &stats)
+			return stats, err
+		}
+		done := make(chan struct{})
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(sigCh)
+		go func() {
+			<-sigCh
+			close(done)
+		}()
+		return watchVolumeStats(os.Stdout, c.volName, c.interval, done, fetch)
+	}
+
 	replicas := strings.Split(volumeInfo.GetReplicaIP(), ",")
 	replicaStatus := strings.Split(volumeInfo.GetReplicaStatus(), ",")
 	replicaStats := make(map[int]*ReplicaStats)
@@ -107,6 +161,14 @@ func (c *CmdVolumeOptions) RunVolumeStats(cmd *cobra.Command) error {
 	}
 
 	controllerClient := client.ControllerClient{}
+	if c.output == "json" {
+		var volStats v1.VolumeStats
+		if _, err := controllerClient.GetVolumeStats(volumeInfo.GetClusterIP()+v1.ControllerPort, v1.StatsAPI, &volStats); err != nil {
+			fmt.Println("Volume not Reachable\n", err)
+			return nil
+		}
+		return displayStatsJSON(os.Stdout, volStats)
+	}
 	// Fetching volume stats from replica controller
 	respStatus, err := controllerClient.GetVolumeStats(volumeInfo.GetClusterIP()+v1.ControllerPort, v1.StatsAPI, &stats1)
 	if err != nil {
@@ -332,6 +394,145 @@ Capacity Stats :
 			return nil
 		}
 		w.Flush()
+
+		if err := displayIOStats(w, stats2); err != nil {
+			fmt.Println("Error in executing I/O template, found error :", err)
+			return nil
+		}
+		w.Flush()
 	}
 	return nil
 }
+
+// IOStats keeps the total I/O's of a volume and the percentage of its
+// capacity which is used.
+type IOStats struct {
+	Reads       int64
+	Writes      int64
+	ReadBytes   float64
+	WriteBytes  float64
+	UsedPercent float64
+}
+
+// newIOStats returns the IOStats of a volume from its stats, the bytes
+// are the block counts multiplied by the sector size.
+func newIOStats(stats v1.VolumeMetrics) IOStats {
+	reads, _ := strconv.ParseInt(stats.ReadIOPS, 10, 64)
+	writes, _ := strconv.ParseInt(stats.WriteIOPS, 10, 64)
+	sectorSize, _ := strconv.ParseFloat(stats.SectorSize, 64)
+	readBlocks, _ := strconv.ParseFloat(stats.TotalReadBlockCount, 64)
+	writeBlocks, _ := strconv.ParseFloat(stats.TotalWriteBlockCount, 64)
+	used, _ := strconv.ParseFloat(stats.UsedLogicalBlocks, 64)
+	size, _ := strconv.ParseFloat(stats.Size, 64)
+	return IOStats{
+		Reads:       reads,
+		Writes:      writes,
+		ReadBytes:   readBlocks * sectorSize,
+		WriteBytes:  writeBlocks * sectorSize,
+		UsedPercent: usedPercent(used, sectorSize, size),
+	}
+}
+
+// usedPercent returns the percentage of the size of a volume used by the
+// given no of logical blocks, it is at most 100.
+func usedPercent(usedBlocks, sectorSize, size float64) float64 {
+	percent, _ := v1.DivideFloat64(usedBlocks*sectorSize*100, size)
+	if percent > 100 {
+		return 100
+	}
+	return percent
+}
+
+// statsUsedPercent returns the percentage of the size of the volume which
+// is used from the stats of its controller.
+func statsUsedPercent(stats v1.VolumeStats) float64 {
+	used, _ := stats.UsedLogicalBlocks.Float64()
+	sectorSize, _ := stats.SectorSize.Float64()
+	size, _ := stats.Size.Float64()
+	return usedPercent(used, sectorSize, size)
+}
+
+// displayIOStats displays the total I/O's and the used capacity of the
+// volume as a table.
+func displayIOStats(w io.Writer, stats v1.VolumeMetrics) error {
+	const ioTemplate = `
+I/O Stats :
+-----------
+{{ printf "READS\t WRITES\t READ(BYTES)\t WRITE(BYTES)\t USED(%%)" }}
+{{ printf "------\t -------\t ------------\t -------------\t --------" }}
+{{ printf "%d\t" .Reads }} {{ printf "%d\t" .Writes }} {{ printf "%.0f\t" .ReadBytes }} {{ printf "%.0f\t" .WriteBytes }} {{ printf "%.2f\t" .UsedPercent }}
+`
+	tmpl, err := template.New("IOStats").Parse(ioTemplate)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, newIOStats(stats))
+}
+
+// displayIORates displays the I/O rates of the volume along with the
+// percentage of its size which is used as a table.
+func displayIORates(w io.Writer, volName string, rates v1.VolumeRates, usedPercent float64) error {
+	const ratesTemplate = `
+Volume Stats of {{ .Name }} (refreshed at {{ .Time }}) :
+{{ printf "READS/SEC\t WRITES/SEC\t READ(BYTES/SEC)\t WRITE(BYTES/SEC)\t USED(%%)" }}
+{{ printf "----------\t -----------\t ----------------\t -----------------\t --------" }}
+{{ with .Rates }}{{ printf "%.2f\t" .ReadIOPS }} {{ printf "%.2f\t" .WriteIOPS }} {{ printf "%.2f\t" .ReadBytesRate }} {{ printf "%.2f\t" .WriteBytesRate }}{{ end }} {{ printf "%.2f\t" .UsedPercent }}
+`
+	tmpl, err := template.New("IORates").Parse(ratesTemplate)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(w, v1.MinWidth, v1.MaxWidth, v1.Padding, ' ', 0)
+	err = tmpl.Execute(tw, struct {
+		Name        string
+		Time        string
+		Rates       v1.VolumeRates
+		UsedPercent float64
+	}{volName, time.Now().Format(time.RFC1123), rates, usedPercent})
+	if err != nil {
+		return err
+	}
+	return tw.Flush()
+}
+
+// watchVolumeStats fetches the stats of the volume every interval and
+// displays the I/O rates between the refreshes until done is closed.
+func watchVolumeStats(w io.Writer, volName string, interval time.Duration, done <-chan struct{}, fetch func() (v1.VolumeStats, error)) error {
+	prev, err := fetch()
+	if err != nil {
+		fmt.Fprintln(w, "Volume not Reachable\n", err)
+		return nil
+	}
+	prevTime := time.Now()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return nil
+		case <-ticker.C:
+		}
+		cur, err := fetch()
+		now := time.Now()
+		fmt.Fprint(w, clearScreen)
+		if err != nil {
+			fmt.Fprintln(w, "Volume not Reachable\n", err)
+			continue
+		}
+		if err := displayIORates(w, volName, cur.RatesSince(prev, now.Sub(prevTime)), statsUsedPercent(cur)); err != nil {
+			return err
+		}
+		prev, prevTime = cur, now
+	}
+}
+
+// displayStatsJSON displays the stats of the volume controller as
+// indented JSON.
+func displayStatsJSON(w io.Writer, stats v1.VolumeStats) error {
+	data, err := json.MarshalIndent(stats, "", "\t")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
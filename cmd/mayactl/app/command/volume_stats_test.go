@@ -1,7 +1,12 @@
 package command
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/openebs/maya/pkg/apis/openebs.io/v1alpha1"
 	"github.com/openebs/maya/types/v1"
@@ -928,3 +933,148 @@ func TestDisplayStats(t *testing.T) {
 	}
 
 }
+
+func TestNewIOStats(t *testing.T) {
+	cases := map[string]struct {
+		stats v1.VolumeMetrics
+		want  IOStats
+	}{
+		"volume with I/O": {
+			stats: v1.VolumeMetrics{
+				ReadIOPS:             "10",
+				WriteIOPS:            "20",
+				TotalReadBlockCount:  "3",
+				TotalWriteBlockCount: "5",
+				SectorSize:           "4096",
+				UsedLogicalBlocks:    "65536",
+				Size:                 "1073741824",
+			},
+			want: IOStats{
+				Reads:       10,
+				Writes:      20,
+				ReadBytes:   12288,
+				WriteBytes:  20480,
+				UsedPercent: 25,
+			},
+		},
+		"volume without size": {
+			stats: v1.VolumeMetrics{
+				ReadIOPS:          "1",
+				SectorSize:        "4096",
+				UsedLogicalBlocks: "10",
+				Size:              "0",
+			},
+			want: IOStats{Reads: 1},
+		},
+		"used is more than the size": {
+			stats: v1.VolumeMetrics{
+				SectorSize:        "4096",
+				UsedLogicalBlocks: "1024",
+				Size:              "4096",
+			},
+			want: IOStats{UsedPercent: 100},
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := newIOStats(tt.stats); got != tt.want {
+				t.Fatalf("newIOStats(%v) => %v, want %v", tt.stats, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDisplayIOStats(t *testing.T) {
+	var buf bytes.Buffer
+	stats := v1.VolumeMetrics{
+		ReadIOPS:             "10",
+		WriteIOPS:            "20",
+		TotalReadBlockCount:  "3",
+		TotalWriteBlockCount: "5",
+		SectorSize:           "4096",
+		UsedLogicalBlocks:    "65536",
+		Size:                 "1073741824",
+	}
+	if err := displayIOStats(&buf, stats); err != nil {
+		t.Fatalf("displayIOStats(%v) => %v, want nil", stats, err)
+	}
+	for _, want := range []string{"READS", "USED(%)", "10\t 20\t 12288\t 20480\t 25.00"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("displayIOStats(%v) => %q, want it to contain %q", stats, buf.String(), want)
+		}
+	}
+}
+
+func TestDisplayStatsJSON(t *testing.T) {
+	var buf bytes.Buffer
+	stats := v1.VolumeStats{Name: "vol1", Reads: "10", SectorSize: "4096"}
+	if err := displayStatsJSON(&buf, stats); err != nil {
+		t.Fatalf("displayStatsJSON(%v) => %v, want nil", stats, err)
+	}
+	var got v1.VolumeStats
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("displayStatsJSON(%v) => invalid json: %v", stats, err)
+	}
+	if got.Name != stats.Name || got.Reads != stats.Reads || got.SectorSize != stats.SectorSize {
+		t.Fatalf("displayStatsJSON(%v) => %v, want %v", stats, got, stats)
+	}
+}
+
+func TestStatsUsedPercent(t *testing.T) {
+	cases := map[string]struct {
+		stats v1.VolumeStats
+		want  float64
+	}{
+		"quarter of the volume is used": {
+			stats: v1.VolumeStats{UsedLogicalBlocks: "65536", SectorSize: "4096", Size: "1073741824"},
+			want:  25,
+		},
+		"used blocks exceed the size": {
+			stats: v1.VolumeStats{UsedLogicalBlocks: "524288", SectorSize: "4096", Size: "1073741824"},
+			want:  100,
+		},
+		"size is unknown": {
+			stats: v1.VolumeStats{UsedLogicalBlocks: "65536", SectorSize: "4096"},
+			want:  0,
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := statsUsedPercent(tt.stats); got != tt.want {
+				t.Fatalf("statsUsedPercent(%v) => %v, want %v", tt.stats, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWatchVolumeStats(t *testing.T) {
+	var (
+		buf   bytes.Buffer
+		calls int
+	)
+	done := make(chan struct{})
+	fetch := func() (v1.VolumeStats, error) {
+		calls++
+		switch calls {
+		case 2:
+			return v1.VolumeStats{}, errors.New("connection refused")
+		case 3:
+			close(done)
+		}
+		return v1.VolumeStats{
+			Reads:             "100",
+			Writes:            "100",
+			SectorSize:        "4096",
+			UsedLogicalBlocks: "65536",
+			Size:              "1073741824",
+		}, nil
+	}
+	if err := watchVolumeStats(&buf, "vol1", time.Millisecond, done, fetch); err != nil {
+		t.Fatalf("watchVolumeStats() => %v, want nil", err)
+	}
+	for _, want := range []string{clearScreen, "Volume not Reachable", "Volume Stats of vol1", "READS/SEC", "USED(%)", "25.00"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("watchVolumeStats() => %q, want it to contain %q", buf.String(), want)
+		}
+	}
+}
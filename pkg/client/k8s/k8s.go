@@ -34,6 +34,7 @@ import (
 
 	mach_apis_meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
 
 	//typed_oe_v1alpha1 "github.com/openebs/maya/pkg/client/clientset/versioned/typed/openebs/v1alpha1"
 	typed_oe_v1alpha1 "github.com/openebs/maya/pkg/client/generated/clientset/internalclientset/typed/openebs.io/v1alpha1"
@@ -628,6 +629,26 @@ func (k *K8sClient) GetPod(name string, opts mach_apis_meta_v1.GetOptions) (*api
 	return pops.Get(name, opts)
 }
 
+// coreV1EventOps is a utility function that provides an instance capable
+// of executing various K8s event related operations.
+func (k *K8sClient) coreV1EventOps() typed_core_v1.EventInterface {
+	return k.cs.CoreV1().Events(k.ns)
+}
+
+// ListEvents fetches the K8s Events with the provided options
+func (k *K8sClient) ListEvents(opts mach_apis_meta_v1.ListOptions) (*api_core_v1.EventList, error) {
+	eops := k.coreV1EventOps()
+	return eops.List(opts)
+}
+
+// WatchEvents watches the K8s Events with the provided options, the
+// events are received on the result channel of the returned watch until
+// it is stopped.
+func (k *K8sClient) WatchEvents(opts mach_apis_meta_v1.ListOptions) (watch.Interface, error) {
+	eops := k.coreV1EventOps()
+	return eops.Watch(opts)
+}
+
 // GetPods fetches the K8s Pods
 func (k *K8sClient) GetPods() ([]api_core_v1.Pod, error) {
 	podLists, err := k.cs.Core().Pods(k.ns).List(mach_apis_meta_v1.ListOptions{})
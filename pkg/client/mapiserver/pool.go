@@ -0,0 +1,64 @@
+/*
+Copyright 2018 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mapiserver
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+const (
+	poolPath = "/latest/pools/"
+)
+
+// PoolInfo stores the details of a cStor pool returned by the pool
+// management API of maya-apiserver.
+type PoolInfo struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	// Capacity is the total size of the pool, e.g. 10G
+	Capacity string `json:"capacity"`
+	// Replicas is the number of volume replicas placed on the pool
+	Replicas int `json:"replicas"`
+}
+
+// PoolList is the list of cStor pools returned by the pool management
+// API of maya-apiserver.
+type PoolList struct {
+	Items []PoolInfo `json:"items"`
+}
+
+// ListPools lists the cStor pools and returns them as obj
+func ListPools(obj interface{}) error {
+	body, err := getRequest(GetURL()+poolPath, "", true)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, obj)
+}
+
+// ReadPool reads the cStor pool of the given name and returns it as obj
+func ReadPool(name string, obj interface{}) error {
+	if len(name) == 0 {
+		return errors.New("Invalid pool name")
+	}
+	body, err := getRequest(GetURL()+poolPath+name, "", true)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, obj)
+}
@@ -0,0 +1,113 @@
+package mapiserver
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+
+	utiltesting "k8s.io/client-go/util/testing"
+)
+
+var (
+	poolListResponse = `{"items":[{"name":"pool1","status":"Online","capacity":"10G","replicas":2},{"name":"pool2","status":"Offline","capacity":"5G","replicas":0}]}`
+	poolResponse     = `{"name":"pool1","status":"Online","capacity":"10G","replicas":2}`
+)
+
+func TestListPools(t *testing.T) {
+	tests := map[string]*struct {
+		fakeHandler utiltesting.FakeHandler
+		pools       PoolList
+		err         error
+	}{
+		"StatusOK": {
+			fakeHandler: utiltesting.FakeHandler{
+				StatusCode:   200,
+				ResponseBody: poolListResponse,
+				T:            t,
+			},
+			pools: PoolList{Items: []PoolInfo{
+				{Name: "pool1", Status: "Online", Capacity: "10G", Replicas: 2},
+				{Name: "pool2", Status: "Offline", Capacity: "5G"},
+			}},
+		},
+		"BadRequest": {
+			fakeHandler: utiltesting.FakeHandler{
+				StatusCode:   404,
+				ResponseBody: "HTTP Error 404 : Not Found",
+				T:            t,
+			},
+			err: fmt.Errorf("HTTP Error 404 : Not Found"),
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(&tt.fakeHandler)
+			os.Setenv("MAPI_ADDR", server.URL)
+			defer os.Unsetenv("MAPI_ADDR")
+			defer server.Close()
+			var pools PoolList
+			got := ListPools(&pools)
+			if !reflect.DeepEqual(got, tt.err) {
+				t.Fatalf("ListPools() => got %v, want %v", got, tt.err)
+			}
+			if !reflect.DeepEqual(pools, tt.pools) {
+				t.Fatalf("ListPools() => got %v, want %v", pools, tt.pools)
+			}
+			tt.fakeHandler.ValidateRequest(t, poolPath, "GET", nil)
+		})
+	}
+}
+
+func TestReadPool(t *testing.T) {
+	tests := map[string]*struct {
+		name        string
+		fakeHandler utiltesting.FakeHandler
+		pool        PoolInfo
+		err         error
+	}{
+		"StatusOK": {
+			name: "pool1",
+			fakeHandler: utiltesting.FakeHandler{
+				StatusCode:   200,
+				ResponseBody: poolResponse,
+				T:            t,
+			},
+			pool: PoolInfo{Name: "pool1", Status: "Online", Capacity: "10G", Replicas: 2},
+		},
+		"NotFound": {
+			name: "pool3",
+			fakeHandler: utiltesting.FakeHandler{
+				StatusCode:   404,
+				ResponseBody: "HTTP Error 404 : Not Found",
+				T:            t,
+			},
+			err: fmt.Errorf("HTTP Error 404 : Not Found"),
+		},
+		"MissingName": {
+			fakeHandler: utiltesting.FakeHandler{
+				StatusCode: 200,
+				T:          t,
+			},
+			err: errors.New("Invalid pool name"),
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(&tt.fakeHandler)
+			os.Setenv("MAPI_ADDR", server.URL)
+			defer os.Unsetenv("MAPI_ADDR")
+			defer server.Close()
+			var pool PoolInfo
+			got := ReadPool(tt.name, &pool)
+			if !reflect.DeepEqual(got, tt.err) {
+				t.Fatalf("ReadPool(%q) => got %v, want %v", tt.name, got, tt.err)
+			}
+			if !reflect.DeepEqual(pool, tt.pool) {
+				t.Fatalf("ReadPool(%q) => got %v, want %v", tt.name, pool, tt.pool)
+			}
+		})
+	}
+}
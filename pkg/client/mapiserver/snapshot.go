@@ -18,6 +18,7 @@ package mapiserver
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"os"
@@ -43,15 +44,20 @@ Snapshot Details:
 `
 )
 
+// ErrSnapshotExists is returned if the volume already has a snapshot of the
+// given name
+var ErrSnapshotExists = errors.New("snapshot already exists")
+
 // SnapshotInfo stores the details of snapshot
 type SnapshotInfo struct {
-	Name    string
-	Created string
-	Size    string
+	Name    string `json:"name"`
+	Created string `json:"created"`
+	// Size is the size of the snapshot in MB
+	Size string `json:"size"`
 	// Parent keeps most recently saved version of current state of volume
-	Parent string
+	Parent string `json:"parent"`
 	// Child keeps latest saved version of volume
-	Children []string
+	Children []string `json:"children"`
 }
 
 // CreateSnapshot creates a snapshot of volume by API request to m-apiserver
@@ -105,50 +111,82 @@ func RevertSnapshot(volName string, snapName string, namespace string) error {
 // ListSnapshot lists snapshots of volume by API request to m-apiserver
 func ListSnapshot(volName string, namespace string) error {
 
-	body, err := getRequest(GetURL()+snapshotListPath+volName, namespace, false)
+	snapshotList, err := GetSnapshots(volName, namespace)
 	if err != nil {
 		return err
 	}
-	snapdisk, err := getInfo(body)
-	if err != nil {
-		return fmt.Errorf("Failed to get the snapshot info, found error - %v", err)
-	}
 
-	if len(snapdisk) == 1 || len(snapdisk) == 0 {
+	if len(snapshotList) == 0 {
 		fmt.Println("No snapshots available. \nUse `mayactl snapshot create --volname <vol-name> --snapname <snap-name>` to create snapshot")
 		return nil
 	}
 
-	snapshotList := make([]SnapshotInfo, len(snapdisk)-1)
+	err = ChangeDateFormatToUnixDate(snapshotList)
+	if err != nil {
+		return fmt.Errorf("Error changing date format to UnixDate, found error - %v", err)
+	}
+
+	err = displayVolumeSnapshot(snapshotList)
+	if err != nil {
+		fmt.Println("Error displaying snapshot list, found error - ", err)
+		return err
+	}
+
+	return nil
+}
+
+// GetSnapshots returns the snapshots of volume ordered by their creation
+// time by API request to m-apiserver
+func GetSnapshots(volName string, namespace string) ([]SnapshotInfo, error) {
 
-	i := 0
+	body, err := getRequest(GetURL()+snapshotListPath+volName, namespace, false)
+	if err != nil {
+		return nil, err
+	}
+	snapdisk, err := getInfo(body)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get the snapshot info, found error - %v", err)
+	}
+
+	snapshotList := []SnapshotInfo{}
 	for _, disk := range snapdisk {
 		if !client.IsHeadDisk(disk.Name) {
 			size, _ := strconv.ParseFloat(disk.Size, 64)
 			size = size / v1.BytesToMB
-			snapshotList[i] = SnapshotInfo{
+			snapshotList = append(snapshotList, SnapshotInfo{
 				Name:     client.TrimSnapshotName(disk.Name),
 				Created:  disk.Created,
 				Size:     fmt.Sprintf("%.4f", size),
 				Parent:   client.TrimSnapshotName(disk.Parent),
 				Children: client.TrimSnapshotNamesOfSlice(disk.Children),
-			}
-			i++
+			})
 		}
 	}
 	SortSnapshotDisksByDateTime(snapshotList)
-	err = ChangeDateFormatToUnixDate(snapshotList)
+	return snapshotList, nil
+}
+
+// GetSnapshot returns the snapshot of the volume with the given name by API
+// request to m-apiserver, found is false if the volume has no such snapshot
+// or if it is removed
+func GetSnapshot(volName string, snapName string, namespace string) (snap client.DiskInfo, found bool, err error) {
+	body, err := getRequest(GetURL()+snapshotListPath+volName, namespace, false)
 	if err != nil {
-		return fmt.Errorf("Error changing date format to UnixDate, found error - %v", err)
+		return snap, false, err
 	}
-
-	err = displayVolumeSnapshot(snapshotList)
+	snapdisk, err := getInfo(body)
 	if err != nil {
-		fmt.Println("Error displaying snapshot list, found error - ", err)
-		return err
+		return snap, false, fmt.Errorf("Failed to get the snapshot info, found error - %v", err)
 	}
-
-	return nil
+	for _, disk := range snapdisk {
+		if client.IsHeadDisk(disk.Name) || disk.Removed {
+			continue
+		}
+		if client.TrimSnapshotName(disk.Name) == snapName {
+			return disk, true, nil
+		}
+	}
+	return snap, false, nil
 }
 
 // getInfo unmarshal http response body to DiskInfo struct
@@ -334,3 +334,103 @@ func TestListSnapshot(t *testing.T) {
 		})
 	}
 }
+
+func TestGetSnapshot(t *testing.T) {
+	tests := map[string]*struct {
+		snapName    string
+		fakeHandler utiltesting.FakeHandler
+		found       bool
+		err         error
+	}{
+		"Found": {
+			snapName: "snap2",
+			fakeHandler: utiltesting.FakeHandler{
+				StatusCode:   200,
+				ResponseBody: SnapshotListResponse,
+				T:            t,
+			},
+			found: true,
+		},
+		"NotFound": {
+			snapName: "snap4",
+			fakeHandler: utiltesting.FakeHandler{
+				StatusCode:   200,
+				ResponseBody: SnapshotListResponse,
+				T:            t,
+			},
+		},
+		"HeadIsNotASnapshot": {
+			snapName: "head-001",
+			fakeHandler: utiltesting.FakeHandler{
+				StatusCode:   200,
+				ResponseBody: ZeroSnapshotResponse,
+				T:            t,
+			},
+		},
+		"NoResponse": {
+			snapName: "snap1",
+			fakeHandler: utiltesting.FakeHandler{
+				StatusCode: 500,
+				T:          t,
+			},
+			err: fmt.Errorf("Server status error: %v", http.StatusText(500)),
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(&tt.fakeHandler)
+			os.Setenv("MAPI_ADDR", server.URL)
+			defer os.Unsetenv("MAPI_ADDR")
+			defer server.Close()
+			snap, found, err := GetSnapshot("testvol", tt.snapName, "")
+			if !reflect.DeepEqual(err, tt.err) {
+				t.Fatalf("GetSnapshot(%q) => got error %v, want %v", tt.snapName, err, tt.err)
+			}
+			if found != tt.found {
+				t.Fatalf("GetSnapshot(%q) => got found %v, want %v", tt.snapName, found, tt.found)
+			}
+			if found && snap.Name != "volume-snap-"+tt.snapName+".img" {
+				t.Fatalf("GetSnapshot(%q) => got snapshot %q", tt.snapName, snap.Name)
+			}
+		})
+	}
+}
+
+func TestGetSnapshots(t *testing.T) {
+	tests := map[string]struct {
+		response string
+		names    []string
+	}{
+		"Snapshots are ordered by creation time": {
+			response: `{"volume-snap-snap1.img": {"name": "volume-snap-snap1.img", "children":["volume-snap-snap2.img"], "created": "2018-06-10T19:33:34Z", "size": "0"}, "volume-snap-snap2.img": {"name": "volume-snap-snap2.img", "parent": "volume-snap-snap1.img", "children":["volume-head-002.img"], "created": "2018-06-12T19:33:34Z", "size": "0"}, "volume-head-002.img": {"name": "volume-head-002.img", "parent": "volume-snap-snap2.img", "created": "2018-06-12T19:33:34Z", "size": "0"}}`,
+			names:    []string{"snap1", "snap2"},
+		},
+		"Head is not listed": {
+			response: ZeroSnapshotResponse,
+			names:    []string{},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(&utiltesting.FakeHandler{
+				StatusCode:   200,
+				ResponseBody: tt.response,
+				T:            t,
+			})
+			os.Setenv("MAPI_ADDR", server.URL)
+			defer os.Unsetenv("MAPI_ADDR")
+			defer server.Close()
+			snapshots, err := GetSnapshots("testvol", "")
+			if err != nil {
+				t.Fatalf("GetSnapshots() => %v", err)
+			}
+			names := []string{}
+			for _, snap := range snapshots {
+				names = append(names, snap.Name)
+			}
+			if !reflect.DeepEqual(names, tt.names) {
+				t.Fatalf("GetSnapshots() => got %v, want %v", names, tt.names)
+			}
+		})
+	}
+}
@@ -823,6 +823,16 @@ func AsTemplatedBytes(context string, yml string, values map[string]interface{})
 	return buf.Bytes(), nil
 }
 
+// Parse parses the provided yaml as a template with the templating
+// functions of this library without executing it, it returns the syntax
+// errors of the template e.g. a call of an unknown function
+func Parse(context string, yml string) error {
+	tpl := template.New(context + "YamlTpl")
+	tpl.Funcs(allCustomFuncs())
+	_, err := tpl.Parse(yml)
+	return err
+}
+
 // AsMapOfObjects returns a map of objects based on the provided yaml & values
 func AsMapOfObjects(yml string, values map[string]interface{}) (map[string]interface{}, error) {
 	// templated & then unmarshall-ed version of this yaml
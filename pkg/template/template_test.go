@@ -2175,3 +2175,34 @@ func TestSplitListTrim(t *testing.T) {
 		})
 	}
 }
+
+func TestParse(t *testing.T) {
+	tests := map[string]struct {
+		yml   string
+		isErr bool
+	}{
+		"Template with library functions": {
+			yml: `id: {{ .Volume.owner | default "" | splitList ", " | toYaml }}`,
+		},
+		"Template without actions": {
+			yml: "id: list",
+		},
+		"Template with unknown function": {
+			yml:   `id: {{ .Volume.owner | unknownFunc }}`,
+			isErr: true,
+		},
+		"Template with unclosed action": {
+			yml:   `id: {{ .Volume.owner`,
+			isErr: true,
+		},
+	}
+
+	for name, mock := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := Parse("test", mock.yml)
+			if (err != nil) != mock.isErr {
+				t.Fatalf("failed to test Parse: expected error '%v': actual '%v'", mock.isErr, err)
+			}
+		})
+	}
+}
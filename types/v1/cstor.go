@@ -0,0 +1,84 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// istgtCommand is the command written to the istgt socket to get the
+	// stats of a cstor volume, the response is prefixed with it.
+	istgtCommand = "IOSTATS"
+	// istgtFooter ends the response of istgt to the IOSTATS command.
+	istgtFooter = "OK IOSTATS"
+	// istgtEOF separates the lines of the response of istgt.
+	istgtEOF = "\r\n"
+)
+
+// ErrEmptyCstorVolumeStats is returned by ParseCstorVolumeStats if the
+// response of istgt doesn't contain any stats.
+var ErrEmptyCstorVolumeStats = errors.New("empty IOSTATS response from istgt")
+
+// CstorVolumeStats is used to store the stats of a cstor volume as they
+// are returned by istgt for the IOSTATS command, e.g.
+//
+//	IOSTATS  { "iqn": "iqn.2017-08.OpenEBS.cstor:vol1", "WriteIOPS": "0",
+//	"ReadIOPS": "0", "TotalWriteBytes": "0", "TotalReadBytes": "0",
+//	"Size": "10737418240", "UsedLogicalBlocks": "19", ... }\r\nOK IOSTATS\r\n
+type CstorVolumeStats struct {
+	Iqn             string     `json:"iqn"`
+	Reads           FlexNumber `json:"ReadIOPS"`
+	Writes          FlexNumber `json:"WriteIOPS"`
+	TotalReadBytes  FlexNumber `json:"TotalReadBytes"`
+	TotalWriteBytes FlexNumber `json:"TotalWriteBytes"`
+	TotalReadTime   FlexNumber `json:"TotalReadTime"`
+	TotalWriteTime  FlexNumber `json:"TotalWriteTime"`
+
+	TotalReadBlockCount FlexNumber `json:"TotalReadBlockCount"`
+	// TotalWriteBlockCount is misspelled by istgt.
+	TotalWriteBlockCount FlexNumber `json:"TotatWriteBlockCount"`
+
+	UsedLogicalBlocks FlexNumber `json:"UsedLogicalBlocks"`
+	SectorSize        FlexNumber `json:"SectorSize"`
+	Size              FlexNumber `json:"Size"`
+	Uptime            FlexNumber `json:"Uptime"`
+}
+
+// ParseCstorVolumeStats parses the response of istgt to the IOSTATS
+// command. The command prefix and the "OK IOSTATS" footer are optional
+// so that the bare JSON payload can be parsed as well.
+func ParseCstorVolumeStats(response string) (CstorVolumeStats, error) {
+	stats := CstorVolumeStats{}
+	payload := strings.TrimSpace(response)
+	payload = strings.TrimSuffix(payload, istgtFooter)
+	payload = strings.TrimSpace(strings.TrimPrefix(payload, istgtCommand))
+	if len(payload) == 0 {
+		return stats, ErrEmptyCstorVolumeStats
+	}
+	if err := json.Unmarshal([]byte(payload), &stats); err != nil {
+		return CstorVolumeStats{}, fmt.Errorf("invalid IOSTATS response from istgt: %w", err)
+	}
+	return stats, nil
+}
+
+// VolumeStats maps the stats of the cstor volume into the VolumeStats
+// shared with jiva.
+func (s CstorVolumeStats) VolumeStats() VolumeStats {
+	return VolumeStats{
+		Iqn:                  s.Iqn,
+		Reads:                s.Reads,
+		Writes:               s.Writes,
+		TotalReadBytes:       s.TotalReadBytes,
+		TotalWriteBytes:      s.TotalWriteBytes,
+		TotalReadTime:        s.TotalReadTime,
+		TotalWriteTime:       s.TotalWriteTime,
+		TotalReadBlockCount:  s.TotalReadBlockCount,
+		TotalWriteBlockCount: s.TotalWriteBlockCount,
+		UsedLogicalBlocks:    s.UsedLogicalBlocks,
+		SectorSize:           s.SectorSize,
+		Size:                 s.Size,
+		CstorUptime:          s.Uptime,
+	}
+}
@@ -0,0 +1,93 @@
+package v1
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// istgtResponse is a response of istgt to the IOSTATS command captured
+// from a cstor target.
+const istgtResponse = "IOSTATS  { \"iqn\": \"iqn.2017-08.OpenEBS.cstor:vol1\", \"WriteIOPS\": \"15\", \"ReadIOPS\": \"12\", \"TotalWriteBytes\": \"7680\", \"TotalReadBytes\": \"6144\", \"Size\": \"10737418240\", \"UsedLogicalBlocks\":\"19\", \"SectorSize\":\"512\", \"Uptime\":\"20\", \"TotalReadBlockCount\":\"12\", \"TotatWriteBlockCount\":\"15\", \"TotalReadTime\":\"13\", \"TotalWriteTime\":\"132\" }\r\nOK IOSTATS\r\n"
+
+func TestParseCstorVolumeStats(t *testing.T) {
+	stats := CstorVolumeStats{
+		Iqn:                  "iqn.2017-08.OpenEBS.cstor:vol1",
+		Reads:                "12",
+		Writes:               "15",
+		TotalReadBytes:       "6144",
+		TotalWriteBytes:      "7680",
+		TotalReadTime:        "13",
+		TotalWriteTime:       "132",
+		TotalReadBlockCount:  "12",
+		TotalWriteBlockCount: "15",
+		UsedLogicalBlocks:    "19",
+		SectorSize:           "512",
+		Size:                 "10737418240",
+		Uptime:               "20",
+	}
+	cases := map[string]struct {
+		response string
+		stats    CstorVolumeStats
+		err      error
+	}{
+		"[Success] captured istgt response": {
+			response: istgtResponse,
+			stats:    stats,
+		},
+		"[Success] bare json payload": {
+			response: `{"iqn": "iqn.2017-08.OpenEBS.cstor:vol1", "ReadIOPS": "12", "UsedLogicalBlocks": "19"}`,
+			stats: CstorVolumeStats{
+				Iqn:               "iqn.2017-08.OpenEBS.cstor:vol1",
+				Reads:             "12",
+				UsedLogicalBlocks: "19",
+			},
+		},
+		"[Failure] response without stats": {
+			response: "OK IOSTATS\r\n",
+			err:      ErrEmptyCstorVolumeStats,
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseCstorVolumeStats(tt.response)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ParseCstorVolumeStats(%q) => error %v, want %v", tt.response, err, tt.err)
+			}
+			if !reflect.DeepEqual(got, tt.stats) {
+				t.Fatalf("ParseCstorVolumeStats(%q) => %+v, want %+v", tt.response, got, tt.stats)
+			}
+		})
+	}
+}
+
+func TestParseCstorVolumeStatsInvalidJSON(t *testing.T) {
+	if _, err := ParseCstorVolumeStats("IOSTATS  { \"iqn\": }\r\nOK IOSTATS\r\n"); err == nil {
+		t.Fatal("ParseCstorVolumeStats() => no error for invalid json")
+	}
+}
+
+func TestCstorVolumeStatsToVolumeStats(t *testing.T) {
+	stats, err := ParseCstorVolumeStats(istgtResponse)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := VolumeStats{
+		Iqn:                  "iqn.2017-08.OpenEBS.cstor:vol1",
+		Reads:                "12",
+		Writes:               "15",
+		TotalReadBytes:       "6144",
+		TotalWriteBytes:      "7680",
+		TotalReadTime:        "13",
+		TotalWriteTime:       "132",
+		TotalReadBlockCount:  "12",
+		TotalWriteBlockCount: "15",
+		UsedLogicalBlocks:    "19",
+		SectorSize:           "512",
+		Size:                 "10737418240",
+		CstorUptime:          "20",
+	}
+	if got := stats.VolumeStats(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("VolumeStats() => %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,78 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FlexNumber is a number which is encoded in JSON either as a number,
+// e.g. "ReplicaCounter":6, or as a string, e.g. "ReadIOPS":"1", since
+// the controllers encode the stats inconsistently and are moving from
+// the string to the numeric encoding. An empty string or null is
+// decoded as an empty FlexNumber, which is not a valid number, so that
+// the missing stats can be told apart from the stats which are 0.
+type FlexNumber string
+
+// String returns the literal text of the number.
+func (n FlexNumber) String() string { return string(n) }
+
+// Float64 returns the number as a float64.
+func (n FlexNumber) Float64() (float64, error) {
+	return strconv.ParseFloat(string(n), 64)
+}
+
+// Int64 returns the number as an int64.
+func (n FlexNumber) Int64() (int64, error) {
+	return strconv.ParseInt(string(n), 10, 64)
+}
+
+// UnmarshalJSON decodes a JSON number, a string holding a JSON number
+// or an empty string, and null. It returns error for the strings which
+// are not numbers and for any other JSON value.
+func (n *FlexNumber) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		*n = ""
+		return nil
+	}
+	literal := string(data)
+	if len(data) != 0 && data[0] == '"' {
+		if err := json.Unmarshal(data, &literal); err != nil {
+			return err
+		}
+		literal = strings.TrimSpace(literal)
+		if len(literal) == 0 {
+			*n = ""
+			return nil
+		}
+	}
+	if !isNumber(literal) {
+		return fmt.Errorf("invalid number %s", data)
+	}
+	*n = FlexNumber(literal)
+	return nil
+}
+
+// MarshalJSON encodes the number as a JSON number, an empty FlexNumber
+// is encoded as 0 like json.Number does.
+func (n FlexNumber) MarshalJSON() ([]byte, error) {
+	if len(n) == 0 {
+		return []byte("0"), nil
+	}
+	if !isNumber(string(n)) {
+		return nil, fmt.Errorf("invalid number %q", string(n))
+	}
+	return []byte(n), nil
+}
+
+// isNumber returns true if s is a JSON number literal.
+func isNumber(s string) bool {
+	if len(s) == 0 || !(s[0] == '-' || (s[0] >= '0' && s[0] <= '9')) {
+		return false
+	}
+	var number json.Number
+	return json.Unmarshal([]byte(s), &number) == nil
+}
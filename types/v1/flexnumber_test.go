@@ -0,0 +1,96 @@
+package v1
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestFlexNumberUnmarshalJSON(t *testing.T) {
+	cases := map[string]struct {
+		json   string
+		number FlexNumber
+		isErr  bool
+	}{
+		"[Success] integer":                {json: `6`, number: "6"},
+		"[Success] float":                  {json: `158.667823193`, number: "158.667823193"},
+		"[Success] negative exponent":      {json: `-1.5e-3`, number: "-1.5e-3"},
+		"[Success] integer string":         {json: `"1"`, number: "1"},
+		"[Success] float string":           {json: `"4.5"`, number: "4.5"},
+		"[Success] string with whitespace": {json: `" 10 "`, number: "10"},
+		"[Success] empty string":           {json: `""`, number: ""},
+		"[Success] blank string":           {json: `"  "`, number: ""},
+		"[Success] null":                   {json: `null`, number: ""},
+		"[Failure] invalid string":         {json: `"invalid"`, isErr: true},
+		"[Failure] hex string":             {json: `"0x10"`, isErr: true},
+		"[Failure] nested string":          {json: `"\"1\""`, isErr: true},
+		"[Failure] boolean":                {json: `true`, isErr: true},
+		"[Failure] object":                 {json: `{}`, isErr: true},
+		"[Failure] array":                  {json: `[1]`, isErr: true},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			var got FlexNumber
+			err := json.Unmarshal([]byte(tt.json), &got)
+			if (err != nil) != tt.isErr {
+				t.Fatalf("Unmarshal(%s) => got error %v, want error %v", tt.json, err, tt.isErr)
+			}
+			if got != tt.number {
+				t.Fatalf("Unmarshal(%s) => got %q, want %q", tt.json, got, tt.number)
+			}
+		})
+	}
+}
+
+func TestFlexNumberMixedEncodings(t *testing.T) {
+	response := `{"Name":"vol1","ReadIOPS":"5","WriteIOPS":11,"ReplicaCounter":2,"RevisionCounter":"10","SectorSize":"4096","Size":1073741824,"TotalReadBlockCount":"","TotatWriteBlockCount":null,"UpTime":158.5}`
+	want := VolumeStats{
+		Name:            "vol1",
+		Reads:           "5",
+		Writes:          "11",
+		ReplicaCounter:  "2",
+		RevisionCounter: "10",
+		SectorSize:      "4096",
+		Size:            "1073741824",
+		UpTime:          158.5,
+	}
+	var got VolumeStats
+	if err := json.Unmarshal([]byte(response), &got); err != nil {
+		t.Fatalf("Unmarshal() => got error %v, want nil", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Unmarshal() => got %+v, want %+v", got, want)
+	}
+	if _, err := got.TotalReadBlockCount.Float64(); err == nil {
+		t.Fatalf("Float64() of an empty string => got nil error, want error")
+	}
+	if reads, err := got.Reads.Int64(); err != nil || reads != 5 {
+		t.Fatalf("Int64() => got %v, %v, want 5, nil", reads, err)
+	}
+	if size, err := got.Size.Float64(); err != nil || size != 1073741824 {
+		t.Fatalf("Float64() => got %v, %v, want 1073741824, nil", size, err)
+	}
+}
+
+func TestFlexNumberMarshalJSON(t *testing.T) {
+	cases := map[string]struct {
+		number FlexNumber
+		json   string
+		isErr  bool
+	}{
+		"[Success] number":     {number: "4.5", json: `4.5`},
+		"[Success] empty":      {number: "", json: `0`},
+		"[Failure] not number": {number: "invalid", isErr: true},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := json.Marshal(tt.number)
+			if (err != nil) != tt.isErr {
+				t.Fatalf("Marshal(%q) => got error %v, want error %v", tt.number, err, tt.isErr)
+			}
+			if string(got) != tt.json {
+				t.Fatalf("Marshal(%q) => got %s, want %s", tt.number, got, tt.json)
+			}
+		})
+	}
+}
@@ -1,7 +1,5 @@
 package v1
 
-import "encoding/json"
-
 // VolumeMetrics is used to store the collected metrics
 // all the stats exposed by jiva stored into OpenEBSVolumeMetrics fields
 type VolumeMetrics struct {
@@ -30,24 +28,93 @@ type VolumeMetrics struct {
 // TODO: Make this generic, so that it can be used by mayactl
 // and other components of maya.
 type VolumeStats struct {
-	Iqn                 string      `json:"iqn"`
-	Reads               json.Number `json:"ReadIOPS"`
-	TotalReadTime       json.Number `json:"TotalReadTime"`
-	TotalReadBlockCount json.Number `json:"TotalReadBlockCount"`
-	TotalReadBytes      json.Number `json:"TotalReadBytes"`
-
-	Writes               json.Number `json:"WriteIOPS"`
-	TotalWriteTime       json.Number `json:"TotalWriteTime"`
-	TotalWriteBlockCount json.Number `json:"TotatWriteBlockCount"`
-	TotalWriteBytes      json.Number `json:"TotalWriteBytes"`
-
-	UsedLogicalBlocks json.Number `json:"UsedLogicalBlocks"`
-	UsedBlocks        json.Number `json:"UsedBlocks"`
-	SectorSize        json.Number `json:"SectorSize"`
-	Size              json.Number `json:"Size"`
-	UpTime            float64     `json:"UpTime"`
-	CstorUptime       json.Number `json:"Uptime"`
-	Name              string      `json:"Name"`
+	Iqn                 string     `json:"iqn"`
+	Reads               FlexNumber `json:"ReadIOPS"`
+	TotalReadTime       FlexNumber `json:"TotalReadTime"`
+	TotalReadBlockCount FlexNumber `json:"TotalReadBlockCount"`
+	TotalReadBytes      FlexNumber `json:"TotalReadBytes"`
+
+	Writes               FlexNumber `json:"WriteIOPS"`
+	TotalWriteTime       FlexNumber `json:"TotalWriteTime"`
+	TotalWriteBlockCount FlexNumber `json:"TotatWriteBlockCount"`
+	TotalWriteBytes      FlexNumber `json:"TotalWriteBytes"`
+
+	UsedLogicalBlocks FlexNumber `json:"UsedLogicalBlocks"`
+	UsedBlocks        FlexNumber `json:"UsedBlocks"`
+	SectorSize        FlexNumber `json:"SectorSize"`
+	Size              FlexNumber `json:"Size"`
+	UpTime            float64    `json:"UpTime"`
+	CstorUptime       FlexNumber `json:"Uptime"`
+	Name              string     `json:"Name"`
+	// RevisionCounter and ReplicaCounter are encoded either as
+	// strings or as numbers by the controller, FlexNumber
+	// handles both the encodings.
+	RevisionCounter FlexNumber `json:"RevisionCounter"`
+	ReplicaCounter  FlexNumber `json:"ReplicaCounter"`
+	// SCSIIOCount keeps the count of the SCSI commands indexed by
+	// their opcode, it is null or empty if there are none.
+	SCSIIOCount map[int]int64 `json:"SCSIIOCount"`
+	// PendingIO and QueueDepth are the no of ios pending in and the
+	// depth of the io queue of the controller, these are sent only by
+	// the controllers which track them and are empty otherwise, so
+	// these are omitted in the JSON if empty.
+	PendingIO  FlexNumber `json:"PendingIO,omitempty"`
+	QueueDepth FlexNumber `json:"QueueDepth,omitempty"`
+	// The sequential and random reads and writes are sent only by the
+	// newer controllers which differentiate them, these are empty
+	// otherwise.
+	SequentialReads  FlexNumber `json:"SequentialReadIOPS,omitempty"`
+	RandomReads      FlexNumber `json:"RandomReadIOPS,omitempty"`
+	SequentialWrites FlexNumber `json:"SequentialWriteIOPS,omitempty"`
+	RandomWrites     FlexNumber `json:"RandomWriteIOPS,omitempty"`
+	// ReadErrors and WriteErrors are the cumulative no of reads and
+	// writes which have failed, these are sent only by the controllers
+	// which count them and are empty otherwise.
+	ReadErrors  FlexNumber `json:"ReadErrors,omitempty"`
+	WriteErrors FlexNumber `json:"WriteErrors,omitempty"`
+	// Version is the version of the controller, it is sent only by the
+	// controllers which report it.
+	Version string `json:"Version,omitempty"`
+	// ActiveSessions is the no of iSCSI sessions of the initiators
+	// connected to the controller, it is sent only by the controllers
+	// which track them and is empty otherwise.
+	ActiveSessions FlexNumber `json:"ActiveSessions,omitempty"`
+}
+
+// ReplicaCollection is used to store the list of replicas returned by
+// the jiva controller on /v1/replicas.
+type ReplicaCollection struct {
+	Data []Replica `json:"data"`
+}
+
+// Replica keeps the address and the mode (RW, WO or ERR) of a jiva
+// replica as reported by the controller.
+type Replica struct {
+	Resource
+	Address string `json:"address"`
+	Mode    string `json:"mode"`
+	// LastConnected is the RFC 3339 time at which the replica has last
+	// connected with the controller, it is empty if the controller
+	// doesn't report it.
+	LastConnected string `json:"lastconnected,omitempty"`
+}
+
+// ReplicaStats is used to store the stats exposed by a jiva replica.
+type ReplicaStats struct {
+	Resource
+	ReadIOPS  FlexNumber `json:"ReadIOPS"`
+	WriteIOPS FlexNumber `json:"WriteIOPS"`
+	// Rebuilding and RebuildProgress are reported only by the replicas
+	// which support it, they are nil and empty otherwise.
+	Rebuilding      *bool      `json:"rebuilding,omitempty"`
+	RebuildProgress FlexNumber `json:"rebuildProgress,omitempty"`
+	// RebuildBytesRemaining is the no of bytes remaining to be rebuilt,
+	// it is reported by the replicas which count the rebuilt bytes
+	// rather than or along with the progress.
+	RebuildBytesRemaining FlexNumber `json:"rebuildBytesRemaining,omitempty"`
+	// RevisionCounter is the no of writes applied to the replica, the
+	// replicas of a healthy volume have the same revision counter.
+	RevisionCounter FlexNumber `json:"revisioncounter,omitempty"`
 }
 
 type VolStatus struct {
@@ -0,0 +1,63 @@
+package v1
+
+import "time"
+
+// VolumeRates is used to store the per second rates of the cumulative
+// counters of VolumeStats between two collections of the stats.
+type VolumeRates struct {
+	ReadIOPS       float64 `json:"ReadIOPS"`
+	WriteIOPS      float64 `json:"WriteIOPS"`
+	ReadBytesRate  float64 `json:"ReadBytesRate"`
+	WriteBytesRate float64 `json:"WriteBytesRate"`
+}
+
+// RatesSince returns the per second rates of read/write IOPS and bytes
+// between prev and s, where s has been collected interval after prev.
+// A counter which went down since prev, e.g. after a restart of the
+// controller, is reported as a rate of 0.
+func (s VolumeStats) RatesSince(prev VolumeStats, interval time.Duration) VolumeRates {
+	if interval <= 0 {
+		return VolumeRates{}
+	}
+	rate := func(prev, cur float64) float64 {
+		if cur < prev {
+			return 0
+		}
+		return (cur - prev) / interval.Seconds()
+	}
+	return VolumeRates{
+		ReadIOPS:       rate(number(prev.Reads), number(s.Reads)),
+		WriteIOPS:      rate(number(prev.Writes), number(s.Writes)),
+		ReadBytesRate:  rate(prev.readBytes(), s.readBytes()),
+		WriteBytesRate: rate(prev.writeBytes(), s.writeBytes()),
+	}
+}
+
+// readBytes returns the total bytes read from the volume. cstor reports
+// them as TotalReadBytes whereas jiva only reports the read blocks.
+func (s VolumeStats) readBytes() float64 {
+	if s.TotalReadBytes != "" {
+		return number(s.TotalReadBytes)
+	}
+	return number(s.TotalReadBlockCount) * number(s.SectorSize)
+}
+
+// writeBytes returns the total bytes written to the volume. cstor
+// reports them as TotalWriteBytes whereas jiva only reports the written
+// blocks.
+func (s VolumeStats) writeBytes() float64 {
+	if s.TotalWriteBytes != "" {
+		return number(s.TotalWriteBytes)
+	}
+	return number(s.TotalWriteBlockCount) * number(s.SectorSize)
+}
+
+// number returns the value of n as float64, or 0 if n is not a valid
+// number.
+func number(n FlexNumber) float64 {
+	f, err := n.Float64()
+	if err != nil {
+		return 0
+	}
+	return f
+}
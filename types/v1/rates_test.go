@@ -0,0 +1,73 @@
+package v1
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRatesSince(t *testing.T) {
+	prev := VolumeStats{
+		Reads:                "10",
+		Writes:               "20",
+		TotalReadBlockCount:  "2",
+		TotalWriteBlockCount: "4",
+		SectorSize:           "4096",
+	}
+	cases := map[string]struct {
+		cur      VolumeStats
+		prev     VolumeStats
+		interval time.Duration
+		want     VolumeRates
+	}{
+		"[Success] delta between the stats": {
+			cur: VolumeStats{
+				Reads:                "30",
+				Writes:               "60",
+				TotalReadBlockCount:  "6",
+				TotalWriteBlockCount: "12",
+				SectorSize:           "4096",
+			},
+			prev:     prev,
+			interval: 2 * time.Second,
+			want: VolumeRates{
+				ReadIOPS:       10,
+				WriteIOPS:      20,
+				ReadBytesRate:  8192,
+				WriteBytesRate: 16384,
+			},
+		},
+		"[Success] delta of the bytes reported by cstor": {
+			cur:      VolumeStats{TotalReadBytes: "3072", TotalWriteBytes: "2048"},
+			prev:     VolumeStats{TotalReadBytes: "1024", TotalWriteBytes: "1024"},
+			interval: time.Second,
+			want: VolumeRates{
+				ReadBytesRate:  2048,
+				WriteBytesRate: 1024,
+			},
+		},
+		"[Success] counters are reset after a restart of the controller": {
+			cur: VolumeStats{
+				Reads:                "1",
+				Writes:               "40",
+				TotalReadBlockCount:  "1",
+				TotalWriteBlockCount: "1",
+				SectorSize:           "4096",
+			},
+			prev:     prev,
+			interval: 2 * time.Second,
+			want:     VolumeRates{WriteIOPS: 10},
+		},
+		"[Success] no interval": {
+			cur:  VolumeStats{Reads: "30"},
+			prev: prev,
+			want: VolumeRates{},
+		},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := tt.cur.RatesSince(tt.prev, tt.interval); got != tt.want {
+				t.Fatalf("RatesSince(%v, %v) => %v, want %v", tt.prev, tt.interval, got, tt.want)
+			}
+		})
+	}
+}